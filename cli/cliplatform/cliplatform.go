@@ -33,6 +33,9 @@ func IsSplitTunRunsApp() bool {
 	}
 	return runtime.GOOS == "linux"
 }
+func IsSplitTunDestinationsSupported() bool {
+	return runtime.GOOS == "linux"
+}
 func IsDnsOverHttpsSupported() bool {
 	return true
 }
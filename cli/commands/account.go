@@ -111,12 +111,16 @@ func doLogin(accountID string, force bool) error {
 		}
 
 		if err != nil {
+			if apiStatus == types.Unauthorized || apiStatus == types.The2FAInvalidToken {
+				return ErrorAuth{Err: err}
+			}
 			return err
 		}
 	}
 
 	fmt.Println("Logged in")
 	PrintTips([]TipType{TipServers, TipConnectHelp})
+	setJsonData(JsonAccount{LoggedIn: true, AccountID: _proto.GetHelloResponse().Session.AccountID})
 
 	return nil
 }
@@ -200,6 +204,7 @@ func doLogout(disableFirewall bool, resetAppSettingsToDefaults bool) error {
 
 		if yn != "Y" && yn != "YES" {
 			fmt.Println("Cancelled")
+			setJsonData(JsonAccount{LoggedIn: true, AccountID: helloResp.Session.AccountID})
 			return nil
 		}
 
@@ -213,6 +218,7 @@ func doLogout(disableFirewall bool, resetAppSettingsToDefaults bool) error {
 
 	fmt.Println("Logged out")
 	PrintTips([]TipType{TipLogin})
+	setJsonData(JsonAccount{LoggedIn: false})
 
 	return nil
 }
@@ -258,5 +264,12 @@ func checkStatus() error {
 	}
 	w.Flush()
 
+	jsonAcc := JsonAccount{LoggedIn: true, AccountID: helloResp.Session.AccountID, Plan: acc.CurrentPlan, IsFreeTrial: acc.IsFreeTrial,
+		ActiveUntil: acc.ActiveUntil, DevicesLimit: acc.Limit}
+	if acc.Upgradable {
+		jsonAcc.UpgradeToPlan, jsonAcc.UpgradeToURL = acc.UpgradeToPlan, acc.UpgradeToURL
+	}
+	setJsonData(jsonAcc)
+
 	return nil
 }
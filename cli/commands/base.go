@@ -106,6 +106,28 @@ func printState(w *tabwriter.Writer, state vpn.State, connected types.ConnectedR
 	return w
 }
 
+func printConnectionStats(w *tabwriter.Writer, stats types.ConnectionStatsResp) *tabwriter.Writer {
+	if w == nil {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	}
+
+	if len(stats.StateDetails) > 0 {
+		fmt.Fprintf(w, "    State details\t:\t%v\n", stats.StateDetails)
+	}
+	if stats.ConnectedSince > 0 {
+		fmt.Fprintf(w, "    Connected time\t:\t%v\n", time.Since(time.Unix(stats.ConnectedSince, 0)).Truncate(time.Second))
+	}
+	if stats.LastHandshake > 0 {
+		fmt.Fprintf(w, "    Latest handshake\t:\t%v ago\n", time.Since(time.Unix(stats.LastHandshake, 0)).Truncate(time.Second))
+	}
+	if stats.RxBytes > 0 || stats.TxBytes > 0 {
+		fmt.Fprintf(w, "    Traffic\t:\treceived %s (%s/s); sent %s (%s/s)\n",
+			bytesToString(stats.RxBytes), bytesToString(stats.RxRate),
+			bytesToString(stats.TxBytes), bytesToString(stats.TxRate))
+	}
+	return w
+}
+
 func printDNSState(w *tabwriter.Writer, dnsCfg dns.DnsSettings, servers *apitypes.ServersInfoResponse) *tabwriter.Writer {
 	if w == nil {
 		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
@@ -136,6 +158,44 @@ func printDNSState(w *tabwriter.Writer, dnsCfg dns.DnsSettings, servers *apitype
 	return w
 }
 
+func printOfflineState(w *tabwriter.Writer, status types.OfflineStatus) *tabwriter.Writer {
+	if w == nil {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	}
+
+	timeStr := func(unixTime int64) string {
+		return time.Unix(unixTime, 0).Format(time.RFC1123)
+	}
+
+	if !status.IsOffline {
+		if !status.IsServersStale && !status.IsWgKeyRotationOverdue {
+			return w
+		}
+		fmt.Fprintf(w, "IVPN API\t:\tOnline\n")
+	} else {
+		fmt.Fprintf(w, "IVPN API\t:\tOffline (API servers are unreachable)\n")
+		if status.OfflineSince > 0 {
+			fmt.Fprintf(w, "    Offline since\t:\t%v\n", timeStr(status.OfflineSince))
+		}
+		if status.LastApiSuccess > 0 {
+			fmt.Fprintf(w, "    Last API access\t:\t%v\n", timeStr(status.LastApiSuccess))
+		}
+	}
+
+	if status.ServersUpdated > 0 {
+		staleStr := ""
+		if status.IsServersStale {
+			staleStr = " (STALE)"
+		}
+		fmt.Fprintf(w, "    Servers updated\t:\t%v%s\n", timeStr(status.ServersUpdated), staleStr)
+	}
+	if status.IsWgKeyRotationOverdue && status.WgKeyUsableUntil > 0 {
+		fmt.Fprintf(w, "    WireGuard key usable until\t:\t%v (rotation overdue)\n", timeStr(status.WgKeyUsableUntil))
+	}
+
+	return w
+}
+
 func printFirewallState(w *tabwriter.Writer, isEnabled, isPersistent, isAllowLAN, isAllowMulticast, isAllowApiServers bool, userExceptions string) *tabwriter.Writer {
 	if w == nil {
 		w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
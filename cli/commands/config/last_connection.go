@@ -50,6 +50,12 @@ type LastConnectionInfo struct {
 	Mtu             int
 
 	MultiopExitSvr string // variable name spelling error ->  'MultihopExitSvr' (keeped as is for compatibility with previous versions)
+	WgCustomHop    string // custom WireGuard server chained after the IVPN server (empty - not in use)
+
+	// Multi-Hop exit server selection rules (in use when 'MultiopExitSvr' is not defined)
+	ExitCountry           string
+	ExitOtherJurisdiction bool
+	ExitFastest           bool
 }
 
 // LastConnectionExist - returns 'true' if available info about last successful connection
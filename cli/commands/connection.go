@@ -29,9 +29,11 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ivpn/desktop-app/cli/commands/config"
 	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/cli/protocol"
 	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
@@ -62,19 +64,35 @@ func (p *port) String() string {
 	return fmt.Sprintf("%s", protoName)
 }
 
+// the default time to wait for the VPN connection\disconnection
+const defaultConnectTimeout = time.Minute * 3
+
 func defaultPort() *port {
 	return &port{port: 2049}
 }
 
 type CmdDisconnect struct {
 	flags.CmdInfo
+	timeout int // seconds
 }
 
 func (c *CmdDisconnect) Init() {
 	c.Initialize("disconnect", "Disconnect active VPN connection (if connected)")
+	c.IntVar(&c.timeout, "timeout", 0, "SEC", fmt.Sprintf("Maximum time to wait for the disconnection (default: %d)\n  (exit code %d when the disconnection is not finished in time)", int(defaultConnectTimeout/time.Second), ExitCodeTimeout))
 }
 func (c *CmdDisconnect) Run() error {
-	if err := _proto.DisconnectVPN(); err != nil {
+	if c.timeout < 0 {
+		return flags.BadParameter{Message: "'-timeout' value must be a positive number of seconds"}
+	}
+	timeout := defaultConnectTimeout
+	if c.timeout > 0 {
+		timeout = time.Duration(c.timeout) * time.Second
+	}
+
+	if err := _proto.DisconnectVPNTimeout(timeout); err != nil {
+		if _, ok := err.(protocol.ResponseTimeout); ok {
+			return ErrorConnectionTimeout{Timeout: timeout, IsDisconnect: true}
+		}
 		return err
 	}
 
@@ -107,10 +125,26 @@ type CmdConnect struct {
 	filter_country     bool
 	filter_countryCode bool
 	filter_invert      bool
+	query              string // servers query (the first host of the result is in use)
 
 	multihopExitSvr string
 
+	// Multi-Hop exit server selection rules (in use when the exit server is not defined explicitly)
+	exitCountry           string
+	exitOtherJurisdiction bool
+	exitFastest           bool
+
+	wgCustomHop string // custom (imported) WireGuard server chained after the IVPN (exit) server
+
+	wgCustomSvr     string // custom (imported) WireGuard server
+	ovpnCustomProfl string // custom (imported) OpenVPN profile
+
 	fastest bool
+	nearest bool
+
+	wait    bool // wait until the VPN connection is established (the default behaviour)
+	timeout int  // seconds
+	detach  bool // do not wait for the connection result
 }
 
 func (c *CmdConnect) Init() {
@@ -126,6 +160,10 @@ func (c *CmdConnect) Init() {
 	c.BoolVar(&c.obfsproxy, "obfsproxy", false, "Use obfsproxy (OpenVPN only)")
 
 	c.StringVar(&c.multihopExitSvr, "exit_svr", "", "LOCATION", "Exit-server for Multi-Hop connection\n  (use full serverID as a parameter, servers filtering not applicable for it)")
+	c.StringVar(&c.exitCountry, "exit_country", "", "COUNTRY_CODE", "Multi-Hop connection with exit-server from the country (comma-separated list of country codes is acceptable)\n  (exit-server is selected automatically; not applicable together with '-exit_svr')")
+	c.BoolVar(&c.exitOtherJurisdiction, "exit_other_jurisdiction", false, "Multi-Hop connection with exit-server from another jurisdiction than the entry-server\n  (exit-server is selected automatically; the 'Fourteen Eyes' countries are considered as one jurisdiction)")
+	c.BoolVar(&c.exitFastest, "exit_fastest", false, "Multi-Hop connection with the fastest exit-server (satisfying '-exit_country' and '-exit_other_jurisdiction' rules)\n  (by default, a random exit-server satisfying the rules is in use)\n  Note: the exit-server is selected by the daemon; the rules are re-applied on each reconnection")
+	c.StringVar(&c.wgCustomHop, "wg_custom_hop", "", "SERVER", "Chain custom WireGuard server after the IVPN server (server ID or name; see 'wgcustom' command)\n  (applicable only for WireGuard; currently, supported only on Linux)")
 
 	c.BoolVar(&c.firewallOff, "fw_off", false, "Do not enable firewall for this connection\n  (has effect only if Firewall not enabled before)")
 
@@ -152,18 +190,52 @@ func (c *CmdConnect) Init() {
 
 	c.BoolVar(&c.filter_invert, "filter_invert", false, "Invert filtering")
 
-	c.BoolVar(&c.fastest, "fastest", false, "Connect to fastest server")
+	c.StringVar(&c.query, "q", "", "QUERY", "Connect to the first server host found by the query (with '-any' - to a random one)\n  (for Multi-Hop connection the query defines the entry server)\n"+serversQueryHelp+
+		"\n  Example: ivpn connect -q \"country in (CH,SE) and proto = wg sort by ping\"")
+	c.StringVar(&c.query, "query", "", "QUERY", "The same as '-q'")
+
+	c.BoolVar(&c.fastest, "fastest", false, "Connect to fastest server\n  (when the servers pinging fails, e.g. ICMP is blocked - the nearest server is in use)")
+	c.BoolVar(&c.nearest, "nearest", false, "Connect to the nearest server (by geographical distance)\n  (the distance is calculated from the home location, if defined, or from the location detected by geo-lookup; see 'ivpn servers -distance')\n  (the server is selected by the daemon, the same way as for WireGuard failover; in servers queries use 'sort by distance')")
 
 	c.BoolVar(&c.last, "last", false, "Connect with last successful connection parameters")
 
 	c.IntVar(&c.mtu, "mtu", 0, "MTU", "Maximum transmission unit (applicable only for WireGuard connections)")
+
+	c.BoolVar(&c.wait, "wait", false, "Do not exit until the VPN connection is established (or until the '-timeout' deadline)\n  (this is the default behaviour unless '-detach' is used; the exit code is 0 only when the VPN connection is established)")
+	c.IntVar(&c.timeout, "timeout", 0, "SEC", fmt.Sprintf("Maximum time to wait for the connection (default: %d)\n  (exit code %d when the connection is not established in time; the connection attempt is cancelled)", int(defaultConnectTimeout/time.Second), ExitCodeTimeout))
+	c.BoolVar(&c.detach, "detach", false, "Send the connection request and exit immediately, without waiting for the result\n  (use 'ivpn status' to check the connection state)")
+
+	c.StringVar(&c.wgCustomSvr, "wg_custom", "", "SERVER", "Connect to custom WireGuard server (server ID or name; see 'wgcustom' command)\n  (IVPN account is not required; only '-fw_off' and '-dns' arguments are applicable; not supported on macOS)")
+	c.StringVar(&c.ovpnCustomProfl, "ovpn_custom", "", "PROFILE", "Connect with custom OpenVPN profile (profile ID or name; see 'ovpncustom' command)\n  (IVPN account is not required; only '-fw_off' and '-dns' arguments are applicable)")
 }
 
 // Run executes command
 func (c *CmdConnect) Run() (retError error) {
-	if len(c.gateway) == 0 && c.fastest == false && c.any == false && c.last == false && c.portsShow == false {
+	if c.detach && (c.wait || c.timeout != 0) {
+		return flags.BadParameter{Message: "'-detach' is not applicable together with '-wait' or '-timeout'"}
+	}
+	if c.timeout < 0 {
+		return flags.BadParameter{Message: "'-timeout' value must be a positive number of seconds"}
+	}
+
+	if len(c.wgCustomSvr) > 0 || len(c.ovpnCustomProfl) > 0 {
+		return c.connectCustom()
+	}
+
+	if len(c.gateway) == 0 && len(c.query) == 0 && c.fastest == false && c.nearest == false && c.any == false && c.last == false && c.portsShow == false {
 		return flags.BadParameter{}
 	}
+	if c.fastest && c.nearest {
+		return flags.BadParameter{Message: "'-fastest' and '-nearest' flags can not be used together"}
+	}
+	if len(c.query) > 0 {
+		if len(c.gateway) > 0 || c.fastest || c.nearest || c.last {
+			return flags.BadParameter{Message: "'-q' is not applicable together with LOCATION, '-fastest', '-nearest' or '-last' (use 'sort by ping' or 'sort by distance' in the query)"}
+		}
+		if c.filter_location || c.filter_city || c.filter_countryCode || c.filter_country || c.filter_invert || len(c.filter_proto) > 0 {
+			return flags.BadParameter{Message: "filtering flags are not applicable together with '-q' (use conditions in the query)"}
+		}
+	}
 
 	// connection request
 	req := types.Connect{}
@@ -250,22 +322,52 @@ func (c *CmdConnect) Run() (retError error) {
 		c.antitracker = ci.Antitracker
 		c.antitrackerHard = ci.AntitrackerHard
 		c.multihopExitSvr = ci.MultiopExitSvr
+		c.exitCountry = ci.ExitCountry
+		c.exitOtherJurisdiction = ci.ExitOtherJurisdiction
+		c.exitFastest = ci.ExitFastest
+		c.wgCustomHop = ci.WgCustomHop
 		c.isIPv6Tunnel = ci.IPv6Tunnel
 
 		c.mtu = ci.Mtu
 	}
 
+	// servers query: defines the (entry) server and its host
+	if len(c.query) > 0 {
+		hosts, err := queryServers(c.query, isWgDisabled, isOpenVPNDisabled)
+		if err != nil {
+			return err
+		}
+		if len(hosts) == 0 {
+			funcWarnDisabledProtocols() // print info about disabled functionality
+			return ErrorNoServerMatched{Message: "no servers found by the query"}
+		}
+
+		host := hosts[0]
+		if c.any && len(hosts) > 1 {
+			fmt.Printf("Taking one random from %d found hosts ...\n", len(hosts))
+			if rnd, err := rand.Int(rand.Reader, big.NewInt(int64(len(hosts)))); err == nil {
+				host = hosts[rnd.Int64()]
+			}
+		}
+		c.gateway = host.Gateway
+		customHostEntryServer = host.Hostname
+	}
+
 	// MULTI\SINGLE -HOP
 	// Check if the parameters are correct and define correct values for c.gateway and c.multihopExitSvr
-	if len(c.multihopExitSvr) > 0 {
+	if len(c.multihopExitSvr) > 0 || c.isExitRuleDefined() {
 		// MULTI-HOP
 
 		if err := helloResp.Account.IsCanConnectMultiHop(); err != nil {
 			return err
 		}
 
-		if c.fastest {
-			return flags.BadParameter{Message: "'fastest' flag is not applicable for Multi-Hop connection [exit_svr]"}
+		if len(c.multihopExitSvr) > 0 && c.isExitRuleDefined() {
+			return flags.BadParameter{Message: "exit-server selection rules [exit_country, exit_other_jurisdiction, exit_fastest] are not applicable together with [exit_svr]"}
+		}
+
+		if c.fastest || c.nearest {
+			return flags.BadParameter{Message: "'fastest' and 'nearest' flags are not applicable for Multi-Hop connection [exit_svr]"}
 		}
 
 		if c.filter_location || c.filter_city || c.filter_countryCode || c.filter_country || c.filter_invert {
@@ -277,22 +379,32 @@ func (c *CmdConnect) Run() (retError error) {
 			return flags.BadParameter{Message: "specify correct entry server ID for multi-hop connection"}
 		}
 
-		exitSvrs := serversFilter(isWgDisabled, isOpenVPNDisabled, svrs, c.multihopExitSvr, c.filter_proto, false, false, false, false, false)
-		if len(exitSvrs) == 0 || len(exitSvrs) > 1 {
-			return flags.BadParameter{Message: "specify correct exit server ID for multi-hop connection"}
-		}
 		entrySvr := entrySvrs[0]
-		exitSvr := exitSvrs[0]
 
-		if entrySvr.gateway == exitSvr.gateway || entrySvr.countryCode == exitSvr.countryCode {
-			return flags.BadParameter{Message: "unable to use entry- and exit- servers from the same country for multi-hop connection"}
+		if len(c.multihopExitSvr) > 0 {
+			exitSvrs := serversFilter(isWgDisabled, isOpenVPNDisabled, svrs, c.multihopExitSvr, c.filter_proto, false, false, false, false, false)
+			if len(exitSvrs) == 0 || len(exitSvrs) > 1 {
+				return flags.BadParameter{Message: "specify correct exit server ID for multi-hop connection"}
+			}
+			exitSvr := exitSvrs[0]
+
+			if entrySvr.gateway == exitSvr.gateway || entrySvr.countryCode == exitSvr.countryCode {
+				return flags.BadParameter{Message: "unable to use entry- and exit- servers from the same country for multi-hop connection"}
+			}
+			c.multihopExitSvr = exitSvr.gateway
+		} else {
+			// the exit server is selected by the daemon according to the rules
+			req.MultihopExitRules = c.exitRules()
 		}
 
 		c.gateway = entrySvr.gateway
-		c.multihopExitSvr = exitSvr.gateway
 	} else {
 		//SINGLE-HOP
 		svrs = serversFilter(isWgDisabled, isOpenVPNDisabled, svrs, c.gateway, c.filter_proto, c.filter_location, c.filter_city, c.filter_countryCode, c.filter_country, c.filter_invert)
+		if c.fastest || c.nearest || c.any {
+			// automatic server selection: the excluded servers are never selected
+			svrs = serversExcludedFilter(svrs)
+		}
 
 		srvID := ""
 
@@ -304,21 +416,36 @@ func (c *CmdConnect) Run() (retError error) {
 					vpnType = &p
 				}
 			}
-			if err := serversPing(svrs, true, false, vpnType); err != nil && c.any == false {
-				if c.any {
-					fmt.Printf("Error: Failed to ping servers to determine fastest: %s\n", err)
+			pingErr := serversPing(svrs, true, false, vpnType, types.PingProbeAuto)
+			fastestSrv := svrs[len(svrs)-1]
+			if pingErr != nil || fastestSrv.pingMs == 0 {
+				// ICMP can be blocked: the nearest server is a better guess than a random one
+				if nearestSrv, err := serversNearest(svrs); err == nil {
+					fmt.Println("WARNING! Servers pinging problem. Using the nearest server.")
+					fastestSrv = nearestSrv
+				} else if pingErr != nil {
+					if c.any == false {
+						return pingErr
+					}
+					fmt.Printf("Error: Failed to ping servers to determine fastest: %s\n", pingErr)
 				} else {
-					return err
+					fmt.Println("WARNING! Servers pinging problem.")
 				}
 			}
-			fastestSrv := svrs[len(svrs)-1]
-			if fastestSrv.pingMs == 0 {
-				fmt.Println("WARNING! Servers pinging problem.")
-			}
 			srvID = fastestSrv.gateway
 		}
 
-		// if we not found required server before (by 'fastest' option)
+		// Nearest server
+		if c.nearest && len(svrs) > 0 {
+			nearestSrv, err := serversNearest(svrs)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Nearest server: %s (%s)\n", nearestSrv.String(), distanceKmStr(nearestSrv.distanceKm))
+			srvID = nearestSrv.gateway
+		}
+
+		// if we not found required server before (by 'fastest' or 'nearest' option)
 		if len(srvID) == 0 {
 			showTipsServerFilterError := func() {
 				fmt.Println()
@@ -337,7 +464,7 @@ func (c *CmdConnect) Run() (retError error) {
 
 				funcWarnDisabledProtocols() // print info about disabled functionality
 				showTipsServerFilterError()
-				return fmt.Errorf("no servers found by your filter")
+				return ErrorNoServerMatched{Message: "no servers found by your filter"}
 			}
 
 			// 'any' option
@@ -362,18 +489,8 @@ func (c *CmdConnect) Run() (retError error) {
 	}
 
 	// Firewall for current connection
-	req.FirewallOnDuringConnection = true
-	if c.firewallOff {
-		// check current FW state
-		state, err := _proto.FirewallStatus()
-		if err != nil {
-			return fmt.Errorf("unable to check Firewall state: %w", err)
-		}
-		if state.IsEnabled == false {
-			req.FirewallOnDuringConnection = false
-		} else {
-			fmt.Println("WARNING! Firewall option ignored (Firewall already enabled manually)")
-		}
+	if req.FirewallOnDuringConnection, err = c.isFirewallOnDuringConnection(); err != nil {
+		return err
 	}
 
 	// Looking for connection server
@@ -416,7 +533,7 @@ func (c *CmdConnect) Run() (retError error) {
 					req.WireGuardParameters.Mtu = c.mtu
 				}
 
-				if len(c.multihopExitSvr) == 0 {
+				if len(c.multihopExitSvr) == 0 && !c.isExitRuleDefined() {
 					// port
 					p, err := getPort(c.port, allowedPortsWg)
 					if err != nil {
@@ -427,8 +544,8 @@ func (c *CmdConnect) Run() (retError error) {
 
 					fmt.Printf("[WireGuard] Connecting to: %s, %s (%s) %s %s...\n", s.City, s.CountryCode, s.Country, s.Gateway, p.String())
 				} else {
-					if exitSvrWg == nil {
-						return fmt.Errorf("serverID not found in servers list (%s)", c.multihopExitSvr)
+					if exitSvrWg == nil && !c.isExitRuleDefined() {
+						return ErrorNoServerMatched{Message: fmt.Sprintf("serverID not found in servers list (%s)", c.multihopExitSvr)}
 					}
 
 					// port definition is not required for WireGuard multi-hop (in use: UDP + port-based-multihop)
@@ -437,12 +554,15 @@ func (c *CmdConnect) Run() (retError error) {
 						fmt.Printf("Note: port definition is ignored for WireGuard Multi-Hop connections\n")
 					}
 
-					req.WireGuardParameters.MultihopExitServer.ExitSrvID = strings.Split(exitSvrWg.Gateway, ".")[0]
-					req.WireGuardParameters.MultihopExitServer.Hosts = funcApplyCustomHost(exitSvrWg.Hosts, customHostExitServer)
-
 					fmt.Printf("[WireGuard] Connecting Multi-Hop...\n")
 					fmt.Printf("\tentry server: %s, %s (%s) %s\n", entrySvrWg.City, entrySvrWg.CountryCode, entrySvrWg.Country, entrySvrWg.Gateway)
-					fmt.Printf("\texit server : %s, %s (%s) %s\n", exitSvrWg.City, exitSvrWg.CountryCode, exitSvrWg.Country, exitSvrWg.Gateway)
+					if exitSvrWg != nil {
+						req.WireGuardParameters.MultihopExitServer.ExitSrvID = strings.Split(exitSvrWg.Gateway, ".")[0]
+						req.WireGuardParameters.MultihopExitServer.Hosts = funcApplyCustomHost(exitSvrWg.Hosts, customHostExitServer)
+						fmt.Printf("\texit server : %s, %s (%s) %s\n", exitSvrWg.City, exitSvrWg.CountryCode, exitSvrWg.Country, exitSvrWg.Gateway)
+					} else {
+						fmt.Printf("\texit server : selected by the daemon according to the rules\n")
+					}
 				}
 				break
 			}
@@ -494,7 +614,7 @@ func (c *CmdConnect) Run() (retError error) {
 				req.VpnType = vpn.OpenVPN
 				req.OpenVpnParameters.EntryVpnServer.Hosts = funcApplyCustomHost(s.Hosts, customHostEntryServer)
 
-				isMultihop := exitSvrOvpn != nil && len(c.multihopExitSvr) > 0
+				isMultihop := (exitSvrOvpn != nil && len(c.multihopExitSvr) > 0) || c.isExitRuleDefined()
 				if !isMultihop {
 					// port
 					destPort, err = getPort(c.port, allowedPortsOvpn)
@@ -510,9 +630,11 @@ func (c *CmdConnect) Run() (retError error) {
 						return err
 					}
 
-					// get Multi-Hop ID
-					req.OpenVpnParameters.MultihopExitServer.ExitSrvID = strings.Split(c.multihopExitSvr, ".")[0]
-					req.OpenVpnParameters.MultihopExitServer.Hosts = funcApplyCustomHost(exitSvrOvpn.Hosts, customHostExitServer)
+					// get Multi-Hop ID (when the exit server is not selected by rules)
+					if exitSvrOvpn != nil {
+						req.OpenVpnParameters.MultihopExitServer.ExitSrvID = strings.Split(c.multihopExitSvr, ".")[0]
+						req.OpenVpnParameters.MultihopExitServer.Hosts = funcApplyCustomHost(exitSvrOvpn.Hosts, customHostExitServer)
+					}
 					destPort.port = 0 // do not use port number (port-based multihop)
 				}
 
@@ -524,10 +646,10 @@ func (c *CmdConnect) Run() (retError error) {
 		}
 
 		if entrySvrOvpn == nil {
-			return fmt.Errorf("serverID not found in servers list (%s)", c.gateway)
+			return ErrorNoServerMatched{Message: fmt.Sprintf("serverID not found in servers list (%s)", c.gateway)}
 		}
 		if len(c.multihopExitSvr) > 0 && exitSvrOvpn == nil {
-			return fmt.Errorf("serverID not found in servers list (%s)", c.multihopExitSvr)
+			return ErrorNoServerMatched{Message: fmt.Sprintf("serverID not found in servers list (%s)", c.multihopExitSvr)}
 		}
 
 		portStrInfo := destPort.String()
@@ -540,7 +662,7 @@ func (c *CmdConnect) Run() (retError error) {
 			destPort.tcp = true
 		}
 
-		if len(c.multihopExitSvr) == 0 {
+		if len(c.multihopExitSvr) == 0 && !c.isExitRuleDefined() {
 			fmt.Printf("[OpenVPN] Connecting to: %s, %s (%s) %s %s...\n", entrySvrOvpn.City, entrySvrOvpn.CountryCode, entrySvrOvpn.Country, entrySvrOvpn.Gateway, portStrInfo)
 		} else {
 			portStrInfo = "UDP"
@@ -550,12 +672,33 @@ func (c *CmdConnect) Run() (retError error) {
 
 			fmt.Printf("[OpenVPN] Connecting Multi-Hop...\n")
 			fmt.Printf("\tentry server: %s, %s (%s) %s %s\n", entrySvrOvpn.City, entrySvrOvpn.CountryCode, entrySvrOvpn.Country, entrySvrOvpn.Gateway, portStrInfo)
-			fmt.Printf("\texit server : %s, %s (%s) %s\n", exitSvrOvpn.City, exitSvrOvpn.CountryCode, exitSvrOvpn.Country, exitSvrOvpn.Gateway)
+			if exitSvrOvpn != nil {
+				fmt.Printf("\texit server : %s, %s (%s) %s\n", exitSvrOvpn.City, exitSvrOvpn.CountryCode, exitSvrOvpn.Country, exitSvrOvpn.Gateway)
+			} else {
+				fmt.Printf("\texit server : selected by the daemon according to the rules\n")
+			}
 		}
 	}
 
 	if serverFound == false {
-		return fmt.Errorf("serverID not found in servers list (%s)", c.gateway)
+		return ErrorNoServerMatched{Message: fmt.Sprintf("serverID not found in servers list (%s)", c.gateway)}
+	}
+
+	// custom WireGuard server chained after the IVPN server
+	if len(c.wgCustomHop) > 0 {
+		if vpntype != vpn.WireGuard {
+			return flags.BadParameter{Message: "'wg_custom_hop' argument is applicable only for WireGuard connections"}
+		}
+		customServers, err := _proto.WGCustomServersGet()
+		if err != nil {
+			return err
+		}
+		srv, err := findWgCustomServer(customServers, c.wgCustomHop)
+		if err != nil {
+			return err
+		}
+		req.WireGuardParameters.NextHopCustomServerID = srv.ID
+		fmt.Printf("\tchained custom server: %s (%s)\n", srv.Name, srv.Endpoint)
 	}
 
 	// Get configuration
@@ -573,7 +716,11 @@ func (c *CmdConnect) Run() (retError error) {
 		}
 	}
 	if c.antitracker || c.antitrackerHard {
-		atDNS, err := GetAntitrackerIP(vpntype, c.antitrackerHard, len(c.multihopExitSvr) > 0, &servers)
+		if len(req.WireGuardParameters.NextHopCustomServerID) > 0 {
+			// the AntiTracker DNS is not reachable: the traffic leaves IVPN network through the chained server
+			return flags.BadParameter{Message: "AntiTracker is not applicable for the connections with chained custom WireGuard server"}
+		}
+		atDNS, err := GetAntitrackerIP(vpntype, c.antitrackerHard, len(c.multihopExitSvr) > 0 || c.isExitRuleDefined(), &servers)
 		if err != nil {
 			return err
 		}
@@ -599,15 +746,13 @@ func (c *CmdConnect) Run() (retError error) {
 	}
 
 	fmt.Println("Connecting...")
-	_, err = _proto.ConnectVPN(req)
-	if err != nil {
-		err = fmt.Errorf("failed to connect: %w", err)
-		fmt.Printf("Disconnecting...\n")
-		if err2 := _proto.DisconnectVPN(); err2 != nil {
-			fmt.Printf("Failed to disconnect: %v\n", err2)
-		}
+	if err = c.connectVPN(req); err != nil {
 		return err
 	}
+	if c.detach {
+		// the connection result is not known
+		return nil
+	}
 
 	if cState, stateResp, stateErr := _proto.GetVPNState(); stateErr == nil && cState == vpn.CONNECTED {
 		if !stateResp.ManualDNS.Equal(req.ManualDNS) {
@@ -631,7 +776,12 @@ func (c *CmdConnect) Run() (retError error) {
 		AntitrackerHard: c.antitrackerHard,
 		IPv6Tunnel:      c.isIPv6Tunnel,
 		MultiopExitSvr:  c.multihopExitSvr,
-		Mtu:             c.mtu})
+		WgCustomHop:     c.wgCustomHop,
+		Mtu:             c.mtu,
+
+		ExitCountry:           c.exitCountry,
+		ExitOtherJurisdiction: c.exitOtherJurisdiction,
+		ExitFastest:           c.exitFastest})
 
 	return nil
 }
@@ -746,3 +896,113 @@ func parsePort(portInfo string) (pPort *int, pIsTCP *bool, err error) {
 
 	return &port, &isTCP, nil
 }
+
+// isFirewallOnDuringConnection returns value for 'Connect.FirewallOnDuringConnection' request parameter
+func (c *CmdConnect) isFirewallOnDuringConnection() (bool, error) {
+	if !c.firewallOff {
+		return true, nil
+	}
+	// check current FW state
+	state, err := _proto.FirewallStatus()
+	if err != nil {
+		return true, ErrorFirewall{Err: fmt.Errorf("unable to check Firewall state: %w", err)}
+	}
+	if state.IsEnabled {
+		fmt.Println("WARNING! Firewall option ignored (Firewall already enabled manually)")
+		return true, nil
+	}
+	return false, nil
+}
+
+// connectCustom connects to the custom (imported) WireGuard server or with custom OpenVPN profile
+func (c *CmdConnect) connectCustom() (retError error) {
+	if len(c.gateway) > 0 || c.fastest || c.nearest || c.any || c.last || len(c.multihopExitSvr) > 0 || c.isExitRuleDefined() || len(c.wgCustomHop) > 0 || c.antitracker || c.antitrackerHard || c.obfsproxy {
+		return flags.BadParameter{Message: "only '-fw_off' and '-dns' arguments are applicable for the connection to a custom server"}
+	}
+	if len(c.wgCustomSvr) > 0 && len(c.ovpnCustomProfl) > 0 {
+		return flags.BadParameter{Message: "'wg_custom' and 'ovpn_custom' arguments can not be used together"}
+	}
+
+	req := types.Connect{}
+	connectingInfo := ""
+	if len(c.wgCustomSvr) > 0 {
+		servers, err := _proto.WGCustomServersGet()
+		if err != nil {
+			return err
+		}
+		srv, err := findWgCustomServer(servers, c.wgCustomSvr)
+		if err != nil {
+			return err
+		}
+		req.VpnType = vpn.WireGuard
+		req.WireGuardParameters.CustomServerID = srv.ID
+		connectingInfo = fmt.Sprintf("[WireGuard] Connecting to custom server: %s (%s)...", srv.Name, srv.Endpoint)
+	} else {
+		profiles, err := _proto.OvpnCustomProfilesGet()
+		if err != nil {
+			return err
+		}
+		p, err := findOvpnCustomProfile(profiles, c.ovpnCustomProfl)
+		if err != nil {
+			return err
+		}
+		req.VpnType = vpn.OpenVPN
+		req.OpenVpnParameters.CustomProfileID = p.ID
+		connectingInfo = fmt.Sprintf("[OpenVPN] Connecting with custom profile: %s (%s:%d)...", p.Name, p.RemoteHost, p.RemotePort)
+	}
+
+	var err error
+	if req.FirewallOnDuringConnection, err = c.isFirewallOnDuringConnection(); err != nil {
+		return err
+	}
+
+	if len(c.dns) > 0 {
+		dnsIp := net.ParseIP(c.dns)
+		if dnsIp == nil {
+			return flags.BadParameter{}
+		}
+		req.ManualDNS = dns.DnsSettings{DnsHost: dnsIp.String(), Encryption: dns.EncryptionNone}
+	}
+
+	// show current state after on finished
+	defer func() {
+		if retError == nil {
+			showState()
+		}
+	}()
+
+	fmt.Println(connectingInfo)
+	return c.connectVPN(req)
+}
+
+// connectVPN sends the connection request and waits for the result (according to '-timeout' and '-detach' arguments).
+// The daemon replies only when the connection is established, so waiting is the default behaviour ('-wait' argument just states it explicitly).
+// When the connection failed - the connection attempt is cancelled.
+func (c *CmdConnect) connectVPN(req types.Connect) error {
+	if c.detach {
+		if err := _proto.ConnectVPNDetached(req); err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		fmt.Println("Connection request sent")
+		return nil
+	}
+
+	timeout := defaultConnectTimeout
+	if c.timeout > 0 {
+		timeout = time.Duration(c.timeout) * time.Second
+	}
+	if _, err := _proto.ConnectVPNTimeout(req, timeout); err != nil {
+		switch err.(type) {
+		case protocol.ResponseTimeout, ErrorConnectionTimeout:
+			err = ErrorConnectionTimeout{Timeout: timeout}
+		default:
+			err = fmt.Errorf("failed to connect: %w", err)
+		}
+		fmt.Printf("Disconnecting...\n")
+		if err2 := _proto.DisconnectVPN(); err2 != nil {
+			fmt.Printf("Failed to disconnect: %v\n", err2)
+		}
+		return err
+	}
+	return nil
+}
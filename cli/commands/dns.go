@@ -217,6 +217,8 @@ func (c *CmdDns) Run() error {
 	w = printDNSConfigInfo(w, cfg.CustomDnsCfg)
 	w.Flush()
 
+	setJsonData(jsonDnsState(state, connected.ManualDNS, servers, cfg))
+
 	return nil
 }
 
@@ -305,13 +307,15 @@ func (c *CmdAntitracker) Run() error {
 	}
 
 	if state == vpn.CONNECTED {
-		servers, _ := _proto.GetServers()
+		servers, _ = _proto.GetServers()
 		w = printDNSState(w, connected.ManualDNS, &servers)
 	}
 
 	w = printAntitrackerConfigInfo(w, cfg.Antitracker, cfg.AntitrackerHardcore)
 	w.Flush()
 
+	setJsonData(jsonDnsState(state, connected.ManualDNS, &servers, cfg))
+
 	return nil
 }
 
@@ -135,6 +135,7 @@ func (c *CmdParanoidMode) Run() error {
 	var w *tabwriter.Writer
 	w = printParamoidModeState(w, _proto.GetHelloResponse())
 	w.Flush()
+	setJsonData(JsonEaaState{Enabled: _proto.GetHelloResponse().ParanoidMode.IsEnabled})
 
 	if _proto.GetHelloResponse().ParanoidMode.IsEnabled {
 		PrintTips([]TipType{TipEaaDisable})
@@ -22,6 +22,11 @@
 
 package commands
 
+import (
+	"fmt"
+	"time"
+)
+
 // NotImplemented error
 type NotImplemented struct {
 	Message string
@@ -33,3 +38,76 @@ func (e NotImplemented) Error() string {
 	}
 	return e.Message
 }
+
+// ErrorUnknownCommand - the command name is not known
+type ErrorUnknownCommand struct {
+	Command string
+}
+
+func (e ErrorUnknownCommand) Error() string {
+	return "unexpected command " + e.Command
+}
+
+// ErrorDaemonUnreachable - unable to connect to the IVPN daemon
+type ErrorDaemonUnreachable struct {
+	Err error
+}
+
+func (e ErrorDaemonUnreachable) Error() string {
+	return "unable to connect to service: " + e.Err.Error()
+}
+
+func (e ErrorDaemonUnreachable) Unwrap() error {
+	return e.Err
+}
+
+// ErrorNoServerMatched - no server satisfies the connection parameters
+type ErrorNoServerMatched struct {
+	Message string
+}
+
+func (e ErrorNoServerMatched) Error() string {
+	if len(e.Message) == 0 {
+		return "no servers found"
+	}
+	return e.Message
+}
+
+// ErrorConnectionTimeout - the VPN connection (or disconnection) was not finished in time
+type ErrorConnectionTimeout struct {
+	Timeout      time.Duration
+	IsDisconnect bool
+}
+
+func (e ErrorConnectionTimeout) Error() string {
+	if e.IsDisconnect {
+		return fmt.Sprintf("disconnection was not finished in %v", e.Timeout)
+	}
+	return fmt.Sprintf("connection was not established in %v", e.Timeout)
+}
+
+// ErrorAuth - authentication failure (wrong account credentials)
+type ErrorAuth struct {
+	Err error
+}
+
+func (e ErrorAuth) Error() string {
+	return "authentication failed: " + e.Err.Error()
+}
+
+func (e ErrorAuth) Unwrap() error {
+	return e.Err
+}
+
+// ErrorFirewall - failed to check or to change the firewall state
+type ErrorFirewall struct {
+	Err error
+}
+
+func (e ErrorFirewall) Error() string {
+	return "firewall error: " + e.Err.Error()
+}
+
+func (e ErrorFirewall) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,72 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+// Exit codes of the CLI process.
+// They allow scripts to distinguish the main failure reasons without parsing the output.
+const (
+	ExitCodeSuccess           = 0
+	ExitCodeError             = 1 // not classified error
+	ExitCodeBadParameter      = 2 // wrong command arguments or unknown command
+	ExitCodeDaemonUnreachable = 3 // unable to connect to the IVPN daemon
+	ExitCodeNotLoggedIn       = 4 // the command requires the account login
+	ExitCodeAuthError         = 5 // authentication failure (wrong account credentials, VPN authentication error or wrong EAA password)
+	ExitCodeNoServerMatched   = 6 // no server satisfies the connection parameters
+	ExitCodeTimeout           = 7 // the VPN connection was not established in time
+	ExitCodeFirewallError     = 8 // failed to check or to change the firewall state
+)
+
+// ExitCodesHelp - the description of exit codes (for the usage info)
+const ExitCodesHelp = `EXIT CODES:
+  0 - success
+  1 - error
+  2 - bad parameter or unknown command
+  3 - IVPN daemon is unreachable
+  4 - not logged in
+  5 - authentication error
+  6 - no server matched
+  7 - connection timeout
+  8 - firewall error`
+
+// ExitCode returns the exit code of the CLI process for the command result
+func ExitCode(err error) int {
+	switch JsonErrorCode(err) {
+	case "":
+		return ExitCodeSuccess
+	case JsonErrorBadParameter, JsonErrorUnknownCommand:
+		return ExitCodeBadParameter
+	case JsonErrorDaemonUnreachable:
+		return ExitCodeDaemonUnreachable
+	case JsonErrorNotLoggedIn:
+		return ExitCodeNotLoggedIn
+	case JsonErrorAuth, JsonErrorEaaPassword:
+		return ExitCodeAuthError
+	case JsonErrorNoServer:
+		return ExitCodeNoServerMatched
+	case JsonErrorTimeout:
+		return ExitCodeTimeout
+	case JsonErrorFirewall:
+		return ExitCodeFirewallError
+	}
+	return ExitCodeError
+}
@@ -84,21 +84,21 @@ func (c *CmdFirewall) Run() error {
 
 	if c.ivpnSvrAccessAllow {
 		if err := _proto.FirewallAllowApiServers(true); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	} else if c.ivpnSvrAccessBlock {
 		if err := _proto.FirewallAllowApiServers(false); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	}
 
 	if c.allowLan {
 		if err := _proto.FirewallAllowLan(true); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	} else if c.blockLan {
 		if err := _proto.FirewallAllowLan(false); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	}
 
@@ -114,29 +114,29 @@ func (c *CmdFirewall) Run() error {
 
 	if c.exceptions != StringValueNoData {
 		if err := _proto.FirewallSetUserExceptions(c.exceptions); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	}
 
 	if c.persistentOn {
 		if err := _proto.FirewallPersistentSet(true); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	} else if c.persistentOff {
 		if err := _proto.FirewallPersistentSet(false); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	}
 
 	if c.on {
 		if err := _proto.FirewallSet(true); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	} else if c.off {
 
 		state, err := _proto.FirewallStatus()
 		if err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 		if err == nil && state.IsPersistent {
 			PrintTips([]TipType{TipFirewallDisablePersistent})
@@ -144,17 +144,18 @@ func (c *CmdFirewall) Run() error {
 		}
 
 		if err := _proto.FirewallSet(false); err != nil {
-			return err
+			return ErrorFirewall{Err: err}
 		}
 	}
 
 	state, err := _proto.FirewallStatus()
 	if err != nil {
-		return err
+		return ErrorFirewall{Err: err}
 	}
 
 	w := printFirewallState(nil, state.IsEnabled, state.IsPersistent, state.IsAllowLAN, state.IsAllowMulticast, state.IsAllowApiServers, state.UserExceptions)
 	w.Flush()
+	setJsonData(jsonFirewallState(state))
 
 	// TIPS
 	tips := make([]TipType, 0, 2)
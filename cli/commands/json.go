@@ -0,0 +1,195 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/cli/protocol"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/srverrors"
+	"github.com/ivpn/desktop-app/daemon/version"
+)
+
+// JSON output mode (global '-json' flag).
+//
+// In this mode the text output of the command is redirected to stderr and the only output to stdout
+// is one JSON object (JsonResult) printed when the command is finished.
+// The format is stable: new fields can be added in future versions but the existing fields
+// are not renamed or removed (see json_types.go for the descriptions of the commands data).
+
+// Error codes of the JSON output (JsonError.Code)
+const (
+	JsonErrorGeneral           = "error"              // not classified error
+	JsonErrorBadParameter      = "bad_parameter"      // wrong command arguments
+	JsonErrorUnknownCommand    = "unknown_command"    // unknown command name
+	JsonErrorDaemonUnreachable = "daemon_unreachable" // unable to connect to the IVPN daemon
+	JsonErrorNotLoggedIn       = "not_logged_in"      // the command requires the account login
+	JsonErrorEaaPassword       = "eaa_password"       // EAA password is not correct or not defined
+	JsonErrorNotImplemented    = "not_implemented"    // the functionality is not available
+	JsonErrorAuth              = "auth_error"         // authentication failure (wrong account credentials or VPN authentication error)
+	JsonErrorNoServer          = "no_server"          // no server satisfies the connection parameters
+	JsonErrorTimeout           = "timeout"            // the VPN connection was not established in time
+	JsonErrorFirewall          = "firewall_error"     // failed to check or to change the firewall state
+)
+
+// JsonResult - the output of a command in JSON output mode
+type JsonResult struct {
+	Command string      `json:"command"`         // command name (e.g. "status", "servers")
+	Success bool        `json:"success"`         // 'false' - command failed (see 'error')
+	Data    interface{} `json:"data,omitempty"`  // command-specific data (see json_types.go)
+	Error   *JsonError  `json:"error,omitempty"` // defined only when 'success' is 'false'
+}
+
+// JsonError - the error description in JSON output mode
+type JsonError struct {
+	Code    string `json:"code"` // machine-readable error code (see JsonError* constants)
+	Message string `json:"message"`
+}
+
+var (
+	_jsonStdout *os.File    // the original stdout (nil - JSON output mode is not enabled)
+	_jsonData   interface{} // the command data to be printed in JSON output mode
+	_jsonLines  bool        // the command prints JSON lines (one compact object per line; e.g. 'monitor' command)
+)
+
+// EnableJsonOutput enables JSON output mode: the text output of the commands is redirected to stderr
+// (must be called before running the command)
+func EnableJsonOutput() {
+	if _jsonStdout != nil {
+		return
+	}
+	_jsonStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// IsJsonOutput returns 'true' when JSON output mode is enabled
+func IsJsonOutput() bool {
+	return _jsonStdout != nil
+}
+
+// setJsonData defines the command data for JSON output mode
+// (the last defined data is in use; has no effect in text output mode)
+func setJsonData(data interface{}) {
+	_jsonData = data
+}
+
+// PrintJsonResult prints the command result in JSON format to stdout (has no effect in text output mode)
+func PrintJsonResult(command string, err error) {
+	if _jsonStdout == nil {
+		return
+	}
+
+	ret := JsonResult{Command: command, Success: err == nil, Data: _jsonData}
+	if err != nil {
+		ret.Error = &JsonError{Code: JsonErrorCode(err), Message: err.Error()}
+	}
+
+	if e := printJson(ret); e != nil {
+		printJson(JsonResult{Command: command, Error: &JsonError{Code: JsonErrorGeneral, Message: fmt.Sprintf("failed to serialize the command output: %v", e)}})
+	}
+}
+
+// setJsonLinesOutput switches the command output to JSON lines: every object is printed in one line
+// (including the final command result)
+func setJsonLinesOutput() {
+	_jsonLines = true
+}
+
+// printJson prints the object in JSON format to stdout (in JSON output mode only)
+func printJson(v interface{}) error {
+	if _jsonStdout == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if !_jsonLines {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := _jsonStdout.Write(buf.Bytes())
+	return err
+}
+
+// PrintJsonVersion prints the version info in JSON format (has no effect in text output mode)
+func PrintJsonVersion() {
+	setJsonData(JsonVersion{Version: version.GetFullVersion(), Arch: runtime.GOARCH})
+	PrintJsonResult("version", nil)
+}
+
+// JsonErrorCode returns machine-readable code of the error
+func JsonErrorCode(err error) string {
+	var (
+		errBadParam       flags.BadParameter
+		errNotLoggedIn    srverrors.ErrorNotLoggedIn
+		errNotImplemented NotImplemented
+		errUnknownCommand ErrorUnknownCommand
+		errDaemon         ErrorDaemonUnreachable
+		errResp           types.ErrorResp
+		errAuth           ErrorAuth
+		errNoServer       ErrorNoServerMatched
+		errTimeout        ErrorConnectionTimeout
+		errFirewall       ErrorFirewall
+		errDisconnected   protocol.DisconnectedError
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &errResp) && errResp.ErrorType == types.ErrorParanoidModePasswordError:
+		return JsonErrorEaaPassword
+	case errors.As(err, &errResp) && errResp.ErrorMessage == (srverrors.ErrorNotLoggedIn{}).Error():
+		return JsonErrorNotLoggedIn
+	case errors.As(err, &errBadParam):
+		return JsonErrorBadParameter
+	case errors.As(err, &errNotLoggedIn):
+		return JsonErrorNotLoggedIn
+	case errors.As(err, &errNotImplemented):
+		return JsonErrorNotImplemented
+	case errors.As(err, &errUnknownCommand):
+		return JsonErrorUnknownCommand
+	case errors.As(err, &errDaemon):
+		return JsonErrorDaemonUnreachable
+	case errors.As(err, &errAuth):
+		return JsonErrorAuth
+	case errors.As(err, &errDisconnected) && errDisconnected.Reason == types.AuthenticationError:
+		return JsonErrorAuth
+	case errors.As(err, &errNoServer):
+		return JsonErrorNoServer
+	case errors.As(err, &errTimeout):
+		return JsonErrorTimeout
+	case errors.As(err, &errFirewall):
+		return JsonErrorFirewall
+	}
+	return JsonErrorGeneral
+}
@@ -0,0 +1,516 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"strings"
+
+	"github.com/ivpn/desktop-app/cli/commands/config"
+	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// The data of the commands in JSON output mode ('data' field of JsonResult).
+// All time values are unix time (seconds); zero or absent value - not defined.
+
+// JsonState - 'status' command data (also 'connect' and 'disconnect' commands data: the state after the operation)
+type JsonState struct {
+	LoggedIn    bool                  `json:"logged_in"`
+	AccountID   string                `json:"account_id,omitempty"`
+	Vpn         JsonVpnState          `json:"vpn"`
+	Firewall    JsonFirewallState     `json:"firewall"`
+	SplitTunnel *JsonSplitTunnelState `json:"split_tunnel,omitempty"` // absent when the functionality is not available
+	Offline     *JsonOfflineState     `json:"offline,omitempty"`
+}
+
+// JsonVpnState - VPN connection state
+type JsonVpnState struct {
+	State          string         `json:"state"` // DISCONNECTED, CONNECTING, CONNECTED, RECONNECTING ...
+	StateDetails   string         `json:"state_details,omitempty"`
+	Protocol       string         `json:"protocol,omitempty"` // WireGuard or OpenVPN
+	Obfsproxy      bool           `json:"obfsproxy,omitempty"`
+	Server         string         `json:"server,omitempty"`      // server description: "LOCATION [HOSTNAME], CITY (COUNTRY_CODE), COUNTRY"
+	ExitServer     string         `json:"exit_server,omitempty"` // Multi-Hop exit server description
+	ExitHostname   string         `json:"exit_hostname,omitempty"`
+	LocalIP        string         `json:"local_ip,omitempty"`
+	LocalIPv6      string         `json:"local_ipv6,omitempty"`
+	ServerIP       string         `json:"server_ip,omitempty"`
+	ServerPort     int            `json:"server_port,omitempty"`
+	ServerPortType string         `json:"server_port_type,omitempty"` // UDP or TCP
+	ConnectedSince int64          `json:"connected_since,omitempty"`
+	LastHandshake  int64          `json:"last_handshake,omitempty"` // WireGuard only
+	RxBytes        uint64         `json:"rx_bytes,omitempty"`
+	TxBytes        uint64         `json:"tx_bytes,omitempty"`
+	Dns            *JsonDnsConfig `json:"dns,omitempty"` // DNS configuration of the connection (absent - default DNS)
+}
+
+// JsonDnsConfig - DNS configuration
+type JsonDnsConfig struct {
+	DnsHost             string `json:"dns_host,omitempty"`
+	Encryption          string `json:"encryption"` // none, doh (DNS-over-HTTPS) or dot (DNS-over-TLS)
+	Template            string `json:"template,omitempty"`
+	Antitracker         bool   `json:"antitracker"`
+	AntitrackerHardcore bool   `json:"antitracker_hardcore"`
+}
+
+// JsonDnsState - 'dns' and 'antitracker' commands data
+type JsonDnsState struct {
+	Connection           *JsonDnsConfig `json:"connection,omitempty"` // DNS of the current VPN connection (absent - not connected)
+	DefaultConfig        JsonDnsConfig  `json:"default_config"`       // configuration in use for new connections
+	LinuxForceResolvconf bool           `json:"linux_force_resolvconf,omitempty"`
+}
+
+// JsonFirewallState - 'firewall' command data
+type JsonFirewallState struct {
+	Enabled           bool   `json:"enabled"`
+	Persistent        bool   `json:"persistent"`
+	AllowLan          bool   `json:"allow_lan"`
+	AllowLanMulticast bool   `json:"allow_lan_multicast"`
+	AllowApiServers   bool   `json:"allow_api_servers"`
+	Exceptions        string `json:"exceptions,omitempty"` // comma-separated list of allowed IP addresses or subnets
+}
+
+// JsonSplitTunnelState - 'splittun' command data
+type JsonSplitTunnelState struct {
+	Enabled            bool                `json:"enabled"`
+	Apps               []string            `json:"apps,omitempty"`
+	RunningAppsPids    []int               `json:"running_apps_pids,omitempty"`
+	BypassDestinations []string            `json:"bypass_destinations,omitempty"`
+	VpnDestinations    []string            `json:"vpn_destinations,omitempty"`
+	ResolvedDomains    map[string][]string `json:"resolved_domains,omitempty"`
+}
+
+// JsonOfflineState - information about offline mode (IVPN API servers are unreachable)
+type JsonOfflineState struct {
+	IsOffline              bool   `json:"is_offline"`
+	OfflineSince           int64  `json:"offline_since,omitempty"`
+	LastError              string `json:"last_error,omitempty"`
+	LastApiSuccess         int64  `json:"last_api_success,omitempty"`
+	ServersUpdated         int64  `json:"servers_updated,omitempty"`
+	ServersValidUntil      int64  `json:"servers_valid_until,omitempty"`
+	IsServersStale         bool   `json:"is_servers_stale"`
+	IsWgKeyRotationOverdue bool   `json:"is_wg_key_rotation_overdue"`
+	WgKeyUsableUntil       int64  `json:"wg_key_usable_until,omitempty"`
+}
+
+// JsonServer - 'servers' command data (array of servers)
+type JsonServer struct {
+	Protocol    string           `json:"protocol"` // WireGuard or OpenVPN
+	Gateway     string           `json:"gateway"`  // server location ID (e.g. "ch.wg.ivpn.net")
+	City        string           `json:"city"`
+	CountryCode string           `json:"country_code"`
+	Country     string           `json:"country"`
+	Latitude    float32          `json:"latitude"`
+	Longitude   float32          `json:"longitude"`
+	IPv6Tunnel  bool             `json:"ipv6_tunnel"`
+	PingMs      int              `json:"ping_ms,omitempty"`     // with '-ping' only (the best host)
+	Score       float64          `json:"score,omitempty"`       // with '-ping' only (lower - better)
+	DistanceKm  *float64         `json:"distance_km,omitempty"` // with '-distance' only (absent - unknown)
+	Hosts       []JsonServerHost `json:"hosts"`
+}
+
+// JsonServerHost - server host
+type JsonServerHost struct {
+	Hostname string  `json:"hostname"`
+	Host     string  `json:"host"` // IP address
+	Load     float32 `json:"load"` // (%)
+	PingMs   int     `json:"ping_ms,omitempty"`
+}
+
+// JsonQueryHost - 'servers -q' command data (array of hosts in the order defined by the query)
+type JsonQueryHost struct {
+	Protocol    string   `json:"protocol"`
+	Gateway     string   `json:"gateway"`
+	City        string   `json:"city"`
+	CountryCode string   `json:"country_code"`
+	Country     string   `json:"country"`
+	Hostname    string   `json:"hostname"`
+	Host        string   `json:"host"`
+	Load        float32  `json:"load"`
+	IPv6Tunnel  bool     `json:"ipv6_tunnel"`
+	Favorite    bool     `json:"favorite"`
+	Excluded    bool     `json:"excluded"`
+	PingMs      int      `json:"ping_ms,omitempty"`
+	DistanceKm  *float64 `json:"distance_km,omitempty"`
+}
+
+// JsonServersLists - 'servers -lists' command data
+type JsonServersLists struct {
+	Favorites JsonServersList `json:"favorites"`
+	Excluded  JsonServersList `json:"excluded"`
+}
+
+// JsonServersList - list of servers
+type JsonServersList struct {
+	Servers   []string `json:"servers"`   // server location IDs (e.g. "ch")
+	Hosts     []string `json:"hosts"`     // host names
+	Countries []string `json:"countries"` // country codes
+}
+
+// JsonPingSample - 'servers -history' command data (array of samples)
+type JsonPingSample struct {
+	Time       int64   `json:"time"`
+	PingMs     int     `json:"ping_ms"`
+	JitterMs   float64 `json:"jitter_ms"`
+	PacketLoss float64 `json:"packet_loss"` // (%)
+	Score      float64 `json:"score"`
+	Probe      string  `json:"probe"`
+	Path       string  `json:"path"` // direct or tunnel
+}
+
+// JsonServersSettings - 'servers -score_weights' and 'servers -home_location' commands data
+type JsonServersSettings struct {
+	ScoreWeights   JsonScoreWeights `json:"score_weights"`
+	HomeLocation   *JsonLocation    `json:"home_location,omitempty"` // absent - not defined
+	HistoryCollect bool             `json:"history_collect"`         // periodical background pinging of all servers (latency history)
+}
+
+// JsonScoreWeights - weights of the servers ranking score
+type JsonScoreWeights struct {
+	Latency float64 `json:"latency"`
+	Jitter  float64 `json:"jitter"`
+	Loss    float64 `json:"loss"`
+	Load    float64 `json:"load"`
+}
+
+// JsonLocation - geographical coordinates
+type JsonLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// JsonAccount - 'account', 'login' and 'logout' commands data
+type JsonAccount struct {
+	LoggedIn      bool   `json:"logged_in"`
+	AccountID     string `json:"account_id,omitempty"`
+	Plan          string `json:"plan,omitempty"`
+	IsFreeTrial   bool   `json:"is_free_trial,omitempty"`
+	ActiveUntil   int64  `json:"active_until,omitempty"`
+	DevicesLimit  int    `json:"devices_limit,omitempty"`
+	UpgradeToPlan string `json:"upgrade_to_plan,omitempty"`
+	UpgradeToURL  string `json:"upgrade_to_url,omitempty"`
+}
+
+// JsonWgKeysState - 'wgkeys' command data
+type JsonWgKeysState struct {
+	LocalIP                  string              `json:"local_ip"`
+	PublicKey                string              `json:"public_key"`
+	Generated                int64               `json:"generated"`
+	RotationIntervalSec      int64               `json:"rotation_interval_sec"`
+	KeyFingerprint           string              `json:"key_fingerprint,omitempty"`
+	RotationWindow           string              `json:"rotation_window,omitempty"`
+	NextRotation             int64               `json:"next_rotation,omitempty"`
+	LastRotation             *JsonWgKeysRotation `json:"last_rotation,omitempty"`
+	PqPskEnabled             bool                `json:"pq_psk_enabled"`
+	PqPskRotationIntervalSec int                 `json:"pq_psk_rotation_interval_sec"`
+}
+
+// JsonWgKeysRotation - WireGuard keys rotation record ('wgkeys -history' command data is an array of records)
+type JsonWgKeysRotation struct {
+	Time              int64  `json:"time"`
+	Trigger           string `json:"trigger"`
+	OldKeyFingerprint string `json:"old_key_fingerprint,omitempty"`
+	NewKeyFingerprint string `json:"new_key_fingerprint,omitempty"`
+	Success           bool   `json:"success"`
+	Error             string `json:"error,omitempty"`
+}
+
+// JsonWgCustomServer - 'wgcustom' command data (array of servers)
+type JsonWgCustomServer struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Endpoint   string   `json:"endpoint"`
+	PublicKey  string   `json:"public_key"`
+	Addresses  []string `json:"addresses"`
+	DNS        []string `json:"dns"`
+	AllowedIPs []string `json:"allowed_ips"`
+	Mtu        int      `json:"mtu,omitempty"`
+}
+
+// JsonOvpnCustomProfile - 'ovpncustom' command data (array of profiles)
+type JsonOvpnCustomProfile struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	RemoteHost string `json:"remote_host"`
+	RemotePort int    `json:"remote_port"`
+	Protocol   string `json:"protocol"` // UDP or TCP
+	Username   string `json:"username,omitempty"`
+}
+
+// JsonApiProxy - 'apiproxy' command data
+type JsonApiProxy struct {
+	Enabled  bool   `json:"enabled"`
+	Type     string `json:"type,omitempty"` // http or socks5
+	Address  string `json:"address,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// JsonLogs - 'logs' command data
+type JsonLogs struct {
+	Enabled *bool  `json:"enabled,omitempty"`  // with '-on' or '-off' only
+	LogFile string `json:"log_file,omitempty"` // with '-show' only
+	Log     string `json:"log,omitempty"`      // the last part of the log (with '-show' only)
+}
+
+// JsonEaaState - 'eaa' command data
+type JsonEaaState struct {
+	Enabled bool `json:"enabled"`
+}
+
+// JsonVersion - 'version' command data
+type JsonVersion struct {
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+// JsonEvent - 'monitor' command event (JSON lines: one event object per line)
+type JsonEvent struct {
+	Event string      `json:"event"` // event type (see MonitorEvent* constants)
+	Time  int64       `json:"time"`
+	Data  interface{} `json:"data,omitempty"` // event-specific data (see below)
+}
+
+// 'monitor' command events data:
+//	vpn_state, reconnecting, connected - JsonVpnState
+//	disconnected                       - JsonDisconnected
+//	firewall                           - JsonFirewallState
+//	dns                                - JsonDnsChange
+//	wifi                               - JsonWiFi
+//	servers_updated                    - JsonServersUpdated
+//	servers_changed                    - JsonServersChanged
+//	offline                            - JsonOfflineState
+//	split_tunnel                       - JsonSplitTunnelState
+//	session                            - JsonAccount
+//	daemon_exiting                     - no data
+
+// JsonDisconnected - 'disconnected' event data
+type JsonDisconnected struct {
+	Reason      string `json:"reason"` // unknown, auth_error or requested (disconnection requested by a client)
+	Description string `json:"description,omitempty"`
+	Failure     bool   `json:"failure"` // 'true' - the connection failed
+}
+
+// JsonDnsChange - 'dns' event data
+type JsonDnsChange struct {
+	Dns *JsonDnsConfig `json:"dns,omitempty"` // DNS of the current VPN connection (absent - default DNS)
+}
+
+// JsonWiFi - 'wifi' event data
+type JsonWiFi struct {
+	SSID       string `json:"ssid,omitempty"` // absent - not connected to WiFi
+	IsInsecure bool   `json:"is_insecure"`
+}
+
+// JsonServersUpdated - 'servers_updated' event data
+type JsonServersUpdated struct {
+	WireGuardServers int `json:"wireguard_servers"`
+	OpenVPNServers   int `json:"openvpn_servers"`
+}
+
+// JsonServersChanged - 'servers_changed' event data
+type JsonServersChanged struct {
+	Added       []JsonServerHostChange `json:"added,omitempty"`
+	Removed     []JsonServerHostChange `json:"removed,omitempty"`
+	KeysChanged []JsonServerHostChange `json:"keys_changed,omitempty"` // WireGuard hosts with changed public key
+}
+
+// JsonServerHostChange - the changed host of the servers list
+type JsonServerHostChange struct {
+	Protocol string `json:"protocol"` // WireGuard or OpenVPN
+	Gateway  string `json:"gateway"`
+	Hostname string `json:"hostname"`
+	Host     string `json:"host"` // IP address
+}
+
+// ---------------------
+
+func jsonVpnState(state vpn.State, connected types.ConnectedResp, serverInfo, exitServerInfo string) JsonVpnState {
+	ret := JsonVpnState{State: state.String(), Server: serverInfo, ExitServer: exitServerInfo}
+	if state != vpn.CONNECTED {
+		return ret
+	}
+	ret.Protocol = jsonProtocolName(connected.VpnType)
+	ret.Obfsproxy = connected.IsObfsproxy
+	ret.ExitHostname = connected.ExitHostname
+	ret.LocalIP = connected.ClientIP
+	ret.LocalIPv6 = connected.ClientIPv6
+	ret.ServerIP = connected.ServerIP
+	if connected.ServerPort > 0 {
+		ret.ServerPort = connected.ServerPort
+		ret.ServerPortType = jsonPortType(connected.IsTCP)
+	}
+	ret.ConnectedSince = connected.TimeSecFrom1970
+	return ret
+}
+
+func jsonVpnStateSetStats(s *JsonVpnState, stats types.ConnectionStatsResp) {
+	s.StateDetails = stats.StateDetails
+	s.LastHandshake = stats.LastHandshake
+	s.RxBytes = stats.RxBytes
+	s.TxBytes = stats.TxBytes
+}
+
+func jsonDnsConfig(cfg dns.DnsSettings, servers *apitypes.ServersInfoResponse) *JsonDnsConfig {
+	ret := &JsonDnsConfig{DnsHost: cfg.DnsHost, Encryption: "none", Template: cfg.DohTemplate}
+	switch cfg.Encryption {
+	case dns.EncryptionDnsOverHttps:
+		ret.Encryption = "doh"
+	case dns.EncryptionDnsOverTls:
+		ret.Encryption = "dot"
+	default:
+		ret.Antitracker, ret.AntitrackerHardcore = IsAntiTrackerIP(cfg.DnsHost, servers)
+	}
+	return ret
+}
+
+func jsonDnsState(state vpn.State, connectionDns dns.DnsSettings, servers *apitypes.ServersInfoResponse, cfg config.Configuration) JsonDnsState {
+	ret := JsonDnsState{DefaultConfig: *jsonDnsConfig(cfg.CustomDnsCfg, nil)}
+	ret.DefaultConfig.Antitracker = cfg.Antitracker || cfg.AntitrackerHardcore
+	ret.DefaultConfig.AntitrackerHardcore = cfg.AntitrackerHardcore
+	if state == vpn.CONNECTED {
+		ret.Connection = jsonDnsConfig(connectionDns, servers)
+	}
+	if isApplicable, _ := IsParamApplicable_LinuxForceModifyResolvconf(); isApplicable {
+		ret.LinuxForceResolvconf = _proto.GetHelloResponse().DaemonSettings.UserPrefs.Linux.IsDnsMgmtOldStyle
+	}
+	return ret
+}
+
+func jsonFirewallState(s types.KillSwitchStatusResp) JsonFirewallState {
+	return JsonFirewallState{Enabled: s.IsEnabled, Persistent: s.IsPersistent, AllowLan: s.IsAllowLAN, AllowLanMulticast: s.IsAllowMulticast,
+		AllowApiServers: s.IsAllowApiServers, Exceptions: s.UserExceptions}
+}
+
+func jsonSplitTunnelState(s types.SplitTunnelStatus) *JsonSplitTunnelState {
+	ret := &JsonSplitTunnelState{Enabled: s.IsEnabled, Apps: s.SplitTunnelApps,
+		BypassDestinations: s.BypassDestinations, VpnDestinations: s.VpnDestinations, ResolvedDomains: s.ResolvedDomains}
+	for _, app := range s.RunningApps {
+		if app.Pid == app.ExtIvpnRootPid {
+			ret.RunningAppsPids = append(ret.RunningAppsPids, app.Pid)
+		}
+	}
+	return ret
+}
+
+func jsonOfflineState(s types.OfflineStatus) *JsonOfflineState {
+	return &JsonOfflineState{IsOffline: s.IsOffline, OfflineSince: s.OfflineSince, LastError: s.LastError, LastApiSuccess: s.LastApiSuccess,
+		ServersUpdated: s.ServersUpdated, ServersValidUntil: s.ServersValidUntil, IsServersStale: s.IsServersStale,
+		IsWgKeyRotationOverdue: s.IsWgKeyRotationOverdue, WgKeyUsableUntil: s.WgKeyUsableUntil}
+}
+
+func jsonServers(servers []serverDesc, isPing, isDistance bool) []JsonServer {
+	ret := make([]JsonServer, 0, len(servers))
+	for _, s := range servers {
+		js := JsonServer{Protocol: s.protocol, Gateway: s.gateway, City: s.city, CountryCode: s.countryCode, Country: s.country,
+			Latitude: s.latitude, Longitude: s.longitude, IPv6Tunnel: s.isIPv6Tunnel, Hosts: make([]JsonServerHost, 0, len(s.hosts))}
+		if isPing && s.pingMs > 0 {
+			js.PingMs = s.pingMs
+			js.Score = rankScore(s.pingStats)
+		}
+		if isDistance && s.distanceKm >= 0 {
+			d := s.distanceKm
+			js.DistanceKm = &d
+		}
+		for _, h := range s.hosts {
+			js.Hosts = append(js.Hosts, JsonServerHost{Hostname: h.hostname, Host: h.host, Load: h.load, PingMs: h.pingMs})
+		}
+		ret = append(ret, js)
+	}
+	return ret
+}
+
+func jsonQueryHosts(hosts []serversquery.Host) []JsonQueryHost {
+	ret := make([]JsonQueryHost, 0, len(hosts))
+	for _, h := range hosts {
+		jh := JsonQueryHost{Protocol: h.VpnType.String(), Gateway: h.Gateway, City: h.City, CountryCode: h.CountryCode, Country: h.Country,
+			Hostname: h.Hostname, Host: h.Host, Load: h.Load, IPv6Tunnel: h.IPv6, Favorite: h.IsFavorite, Excluded: h.IsExcluded, PingMs: h.PingMs}
+		if h.DistanceKm >= 0 {
+			d := h.DistanceKm
+			jh.DistanceKm = &d
+		}
+		ret = append(ret, jh)
+	}
+	return ret
+}
+
+func jsonServersList(l preferences.ServersList) JsonServersList {
+	nonNil := func(v []string) []string {
+		if v == nil {
+			return []string{}
+		}
+		return v
+	}
+	return JsonServersList{Servers: nonNil(l.Servers), Hosts: nonNil(l.Hosts), Countries: nonNil(l.Countries)}
+}
+
+func jsonServersSettings(uPrefs preferences.UserPreferences) JsonServersSettings {
+	w := uPrefs.PingScoreWeights
+	if !w.IsDefined() {
+		w = preferences.DefaultPingScoreWeights()
+	}
+	ret := JsonServersSettings{ScoreWeights: JsonScoreWeights{Latency: w.Latency, Jitter: w.Jitter, Loss: w.Loss, Load: w.Load},
+		HistoryCollect: uPrefs.PingHistoryBackgroundCollection}
+	if home := uPrefs.HomeLocation; home.IsDefined() {
+		ret.HomeLocation = &JsonLocation{Latitude: home.Latitude, Longitude: home.Longitude}
+	}
+	return ret
+}
+
+func jsonWgKeysRotation(r preferences.WgKeysRotationRecord) JsonWgKeysRotation {
+	return JsonWgKeysRotation{Time: r.Time.Unix(), Trigger: r.Trigger, OldKeyFingerprint: r.OldKeyFingerprint, NewKeyFingerprint: r.NewKeyFingerprint,
+		Success: r.Success, Error: r.Error}
+}
+
+func jsonProtocolName(t vpn.Type) string {
+	if t == vpn.WireGuard {
+		return ProtoName_WireGuard
+	}
+	return ProtoName_OpenVPN
+}
+
+func jsonPortType(isTCP bool) string {
+	if isTCP {
+		return "TCP"
+	}
+	return "UDP"
+}
+
+func jsonIsLoggedIn() bool {
+	return len(strings.TrimSpace(_proto.GetHelloResponse().Session.Session)) > 0
+}
+
+func jsonServerHostChanges(changes []types.ServerHostChange) []JsonServerHostChange {
+	if len(changes) == 0 {
+		return nil
+	}
+	ret := make([]JsonServerHostChange, 0, len(changes))
+	for _, c := range changes {
+		ret = append(ret, JsonServerHostChange{Protocol: jsonProtocolName(c.VpnType), Gateway: c.Gateway, Hostname: c.Hostname, Host: c.Host})
+	}
+	return ret
+}
@@ -27,6 +27,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/ivpn/desktop-app/cli/flags"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
@@ -58,6 +59,10 @@ func (c *CmdLogs) Run() error {
 	}
 
 	if err != nil || c.enable || c.disable {
+		if err == nil {
+			isEnabled := c.enable
+			setJsonData(JsonLogs{Enabled: &isEnabled})
+		}
 		return err
 	}
 	return c.doShow()
@@ -112,6 +117,7 @@ func (c *CmdLogs) doShow() error {
 
 	fmt.Println(string(buff))
 	isSomethingPrinted = true
+	setJsonData(JsonLogs{LogFile: fname, Log: strings.TrimRight(string(buff), "\x00")})
 
 	if isPartOfFile {
 		fmt.Println("##############")
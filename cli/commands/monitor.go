@@ -0,0 +1,357 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// 'monitor' command event types
+const (
+	MonitorEventVpnState       = "vpn_state"       // VPN state changed (e.g. CONNECTING, DISCONNECTING)
+	MonitorEventReconnecting   = "reconnecting"    // VPN connection is reconnecting
+	MonitorEventConnected      = "connected"       // VPN connection established
+	MonitorEventDisconnected   = "disconnected"    // VPN disconnected (or the connection failed)
+	MonitorEventFirewall       = "firewall"        // firewall state or configuration changed
+	MonitorEventDns            = "dns"             // DNS of the current connection changed
+	MonitorEventWiFi           = "wifi"            // WiFi network changed
+	MonitorEventServersUpdated = "servers_updated" // servers list updated
+	MonitorEventServersChanged = "servers_changed" // servers list updated and the hosts were added, removed or changed
+	MonitorEventOffline        = "offline"         // offline mode state changed
+	MonitorEventSplitTunnel    = "split_tunnel"    // split tunnel state changed (e.g. an application started in split tunnel environment)
+	MonitorEventSession        = "session"         // logged in or logged out
+	MonitorEventDaemonExiting  = "daemon_exiting"  // the daemon is stopping
+)
+
+var monitorEventsAll = []string{MonitorEventVpnState, MonitorEventReconnecting, MonitorEventConnected, MonitorEventDisconnected,
+	MonitorEventFirewall, MonitorEventDns, MonitorEventWiFi, MonitorEventServersUpdated, MonitorEventServersChanged,
+	MonitorEventOffline, MonitorEventSplitTunnel, MonitorEventSession, MonitorEventDaemonExiting}
+
+type CmdMonitor struct {
+	flags.CmdInfo
+	events    string // comma-separated list of events to show (empty - all events)
+	noInitial bool
+
+	eventsFilter map[string]struct{}
+	servers      apitypes.ServersInfoResponse
+	sessionID    string
+}
+
+func (c *CmdMonitor) Init() {
+	c.Initialize("monitor", "Print the events of IVPN daemon as they happen (VPN state changes, firewall, DNS, WiFi, servers list updates ...)\nThe command is running until interrupted (Ctrl+C) or until the connection with the daemon is lost\nWith the global '-json' flag, each event is printed as a JSON object in a separate line (JSON lines)")
+	c.StringVar(&c.events, "events", "", "LIST", "Comma-separated list of events to print (default: all events)\n  Events: "+strings.Join(monitorEventsAll, ", ")+
+		"\n  Example: ivpn monitor -events connected,disconnected,reconnecting")
+	c.BoolVar(&c.noInitial, "no_initial", false, "Do not print the current VPN and firewall state on start")
+}
+
+// Run executes command
+func (c *CmdMonitor) Run() error {
+	c.eventsFilter = make(map[string]struct{})
+	for _, e := range strings.Split(c.events, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if len(e) == 0 {
+			continue
+		}
+		if !isMonitorEventKnown(e) {
+			return flags.BadParameter{Message: fmt.Sprintf("unknown event '%s' (supported events: %s)", e, strings.Join(monitorEventsAll, ", "))}
+		}
+		c.eventsFilter[e] = struct{}{}
+	}
+
+	setJsonLinesOutput()
+
+	// subscribe before requesting the current state: no changes are missed
+	notifications, unsubscribe, err := _proto.NotificationsSubscribe()
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	c.sessionID = _proto.GetHelloResponse().Session.Session
+	if servers, err := _proto.GetServers(); err == nil {
+		c.servers = servers
+	}
+
+	if !IsJsonOutput() {
+		fmt.Println("Monitoring IVPN daemon events (Ctrl+C to exit)...")
+	}
+
+	if !c.noInitial {
+		state, connected, err := _proto.GetVPNState()
+		if err != nil {
+			return err
+		}
+		if state == vpn.CONNECTED {
+			c.onConnected(connected)
+		} else {
+			c.printEvent(MonitorEventVpnState, jsonVpnState(state, connected, "", ""), fmt.Sprintf("VPN: %s", state))
+		}
+
+		fwState, err := _proto.FirewallStatus()
+		if err != nil {
+			return err
+		}
+		c.onFirewall(fwState)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	for {
+		select {
+		case <-interrupted:
+			return nil
+		case data, ok := <-notifications:
+			if !ok {
+				return ErrorDaemonUnreachable{Err: fmt.Errorf("connection lost")}
+			}
+			if err := c.processNotification(data); err != nil {
+				// do not stop monitoring because of one broken message
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+func isMonitorEventKnown(event string) bool {
+	for _, e := range monitorEventsAll {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// processNotification prints the event for the daemon message (messages not related to the monitored events are ignored)
+func (c *CmdMonitor) processNotification(data []byte) error {
+	cmd, err := types.GetCommandBase(data)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Command {
+	case types.GetTypeName(types.VpnStateResp{}):
+		var resp types.VpnStateResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		event := MonitorEventVpnState
+		if resp.StateVal == vpn.RECONNECTING {
+			event = MonitorEventReconnecting
+		}
+		vpnState := jsonVpnState(resp.StateVal, types.ConnectedResp{}, "", "")
+		vpnState.StateDetails = resp.StateAdditionalInfo
+		text := fmt.Sprintf("VPN: %s", resp.StateVal)
+		if len(resp.StateAdditionalInfo) > 0 {
+			text += fmt.Sprintf(" (%s)", resp.StateAdditionalInfo)
+		}
+		c.printEvent(event, vpnState, text)
+
+	case types.GetTypeName(types.ConnectedResp{}):
+		var resp types.ConnectedResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		c.onConnected(resp)
+
+	case types.GetTypeName(types.DisconnectedResp{}):
+		var resp types.DisconnectedResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		reason := "unknown"
+		switch resp.Reason {
+		case types.AuthenticationError:
+			reason = "auth_error"
+		case types.DisconnectRequested:
+			reason = "requested"
+		}
+		text := "VPN: DISCONNECTED"
+		if len(resp.ReasonDescription) > 0 {
+			text += fmt.Sprintf(" (%s)", resp.ReasonDescription)
+		}
+		c.printEvent(MonitorEventDisconnected, JsonDisconnected{Reason: reason, Description: resp.ReasonDescription, Failure: resp.Failure}, text)
+
+	case types.GetTypeName(types.KillSwitchStatusResp{}):
+		var resp types.KillSwitchStatusResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		c.onFirewall(resp)
+
+	case types.GetTypeName(types.SetAlternateDNSResp{}):
+		var resp types.SetAlternateDNSResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		if !resp.IsSuccess {
+			return nil
+		}
+		if resp.ChangedDNS.IsEmpty() {
+			c.printEvent(MonitorEventDns, JsonDnsChange{}, "DNS: default")
+		} else {
+			c.printEvent(MonitorEventDns, JsonDnsChange{Dns: jsonDnsConfig(resp.ChangedDNS, &c.servers)}, fmt.Sprintf("DNS: %s", resp.ChangedDNS.InfoString()))
+		}
+
+	case types.GetTypeName(types.WiFiCurrentNetworkResp{}):
+		var resp types.WiFiCurrentNetworkResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		text := "WiFi: not connected"
+		if len(resp.SSID) > 0 {
+			text = fmt.Sprintf("WiFi: '%s'", resp.SSID)
+			if resp.IsInsecureNetwork {
+				text += " (insecure network)"
+			}
+		}
+		c.printEvent(MonitorEventWiFi, JsonWiFi{SSID: resp.SSID, IsInsecure: resp.IsInsecureNetwork}, text)
+
+	case types.GetTypeName(types.ServerListResp{}):
+		var resp types.ServerListResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		c.servers = resp.VpnServers
+		wgCnt, ovpnCnt := len(resp.VpnServers.WireguardServers), len(resp.VpnServers.OpenvpnServers)
+		c.printEvent(MonitorEventServersUpdated, JsonServersUpdated{WireGuardServers: wgCnt, OpenVPNServers: ovpnCnt},
+			fmt.Sprintf("Servers list updated (WireGuard: %d, OpenVPN: %d servers)", wgCnt, ovpnCnt))
+
+	case types.GetTypeName(types.ServersChangedResp{}):
+		var resp types.ServersChangedResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		if resp.Diff.IsEmpty() {
+			return nil
+		}
+		c.printEvent(MonitorEventServersChanged,
+			JsonServersChanged{Added: jsonServerHostChanges(resp.Diff.Added), Removed: jsonServerHostChanges(resp.Diff.Removed), KeysChanged: jsonServerHostChanges(resp.Diff.KeysChanged)},
+			fmt.Sprintf("Servers list changed (hosts added: %d, removed: %d, keys changed: %d)", len(resp.Diff.Added), len(resp.Diff.Removed), len(resp.Diff.KeysChanged)))
+
+	case types.GetTypeName(types.OfflineStatusResp{}):
+		var resp types.OfflineStatusResp
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		text := "Online: the API servers are reachable"
+		if resp.IsOffline {
+			text = "Offline: the API servers are unreachable"
+			if len(resp.LastError) > 0 {
+				text += fmt.Sprintf(" (%s)", resp.LastError)
+			}
+		}
+		c.printEvent(MonitorEventOffline, jsonOfflineState(resp.OfflineStatus), text)
+
+	case types.GetTypeName(types.SplitTunnelStatus{}):
+		var resp types.SplitTunnelStatus
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		stState := jsonSplitTunnelState(resp)
+		text := "Split Tunnel: disabled"
+		if resp.IsEnabled {
+			text = fmt.Sprintf("Split Tunnel: enabled (running applications: %d)", len(stState.RunningAppsPids))
+		}
+		c.printEvent(MonitorEventSplitTunnel, stState, text)
+
+	case types.GetTypeName(types.HelloResp{}):
+		// the last 'hello' response is already saved by the client
+		helloResp := _proto.GetHelloResponse()
+		if helloResp.Session.Session == c.sessionID {
+			return nil // session not changed (e.g. settings updated)
+		}
+		c.sessionID = helloResp.Session.Session
+		if len(c.sessionID) > 0 {
+			c.printEvent(MonitorEventSession, JsonAccount{LoggedIn: true, AccountID: helloResp.Session.AccountID}, "Logged in")
+		} else {
+			c.printEvent(MonitorEventSession, JsonAccount{LoggedIn: false}, "Logged out")
+		}
+
+	case types.GetTypeName(types.ServiceExitingResp{}):
+		c.printEvent(MonitorEventDaemonExiting, nil, "IVPN daemon is stopping")
+	}
+
+	return nil
+}
+
+func (c *CmdMonitor) onConnected(resp types.ConnectedResp) {
+	slist := serversListByVpnType(c.servers, resp.VpnType)
+	vpnState := jsonVpnState(vpn.CONNECTED, resp, getServerInfoByIP(slist, resp.ServerIP), getServerInfoByHostName(slist, resp.ExitHostname))
+	if !resp.ManualDNS.IsEmpty() {
+		vpnState.Dns = jsonDnsConfig(resp.ManualDNS, &c.servers)
+	}
+
+	text := fmt.Sprintf("VPN: CONNECTED (%s) %s", jsonProtocolName(resp.VpnType), resp.ServerIP)
+	if len(vpnState.Server) > 0 {
+		text = fmt.Sprintf("VPN: CONNECTED (%s) %s", jsonProtocolName(resp.VpnType), vpnState.Server)
+	}
+	if len(vpnState.ExitServer) > 0 {
+		text += fmt.Sprintf(" -> %s", vpnState.ExitServer)
+	}
+	c.printEvent(MonitorEventConnected, vpnState, text)
+}
+
+func (c *CmdMonitor) onFirewall(resp types.KillSwitchStatusResp) {
+	text := "Firewall: disabled"
+	if resp.IsEnabled {
+		text = "Firewall: enabled"
+		if resp.IsPersistent {
+			text += " (always-on)"
+		}
+	}
+	if resp.IsAllowLAN {
+		text += "; LAN allowed"
+	}
+	c.printEvent(MonitorEventFirewall, jsonFirewallState(resp), text)
+}
+
+// printEvent prints the event (JSON line in JSON output mode; otherwise - text line)
+func (c *CmdMonitor) printEvent(event string, data interface{}, text string) {
+	if len(c.eventsFilter) > 0 {
+		if _, ok := c.eventsFilter[event]; !ok {
+			return
+		}
+	}
+
+	now := time.Now()
+	if IsJsonOutput() {
+		if err := printJson(JsonEvent{Event: event, Time: now.Unix(), Data: data}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return
+	}
+	fmt.Printf("%s [%s] %s\n", now.Format("2006-01-02 15:04:05"), event, text)
+}
@@ -0,0 +1,48 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+)
+
+// Multi-Hop exit server selection rules ('-exit_country', '-exit_other_jurisdiction', '-exit_fastest').
+// The rules are sent to the daemon: the daemon selects the exit server and re-applies the rules on each reconnection.
+
+// isExitRuleDefined returns 'true' when the Multi-Hop exit server have to be selected by rules
+func (c *CmdConnect) isExitRuleDefined() bool {
+	return c.exitRules().IsDefined()
+}
+
+// exitRules returns the Multi-Hop exit server selection rules defined by the command arguments
+func (c *CmdConnect) exitRules() types.MultihopExitRules {
+	rules := types.MultihopExitRules{OtherJurisdiction: c.exitOtherJurisdiction, Fastest: c.exitFastest}
+	for _, cc := range strings.Split(c.exitCountry, ",") {
+		if cc = strings.ToUpper(strings.TrimSpace(cc)); len(cc) > 0 {
+			rules.Countries = append(rules.Countries, cc)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,153 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"golang.org/x/term"
+)
+
+type CmdOpenVpnCustom struct {
+	flags.CmdInfo
+	list       bool
+	importFile string
+	name       string
+	username   string
+	delete     string
+}
+
+func (c *CmdOpenVpnCustom) Init() {
+	c.Initialize("ovpncustom", "Custom OpenVPN profiles management\nUser-supplied OpenVPN profiles (.ovpn files) can be imported and used for connection\n(use 'ivpn connect -ovpn_custom PROFILE' to connect; IVPN account is not required)")
+	c.BoolVar(&c.list, "list", false, "(default) Show custom OpenVPN profiles")
+	c.StringVar(&c.importFile, "import", "", "FILE", "Import OpenVPN profile\n  Note: certificates and keys must be inline; only the common client-side parameters are supported\n  (script hooks, plugins, crypto engines/providers and references to local files are not accepted)")
+	c.StringVar(&c.name, "name", "", "NAME", "Name for the imported profile (applicable only with '-import')")
+	c.StringVar(&c.username, "user", "", "USERNAME", "Username for 'auth-user-pass' authentication (applicable only with '-import')\n  The password will be requested interactively")
+	c.StringVar(&c.delete, "delete", "", "PROFILE", "Delete custom profile (profile ID or name)")
+}
+
+func (c *CmdOpenVpnCustom) Run() error {
+	var profiles []types.OpenVpnCustomProfileInfo
+	var err error
+
+	switch {
+	case len(c.importFile) > 0:
+		data, err := os.ReadFile(c.importFile)
+		if err != nil {
+			return fmt.Errorf("failed to read profile file: %w", err)
+		}
+
+		password := ""
+		if len(c.username) > 0 {
+			fmt.Print("Enter password: ")
+			pass, err := term.ReadPassword(int(syscall.Stdin))
+			fmt.Println("")
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			password = string(pass)
+		}
+
+		if profiles, err = _proto.OvpnCustomProfileImport(c.name, string(data), c.username, password); err != nil {
+			return err
+		}
+		fmt.Println("Profile imported")
+
+	case len(c.delete) > 0:
+		if profiles, err = _proto.OvpnCustomProfilesGet(); err != nil {
+			return err
+		}
+		p, err := findOvpnCustomProfile(profiles, c.delete)
+		if err != nil {
+			return err
+		}
+		if profiles, err = _proto.OvpnCustomProfileDelete(p.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Profile '%s' deleted\n", p.Name)
+
+	default:
+		if profiles, err = _proto.OvpnCustomProfilesGet(); err != nil {
+			return err
+		}
+	}
+
+	printOvpnCustomProfiles(profiles)
+	return nil
+}
+
+// findOvpnCustomProfile looks for user-supplied OpenVPN profile by ID (or ID prefix) or by name
+func findOvpnCustomProfile(profiles []types.OpenVpnCustomProfileInfo, idOrName string) (types.OpenVpnCustomProfileInfo, error) {
+	var found []types.OpenVpnCustomProfileInfo
+	for _, p := range profiles {
+		if p.ID == idOrName || p.Name == idOrName {
+			return p, nil
+		}
+		if strings.HasPrefix(p.ID, idOrName) || strings.EqualFold(p.Name, idOrName) {
+			found = append(found, p)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	if len(found) > 1 {
+		return types.OpenVpnCustomProfileInfo{}, fmt.Errorf("more than one custom OpenVPN profile found ('%s')", idOrName)
+	}
+	return types.OpenVpnCustomProfileInfo{}, fmt.Errorf("custom OpenVPN profile not found ('%s')", idOrName)
+}
+
+func printOvpnCustomProfiles(profiles []types.OpenVpnCustomProfileInfo) {
+	jsonProfiles := make([]JsonOvpnCustomProfile, 0, len(profiles))
+	for _, p := range profiles {
+		jsonProfiles = append(jsonProfiles, JsonOvpnCustomProfile{ID: p.ID, Name: p.Name, RemoteHost: p.RemoteHost, RemotePort: p.RemotePort,
+			Protocol: jsonPortType(p.IsTCP), Username: p.Username})
+	}
+	setJsonData(jsonProfiles)
+
+	if len(profiles) == 0 {
+		fmt.Println("No custom OpenVPN profiles")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "ID\t|NAME\t|REMOTE\t|PROTOCOL\t|USERNAME\t")
+	for _, p := range profiles {
+		proto := "UDP"
+		if p.IsTCP {
+			proto = "TCP"
+		}
+		fmt.Fprintln(w, fmt.Sprintf("%s\t|%s\t|%s:%d\t|%s\t|%s\t",
+			strings.Split(p.ID, "-")[0],
+			p.Name,
+			p.RemoteHost, p.RemotePort,
+			proto,
+			p.Username))
+	}
+	w.Flush()
+}
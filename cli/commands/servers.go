@@ -24,12 +24,18 @@ package commands
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	apitypes "github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 
 	"github.com/ivpn/desktop-app/cli/flags"
@@ -42,18 +48,39 @@ const (
 
 type CmdServers struct {
 	flags.CmdInfo
-	proto        string
-	location     bool
-	city         bool
-	country      bool
-	countryCode  bool
-	filter       string
-	ping         bool
-	hosts        bool
-	load         bool
-	filterInvert bool
+	proto          string
+	location       bool
+	city           bool
+	country        bool
+	countryCode    bool
+	filter         string
+	ping           bool
+	hosts          bool
+	load           bool
+	filterInvert   bool
+	scoreWeights   string
+	probe          string
+	history        string
+	historyCollect string
+	query          string
+	distance       bool
+	homeLocation   string
+
+	// favorite and excluded servers (stored by the daemon)
+	lists          bool
+	favoriteAdd    string
+	favoriteRemove string
+	excludeAdd     string
+	excludeRemove  string
 }
 
+// serversQueryHelp - description of the servers query syntax (for the commands help)
+const serversQueryHelp = `Servers query. Conditions: FIELD OPERATOR VALUE, FIELD in (VALUE, ...), combined with 'and', 'or', 'not', '(...)'
+  Fields: country (code or name), cc, city, location, hostname, host, proto (wg|ovpn), load (%), ping (ms; the latest measured), distance (km), ipv6, favorite, excluded
+  (excluded servers are skipped unless the query checks the 'excluded' field)
+  Operators: = != < <= > >= ~ (contains)
+  Optional suffix: sort by ping|load|distance [asc|desc] limit N`
+
 func (c *CmdServers) Init() {
 	c.Initialize("servers", "Show servers list\n(FILTER - optional parameter: show only servers which contains FILTER in server description)")
 	c.DefaultStringVar(&c.filter, "FILTER")
@@ -72,14 +99,58 @@ func (c *CmdServers) Init() {
 
 	c.BoolVar(&c.city, "city", false, "Apply FILTER to city name")
 
-	c.BoolVar(&c.ping, "ping", false, "Ping servers and view ping result")
+	c.BoolVar(&c.ping, "ping", false, "Ping servers and view ping result\n  (latency, jitter, packet loss and ranking score; servers are sorted by score: the best - last)\n  (the score is the median of the latest measurements, when the latency history is available)")
+
+	c.StringVar(&c.probe, "probe", "", "MODE", "The way the servers reachability is checked by '-ping' (auto|icmp|protocol)\n  auto - ICMP; the servers which are not replying are probed on the VPN protocol level (default)\n  icmp - ICMP only\n  protocol - VPN protocol probes only (WireGuard handshake; OpenVPN UDP or TCP connection)")
+
+	c.BoolVar(&c.distance, "distance", false, "Show distance to servers (servers are sorted by distance: the nearest - last; sorting by '-ping' has priority)\n  (the distance is calculated from the home location, if defined, or from the location detected by geo-lookup)")
+	c.StringVar(&c.homeLocation, "home_location", "", "LATITUDE,LONGITUDE", "Set home location to calculate distance to servers ('off' - use the location detected by geo-lookup)\n  (useful when the geo-lookup is blocked or not accurate; e.g. '46.20,6.14')")
 
 	c.BoolVar(&c.hosts, "hosts", false, "Show location hosts")
 	c.BoolVar(&c.load, "load", false, "Show load info for each host")
 
 	c.BoolVar(&c.filterInvert, "filter_invert", false, "Invert filtering result")
+
+	c.StringVar(&c.query, "q", "", "QUERY", serversQueryHelp+"\n  Example: ivpn servers -q \"country in (CH,SE) and load < 50 and proto = wg and ipv6 sort by ping limit 3\"\n  (use together with '-ping' to measure the latency before the query)")
+	c.StringVar(&c.query, "query", "", "QUERY", "The same as '-q'")
+
+	c.BoolVar(&c.lists, "lists", false, "Show the lists of favorite and excluded servers")
+	c.StringVar(&c.favoriteAdd, "favorite", "", "SERVERS", "Add servers to the favorites (comma-separated list)\n  Server can be defined by location (e.g. 'ch' or 'ch.wg.ivpn.net'), host name (e.g. 'ch1.wg.ivpn.net') or country ('country:CH')\n  (favorite servers can be selected by query: e.g. 'ivpn connect -q favorite -any')")
+	c.StringVar(&c.favoriteRemove, "favorite_remove", "", "SERVERS", "Remove servers from the favorites (comma-separated list)")
+	c.StringVar(&c.excludeAdd, "exclude", "", "SERVERS", "Add servers to the excluded list (comma-separated list; the format is the same as for '-favorite')\n  Excluded servers are never in use for connection (including '-fastest', '-any', '-q' and Multi-Hop exit server selection)\n  Example: ivpn servers -exclude country:US,country:GB,de1.wg.ivpn.net")
+	c.StringVar(&c.excludeRemove, "exclude_remove", "", "SERVERS", "Remove servers from the excluded list (comma-separated list)")
+
+	c.StringVar(&c.history, "history", "", "HOST", "Show the latency history of the host (host name or IP address)\n  (the daemon keeps the ping results measured during the last days)")
+	c.StringVar(&c.historyCollect, "history_collect", "", "ON/OFF", "Enable/disable periodical background pinging of all servers to keep the latency history up to date\n  (disabled by default; ICMP only; skipped when logged out or when the firewall is enabled while disconnected)")
+
+	c.StringVar(&c.scoreWeights, "score_weights", "", "LATENCY:JITTER:LOSS:LOAD", "Set weights of the servers ranking score components ('default' - reset to default weights)\n  score = LATENCY*ping(ms) + JITTER*jitter(ms) + LOSS*packet_loss(%) + LOAD*server_load(%)\n  (lower score - better server; e.g. '1:0:0:0' - rank servers by latency only)")
 }
 func (c *CmdServers) Run() error {
+	if len(c.scoreWeights) > 0 {
+		if err := c.setScoreWeights(); err != nil {
+			return err
+		}
+		if !c.ping {
+			return nil
+		}
+	}
+	if len(c.historyCollect) > 0 {
+		if err := c.setHistoryCollect(); err != nil {
+			return err
+		}
+		if len(c.history) == 0 {
+			return nil
+		}
+	}
+	if len(c.homeLocation) > 0 {
+		if err := c.setHomeLocation(); err != nil {
+			return err
+		}
+		if !c.distance {
+			return nil
+		}
+	}
+
 	var servers apitypes.ServersInfoResponse
 	var err error
 
@@ -104,6 +175,21 @@ func (c *CmdServers) Run() error {
 
 	slist := serversList(servers)
 
+	if c.isServersListsUpdateRequested() {
+		if err := c.updateServersLists(slist); err != nil {
+			return err
+		}
+		c.lists = true
+	}
+	if c.lists {
+		c.showServersLists()
+		return nil
+	}
+
+	if len(c.history) > 0 {
+		return c.showHistory(slist)
+	}
+
 	if c.ping {
 		var vpnType *vpn.Type = nil
 		if len(c.proto) > 0 {
@@ -111,18 +197,35 @@ func (c *CmdServers) Run() error {
 				vpnType = &p
 			}
 		}
-		if err := serversPing(slist, true, c.hosts, vpnType); err != nil {
+		probeStrategy := ""
+		switch strings.ToLower(c.probe) {
+		case "", "auto":
+			probeStrategy = types.PingProbeAuto
+		case types.PingProbeICMP, types.PingProbeProtocol:
+			probeStrategy = strings.ToLower(c.probe)
+		default:
+			return flags.BadParameter{Message: "use 'auto', 'icmp' or 'protocol' value for '-probe' argument"}
+		}
+		if err := serversPing(slist, true, c.hosts || len(c.query) > 0, vpnType, probeStrategy); err != nil {
 			return err
 		}
 	}
 
+	if len(c.query) > 0 {
+		return c.showQueryResult()
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight|tabwriter.Debug)
 
 	pingHeader := ""
+	distanceHeader := ""
 	hostsHeader := ""
 	hostsLoadHeader := ""
 	if c.ping {
-		pingHeader = "PING\t"
+		pingHeader = "PING (MIN-MAX)\tJITTER\tLOSS\tSCORE\tPROBE\t"
+	}
+	if c.distance {
+		distanceHeader = "DISTANCE\t"
 	}
 	if c.hosts {
 		hostsHeader = "HOSTS\t"
@@ -131,7 +234,7 @@ func (c *CmdServers) Run() error {
 		}
 	}
 
-	fmt.Fprintln(w, "PROTOCOL\tLOCATION\tCITY\tCOUNTRY\tIPv? tunnel\t"+pingHeader+hostsHeader+hostsLoadHeader)
+	fmt.Fprintln(w, "PROTOCOL\tLOCATION\tCITY\tCOUNTRY\tIPv? tunnel\t"+pingHeader+distanceHeader+hostsHeader+hostsLoadHeader)
 
 	helloResp := _proto.GetHelloResponse()
 	isWgDisabled := len(helloResp.DisabledFunctions.WireGuardError) > 0
@@ -139,6 +242,17 @@ func (c *CmdServers) Run() error {
 
 	svrs := serversFilter(isWgDisabled, isOpenVPNDisabled,
 		slist, c.filter, c.proto, c.location, c.city, c.countryCode, c.country, c.filterInvert)
+
+	var location types.CurrentLocationResp
+	if c.distance {
+		if location, err = serversSetDistance(svrs); err != nil {
+			return err
+		}
+		if !c.ping {
+			serversSortByDistance(svrs)
+		}
+	}
+	setJsonData(jsonServers(svrs, c.ping, c.distance))
 	for _, s := range svrs {
 		str := ""
 		IPvInfo := "IPv4"
@@ -148,10 +262,11 @@ func (c *CmdServers) Run() error {
 
 		pingStr := ""
 		if c.ping {
-			pingStr = " ?  \t"
-			if s.pingMs > 0 {
-				pingStr = fmt.Sprintf("%dms\t", s.pingMs)
-			}
+			pingStr = pingStatsStr(s.pingMs, s.pingStats)
+		}
+		distanceStr := ""
+		if c.distance {
+			distanceStr = distanceKmStr(s.distanceKm) + "\t"
 		}
 
 		firstHostStr := ""
@@ -167,23 +282,23 @@ func (c *CmdServers) Run() error {
 			}
 		}
 
-		str = fmt.Sprintf("%s\t%s\t%s (%s)\t %s\t%s\t%s%s%s", s.protocol, s.gateway, s.city, s.countryCode, s.country, IPvInfo, pingStr, firstHostStr, firstHostLoadStr)
+		str = fmt.Sprintf("%s\t%s\t%s (%s)\t %s\t%s\t%s%s%s%s", s.protocol, s.gateway, s.city, s.countryCode, s.country, IPvInfo, pingStr, distanceStr, firstHostStr, firstHostLoadStr)
 		fmt.Fprintln(w, str)
 
 		if c.hosts && len(s.hosts) > 1 {
 			for _, h := range s.hosts[1:] {
 				if c.ping {
-					pingStr = " ?  \t"
-					if h.pingMs > 0 {
-						pingStr = fmt.Sprintf("%dms\t", h.pingMs)
-					}
+					pingStr = pingStatsStr(h.pingMs, h.pingStats)
 				}
 
 				loadStr := ""
 				if c.load {
 					loadStr = fmt.Sprintf("%d", int(h.load+0.5)) + "%\t"
 				}
-				str = fmt.Sprintf("%s\t%s\t%s %s\t %s\t%s\t%s%s%s", "", "", "", "", "", "", pingStr, h.hostname+"\t", loadStr)
+				if c.distance {
+					distanceStr = "\t"
+				}
+				str = fmt.Sprintf("%s\t%s\t%s %s\t %s\t%s\t%s%s%s%s", "", "", "", "", "", "", pingStr, distanceStr, h.hostname+"\t", loadStr)
 				fmt.Fprintln(w, str)
 			}
 		}
@@ -192,6 +307,19 @@ func (c *CmdServers) Run() error {
 
 	w.Flush()
 
+	if c.distance {
+		printLocationInfo(location)
+	}
+
+	if c.ping {
+		for _, s := range svrs {
+			if s.pingStats.Path == types.PingPathTunnel {
+				fmt.Println("NOTE: VPN is connected; the latency was measured through the VPN tunnel")
+				break
+			}
+		}
+	}
+
 	if isOpenVPNDisabled {
 		fmt.Println("WARNING: OpenVPN servers were not shown because OpenVPN functionality disabled:\n\t", helloResp.DisabledFunctions.OpenVPNError)
 	}
@@ -202,6 +330,182 @@ func (c *CmdServers) Run() error {
 	return nil
 }
 
+func (c *CmdServers) showQueryResult() error {
+	helloResp := _proto.GetHelloResponse()
+	isWgDisabled := len(helloResp.DisabledFunctions.WireGuardError) > 0
+	isOpenVPNDisabled := len(helloResp.DisabledFunctions.OpenVPNError) > 0
+
+	hosts, err := queryServers(c.query, isWgDisabled, isOpenVPNDisabled)
+	if err != nil {
+		return err
+	}
+	setJsonData(jsonQueryHosts(hosts))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	fmt.Fprintln(w, "PROTOCOL\tLOCATION\tCITY\tCOUNTRY\tHOST\tLOAD\tIPv? tunnel\tPING\tDISTANCE\t")
+	for _, h := range hosts {
+		IPvInfo := "IPv4"
+		if h.IPv6 {
+			IPvInfo = "IPv4/IPv6"
+		}
+		pingStr := "-"
+		if h.PingMs > 0 {
+			pingStr = fmt.Sprintf("%dms", h.PingMs)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s (%s)\t %s\t%s\t%d%%\t%s\t%s\t%s\t\n", h.VpnType, h.Gateway, h.City, h.CountryCode, h.Country, h.Hostname,
+			int(h.Load+0.5), IPvInfo, pingStr, distanceKmStr(h.DistanceKm))
+	}
+	w.Flush()
+
+	if len(hosts) == 0 {
+		fmt.Println("No servers found by the query")
+	}
+	return nil
+}
+
+// queryServers returns the servers hosts which are matching the query (the hosts of disabled VPN protocols are skipped)
+func queryServers(query string, isWgDisabled, isOvpnDisabled bool) ([]serversquery.Host, error) {
+	hosts, err := _proto.QueryServers(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]serversquery.Host, 0, len(hosts))
+	for _, h := range hosts {
+		if (isWgDisabled && h.VpnType == vpn.WireGuard) || (isOvpnDisabled && h.VpnType == vpn.OpenVPN) {
+			continue
+		}
+		ret = append(ret, h)
+	}
+	return ret, nil
+}
+
+func (c *CmdServers) showHistory(servers []serverDesc) error {
+	hostname, hostIP := "", ""
+	for _, s := range servers {
+		for _, h := range s.hosts {
+			if strings.EqualFold(h.hostname, c.history) || h.host == c.history {
+				hostname, hostIP = h.hostname, h.host
+				break
+			}
+		}
+	}
+	if len(hostIP) == 0 {
+		if net.ParseIP(c.history) == nil {
+			return fmt.Errorf("unknown host '%s'", c.history)
+		}
+		hostIP = c.history
+	}
+
+	samples, err := _proto.PingHistory(hostIP, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	jsonSamples := make([]JsonPingSample, 0, len(samples))
+	for _, smp := range samples {
+		jsonSamples = append(jsonSamples, JsonPingSample{Time: smp.Time, PingMs: smp.Ping, JitterMs: smp.Jitter, PacketLoss: smp.PacketLoss,
+			Score: smp.Score, Probe: smp.Probe, Path: smp.Path})
+	}
+	setJsonData(jsonSamples)
+
+	hostDescription := hostIP
+	if len(hostname) > 0 {
+		hostDescription = fmt.Sprintf("%s (%s)", hostname, hostIP)
+	}
+	if len(samples) == 0 {
+		fmt.Printf("No latency history for %s\n", hostDescription)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight|tabwriter.Debug)
+	fmt.Fprintln(w, "TIME\tPING\tJITTER\tLOSS\tSCORE\tPROBE\tPATH\t")
+	pingMin, pingMax, pingSum, replied := 0, 0, 0, 0
+	for _, smp := range samples {
+		if smp.IsFailed() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d%%\t%.0f\t%s\t%s\t\n", time.Unix(smp.Time, 0).Format("2006-01-02 15:04:05"),
+				"no reply", "-", int(smp.PacketLoss+0.5), smp.Score, smp.Probe, smp.Path)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%dms\t%.1fms\t%d%%\t%.0f\t%s\t%s\t\n", time.Unix(smp.Time, 0).Format("2006-01-02 15:04:05"),
+			smp.Ping, smp.Jitter, int(smp.PacketLoss+0.5), smp.Score, smp.Probe, smp.Path)
+
+		if replied == 0 || smp.Ping < pingMin {
+			pingMin = smp.Ping
+		}
+		if smp.Ping > pingMax {
+			pingMax = smp.Ping
+		}
+		pingSum += smp.Ping
+		replied++
+	}
+	w.Flush()
+
+	if replied == 0 {
+		fmt.Printf("Latency history for %s: %d samples; no replies\n", hostDescription, len(samples))
+		return nil
+	}
+	fmt.Printf("Latency history for %s: %d samples (%d without reply); ping min/avg/max: %d/%d/%d ms\n",
+		hostDescription, len(samples), len(samples)-replied, pingMin, pingSum/replied, pingMax)
+	return nil
+}
+
+func (c *CmdServers) setHistoryCollect() error {
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+	switch strings.ToLower(c.historyCollect) {
+	case "on":
+		uPrefs.PingHistoryBackgroundCollection = true
+	case "off":
+		uPrefs.PingHistoryBackgroundCollection = false
+	default:
+		return flags.BadParameter{Message: "use 'on' or 'off' value for '-history_collect' argument"}
+	}
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+	setJsonData(jsonServersSettings(uPrefs))
+
+	if uPrefs.PingHistoryBackgroundCollection {
+		fmt.Println("Background collection of the latency history: enabled")
+	} else {
+		fmt.Println("Background collection of the latency history: disabled")
+	}
+	return nil
+}
+
+func (c *CmdServers) setScoreWeights() error {
+	var weights preferences.PingScoreWeights
+	if strings.ToLower(c.scoreWeights) != "default" {
+		cols := strings.Split(c.scoreWeights, ":")
+		if len(cols) != 4 {
+			return flags.BadParameter{Message: "use 'LATENCY:JITTER:LOSS:LOAD' format for '-score_weights' argument (e.g. '1:1:5:1')"}
+		}
+		values := make([]float64, len(cols))
+		for i, v := range cols {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || f < 0 {
+				return flags.BadParameter{Message: fmt.Sprintf("bad weight value '%s' (non-negative number expected)", v)}
+			}
+			values[i] = f
+		}
+		weights = preferences.PingScoreWeights{Latency: values[0], Jitter: values[1], Loss: values[2], Load: values[3]}
+	}
+
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+	uPrefs.PingScoreWeights = weights
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+
+	setJsonData(jsonServersSettings(uPrefs))
+
+	if !weights.IsDefined() {
+		weights = preferences.DefaultPingScoreWeights()
+	}
+	fmt.Printf("Servers ranking score weights: latency=%v jitter=%v loss=%v load=%v\n", weights.Latency, weights.Jitter, weights.Loss, weights.Load)
+	return nil
+}
+
 // ---------------------
 
 func getVpnTypeByFlag(proto string) (t vpn.Type, err error) {
@@ -244,7 +548,7 @@ func serversListByVpnType(servers apitypes.ServersInfoResponse, t vpn.Type) []se
 				}
 				hosts = append(hosts, hostDesc{host: strings.TrimSpace(h.Host), hostname: strings.TrimSpace(h.Hostname), load: h.Load})
 			}
-			ret = append(ret, serverDesc{protocol: ProtoName_WireGuard, gateway: s.Gateway, city: s.City, countryCode: s.CountryCode, country: s.Country, hosts: hosts, isIPv6Tunnel: isIPv6Tunnel})
+			ret = append(ret, serverDesc{protocol: ProtoName_WireGuard, gateway: s.Gateway, city: s.City, countryCode: s.CountryCode, country: s.Country, latitude: s.Latitude, longitude: s.Longitude, hosts: hosts, isIPv6Tunnel: isIPv6Tunnel})
 		}
 	} else {
 		ret = make([]serverDesc, 0, len(servers.OpenvpnServers))
@@ -255,7 +559,7 @@ func serversListByVpnType(servers apitypes.ServersInfoResponse, t vpn.Type) []se
 			for _, h := range s.Hosts {
 				hosts = append(hosts, hostDesc{host: strings.TrimSpace(h.Host), hostname: strings.TrimSpace(h.Hostname), load: h.Load})
 			}
-			ret = append(ret, serverDesc{protocol: ProtoName_OpenVPN, gateway: s.Gateway, city: s.City, countryCode: s.CountryCode, country: s.Country, hosts: hosts})
+			ret = append(ret, serverDesc{protocol: ProtoName_OpenVPN, gateway: s.Gateway, city: s.City, countryCode: s.CountryCode, country: s.Country, latitude: s.Latitude, longitude: s.Longitude, hosts: hosts})
 		}
 	}
 	return ret
@@ -328,9 +632,9 @@ func serversFilter(isWgDisabled bool, isOvpnDisabled bool, servers []serverDesc,
 	return ret
 }
 
-func serversPing(servers []serverDesc, needSort bool, pingAllHostsOnFirstPhase bool, vpnTypePrioritized *vpn.Type) error {
+func serversPing(servers []serverDesc, needSort bool, pingAllHostsOnFirstPhase bool, vpnTypePrioritized *vpn.Type, probeStrategy string) error {
 	fmt.Println("Pinging servers ...")
-	pingRes, err := _proto.PingServers(pingAllHostsOnFirstPhase, vpnTypePrioritized)
+	pingRes, err := _proto.PingServers(pingAllHostsOnFirstPhase, vpnTypePrioritized, probeStrategy)
 	if err != nil {
 		return err
 	}
@@ -340,26 +644,25 @@ func serversPing(servers []serverDesc, needSort bool, pingAllHostsOnFirstPhase b
 
 	for _, pr := range pingRes {
 		for i, s := range servers {
-			serverPing := 0
 			// set ping result for each host
 			for j, h := range s.hosts {
 				if h.host == pr.Host {
 					h.pingMs = pr.Ping
+					h.pingStats = pr
 					s.hosts[j] = h
-					if serverPing <= 0 || serverPing > pr.Ping {
-						serverPing = pr.Ping
+					// the server ping result is the result of its best host (lowest score)
+					if s.pingMs <= 0 || rankScore(s.pingStats) > rankScore(pr) {
+						s.pingMs = pr.Ping
+						s.pingStats = pr
+						servers[i] = s
 					}
 				}
 			}
-			// set min ping result for server
-			if serverPing > 0 {
-				s.pingMs = serverPing
-				servers[i] = s
-			}
 		}
 	}
 
 	if needSort {
+		// sort by score (the best server - last)
 		sort.Slice(servers, func(i, j int) bool {
 			if servers[i].pingMs == 0 && servers[j].pingMs == 0 {
 				return strings.Compare(servers[i].city, servers[j].city) < 0
@@ -369,18 +672,40 @@ func serversPing(servers []serverDesc, needSort bool, pingAllHostsOnFirstPhase b
 				return false
 			}
 
-			return servers[i].pingMs > servers[j].pingMs
+			return rankScore(servers[i].pingStats) > rankScore(servers[j].pingStats)
 		})
 	}
 
 	return nil
 }
 
+// rankScore returns the score in use for the servers ranking (lower - better).
+// The stable score (median of the latest measurements) is preferred: it prevents the ranking from flipping from run to run.
+func rankScore(stats types.PingResultType) float64 {
+	if stats.HistorySamples > 1 {
+		return stats.StableScore
+	}
+	return stats.Score
+}
+
+// pingStatsStr returns ping statistics columns for the servers table
+func pingStatsStr(pingMs int, stats types.PingResultType) string {
+	if pingMs <= 0 {
+		return " ?  \t\t\t\t\t"
+	}
+	if stats.Probe != "" && stats.Probe != "icmp" {
+		// single VPN protocol probe: no jitter and packet loss info
+		return fmt.Sprintf("%dms\t-\t-\t%.0f\t%s\t", pingMs, rankScore(stats), stats.Probe)
+	}
+	return fmt.Sprintf("%dms (%d-%d)\t%.1fms\t%d%%\t%.0f\t%s\t", pingMs, stats.PingMin, stats.PingMax, stats.Jitter, int(stats.PacketLoss+0.5), rankScore(stats), stats.Probe)
+}
+
 type hostDesc struct {
-	hostname string
-	host     string // ip
-	pingMs   int
-	load     float32
+	hostname  string
+	host      string // ip
+	pingMs    int
+	pingStats types.PingResultType
+	load      float32
 }
 
 type serverDesc struct {
@@ -389,9 +714,13 @@ type serverDesc struct {
 	city         string
 	countryCode  string
 	country      string
+	latitude     float32
+	longitude    float32
 	hosts        []hostDesc
 	pingMs       int
+	pingStats    types.PingResultType // ping statistics of the best host
 	isIPv6Tunnel bool
+	distanceKm   float64 // distance from the current location (negative - unknown; see serversSetDistance())
 }
 
 func (s *serverDesc) String() string {
@@ -0,0 +1,146 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// serversSetDistance calculates the distance from the current location to the servers.
+// The current location is defined by the daemon: the home location (if defined) or the location detected by geo-lookup.
+// When the current location is unknown - the distance of all servers is negative.
+func serversSetDistance(servers []serverDesc) (types.CurrentLocationResp, error) {
+	location, err := _proto.CurrentLocation()
+	if err != nil {
+		return location, err
+	}
+
+	for i, s := range servers {
+		if !location.IsKnown {
+			servers[i].distanceKm = -1
+			continue
+		}
+		servers[i].distanceKm = helpers.GetDistanceFromLatLonInKm(location.Latitude, location.Longitude, float64(s.latitude), float64(s.longitude))
+	}
+	return location, nil
+}
+
+// serversSortByDistance sorts the servers by distance (the nearest server - last; the servers with unknown distance - first)
+func serversSortByDistance(servers []serverDesc) {
+	sort.SliceStable(servers, func(i, j int) bool {
+		if servers[i].distanceKm < 0 || servers[j].distanceKm < 0 {
+			return servers[i].distanceKm < 0 && servers[j].distanceKm >= 0
+		}
+		return servers[i].distanceKm > servers[j].distanceKm
+	})
+}
+
+// serversNearest returns the nearest server to the current location (from the 'servers' list).
+// The selection is done by the daemon (servers query: 'sort by distance'): the same way as the daemon selects
+// the nearest server itself (e.g. WireGuard failover to the nearest server when the tunnel stalls repeatedly).
+func serversNearest(servers []serverDesc) (serverDesc, error) {
+	if len(servers) == 0 {
+		return serverDesc{}, fmt.Errorf("no servers to select the nearest one")
+	}
+
+	hosts, err := _proto.QueryServers("sort by distance")
+	if err != nil {
+		return serverDesc{}, err
+	}
+
+	for _, h := range hosts {
+		if h.DistanceKm < 0 {
+			return serverDesc{}, fmt.Errorf("unable to determine the nearest server: the current location is unknown (the home location can be defined: 'ivpn servers -home_location LATITUDE,LONGITUDE')")
+		}
+		for _, svr := range servers {
+			if svr.gateway == h.Gateway && svr.protocol == jsonProtocolName(h.VpnType) {
+				svr.distanceKm = h.DistanceKm
+				return svr, nil
+			}
+		}
+	}
+	return serverDesc{}, fmt.Errorf("unable to determine the nearest server")
+}
+
+// distanceKmStr returns text representation of the distance (negative value - unknown distance)
+func distanceKmStr(distanceKm float64) string {
+	if distanceKm < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dkm", int(distanceKm+0.5))
+}
+
+func printLocationInfo(location types.CurrentLocationResp) {
+	switch {
+	case !location.IsKnown:
+		fmt.Println("WARNING: the current location is unknown, the distance to servers can not be calculated\n\t(the home location can be defined: 'ivpn servers -home_location LATITUDE,LONGITUDE')")
+	case location.Source == types.LocationSourceHome:
+		fmt.Printf("Distance from the home location (%.2f, %.2f)\n", location.Latitude, location.Longitude)
+	default:
+		fmt.Printf("Distance from the location detected by geo-lookup (%.2f, %.2f)\n", location.Latitude, location.Longitude)
+	}
+}
+
+func (c *CmdServers) setHomeLocation() error {
+	var home preferences.HomeLocation
+	if strings.ToLower(c.homeLocation) != "off" {
+		cols := strings.Split(c.homeLocation, ",")
+		if len(cols) != 2 {
+			return flags.BadParameter{Message: "use 'LATITUDE,LONGITUDE' format for '-home_location' argument (e.g. '46.20,6.14')"}
+		}
+		lat, err1 := strconv.ParseFloat(strings.TrimSpace(cols[0]), 64)
+		lon, err2 := strconv.ParseFloat(strings.TrimSpace(cols[1]), 64)
+		if err1 != nil || err2 != nil {
+			return flags.BadParameter{Message: "bad coordinates value for '-home_location' argument (decimal degrees expected; e.g. '46.20,6.14')"}
+		}
+		home = preferences.HomeLocation{Latitude: lat, Longitude: lon}
+		if !home.IsDefined() {
+			return flags.BadParameter{Message: "zero coordinates are not acceptable for '-home_location' argument (use 'off' to reset the home location)"}
+		}
+		if err := home.Validate(); err != nil {
+			return flags.BadParameter{Message: err.Error()}
+		}
+	}
+
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+	uPrefs.HomeLocation = home
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+	setJsonData(jsonServersSettings(uPrefs))
+
+	if home.IsDefined() {
+		fmt.Printf("Home location: %v, %v\n", home.Latitude, home.Longitude)
+	} else {
+		fmt.Println("Home location is not defined (the location detected by geo-lookup is in use)")
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// prefix of the country code in values of '-favorite' and '-exclude' arguments (e.g. "country:US")
+const serversListCountryPrefix = "country:"
+
+// isServersListsUpdateRequested returns true if any of the favorite\excluded servers arguments is defined
+func (c *CmdServers) isServersListsUpdateRequested() bool {
+	return len(c.favoriteAdd) > 0 || len(c.favoriteRemove) > 0 || len(c.excludeAdd) > 0 || len(c.excludeRemove) > 0
+}
+
+// updateServersLists applies '-favorite', '-favorite_remove', '-exclude' and '-exclude_remove' arguments
+func (c *CmdServers) updateServersLists(servers []serverDesc) error {
+	settings := _proto.GetHelloResponse().DaemonSettings
+
+	if len(c.favoriteAdd) > 0 || len(c.favoriteRemove) > 0 {
+		list, err := serversListUpdate(settings.ServersFavorites, servers, c.favoriteAdd, c.favoriteRemove)
+		if err != nil {
+			return err
+		}
+		if err := _proto.SetServersFavorites(list); err != nil {
+			return err
+		}
+	}
+
+	if len(c.excludeAdd) > 0 || len(c.excludeRemove) > 0 {
+		list, err := serversListUpdate(settings.ServersExcluded, servers, c.excludeAdd, c.excludeRemove)
+		if err != nil {
+			return err
+		}
+		if err := _proto.SetServersExcluded(list); err != nil {
+			return err
+		}
+	}
+
+	// update local copy of the daemon settings
+	_, err := _proto.SendHello()
+	return err
+}
+
+func (c *CmdServers) showServersLists() {
+	settings := _proto.GetHelloResponse().DaemonSettings
+
+	listStr := func(l preferences.ServersList) string {
+		var values []string
+		for _, cc := range l.Countries {
+			values = append(values, serversListCountryPrefix+cc)
+		}
+		values = append(values, l.Servers...)
+		values = append(values, l.Hosts...)
+		if len(values) == 0 {
+			return "<empty>"
+		}
+		return strings.Join(values, ", ")
+	}
+
+	fmt.Printf("Favorite servers : %s\n", listStr(settings.ServersFavorites))
+	fmt.Printf("Excluded servers : %s\n", listStr(settings.ServersExcluded))
+
+	setJsonData(JsonServersLists{Favorites: jsonServersList(settings.ServersFavorites), Excluded: jsonServersList(settings.ServersExcluded)})
+}
+
+// serversListUpdate adds\removes the comma-separated values to\from the list
+func serversListUpdate(list preferences.ServersList, servers []serverDesc, toAdd, toRemove string) (preferences.ServersList, error) {
+	remove := func(values []string, v string) []string {
+		ret := values[:0]
+		for _, s := range values {
+			if !strings.EqualFold(s, v) {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+
+	for _, v := range splitServersListValues(toAdd) {
+		isCountry, value, err := parseServersListValue(v, servers)
+		if err != nil {
+			return list, err
+		}
+		switch {
+		case isCountry:
+			list.Countries = append(list.Countries, value)
+		case isHostName(servers, value):
+			list.Hosts = append(list.Hosts, value)
+		default:
+			list.Servers = append(list.Servers, value)
+		}
+	}
+
+	for _, v := range splitServersListValues(toRemove) {
+		if strings.HasPrefix(strings.ToLower(v), serversListCountryPrefix) {
+			list.Countries = remove(list.Countries, v[len(serversListCountryPrefix):])
+			continue
+		}
+		// the value could be already removed from the servers list: do not check it
+		list.Servers = remove(list.Servers, preferences.ServerLocationID(v))
+		list.Hosts = remove(list.Hosts, v)
+	}
+
+	return list.Normalized(), nil
+}
+
+func splitServersListValues(values string) []string {
+	var ret []string
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// parseServersListValue parses the value of '-favorite' or '-exclude' argument:
+// "country:CC" - country code; server location (e.g. "ch.wg.ivpn.net" or "ch"); host name (e.g. "ch1.wg.ivpn.net")
+func parseServersListValue(v string, servers []serverDesc) (isCountry bool, value string, err error) {
+	if strings.HasPrefix(strings.ToLower(v), serversListCountryPrefix) {
+		cc := strings.ToUpper(v[len(serversListCountryPrefix):])
+		for _, s := range servers {
+			if strings.EqualFold(s.countryCode, cc) {
+				return true, cc, nil
+			}
+		}
+		return false, "", flags.BadParameter{Message: fmt.Sprintf("no servers in the country '%s'", cc)}
+	}
+
+	if isHostName(servers, v) {
+		return false, strings.ToLower(v), nil
+	}
+
+	locationID := preferences.ServerLocationID(v)
+	for _, s := range servers {
+		if preferences.ServerLocationID(s.gateway) == locationID {
+			return false, locationID, nil
+		}
+	}
+
+	return false, "", flags.BadParameter{Message: fmt.Sprintf("unknown server '%s' (expected: server location, host name or '%sCOUNTRY_CODE')", v, serversListCountryPrefix)}
+}
+
+func isHostName(servers []serverDesc, v string) bool {
+	for _, s := range servers {
+		for _, h := range s.hosts {
+			if strings.EqualFold(h.hostname, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serversExcludedFilter removes the excluded servers and hosts (the daemon-stored list of excluded servers).
+// The servers which have no allowed hosts are removed.
+// Note: the daemon rejects the connections to the excluded servers; the filter prevents selecting them automatically.
+func serversExcludedFilter(servers []serverDesc) []serverDesc {
+	excluded := _proto.GetHelloResponse().DaemonSettings.ServersExcluded
+	if excluded.IsEmpty() {
+		return servers
+	}
+
+	ret := make([]serverDesc, 0, len(servers))
+	for _, s := range servers {
+		if excluded.IsServerMatch(s.gateway, s.countryCode) {
+			continue
+		}
+		hosts := make([]hostDesc, 0, len(s.hosts))
+		for _, h := range s.hosts {
+			if !excluded.IsHostMatch(s.gateway, s.countryCode, h.hostname) {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		s.hosts = hosts
+		ret = append(ret, s)
+	}
+	return ret
+}
@@ -26,13 +26,17 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ivpn/desktop-app/cli/cliplatform"
 	"github.com/ivpn/desktop-app/cli/flags"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/splittun"
 )
 
 type Exclude struct {
@@ -161,6 +165,7 @@ type SplitTun struct {
 	flags.CmdInfo
 	status     bool
 	statusFull bool
+	watch      bool
 	on         bool
 	off        bool
 	reset      bool
@@ -168,6 +173,10 @@ type SplitTun struct {
 	appremove  string
 	appadd     string // this parameter is not in use. We need it just for help info (using 'appaddArgs' parsed with specific logic)
 	appaddArgs []string
+
+	destBypass string
+	destVpn    string
+	destRemove string
 }
 
 func (c *SplitTun) Init() {
@@ -185,11 +194,18 @@ func (c *SplitTun) Init() {
 	} else {
 		// Linux
 		c.BoolVar(&c.statusFull, "status_full", false, "(extended status info) Show detailed Split Tunnel status")
+		c.BoolVar(&c.watch, "watch", false, "Show live information about processes in Split Tunnel environment\n(including child processes, their active sockets and TCP traffic)\nThe information is refreshing until interrupted (Ctrl+C)\nExample:\n    ivpn splittun -status -watch")
 		c.BoolVar(&c.reset, "clean", false, "Erase configuration (delete all applications from configuration and disable)")
 		c.StringVar(&c.appadd, "appadd", "", "COMMAND", "Execute command (binary) in Split Tunnel environment (exclude it's traffic from the VPN tunnel)\nInfo: short version of this command is 'ivpn exclude <command>'\nExamples:\n    ivpn splittun -appadd firefox\n    ivpn splittun -appadd ping 1.1.1.1\n    ivpn splittun -appadd /usr/bin/google-chrome")
 		c.StringVar(&c.appremove, "appremove", "", "PID", "Remove application from Split Tunnel environment\n(argument: Process ID)")
 	}
 
+	if cliplatform.IsSplitTunDestinationsSupported() {
+		c.StringVar(&c.destBypass, "bypass", "", "DEST", "Add destination(s) which always bypass the VPN tunnel\n(comma separated list of IP addresses, masks or domain names)\nExample:\n    ivpn splittun -bypass 192.168.100.0/24,zoom.us")
+		c.StringVar(&c.destVpn, "vpn", "", "DEST", "Add destination(s) which always use the VPN tunnel (have priority over '-bypass')\n(comma separated list of IP addresses, masks or domain names)\nExample:\n    ivpn splittun -vpn 192.168.100.15")
+		c.StringVar(&c.destRemove, "destremove", "", "DEST", "Remove destination(s) from configuration\n(comma separated list of IP addresses, masks or domain names)")
+	}
+
 	c.BoolVar(&c.on, "on", false, "Enable")
 	c.BoolVar(&c.off, "off", false, "Disable")
 }
@@ -202,11 +218,23 @@ func (c *SplitTun) Run() error {
 		return flags.BadParameter{}
 	}
 
+	if c.watch {
+		if IsJsonOutput() {
+			return flags.BadParameter{Message: "'-watch' is not applicable in JSON output mode (use 'monitor' command)"}
+		}
+		return c.doWatch()
+	}
+
 	cfg, err := _proto.GetSplitTunnelStatus()
 	if err != nil {
 		return err
 	}
 
+	// destination-based configuration does not depend on application-based Split Tunnel functionality
+	if len(c.destBypass) > 0 || len(c.destVpn) > 0 || len(c.destRemove) > 0 {
+		return c.doSetDestinations(cfg)
+	}
+
 	if cfg.IsFunctionalityNotAvailable {
 		return fmt.Errorf("the Split Tunneling functionality not available")
 	}
@@ -260,15 +288,171 @@ func (c *SplitTun) Run() error {
 	return c.doShowStatus(cfg, c.statusFull)
 }
 
+func (c *SplitTun) doWatch() error {
+	const refreshInterval = time.Second * 2
+	for {
+		cfg, err := _proto.GetSplitTunnelStatus()
+		if err != nil {
+			return err
+		}
+		status, err := _proto.GetSplitTunnelLiveStatus()
+		if err != nil {
+			return err
+		}
+
+		fmt.Print("\033[H\033[2J") // clear screen
+		fmt.Printf("%s (refreshing every %v; Ctrl+C to exit)\n\n", time.Now().Format("15:04:05"), refreshInterval)
+
+		// the same status info as for '-status' ('-status_full') followed by the live info about processes
+		w := printSplitTunState(nil, false, c.statusFull, cfg.IsEnabled, cfg.SplitTunnelApps, cfg.RunningApps)
+		printSplitTunDestinations(w, cfg)
+		printSplitTunLiveStatus(w, status)
+		w.Flush()
+
+		time.Sleep(refreshInterval)
+	}
+}
+
+func printSplitTunLiveStatus(w *tabwriter.Writer, status types.SplitTunnelLiveStatus) {
+	if !status.IsEnabled {
+		return
+	}
+
+	apps := status.RunningApps
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Pid < apps[j].Pid })
+
+	// group processes by the root process (the command started in Split Tunnel environment)
+	roots := make([]int, 0)
+	children := make(map[int][]splittun.RunningAppTraffic)
+	for _, app := range apps {
+		rootPid := app.ExtIvpnRootPid
+		if rootPid <= 0 {
+			rootPid = app.Pid
+		}
+		if _, ok := children[rootPid]; !ok {
+			roots = append(roots, rootPid)
+		}
+		children[rootPid] = append(children[rootPid], app)
+	}
+
+	if len(roots) == 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "No processes in Split Tunnel environment")
+		return
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "PID\tPPID\tEXCLUDED\tTCP SENT*\tTCP RECEIVED*\tCOMMAND")
+	for _, rootPid := range roots {
+		for _, app := range children[rootPid] {
+			cmd := app.ExtModifiedCmdLine
+			if len(cmd) <= 0 {
+				cmd = app.Cmdline
+			}
+			if len(cmd) > 60 {
+				cmd = cmd[:60] + "..."
+			}
+			indent := ""
+			if app.Pid != rootPid {
+				indent = "  └ "
+			}
+
+			excluded := "yes"
+			if !app.IsExcluded {
+				excluded = "NO"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s%s\n", app.Pid, app.Ppid, excluded, bytesToString(app.TcpBytesSent), bytesToString(app.TcpBytesReceived), indent, cmd)
+
+			for _, sock := range app.Sockets {
+				sent, received := "-", "-" // no bytes counters for UDP sockets
+				if sock.Protocol == "tcp" {
+					sent, received = bytesToString(sock.BytesSent), bytesToString(sock.BytesReceived)
+				}
+				fmt.Fprintf(w, "\t\t\t%s\t%s\t%s    %s %s %s -> %s\n", sent, received, indent, sock.Protocol, sock.State, sock.LocalAddr, sock.RemoteAddr)
+			}
+		}
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "* TCP traffic of the process, including closed sockets (UDP traffic is not counted)")
+}
+
+func bytesToString(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func (c *SplitTun) doSetDestinations(cfg types.SplitTunnelStatus) error {
+	splitFunc := func(r rune) bool { return r == ',' || r == ' ' }
+
+	toRemove := make(map[string]struct{})
+	for _, d := range strings.FieldsFunc(c.destRemove, splitFunc) {
+		toRemove[strings.ToLower(d)] = struct{}{}
+	}
+	filter := func(dests []string, toAdd string) []string {
+		ret := make([]string, 0, len(dests))
+		for _, d := range append(dests, strings.FieldsFunc(toAdd, splitFunc)...) {
+			if _, ok := toRemove[strings.ToLower(d)]; !ok {
+				ret = append(ret, d)
+			}
+		}
+		return ret
+	}
+
+	bypass := filter(cfg.BypassDestinations, c.destBypass)
+	vpn := filter(cfg.VpnDestinations, c.destVpn)
+
+	if err := _proto.SetSplitTunnelDestinations(bypass, vpn); err != nil {
+		return err
+	}
+
+	cfg, err := _proto.GetSplitTunnelStatus()
+	if err != nil {
+		return err
+	}
+	return c.doShowStatus(cfg, c.statusFull)
+}
+
 func (c *SplitTun) doShowStatus(cfg types.SplitTunnelStatus, isFull bool) error {
 	w := printSplitTunState(nil, false, isFull, cfg.IsEnabled, cfg.SplitTunnelApps, cfg.RunningApps)
+	printSplitTunDestinations(w, cfg)
 	w.Flush()
+	setJsonData(jsonSplitTunnelState(cfg))
 	return nil
 }
 
+func printSplitTunDestinations(w *tabwriter.Writer, cfg types.SplitTunnelStatus) {
+	if !cliplatform.IsSplitTunDestinationsSupported() {
+		return
+	}
+
+	printList := func(title string, dests []string) {
+		for i, d := range dests {
+			if ips, ok := cfg.ResolvedDomains[d]; ok {
+				d = fmt.Sprintf("%s (%s)", d, strings.Join(ips, ", "))
+			}
+			if i == 0 {
+				fmt.Fprintf(w, "%s\t:\t%v\n", title, d)
+			} else {
+				fmt.Fprintf(w, "\t\t%v\n", d)
+			}
+		}
+	}
+	printList("Bypass destinations", cfg.BypassDestinations)
+	printList("VPN destinations", cfg.VpnDestinations)
+}
+
 func (c *SplitTun) doShowStatusShort(status types.SplitTunnelStatus) error {
 	w := printSplitTunState(nil, true, false, status.IsEnabled, status.SplitTunnelApps, status.RunningApps)
 	w.Flush()
+	setJsonData(jsonSplitTunnelState(status))
 	return nil
 }
 
@@ -73,17 +73,39 @@ func showState() error {
 		}
 	}
 
+	jsonState := JsonState{
+		LoggedIn:  jsonIsLoggedIn(),
+		AccountID: _proto.GetHelloResponse().Session.AccountID,
+		Vpn:       jsonVpnState(state, connected, serverInfo, exitServerInfo),
+		Firewall:  jsonFirewallState(fwstate)}
+
 	w := printAccountInfo(nil, _proto.GetHelloResponse().Session.AccountID)
 	printState(w, state, connected, serverInfo, exitServerInfo)
+	if state != vpn.DISCONNECTED {
+		if stats, err := _proto.GetConnectionStats(); err == nil {
+			printConnectionStats(w, stats)
+			jsonVpnStateSetStats(&jsonState.Vpn, stats)
+		}
+	}
 	if state == vpn.CONNECTED {
 		printDNSState(w, connected.ManualDNS, &servers)
+		if !connected.ManualDNS.IsEmpty() {
+			jsonState.Vpn.Dns = jsonDnsConfig(connected.ManualDNS, &servers)
+		}
 	}
 	if !stStatus.IsFunctionalityNotAvailable {
 		printSplitTunState(w, true, false, stStatus.IsEnabled, stStatus.SplitTunnelApps, stStatus.RunningApps)
+		jsonState.SplitTunnel = jsonSplitTunnelState(stStatus)
+	}
+	if offline, err := _proto.OfflineStatus(); err == nil {
+		printOfflineState(w, offline.OfflineStatus)
+		jsonState.Offline = jsonOfflineState(offline.OfflineStatus)
 	}
 	printFirewallState(w, fwstate.IsEnabled, fwstate.IsPersistent, fwstate.IsAllowLAN, fwstate.IsAllowMulticast, fwstate.IsAllowApiServers, fwstate.UserExceptions)
 	w.Flush()
 
+	setJsonData(jsonState)
+
 	// TIPS
 	tips := make([]TipType, 0, 3)
 	if len(_proto.GetHelloResponse().Session.Session) == 0 {
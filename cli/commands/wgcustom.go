@@ -0,0 +1,134 @@
+//
+//  IVPN command line interface (CLI)
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the IVPN command line interface.
+//
+//  The IVPN command line interface is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The IVPN command line interface is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the IVPN command line interface. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/protocol/types"
+)
+
+type CmdWireGuardCustom struct {
+	flags.CmdInfo
+	list       bool
+	importFile string
+	name       string
+	delete     string
+}
+
+func (c *CmdWireGuardCustom) Init() {
+	c.Initialize("wgcustom", "Custom WireGuard servers management\nThird-party WireGuard configurations (in 'wg-quick' format) can be imported and used for connection\n(use 'ivpn connect -wg_custom SERVER' to connect; IVPN account is not required)\nNote: connection to custom WireGuard servers is not supported on macOS")
+	c.BoolVar(&c.list, "list", false, "(default) Show custom WireGuard servers")
+	c.StringVar(&c.importFile, "import", "", "FILE", "Import WireGuard configuration file\n  Note: configurations with script hooks (PreUp, PostUp, PreDown, PostDown) are not supported")
+	c.StringVar(&c.name, "name", "", "NAME", "Name for the imported server (applicable only with '-import')")
+	c.StringVar(&c.delete, "delete", "", "SERVER", "Delete custom server (server ID or name)")
+}
+
+func (c *CmdWireGuardCustom) Run() error {
+	var servers []types.WireGuardCustomServerInfo
+	var err error
+
+	switch {
+	case len(c.importFile) > 0:
+		data, err := os.ReadFile(c.importFile)
+		if err != nil {
+			return fmt.Errorf("failed to read configuration file: %w", err)
+		}
+		if servers, err = _proto.WGCustomServerImport(c.name, string(data)); err != nil {
+			return err
+		}
+		fmt.Println("Configuration imported")
+
+	case len(c.delete) > 0:
+		if servers, err = _proto.WGCustomServersGet(); err != nil {
+			return err
+		}
+		srv, err := findWgCustomServer(servers, c.delete)
+		if err != nil {
+			return err
+		}
+		if servers, err = _proto.WGCustomServerDelete(srv.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Server '%s' deleted\n", srv.Name)
+
+	default:
+		if servers, err = _proto.WGCustomServersGet(); err != nil {
+			return err
+		}
+	}
+
+	printWgCustomServers(servers)
+	return nil
+}
+
+// findWgCustomServer looks for custom WireGuard server by ID (or ID prefix) or by name
+func findWgCustomServer(servers []types.WireGuardCustomServerInfo, idOrName string) (types.WireGuardCustomServerInfo, error) {
+	var found []types.WireGuardCustomServerInfo
+	for _, s := range servers {
+		if s.ID == idOrName || s.Name == idOrName {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, idOrName) || strings.EqualFold(s.Name, idOrName) {
+			found = append(found, s)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	if len(found) > 1 {
+		return types.WireGuardCustomServerInfo{}, fmt.Errorf("more than one custom WireGuard server found ('%s')", idOrName)
+	}
+	return types.WireGuardCustomServerInfo{}, fmt.Errorf("custom WireGuard server not found ('%s')", idOrName)
+}
+
+func printWgCustomServers(servers []types.WireGuardCustomServerInfo) {
+	jsonServers := make([]JsonWgCustomServer, 0, len(servers))
+	for _, s := range servers {
+		jsonServers = append(jsonServers, JsonWgCustomServer{ID: s.ID, Name: s.Name, Endpoint: s.Endpoint, PublicKey: s.PublicKey,
+			Addresses: s.Addresses, DNS: s.DNS, AllowedIPs: s.AllowedIPs, Mtu: s.Mtu})
+	}
+	setJsonData(jsonServers)
+
+	if len(servers) == 0 {
+		fmt.Println("No custom WireGuard servers")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "ID\t|NAME\t|ENDPOINT\t|ADDRESS\t|DNS\t|ALLOWED IPs\t")
+	for _, s := range servers {
+		fmt.Fprintln(w, fmt.Sprintf("%s\t|%s\t|%s\t|%s\t|%s\t|%s\t",
+			strings.Split(s.ID, "-")[0],
+			s.Name,
+			s.Endpoint,
+			strings.Join(s.Addresses, ","),
+			strings.Join(s.DNS, ","),
+			strings.Join(s.AllowedIPs, ",")))
+	}
+	w.Flush()
+}
@@ -25,10 +25,12 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/ivpn/desktop-app/cli/flags"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/service/srverrors"
 )
 
@@ -36,14 +38,24 @@ type CmdWireGuard struct {
 	flags.CmdInfo
 	state            bool
 	regenerate       bool
+	rotateNow        bool
+	history          bool
 	rotationInterval int
+	rotationWindow   string
+	pqPsk            string
+	pqPskRotation    int
 }
 
 func (c *CmdWireGuard) Init() {
 	c.Initialize("wgkeys", "WireGuard keys management")
 	c.BoolVar(&c.state, "status", false, "(default) Show WireGuard configuration")
 	c.IntVar(&c.rotationInterval, "rotation_interval", 0, "DAYS", "Set WireGuard keys rotation interval. [1-30] days")
+	c.StringVar(&c.rotationWindow, "rotation_window", "", "HH:MM-HH:MM", "Allow automatic keys rotation only inside the local time window (e.g. '09:00-18:00'; 'off' - any time)")
 	c.BoolVar(&c.regenerate, "regenerate", false, "Regenerate WireGuard keys")
+	c.BoolVar(&c.rotateNow, "rotate-now", false, "Rotate WireGuard keys right now (the same as '-regenerate')")
+	c.BoolVar(&c.history, "history", false, "Show WireGuard keys rotation history")
+	c.StringVar(&c.pqPsk, "pq", "", "ON/OFF", "Enable/disable post-quantum preshared key negotiation (new key for each connection)")
+	c.IntVar(&c.pqPskRotation, "pq_rotation", -1, "MINUTES", "Set post-quantum preshared key rotation interval while connected (0 - only on connection; minimum 5 minutes)")
 }
 func (c *CmdWireGuard) Run() error {
 	if c.rotationInterval < 0 || c.rotationInterval > 30 {
@@ -68,7 +80,7 @@ func (c *CmdWireGuard) Run() error {
 		return fmt.Errorf("WireGuard functionality disabled:\n\t" + resp.DisabledFunctions.WireGuardError)
 	}
 
-	if c.regenerate {
+	if c.regenerate || c.rotateNow {
 		fmt.Println("Regenerating WG keys...")
 		if err := c.generate(); err != nil {
 			return err
@@ -83,6 +95,27 @@ func (c *CmdWireGuard) Run() error {
 		}
 	}
 
+	if len(c.rotationWindow) > 0 {
+		startMinute, endMinute, err := parseTimeWindow(c.rotationWindow)
+		if err != nil {
+			return flags.BadParameter{Message: err.Error()}
+		}
+		fmt.Println("Changing WG keys rotation time window...")
+		if err := _proto.WGKeysRotationWindow(startMinute, endMinute); err != nil {
+			return err
+		}
+	}
+
+	if len(c.pqPsk) > 0 || c.pqPskRotation >= 0 {
+		if err := c.setPqPsk(); err != nil {
+			return err
+		}
+	}
+
+	if c.history {
+		return c.printHistory()
+	}
+
 	if err := c.getState(); err != nil {
 		return err
 	}
@@ -90,6 +123,70 @@ func (c *CmdWireGuard) Run() error {
 	return nil
 }
 
+// parseTimeWindow parses the time window in format 'HH:MM-HH:MM' (returns minutes since midnight)
+// The 'off' value means no restrictions (startMinute == endMinute)
+func parseTimeWindow(val string) (startMinute, endMinute int, err error) {
+	val = strings.TrimSpace(strings.ToLower(val))
+	if val == "off" {
+		return 0, 0, nil
+	}
+
+	parseTime := func(t string) (int, error) {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(t))
+		if err != nil {
+			return 0, fmt.Errorf("bad time '%s' (expected format: HH:MM)", t)
+		}
+		return parsed.Hour()*60 + parsed.Minute(), nil
+	}
+
+	times := strings.Split(val, "-")
+	if len(times) != 2 {
+		return 0, 0, fmt.Errorf("bad time window '%s' (expected format: HH:MM-HH:MM)", val)
+	}
+	if startMinute, err = parseTime(times[0]); err != nil {
+		return 0, 0, err
+	}
+	if endMinute, err = parseTime(times[1]); err != nil {
+		return 0, 0, err
+	}
+	if startMinute == endMinute {
+		return 0, 0, fmt.Errorf("the time window is empty (use 'off' to remove the restriction)")
+	}
+	return startMinute, endMinute, nil
+}
+
+func rotationResultString(r preferences.WgKeysRotationRecord) string {
+	if r.Success {
+		return "OK"
+	}
+	return "FAILED: " + r.Error
+}
+
+func (c *CmdWireGuard) printHistory() error {
+	history, err := _proto.WGKeysHistory()
+	if err != nil {
+		return err
+	}
+	jsonHistory := make([]JsonWgKeysRotation, 0, len(history))
+	for _, r := range history {
+		jsonHistory = append(jsonHistory, jsonWgKeysRotation(r))
+	}
+	setJsonData(jsonHistory)
+
+	if len(history) == 0 {
+		fmt.Println("No WireGuard keys rotations yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTRIGGER\tOLD KEY\tNEW KEY\tRESULT")
+	for _, r := range history {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Time.Local().Format("2006-01-02 15:04:05"), r.Trigger, r.OldKeyFingerprint, r.NewKeyFingerprint, rotationResultString(r))
+	}
+	w.Flush()
+	return nil
+}
+
 func (c *CmdWireGuard) generate() error {
 	return _proto.WGKeysGenerate()
 }
@@ -98,6 +195,31 @@ func (c *CmdWireGuard) setRotateInterval(interval int64) error {
 	return _proto.WGKeysRotationInterval(interval)
 }
 
+func (c *CmdWireGuard) setPqPsk() error {
+	uPrefs := _proto.GetHelloResponse().DaemonSettings.UserPrefs
+
+	if len(c.pqPsk) > 0 {
+		switch strings.ToLower(c.pqPsk) {
+		case "on":
+			uPrefs.WgQuantumResistance = true
+		case "off":
+			uPrefs.WgQuantumResistance = false
+		default:
+			return flags.BadParameter{Message: "use 'on' or 'off' value for '-pq' argument"}
+		}
+	}
+	if c.pqPskRotation >= 0 {
+		uPrefs.WgPskRotationIntervalSec = c.pqPskRotation * 60
+	}
+
+	fmt.Println("Applying post-quantum preshared key configuration...")
+	if err := _proto.SetUserPreferences(uPrefs); err != nil {
+		return err
+	}
+	fmt.Println("The new configuration will be applied on the next connection")
+	return nil
+}
+
 func (c *CmdWireGuard) getState() error {
 	resp, err := _proto.SendHello()
 	if err != nil {
@@ -108,11 +230,47 @@ func (c *CmdWireGuard) getState() error {
 		return nil
 	}
 
+	uPrefs := resp.DaemonSettings.UserPrefs
+	jsonState := JsonWgKeysState{LocalIP: resp.Session.WgLocalIP, PublicKey: resp.Session.WgPublicKey, Generated: resp.Session.WgKeyGenerated,
+		RotationIntervalSec: resp.Session.WgKeysRegenInerval, PqPskEnabled: uPrefs.WgQuantumResistance, PqPskRotationIntervalSec: uPrefs.WgPskRotationIntervalSec}
+	defer func() { setJsonData(jsonState) }()
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
 	fmt.Fprintln(w, fmt.Sprintf("Local IP:\t%v", resp.Session.WgLocalIP))
 	fmt.Fprintln(w, fmt.Sprintf("Public KEY:\t%v", resp.Session.WgPublicKey))
 	fmt.Fprintln(w, fmt.Sprintf("Generated:\t%v", time.Unix(resp.Session.WgKeyGenerated, 0)))
 	fmt.Fprintln(w, fmt.Sprintf("Rotation interval:\t%v", time.Duration(time.Second*time.Duration(resp.Session.WgKeysRegenInerval))))
+
+	if status, err := _proto.WGKeysStatus(); err != nil {
+		fmt.Fprintln(w, fmt.Sprintf("Rotation status:\tunknown (%s)", err))
+	} else {
+		jsonState.KeyFingerprint = status.PublicKeyFingerprint
+		jsonState.RotationWindow = status.RotationWindow.String()
+		jsonState.NextRotation = status.NextRotation
+		if status.LastRotation != nil {
+			r := jsonWgKeysRotation(*status.LastRotation)
+			jsonState.LastRotation = &r
+		}
+
+		fmt.Fprintln(w, fmt.Sprintf("Key fingerprint:\t%s", status.PublicKeyFingerprint))
+		fmt.Fprintln(w, fmt.Sprintf("Rotation window:\t%s", status.RotationWindow))
+		if status.NextRotation > 0 {
+			fmt.Fprintln(w, fmt.Sprintf("Next rotation:\t%v", time.Unix(status.NextRotation, 0)))
+		} else {
+			fmt.Fprintln(w, "Next rotation:\tnot scheduled")
+		}
+		if r := status.LastRotation; r != nil {
+			fmt.Fprintln(w, fmt.Sprintf("Last rotation:\t%v (%s): %s", r.Time.Local().Format("2006-01-02 15:04:05"), r.Trigger, rotationResultString(*r)))
+		}
+	}
+
+	if !uPrefs.WgQuantumResistance {
+		fmt.Fprintln(w, "Post-quantum PSK:\tDisabled")
+	} else if uPrefs.WgPskRotationIntervalSec > 0 {
+		fmt.Fprintln(w, fmt.Sprintf("Post-quantum PSK:\tEnabled (rotation interval: %v)", time.Duration(time.Second*time.Duration(uPrefs.WgPskRotationIntervalSec))))
+	} else {
+		fmt.Fprintln(w, "Post-quantum PSK:\tEnabled (new key for each connection)")
+	}
 	w.Flush()
 
 	return nil
@@ -25,12 +25,22 @@ package flags
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
 )
 
+// if 'true' - the arguments parsing errors are returned by Parse() (by default, the process exits on parsing error)
+var _isReturnParseErrors bool
+
+// SetReturnParseErrors defines the arguments parsing errors handling:
+// 'true' - the errors are returned by Parse() (as BadParameter); 'false' - the process exits on parsing error
+func SetReturnParseErrors(isReturnErrors bool) {
+	_isReturnParseErrors = isReturnErrors
+}
+
 // NewFlagSetEx - create new command object
 func NewFlagSetEx(name, description string) *CmdInfo {
 	ret := &CmdInfo{}
@@ -63,7 +73,13 @@ func (c *CmdInfo) Initialize(name, description string) {
 // Parse parses flag definitions from the argument list
 // see description of Flagset.Parse()
 func (c *CmdInfo) Parse(arguments []string) error {
-	if err := c.fs.Parse(arguments); err != nil {
+	if _isReturnParseErrors {
+		c.fs.Init(c.fs.Name(), flag.ContinueOnError)
+		c.fs.SetOutput(io.Discard) // the error (and usage) is printed by the caller
+		if err := c.fs.Parse(arguments); err != nil {
+			return BadParameter{Message: err.Error()}
+		}
+	} else if err := c.fs.Parse(arguments); err != nil {
 		return err
 	}
 
@@ -72,7 +72,10 @@ func printHeader() {
 
 func printUsageAll(short bool) {
 	printHeader()
-	fmt.Printf("Usage: %s COMMAND [OPTIONS...] [COMMAND_PARAMETER] [-h|-help]\n\n", filepath.Base(os.Args[0]))
+	fmt.Printf("Usage: %s COMMAND [OPTIONS...] [COMMAND_PARAMETER] [-h|-help] [-json]\n\n", filepath.Base(os.Args[0]))
+	fmt.Println("  -json - print the command result as one JSON object to stdout: {\"command\", \"success\", \"data\", \"error\": {\"code\", \"message\"}}")
+	fmt.Println("          (the text output is redirected to stderr; exit code is non-zero when the command failed; see 'EXIT CODES' in the full help)")
+	fmt.Println()
 
 	fmt.Println("COMMANDS:")
 	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
@@ -84,6 +87,11 @@ func printUsageAll(short bool) {
 	}
 	writer.Flush()
 
+	if !short {
+		fmt.Println(commands.ExitCodesHelp)
+		fmt.Println()
+	}
+
 	if short {
 		commands.PrintTips([]commands.TipType{commands.TipHelpCommand, commands.TipHelpFull})
 	}
@@ -105,14 +113,30 @@ func main() {
 		}
 	}
 	addCommand(&commands.CmdWireGuard{})
+	addCommand(&commands.CmdWireGuardCustom{})
+	addCommand(&commands.CmdOpenVpnCustom{})
 	addCommand(&commands.CmdDns{})
+	addCommand(&commands.CmdApiProxy{})
 	addCommand(&commands.CmdAntitracker{})
 	addCommand(&commands.CmdLogs{})
+	addCommand(&commands.CmdMonitor{})
 	addCommand(&commands.CmdLogin{})
 	addCommand(&commands.CmdLogout{})
 	addCommand(&commands.CmdAccount{})
 	addCommand(&commands.CmdParanoidMode{})
 
+	// global '-json' flag (can be defined at any position)
+	args := make([]string, 0, len(os.Args))
+	for _, a := range os.Args {
+		if a == "-json" || a == "--json" {
+			commands.EnableJsonOutput()
+			flags.SetReturnParseErrors(true)
+			continue
+		}
+		args = append(args, a)
+	}
+	os.Args = args
+
 	if len(os.Args) >= 2 {
 		arg1 := strings.TrimLeft(strings.ToLower(os.Args[1]), "-")
 		arg2 := ""
@@ -122,6 +146,7 @@ func main() {
 
 		if arg1 == "v" || arg1 == "version" {
 			printHeader()
+			commands.PrintJsonVersion()
 			os.Exit(0)
 		}
 
@@ -140,14 +165,16 @@ func main() {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Unable to connect to service: %s\n", err)
 		printServStartInstructions()
-		os.Exit(1)
+		commands.PrintJsonResult(commandName(), commands.ErrorDaemonUnreachable{Err: err})
+		os.Exit(commands.ExitCodeDaemonUnreachable)
 	}
 
 	proto := protocol.CreateClient(port, secret)
 	if err := proto.Connect(); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to connect to service : %s\n", err)
 		printServStartInstructions()
-		os.Exit(1)
+		commands.PrintJsonResult(commandName(), commands.ErrorDaemonUnreachable{Err: err})
+		os.Exit(commands.ExitCodeDaemonUnreachable)
 	}
 
 	proto.SetParanoidModeSecretRequestFunc(RequestParanoidModePassword)
@@ -155,9 +182,11 @@ func main() {
 	commands.Initialize(proto)
 
 	if len(os.Args) < 2 {
-		if err := stateCmd.Run(); err != nil {
+		err := stateCmd.Run()
+		commands.PrintJsonResult(stateCmd.Name(), err)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "\n%v\n", err)
-			os.Exit(1)
+			os.Exit(commands.ExitCode(err))
 		}
 		return
 	}
@@ -176,8 +205,17 @@ func main() {
 	if isProcessed == false {
 		fmt.Fprintf(os.Stderr, "Error. Unexpected command %s\n", os.Args[1])
 		printUsageAll(true)
-		os.Exit(1)
+		commands.PrintJsonResult(os.Args[1], commands.ErrorUnknownCommand{Command: os.Args[1]})
+		os.Exit(commands.ExitCodeBadParameter)
+	}
+}
+
+// commandName returns the name of the command requested by user
+func commandName() string {
+	if len(os.Args) < 2 {
+		return "status"
 	}
+	return os.Args[1]
 }
 
 func RequestParanoidModePassword(c *protocol.Client) (string, error) {
@@ -205,7 +243,8 @@ func runCommand(c ICommand, args []string) {
 			if _, ok := err.(flags.BadParameter); ok == true {
 				c.Usage(false)
 			}
-			os.Exit(1)
+			commands.PrintJsonResult(c.Name(), err)
+			os.Exit(commands.ExitCode(err))
 		}
 	}
 
@@ -214,8 +253,10 @@ func runCommand(c ICommand, args []string) {
 		if _, ok := err.(flags.BadParameter); ok == true {
 			c.Usage(false)
 		}
-		os.Exit(1)
+		commands.PrintJsonResult(c.Name(), err)
+		os.Exit(commands.ExitCode(err))
 	}
+	commands.PrintJsonResult(c.Name(), nil)
 }
 
 // read port+secret to be able to connect to a daemon
@@ -39,7 +39,9 @@ import (
 	"github.com/ivpn/desktop-app/daemon/logger"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 	"golang.org/x/crypto/pbkdf2"
 )
@@ -62,6 +64,9 @@ type Client struct {
 	_paranoidModeSecretRequestFunc func(*Client) (string, error)
 }
 
+// the max number of the daemon notifications waiting to be processed by the subscriber
+const notificationsBufferSize = 256
+
 // ResponseTimeout error
 type ResponseTimeout struct {
 }
@@ -70,6 +75,16 @@ func (e ResponseTimeout) Error() string {
 	return "response timeout"
 }
 
+// DisconnectedError - the daemon reported disconnection instead of establishing the VPN connection
+type DisconnectedError struct {
+	Reason      types.DisconnectionReason
+	Description string
+}
+
+func (e DisconnectedError) Error() string {
+	return e.Description
+}
+
 // CreateClient initialising new client for IVPN daemon
 func CreateClient(port int, secret uint64) *Client {
 	return &Client{
@@ -123,6 +138,31 @@ func (c *Client) SetParanoidModeSecretRequestFunc(f func(*Client) (string, error
 	c._paranoidModeSecretRequestFunc = f
 }
 
+// NotificationsSubscribe starts receiving the daemon notifications (e.g. VPN state changes).
+// All the messages from the daemon which are not responses to the client requests are forwarded to the returned channel
+// (raw data; use types.GetCommandBase() to get the message type).
+// The channel is closed when the connection with the daemon is lost.
+// The 'unsubscribe' function must be called when the notifications are not required anymore.
+func (c *Client) NotificationsSubscribe() (notifications <-chan []byte, unsubscribe func(), err error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, nil, err
+	}
+
+	receiver := createNotificationsReceiver(notificationsBufferSize)
+
+	c._receiversLocker.Lock()
+	defer c._receiversLocker.Unlock()
+	c._receivers[receiver] = struct{}{}
+
+	unsubscribe = func() {
+		c._receiversLocker.Lock()
+		defer c._receiversLocker.Unlock()
+		delete(c._receivers, receiver)
+	}
+
+	return receiver._channel, unsubscribe, nil
+}
+
 // SendHello - send initial message and get current status
 func (c *Client) SendHello() (helloResponse types.HelloResp, err error) {
 	return c.SendHelloEx(false)
@@ -359,6 +399,20 @@ func (c *Client) FirewallStatus() (state types.KillSwitchStatusResp, err error)
 	return state, nil
 }
 
+// OfflineStatus requests information about offline mode (API servers are unreachable)
+func (c *Client) OfflineStatus() (status types.OfflineStatusResp, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return status, err
+	}
+
+	req := types.OfflineGetStatus{}
+	if err := c.sendRecv(&req, &status); err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
 // GetSplitTunnelStatus requests the Split-Tunnelling configuration
 func (c *Client) GetSplitTunnelStatus() (cfg types.SplitTunnelStatus, err error) {
 	if err := c.ensureConnected(); err != nil {
@@ -374,6 +428,20 @@ func (c *Client) GetSplitTunnelStatus() (cfg types.SplitTunnelStatus, err error)
 	return cfg, nil
 }
 
+// GetSplitTunnelLiveStatus requests live information about processes in Split-Tunnel environment
+func (c *Client) GetSplitTunnelLiveStatus() (status types.SplitTunnelLiveStatus, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return status, err
+	}
+
+	req := types.SplitTunnelGetLiveStatus{}
+	if err := c.sendRecv(&req, &status); err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
 // SetSplitTunnelConfig sets the split-tunnelling configuration
 func (c *Client) SetSplitTunnelConfig(isEnable, reset bool) (err error) {
 	if err := c.ensureConnected(); err != nil {
@@ -389,6 +457,21 @@ func (c *Client) SetSplitTunnelConfig(isEnable, reset bool) (err error) {
 	return nil
 }
 
+// SetSplitTunnelDestinations sets the destination-based split-tunnelling configuration
+func (c *Client) SetSplitTunnelDestinations(bypassDests, vpnDests []string) (err error) {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SplitTunnelSetDestinations{BypassDestinations: bypassDests, VpnDestinations: vpnDests}
+	resp := types.SplitTunnelStatus{}
+	if _, _, err := c.sendRecvAny(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (c *Client) SplitTunnelAddApp(execCmd string) (isRequiredToExecuteCommand bool, retErr error) {
 	if err := c.ensureConnected(); err != nil {
 		return false, err
@@ -411,7 +494,7 @@ func (c *Client) SplitTunnelAddApp(execCmd string) (isRequiredToExecuteCommand b
 		// It is already done by IVPN UI
 
 		req := types.SplitTunnelAddApp{Exec: execCmd}
-		_, _, err := c.sendRecvAnyEx(&req, false, &respEmpty, &respAppCmdResp)
+		_, _, err := c.sendRecvAnyEx(&req, false, c._defaultTimeout, &respEmpty, &respAppCmdResp)
 		if err != nil {
 			return false, err
 		}
@@ -515,6 +598,20 @@ func (c *Client) GetServersForceUpdate() (apitypes.ServersInfoResponse, error) {
 	return resp.VpnServers, nil
 }
 
+// GetConnectionStats returns statistics of the active VPN connection
+func (c *Client) GetConnectionStats() (stats types.ConnectionStatsResp, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return stats, err
+	}
+
+	req := types.GetConnectionStats{}
+	if err := c.sendRecv(&req, &stats); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
 // GetVPNState returns current VPN connection state
 func (c *Client) GetVPNState() (vpn.State, types.ConnectedResp, error) {
 	respConnected := types.ConnectedResp{}
@@ -549,6 +646,12 @@ func (c *Client) GetVPNState() (vpn.State, types.ConnectedResp, error) {
 
 // DisconnectVPN disconnect active VPN connection
 func (c *Client) DisconnectVPN() error {
+	return c.DisconnectVPNTimeout(c._defaultTimeout)
+}
+
+// DisconnectVPNTimeout disconnect active VPN connection
+// (returns ResponseTimeout error when the disconnection not finished in time)
+func (c *Client) DisconnectVPNTimeout(timeout time.Duration) error {
 	if err := c.ensureConnected(); err != nil {
 		return err
 	}
@@ -557,7 +660,7 @@ func (c *Client) DisconnectVPN() error {
 	respEmpty := types.EmptyResp{}
 	respDisconnected := types.DisconnectedResp{}
 
-	_, _, err := c.sendRecvAny(&req, &respDisconnected, &respEmpty)
+	_, _, err := c.sendRecvAnyTimeOut(&req, timeout, &respDisconnected, &respEmpty)
 	if err != nil {
 		return err
 	}
@@ -571,6 +674,13 @@ func (c *Client) DisconnectVPN() error {
 
 // ConnectVPN - establish new VPN connection
 func (c *Client) ConnectVPN(req types.Connect) (types.ConnectedResp, error) {
+	return c.ConnectVPNTimeout(req, c._defaultTimeout)
+}
+
+// ConnectVPNTimeout - establish new VPN connection
+// (returns ResponseTimeout error when the connection not established in time;
+// DisconnectedError - when the daemon reported the connection failure)
+func (c *Client) ConnectVPNTimeout(req types.Connect, timeout time.Duration) (types.ConnectedResp, error) {
 	respConnected := types.ConnectedResp{}
 	respDisconnected := types.DisconnectedResp{}
 
@@ -578,7 +688,7 @@ func (c *Client) ConnectVPN(req types.Connect) (types.ConnectedResp, error) {
 		return respConnected, err
 	}
 
-	_, _, err := c.sendRecvAny(&req, &respConnected, &respDisconnected)
+	_, _, err := c.sendRecvAnyTimeOut(&req, timeout, &respConnected, &respDisconnected)
 	if err != nil {
 		return respConnected, err
 	}
@@ -588,12 +698,21 @@ func (c *Client) ConnectVPN(req types.Connect) (types.ConnectedResp, error) {
 	}
 
 	if len(respDisconnected.Command) > 0 {
-		return respConnected, fmt.Errorf("%s", respDisconnected.ReasonDescription)
+		return respConnected, DisconnectedError{Reason: respDisconnected.Reason, Description: respDisconnected.ReasonDescription}
 	}
 
 	return respConnected, fmt.Errorf("connect request failed (not expected return type)")
 }
 
+// ConnectVPNDetached - send the request to establish new VPN connection without waiting for the result
+func (c *Client) ConnectVPNDetached(req types.Connect) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	return c.send(&req, 0)
+}
+
 // WGKeysGenerate regenerate WG keys
 func (c *Client) WGKeysGenerate() error {
 	if err := c.ensureConnected(); err != nil {
@@ -624,8 +743,145 @@ func (c *Client) WGKeysRotationInterval(uinxTimeInterval int64) error {
 	return nil
 }
 
+// WGKeysRotationWindow changes the local time window when automatic WG keys rotation is allowed
+// (minutes since midnight; startMinute == endMinute - no restrictions)
+func (c *Client) WGKeysRotationWindow(startMinute, endMinute int) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.WireGuardSetKeysRotationWindow{StartMinute: startMinute, EndMinute: endMinute}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WGKeysStatus returns the status of WG keys rotation
+func (c *Client) WGKeysStatus() (types.WireGuardKeysStatusResp, error) {
+	var resp types.WireGuardKeysStatusResp
+	if err := c.ensureConnected(); err != nil {
+		return resp, err
+	}
+
+	req := types.WireGuardKeysStatus{}
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// WGKeysHistory returns the history of WG keys rotation
+func (c *Client) WGKeysHistory() ([]preferences.WgKeysRotationRecord, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.WireGuardKeysHistory{}
+	var resp types.WireGuardKeysHistoryResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Records, nil
+}
+
+// WGCustomServersGet returns the list of custom (imported) WireGuard servers
+func (c *Client) WGCustomServersGet() ([]types.WireGuardCustomServerInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.WireGuardCustomServersGet{}
+	var resp types.WireGuardCustomServersResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Servers, nil
+}
+
+// WGCustomServerImport imports third-party WireGuard configuration (in 'wg-quick' format) as a custom server
+func (c *Client) WGCustomServerImport(name string, config string) ([]types.WireGuardCustomServerInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.WireGuardCustomServerImport{Name: name, Config: config}
+	var resp types.WireGuardCustomServersResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Servers, nil
+}
+
+// WGCustomServerDelete removes custom WireGuard server
+func (c *Client) WGCustomServerDelete(id string) ([]types.WireGuardCustomServerInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.WireGuardCustomServerDelete{ID: id}
+	var resp types.WireGuardCustomServersResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Servers, nil
+}
+
+// OvpnCustomProfilesGet returns the list of user-supplied (imported) OpenVPN profiles
+func (c *Client) OvpnCustomProfilesGet() ([]types.OpenVpnCustomProfileInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.OpenVpnCustomProfilesGet{}
+	var resp types.OpenVpnCustomProfilesResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Profiles, nil
+}
+
+// OvpnCustomProfileImport imports user-supplied OpenVPN profile
+func (c *Client) OvpnCustomProfileImport(name, profile, username, password string) ([]types.OpenVpnCustomProfileInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.OpenVpnCustomProfileImport{Name: name, Profile: profile, Username: username, Password: password}
+	var resp types.OpenVpnCustomProfilesResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Profiles, nil
+}
+
+// OvpnCustomProfileDelete removes user-supplied OpenVPN profile
+func (c *Client) OvpnCustomProfileDelete(id string) ([]types.OpenVpnCustomProfileInfo, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	req := types.OpenVpnCustomProfileDelete{ID: id}
+	var resp types.OpenVpnCustomProfilesResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Profiles, nil
+}
+
 // PingServers
-func (c *Client) PingServers(pingAllHostsOnFirstPhase bool, vpnTypePrioritized *vpn.Type) (pingResults []types.PingResultType, err error) {
+// probeStrategy - the way the hosts reachability is checked (types.PingProbeAuto, types.PingProbeICMP or types.PingProbeProtocol)
+func (c *Client) PingServers(pingAllHostsOnFirstPhase bool, vpnTypePrioritized *vpn.Type, probeStrategy string) (pingResults []types.PingResultType, err error) {
 	if err := c.ensureConnected(); err != nil {
 		return pingResults, err
 	}
@@ -644,6 +900,7 @@ func (c *Client) PingServers(pingAllHostsOnFirstPhase bool, vpnTypePrioritized *
 		SkipSecondPhase:          true,
 		VpnTypePrioritized:       vpnType,
 		VpnTypePrioritization:    vpnTypePrioritization,
+		ProbeStrategy:            probeStrategy,
 	}
 	var resp types.PingServersResp
 	if err := c.sendRecv(&req, &resp); err != nil {
@@ -653,6 +910,54 @@ func (c *Client) PingServers(pingAllHostsOnFirstPhase bool, vpnTypePrioritized *
 	return resp.PingResults, nil
 }
 
+// PingHistory - get the history of ping results for the host (the latest sample is the last one)
+// If 'since' is defined - only the samples measured since this time are returned
+func (c *Client) PingHistory(host string, since time.Time) (samples []pinghistory.Sample, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return samples, err
+	}
+
+	req := types.PingHistory{Host: host}
+	if !since.IsZero() {
+		req.SinceUnix = since.Unix()
+	}
+	var resp types.PingHistoryResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return samples, err
+	}
+
+	return resp.Samples, nil
+}
+
+// QueryServers requests the servers hosts which are matching the query (e.g. "country in (CH,SE) and proto = wg sort by ping limit 3")
+func (c *Client) QueryServers(query string) (hosts []serversquery.Host, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return hosts, err
+	}
+
+	req := types.QueryServers{Query: query}
+	var resp types.QueryServersResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return hosts, err
+	}
+
+	return resp.Hosts, nil
+}
+
+// CurrentLocation requests the current geographical location which is in use to calculate distance to servers
+func (c *Client) CurrentLocation() (location types.CurrentLocationResp, err error) {
+	if err := c.ensureConnected(); err != nil {
+		return location, err
+	}
+
+	req := types.GetCurrentLocation{}
+	if err := c.sendRecv(&req, &location); err != nil {
+		return location, err
+	}
+
+	return location, nil
+}
+
 // SetManualDNS - sets manual DNS for current VPN connection
 func (c *Client) SetManualDNS(dnsCfg dns.DnsSettings) error {
 	if err := c.ensureConnected(); err != nil {
@@ -703,3 +1008,45 @@ func (c *Client) SetUserPreferences(upref preferences.UserPreferences) error {
 	}
 	return nil
 }
+
+// SetServersFavorites sets the list of favorite servers
+func (c *Client) SetServersFavorites(list preferences.ServersList) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SetServersFavorites{List: list}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetServersExcluded sets the list of excluded servers (they are never in use for connection)
+func (c *Client) SetServersExcluded(list preferences.ServersList) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SetServersExcluded{List: list}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetApiProxy sets proxy server for the daemon API traffic (empty proxy type - direct connection)
+func (c *Client) SetApiProxy(proxy preferences.ApiProxy) error {
+	if err := c.ensureConnected(); err != nil {
+		return err
+	}
+
+	req := types.SetApiProxy{Proxy: proxy}
+	var resp types.EmptyResp
+	if err := c.sendRecv(&req, &resp); err != nil {
+		return err
+	}
+	return nil
+}
@@ -114,10 +114,15 @@ func (c *Client) sendRecvTimeOut(request interface{}, response interface{}, time
 
 func (c *Client) sendRecvAny(request interface{}, waitingObjects ...interface{}) (data []byte, cmdBase types.CommandBase, err error) {
 	isIgnoreResponseIndex := true
-	return c.sendRecvAnyEx(request, isIgnoreResponseIndex, waitingObjects...)
+	return c.sendRecvAnyEx(request, isIgnoreResponseIndex, c._defaultTimeout, waitingObjects...)
 }
 
-func (c *Client) sendRecvAnyEx(request interface{}, isIgnoreResponseIndex bool, waitingObjects ...interface{}) (data []byte, cmdBase types.CommandBase, err error) {
+func (c *Client) sendRecvAnyTimeOut(request interface{}, timeout time.Duration, waitingObjects ...interface{}) (data []byte, cmdBase types.CommandBase, err error) {
+	isIgnoreResponseIndex := true
+	return c.sendRecvAnyEx(request, isIgnoreResponseIndex, timeout, waitingObjects...)
+}
+
+func (c *Client) sendRecvAnyEx(request interface{}, isIgnoreResponseIndex bool, timeout time.Duration, waitingObjects ...interface{}) (data []byte, cmdBase types.CommandBase, err error) {
 
 	doJob := func() (data []byte, cmdBase types.CommandBase, err error) {
 		var receiver *receiverChannel
@@ -150,7 +155,7 @@ func (c *Client) sendRecvAnyEx(request interface{}, isIgnoreResponseIndex bool,
 		}
 
 		// waiting for response
-		if err := receiver.Wait(c._defaultTimeout); err != nil {
+		if err := receiver.Wait(timeout); err != nil {
 			return nil, types.CommandBase{}, err
 		}
 
@@ -222,6 +227,16 @@ func (c *Client) receiverRoutine() {
 	defer func() {
 		logger.Info("Receiver stopped")
 		c._conn.Close()
+
+		// notify subscribers that the connection with the daemon is lost
+		c._receiversLocker.Lock()
+		defer c._receiversLocker.Unlock()
+		for receiver := range c._receivers {
+			if receiver._isNotificationsReceiver {
+				close(receiver._channel)
+				delete(c._receivers, receiver)
+			}
+		}
 	}()
 
 	logger.Info("Receiver started")
@@ -276,6 +291,16 @@ func (c *Client) receiverRoutine() {
 					break
 				}
 			}
+
+			// the message is not a response to any request: forward it to the notifications subscribers
+			if !isProcessed {
+				for receiver := range c._receivers {
+					if receiver._isNotificationsReceiver {
+						isProcessed = true
+						receiver.PushResponse(messageData)
+					}
+				}
+			}
 		}()
 
 		if isProcessed == false {
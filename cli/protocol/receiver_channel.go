@@ -51,13 +51,24 @@ func createReceiver(waitingIdx int, isIgnoreWaitingIndex bool, waitingObjectsLis
 	return receiver
 }
 
+// createNotificationsReceiver creates the receiver for the daemon messages which are not expected by other receivers
+// (the daemon notifications, e.g. VPN state changes)
+func createNotificationsReceiver(bufferSize int) *receiverChannel {
+	return &receiverChannel{
+		_isNotificationsReceiver: true,
+		_isIgnoreWaitingIndex:    true,
+		_waitingObjects:          make(map[string]interface{}),
+		_channel:                 make(chan []byte, bufferSize)}
+}
+
 type receiverChannel struct {
-	_isIgnoreWaitingIndex bool
-	_waitingIdx           int
-	_waitingObjects       map[string]interface{}
-	_channel              chan []byte
-	_receivedData         []byte
-	_receivedCmdBase      types.CommandBase
+	_isNotificationsReceiver bool
+	_isIgnoreWaitingIndex    bool
+	_waitingIdx              int
+	_waitingObjects          map[string]interface{}
+	_channel                 chan []byte
+	_receivedData            []byte
+	_receivedCmdBase         types.CommandBase
 }
 
 func (r *receiverChannel) GetReceivedRawData() (data []byte, cmdBaseObj types.CommandBase) {
@@ -70,7 +81,11 @@ func (r *receiverChannel) IsExpectedResponse(cmd types.CommandBase) bool {
 	// - received error (types.ErrorResp) with correspond responseIndex (even if we are not waiting for response index)
 	// - we are not waiting for response index but received one of responses from _waitingObjects
 	// - when we do not care about responseIndex and response objects
+	// (the notifications receiver gets only the messages which are not expected by other receivers)
 
+	if r._isNotificationsReceiver {
+		return false
+	}
 	if r._isIgnoreWaitingIndex && len(r._waitingObjects) == 0 {
 		return true // - when we do not care about responseIndex and response objects
 	}
@@ -420,13 +420,20 @@ function add_direction_exception {
   DST_PORT=$5
   PROTOCOL=$6
 
-  create_chain ${IPv4BIN} ${IN_CH}
-  create_chain ${IPv4BIN} ${OUT_CH}
+  BIN=${IPv4BIN}
+  if [[ ${DST_ADDR} == *:* ]]; then
+    # IPv6 host (e.g. endpoint of a custom WireGuard configuration)
+    [ -f /proc/net/if_inet6 ] || return 0
+    BIN=${IPv6BIN}
+  fi
+
+  create_chain ${BIN} ${IN_CH}
+  create_chain ${BIN} ${OUT_CH}
 
   #add new rule
   # '-C' option is checking if the rule already exists (needed to avoid duplicates)
-  ${IPv4BIN} -w ${LOCKWAITTIME} -C ${IN_CH}  -s ${DST_ADDR} -p ${PROTOCOL} --sport ${DST_PORT} -j ACCEPT || ${IPv4BIN} -w ${LOCKWAITTIME} -A ${IN_CH}  -s ${DST_ADDR} -p ${PROTOCOL} --sport ${DST_PORT} -j ACCEPT
-  ${IPv4BIN} -w ${LOCKWAITTIME} -C ${OUT_CH} -d ${DST_ADDR} -p ${PROTOCOL} --dport ${DST_PORT} -j ACCEPT || ${IPv4BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d ${DST_ADDR} -p ${PROTOCOL} --dport ${DST_PORT} -j ACCEPT
+  ${BIN} -w ${LOCKWAITTIME} -C ${IN_CH}  -s ${DST_ADDR} -p ${PROTOCOL} --sport ${DST_PORT} -j ACCEPT || ${BIN} -w ${LOCKWAITTIME} -A ${IN_CH}  -s ${DST_ADDR} -p ${PROTOCOL} --sport ${DST_PORT} -j ACCEPT
+  ${BIN} -w ${LOCKWAITTIME} -C ${OUT_CH} -d ${DST_ADDR} -p ${PROTOCOL} --dport ${DST_PORT} -j ACCEPT || ${BIN} -w ${LOCKWAITTIME} -A ${OUT_CH} -d ${DST_ADDR} -p ${PROTOCOL} --dport ${DST_PORT} -j ACCEPT
 }
 
 function remove_exceptions_icmp {
@@ -489,6 +496,20 @@ function main {
       shift
       remove_exceptions ${IPv4BIN} ${IN_IVPN_IF0} ${OUT_IVPN_IF0} $@
 
+    elif [[ $1 = "-add_exceptions_ipv6" ]]; then
+      get_firewall_enabled || return 0
+
+      if [ -f /proc/net/if_inet6 ]; then
+        shift
+        add_exceptions ${IPv6BIN} ${IN_IVPN_IF0} ${OUT_IVPN_IF0} $@
+      fi
+
+    elif [[ $1 = "-remove_exceptions_ipv6" ]]; then
+      if [ -f /proc/net/if_inet6 ]; then
+        shift
+        remove_exceptions ${IPv6BIN} ${IN_IVPN_IF0} ${OUT_IVPN_IF0} $@
+      fi
+
     elif [[ $1 = "-add_exceptions_static" ]]; then
 
       shift
@@ -566,6 +587,14 @@ function main {
 
         # allow communication with host only srcPort <=> host.dstsPort
         add_direction_exception ${IN_IVPN_IF0} ${OUT_IVPN_IF0} ${SRC_PORT} ${DST_ADDR} ${DST_PORT} ${PROTOCOL}
+    elif [[ $1 = "-connected_iface" ]]; then
+
+        get_firewall_enabled || return 0
+
+        IFACE=$2
+
+        # allow all communication trough additional vpn interface (e.g. chained hop)
+        client_connected ${IFACE}
     elif [[ $1 = "-disconnected" ]]; then
         get_firewall_enabled || return 0
 
@@ -574,6 +603,11 @@ function main {
 
         clean_chain ${IPv4BIN} ${OUT_IVPN_IF0}
         clean_chain ${IPv4BIN} ${IN_IVPN_IF0}
+
+        if [ -f /proc/net/if_inet6 ]; then
+          clean_chain ${IPv6BIN} ${OUT_IVPN_IF0}
+          clean_chain ${IPv6BIN} ${IN_IVPN_IF0}
+        fi
     else
         echo "Unknown command"
         return 2
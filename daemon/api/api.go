@@ -23,9 +23,13 @@
 package api
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
 	"runtime"
 	"strings"
 	"sync"
@@ -43,14 +47,19 @@ const (
 	_apiHost               = "api.ivpn.net"
 	_updateHost            = "repo.ivpn.net"
 	_serversPath           = "v5/servers.json"
+	_serversSignPath       = _serversPath + ".sign.sha256.base64"
 	_apiPathPrefix         = "v4"
 	_sessionNewPath        = _apiPathPrefix + "/session/new"
 	_sessionStatusPath     = _apiPathPrefix + "/session/status"
 	_sessionDeletePath     = _apiPathPrefix + "/session/delete"
 	_wgKeySetPath          = _apiPathPrefix + "/session/wg/set"
+	_wgPskExchangePath     = _apiPathPrefix + "/session/wg/psk" // not provided by all API servers (see ErrPresharedKeyExchangeNotSupported)
 	_geoLookupPath         = _apiPathPrefix + "/geo-lookup"
 )
 
+// ErrPresharedKeyExchangeNotSupported - the API server does not provide the post-quantum preshared key exchange
+var ErrPresharedKeyExchangeNotSupported = errors.New("post-quantum preshared key exchange is not supported by the API server")
+
 // Alias - alias description of API request (can be requested by UI client)
 type Alias struct {
 	host string
@@ -113,11 +122,22 @@ type API struct {
 	alternateIPsV6        []net.IP
 	lastGoodAlternateIPv6 net.IP
 	connectivityChecker   IConnectivityInfo
+
+	endpoints *Endpoints     // nil - default endpoints (production servers)
+	rootCAs   *x509.CertPool // nil - system root certificates
+	proxy     *ProxyConfig   // nil - direct connection to API servers
+
+	reachability         Reachability
+	reachabilityObserver IReachabilityObserver
 }
 
 // CreateAPI creates new API object
 func CreateAPI() (*API, error) {
-	return &API{}, nil
+	a := &API{}
+	if err := a.initEndpoints(); err != nil {
+		return nil, err
+	}
+	return a, nil
 }
 
 func (a *API) SetConnectivityChecker(connectivityChecker IConnectivityInfo) {
@@ -216,16 +236,87 @@ func (a *API) doSetAlternateIPs(IPv6 bool, IPs []string) error {
 	return nil
 }
 
+// ServersListCacheInfo - the validators of the downloaded servers list
+// (in use for conditional requests: 'If-None-Match' and 'If-Modified-Since' HTTP headers)
+type ServersListCacheInfo struct {
+	ETag         string
+	LastModified string
+}
+
 // DownloadServersList - download servers list form API IVPN server
 func (a *API) DownloadServersList() (*types.ServersInfoResponse, error) {
-	servers := new(types.ServersInfoResponse)
-	if err := a.request("", _serversPath, "GET", "", nil, servers); err != nil {
-		return nil, err
+	servers, _, _, err := a.DownloadServersListIfModified(ServersListCacheInfo{})
+	return servers, err
+}
+
+// DownloadServersListIfModified - download servers list form API IVPN server (only if it was modified since the last download)
+// The signature of the servers list is verified (SHA256 RSA signature, base64 encoded: the same as for the update info).
+// The list without valid signature (bad, missing or failed to download) is rejected: the previously downloaded list stays in use.
+// Returns isNotModified=true (and the same 'cacheInfo') if the servers list was not modified since the download described by 'cacheInfo'.
+func (a *API) DownloadServersListIfModified(cacheInfo ServersListCacheInfo) (servers *types.ServersInfoResponse, newCacheInfo ServersListCacheInfo, isNotModified bool, err error) {
+	headers := http.Header{}
+	if len(cacheInfo.ETag) > 0 {
+		headers.Set("If-None-Match", cacheInfo.ETag)
+	}
+	if len(cacheInfo.LastModified) > 0 {
+		headers.Set("If-Modified-Since", cacheInfo.LastModified)
+	}
+
+	resp, err := a.doRequest(protocolTypes.IPvAny, "", _serversPath, "GET", "", nil, headers, 0, 0)
+	if err != nil {
+		return nil, cacheInfo, false, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, cacheInfo, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, cacheInfo, false, fmt.Errorf("API request failed: %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cacheInfo, false, fmt.Errorf("failed to get API HTTP response body: %w", err)
+	}
+
+	// verify the signature of the servers list
+	signature, err := a.downloadServersListSignature()
+	if err != nil {
+		return nil, cacheInfo, false, fmt.Errorf("failed to download servers list signature: %w", err)
+	}
+	if err := a.verifySignature(data, signature); err != nil {
+		return nil, cacheInfo, false, fmt.Errorf("servers list signature verification failed: %w", err)
+	}
+
+	servers = new(types.ServersInfoResponse)
+	if err := json.Unmarshal(data, servers); err != nil {
+		return nil, cacheInfo, false, fmt.Errorf("failed to deserialize API response: %w", err)
 	}
 
 	// save info about alternate API hosts
 	a.SetAlternateIPs(servers.Config.API.IPAddresses, servers.Config.API.IPv6Addresses)
-	return servers, nil
+	return servers, ServersListCacheInfo{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, false, nil
+}
+
+// downloadServersListSignature downloads the signature of the servers list
+// (any response except HTTP 200 is an error: e.g. missing signature must not be treated as an empty one)
+func (a *API) downloadServersListSignature() (signature []byte, err error) {
+	resp, err := a.doRequest(protocolTypes.IPvAny, "", _serversSignPath, "GET", "", nil, nil, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed: %s", resp.Status)
+	}
+
+	signature, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API HTTP response body: %w", err)
+	}
+	return signature, nil
 }
 
 // DoRequestByAlias do API request (by API endpoint alias). Returns raw data of response
@@ -376,6 +467,48 @@ func (a *API) WireGuardKeySet(session string, newPublicWgKey string, activePubli
 	return localIP, nil
 }
 
+// WireGuardPresharedKeyExchange - post-quantum preshared key negotiation for the WireGuard key (hybrid KEM)
+// The server encapsulates new shared secret for 'kemPublicKey' and configures it as a preshared key for the peer 'wgPublicKey'
+// Returns the KEM ciphertext (base64) which has to be decapsulated to obtain the preshared key
+// Returns ErrPresharedKeyExchangeNotSupported when the API server does not provide the exchange (HTTP 404)
+func (a *API) WireGuardPresharedKeyExchange(session string, wgPublicKey string, kemAlgorithm string, kemPublicKey string) (kemCiphertext string, err error) {
+	request := &types.SessionWireGuardPskRequest{
+		Session:      session,
+		PublicKey:    wgPublicKey,
+		KemAlgorithm: kemAlgorithm,
+		KemPublicKey: kemPublicKey}
+
+	httpResp, err := a.doRequest(protocolTypes.IPvAny, "", _wgPskExchangePath, "POST", "application/json", request, nil, 0, 0)
+	if err != nil {
+		return "", fmt.Errorf("API request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusNotFound {
+		return "", ErrPresharedKeyExchangeNotSupported
+	}
+
+	data, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to get API HTTP response body: %w", err)
+	}
+
+	resp := &types.SessionWireGuardPskResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return "", fmt.Errorf("failed to deserialize API response: %w", err)
+	}
+
+	if resp.Status != types.CodeSuccess {
+		return "", types.CreateAPIError(resp.Status, resp.Message)
+	}
+
+	if len(resp.KemCiphertext) == 0 {
+		return "", fmt.Errorf("failed to negotiate WG preshared key (no KEM ciphertext in API response)")
+	}
+
+	return resp.KemCiphertext, nil
+}
+
 // GeoLookup get geolocation
 func (a *API) GeoLookup(timeoutMs int) (location *types.GeoLookupResponse, err error) {
 	resp := &types.GeoLookupResponse{}
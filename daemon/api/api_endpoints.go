@@ -0,0 +1,109 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+)
+
+// Endpoints - the addresses of IVPN servers in use by API and the trust anchors to verify them
+// (by default - production servers; can be changed for testing purposes, e.g. to use the mock API server)
+type Endpoints struct {
+	APIHost    string // API server host (port can be specified: e.g. "127.0.0.1:8443")
+	UpdateHost string // update server host (port can be specified)
+
+	// base64-encoded SHA256 hashes of the servers public keys (in use for certificate key pinning)
+	APIHashes    []string
+	UpdateHashes []string
+
+	// PEM-encoded root certificates to verify the servers certificates (empty - use system root certificates)
+	RootCAs []string
+	// PEM-encoded public key to verify the signatures of the downloaded data (empty - use the default key)
+	SignaturePublicKey string
+}
+
+// DefaultEndpoints returns the configuration of production IVPN servers
+func DefaultEndpoints() Endpoints {
+	return Endpoints{
+		APIHost:      _apiHost,
+		UpdateHost:   _updateHost,
+		APIHashes:    APIIvpnHashes,
+		UpdateHashes: UpdateIvpnHashes,
+	}
+}
+
+// SetEndpoints changes the addresses of IVPN servers in use by API and the trust anchors to verify them
+func (a *API) SetEndpoints(e Endpoints) error {
+	if len(e.APIHost) == 0 || len(e.UpdateHost) == 0 {
+		return fmt.Errorf("API endpoints not defined")
+	}
+	if len(e.APIHashes) == 0 || len(e.UpdateHashes) == 0 {
+		return fmt.Errorf("no pinned certificates defined for API endpoints")
+	}
+
+	var rootCAs *x509.CertPool
+	if len(e.RootCAs) > 0 {
+		rootCAs = x509.NewCertPool()
+		for _, c := range e.RootCAs {
+			if !rootCAs.AppendCertsFromPEM([]byte(c)) {
+				return fmt.Errorf("failed to parse root certificate")
+			}
+		}
+	}
+	if len(e.SignaturePublicKey) > 0 {
+		if _, err := parsePublicKey(e.SignaturePublicKey); err != nil {
+			return err
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.endpoints = &e
+	a.rootCAs = rootCAs
+
+	if e.APIHost != _apiHost || e.UpdateHost != _updateHost {
+		log.Warning(fmt.Sprintf("Using non-default API endpoints: API='%s' Update='%s'", e.APIHost, e.UpdateHost))
+	}
+	return nil
+}
+
+// getEndpoints returns the current endpoints configuration and the root certificates to verify the servers (nil - system roots)
+func (a *API) getEndpoints() (Endpoints, *x509.CertPool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.endpoints == nil {
+		return DefaultEndpoints(), nil
+	}
+	return *a.endpoints, a.rootCAs
+}
+
+// hostName returns the host name without port (in use as TLS server name)
+func hostName(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,53 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build debug
+// +build debug
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// EnvEndpointsConfig - environment variable which contains the path to the JSON file with API endpoints configuration ('Endpoints' object)
+// (only for debug builds: in use for testing with the mock API server)
+const EnvEndpointsConfig = "IVPN_API_ENDPOINTS_CONFIG"
+
+func (a *API) initEndpoints() error {
+	file := os.Getenv(EnvEndpointsConfig)
+	if len(file) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read API endpoints configuration: %w", err)
+	}
+	var e Endpoints
+	if err := json.Unmarshal(data, &e); err != nil {
+		return fmt.Errorf("failed to parse API endpoints configuration: %w", err)
+	}
+	return a.SetEndpoints(e)
+}
@@ -54,7 +54,7 @@ func getURL_IPHost(ip net.IP, isIPv6 bool, urlpath string) string {
 	return "https://" + path.Join(ip.String(), urlpath)
 }
 
-func newRequest(urlPath string, method string, contentType string, body io.Reader) (*http.Request, error) {
+func newRequest(urlPath string, method string, contentType string, headers http.Header, body io.Reader) (*http.Request, error) {
 	if len(method) == 0 {
 		method = "GET"
 	}
@@ -67,6 +67,11 @@ func newRequest(urlPath string, method string, contentType string, body io.Reade
 	if len(contentType) > 0 {
 		req.Header.Add("Content-type", contentType)
 	}
+	for name, values := range headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
 
 	return req, nil
 }
@@ -82,9 +87,9 @@ func findPinnedKey(certHashes []string, certBase64hash256 string) bool {
 
 type dialer func(network, addr string) (net.Conn, error)
 
-func makeDialer(certHashes []string, skipCAVerification bool, serverName string, dialTimeout time.Duration) dialer {
+func makeDialer(certHashes []string, skipCAVerification bool, serverName string, rootCAs *x509.CertPool, proxyCfg *ProxyConfig, dialTimeout time.Duration) dialer {
 	if len(certHashes) == 0 {
-		log.Warning("No pinned certificates for ", _apiHost)
+		log.Warning("No pinned certificates for ", serverName)
 		return nil
 	}
 
@@ -105,13 +110,24 @@ func makeDialer(certHashes []string, skipCAVerification bool, serverName string,
 			MinVersion:         tls.VersionTLS12,
 			InsecureSkipVerify: skipCAVerification,
 			ServerName:         serverName, // only have sense when skipCAVerification == false
+			RootCAs:            rootCAs,    // nil - system root certificates
 		}
 
-		c, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, network, addr, tlsConfig)
-
+		// establish TCP connection (through the proxy server, if defined)
+		rawConn, err := dialTCP(proxyCfg, network, addr, dialTimeout)
 		if err != nil {
-			return c, err
+			return nil, err
+		}
+
+		if dialTimeout > 0 {
+			rawConn.SetDeadline(time.Now().Add(dialTimeout))
 		}
+		c := tls.Client(rawConn, tlsConfig)
+		if err := c.Handshake(); err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+		rawConn.SetDeadline(time.Time{})
 		connstate := c.ConnectionState()
 		var lastErr error = nil
 		for _, peercert := range connstate.PeerCertificates {
@@ -140,7 +156,7 @@ func makeDialer(certHashes []string, skipCAVerification bool, serverName string,
 	}
 }
 
-func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, urlPath string, method string, contentType string, request interface{}, timeoutMs int, timeoutDialMs int) (resp *http.Response, err error) {
+func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, urlPath string, method string, contentType string, request interface{}, headers http.Header, timeoutMs int, timeoutDialMs int) (resp *http.Response, err error) {
 	connectivityChecker := a.connectivityChecker
 	if connectivityChecker != nil {
 		if isBlocked, reasonDescription, err := connectivityChecker.IsConnectivityBlocked(); err == nil && isBlocked {
@@ -148,15 +164,18 @@ func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, ur
 		}
 	}
 
+	// the request was made: keep info about reachability of the API servers
+	defer func() { a.updateReachability(err) }()
+
 	if len(host) == 0 || host == _apiHost {
 		if ipTypeRequired != types.IPvAny {
 			// The specific IP version required to use
-			return a.doRequestAPIHost(ipTypeRequired, false, urlPath, method, contentType, request, timeoutMs, timeoutDialMs)
+			return a.doRequestAPIHost(ipTypeRequired, false, urlPath, method, contentType, request, headers, timeoutMs, timeoutDialMs)
 		} else {
 			// No specific IP version required to use
 			// Trying first to use IPv4, as fallback - try to use IPv6
 			canUseDNS := true
-			resp4, err4 := a.doRequestAPIHost(types.IPv4, canUseDNS, urlPath, method, contentType, request, timeoutMs, timeoutDialMs)
+			resp4, err4 := a.doRequestAPIHost(types.IPv4, canUseDNS, urlPath, method, contentType, request, headers, timeoutMs, timeoutDialMs)
 			if err4 != nil {
 				// checking if IPv6 connectivity exists
 				_, errIPv6 := netinfo.GetOutboundIP(true)
@@ -176,7 +195,7 @@ func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, ur
 					log.Info("Failed to access API server using IPv4. Trying IPv6 ...")
 					// we already tried to access using DNS. No sense to try it again
 					canUseDNS = false
-					resp6, err6 := a.doRequestAPIHost(types.IPv6, canUseDNS, urlPath, method, contentType, request, timeoutMs, timeoutDialMs)
+					resp6, err6 := a.doRequestAPIHost(types.IPv6, canUseDNS, urlPath, method, contentType, request, headers, timeoutMs, timeoutDialMs)
 					if err6 == nil {
 						return resp6, err6
 					}
@@ -186,20 +205,24 @@ func (a *API) doRequest(ipTypeRequired types.RequiredIPProtocol, host string, ur
 		}
 
 	} else if host == _updateHost {
-		return a.doRequestUpdateHost(urlPath, method, contentType, request, timeoutMs)
+		return a.doRequestUpdateHost(urlPath, method, contentType, request, headers, timeoutMs)
 	}
 	return nil, fmt.Errorf("unknown host type")
 }
 
-func (a *API) doRequestUpdateHost(urlPath string, method string, contentType string, request interface{}, timeoutMs int) (resp *http.Response, err error) {
+func (a *API) doRequestUpdateHost(urlPath string, method string, contentType string, request interface{}, headers http.Header, timeoutMs int) (resp *http.Response, err error) {
+	endpoints, rootCAs := a.getEndpoints()
+	updateHost := endpoints.UpdateHost
+
 	transCfg := &http.Transport{
 		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12, // seems, it is redundant (since we use custom DialTLS)
-			ServerName: _updateHost,      // despite, we using custom DialTLS, we have to define ServerName (this avoids certificate verification problems, for example, when the request is going through a proxy server)
+			MinVersion: tls.VersionTLS12,     // seems, it is redundant (since we use custom DialTLS)
+			ServerName: hostName(updateHost), // despite, we using custom DialTLS, we have to define ServerName (this avoids certificate verification problems, for example, when the request is going through a proxy server)
+			RootCAs:    rootCAs,
 		},
 
 		// using certificate key pinning
-		DialTLS: makeDialer(UpdateIvpnHashes, false, _updateHost, 0),
+		DialTLS: makeDialer(endpoints.UpdateHashes, false, hostName(updateHost), rootCAs, a.getProxy(), 0),
 	}
 
 	// configure http-client with preconfigured TLS transport
@@ -220,21 +243,21 @@ func (a *API) doRequestUpdateHost(urlPath string, method string, contentType str
 	bodyBuffer := bytes.NewBuffer(data)
 
 	// try to access API server by host DNS
-	req, err := newRequest(getURL(_updateHost, urlPath), method, contentType, bodyBuffer)
+	req, err := newRequest(getURL(updateHost, urlPath), method, contentType, headers, bodyBuffer)
 	if err != nil {
 		return nil, err
 	}
 
 	resp, e := client.Do(req)
 	if e != nil {
-		log.Warning("Failed to access " + _updateHost)
+		log.Warning("Failed to access " + updateHost)
 		return resp, fmt.Errorf("unable to access IVPN repo server: %w", e)
 	}
 
 	return resp, nil
 }
 
-func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUseDNS bool, urlPath string, method string, contentType string, request interface{}, timeoutMs int, timeoutDialMs int) (resp *http.Response, err error) {
+func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUseDNS bool, urlPath string, method string, contentType string, request interface{}, headers http.Header, timeoutMs int, timeoutDialMs int) (resp *http.Response, err error) {
 	isIPv6 := ipTypeRequired == types.IPv6
 
 	// timeout time for full request
@@ -254,14 +277,18 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 	// When trying to access API server by alternate IPs (not by DNS name)
 	// we need to configure TLS to use api.ivpn.net hostname
 	// (to avoid certificate errors)
+	endpoints, rootCAs := a.getEndpoints()
+	apiHost := endpoints.APIHost
+
 	transCfg := &http.Transport{
 		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12, // seems, it is redundant (since we use custom DialTLS)
-			ServerName: _apiHost,         // despite, we using custom DialTLS, we have to define ServerName (this avoids certificate verification problems, for example, when the request is going through a proxy server)
+			MinVersion: tls.VersionTLS12,  // seems, it is redundant (since we use custom DialTLS)
+			ServerName: hostName(apiHost), // despite, we using custom DialTLS, we have to define ServerName (this avoids certificate verification problems, for example, when the request is going through a proxy server)
+			RootCAs:    rootCAs,
 		},
 
 		// using certificate key pinning
-		DialTLS: makeDialer(APIIvpnHashes, false, _apiHost, timeoutDial),
+		DialTLS: makeDialer(endpoints.APIHashes, false, hostName(apiHost), rootCAs, a.getProxy(), timeoutDial),
 	}
 
 	// configure http-client with preconfigured TLS transport
@@ -280,7 +307,7 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 	// access API by last good IP (if defined)
 	lastGoodIP := a.GetLastGoodAlternateIP(isIPv6)
 	if lastGoodIP != nil {
-		req, err := newRequest(getURL_IPHost(lastGoodIP, isIPv6, urlPath), method, contentType, bodyBuffer)
+		req, err := newRequest(getURL_IPHost(lastGoodIP, isIPv6, urlPath), method, contentType, headers, bodyBuffer)
 		if err != nil {
 			return nil, err
 		}
@@ -295,7 +322,7 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 	var firstResp *http.Response
 	var firstErr error
 	if isCanUseDNS {
-		req, err := newRequest(getURL(_apiHost, urlPath), method, contentType, bodyBuffer)
+		req, err := newRequest(getURL(apiHost, urlPath), method, contentType, headers, bodyBuffer)
 		if err != nil {
 			return nil, err
 		}
@@ -303,7 +330,7 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 		if firstErr == nil {
 			return firstResp, firstErr
 		}
-		log.Warning("Failed to access " + _apiHost)
+		log.Warning("Failed to access " + apiHost)
 	}
 
 	isLogNotificationPrinted := false
@@ -324,7 +351,7 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 			log.Info(fmt.Sprintf("Trying to use alternate API IPs %s...", ipVerStr))
 		}
 
-		req, err := newRequest(getURL_IPHost(ip, isIPv6, urlPath), method, contentType, bodyBuffer)
+		req, err := newRequest(getURL_IPHost(ip, isIPv6, urlPath), method, contentType, headers, bodyBuffer)
 		if err != nil {
 			return nil, err
 		}
@@ -348,7 +375,7 @@ func (a *API) doRequestAPIHost(ipTypeRequired types.RequiredIPProtocol, isCanUse
 }
 
 func (a *API) requestRaw(ipTypeRequired types.RequiredIPProtocol, host string, urlPath string, method string, contentType string, requestObject interface{}, timeoutMs int, timeoutDialMs int) (responseData []byte, err error) {
-	resp, err := a.doRequest(ipTypeRequired, host, urlPath, method, contentType, requestObject, timeoutMs, timeoutDialMs)
+	resp, err := a.doRequest(ipTypeRequired, host, urlPath, method, contentType, requestObject, nil, timeoutMs, timeoutDialMs)
 	if err != nil {
 		return nil, fmt.Errorf("API request failed: %w", err)
 	}
@@ -0,0 +1,269 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/api/apimock"
+	"github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+func newMockAPI(t *testing.T) (*api.API, *apimock.Server) {
+	mock, err := apimock.NewServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mock.Close)
+
+	a, err := api.CreateAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetEndpoints(mock.Endpoints()); err != nil {
+		t.Fatal(err)
+	}
+	return a, mock
+}
+
+func TestSession(t *testing.T) {
+	a, mock := newMockAPI(t)
+	mock.AddAccount("i-TEST-TEST-TEST", types.ServiceStatusAPIResp{Active: true, CurrentPlan: "IVPN Pro"})
+
+	if _, _, _, _, err := a.SessionNew("i-UNKNOWN", "", false, "", "", ""); err == nil {
+		t.Fatal("login with unknown account must fail")
+	}
+
+	resp, _, _, _, err := a.SessionNew("i-TEST-TEST-TEST", "wgPublicKey", false, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Token) == 0 || len(resp.WireGuard.IPAddress) == 0 {
+		t.Fatalf("unexpected session info: %+v", resp)
+	}
+
+	status, _, err := a.SessionStatus(resp.Token)
+	if err != nil || status.CurrentPlan != "IVPN Pro" {
+		t.Fatalf("unexpected session status: %+v (%v)", status, err)
+	}
+
+	if _, err := a.WireGuardKeySet(resp.Token, "wgPublicKeyNew", "wgPublicKey"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.WireGuardKeySet(resp.Token, "wgPublicKeyNew2", "wgPublicKey"); err == nil {
+		t.Error("WG key update with wrong active key must fail")
+	}
+
+	if err := a.SessionDelete(resp.Token); err != nil {
+		t.Fatal(err)
+	}
+	var apiErr types.APIError
+	if _, _, err := a.SessionStatus(resp.Token); !errors.As(err, &apiErr) || apiErr.ErrorCode != types.SessionNotFound {
+		t.Errorf("'session not found' error expected: %v", err)
+	}
+}
+
+func TestScriptedErrors(t *testing.T) {
+	a, mock := newMockAPI(t)
+	mock.AddAccount("i-TEST-TEST-TEST", types.ServiceStatusAPIResp{Active: true})
+
+	mock.ScriptAPIError(apimock.PathSessionNew, types.CodeSessionsLimitReached, "Session limit reached")
+	_, limitResp, _, _, err := a.SessionNew("i-TEST-TEST-TEST", "", false, "", "", "")
+	if err == nil || limitResp == nil {
+		t.Errorf("session limit error expected: %v", err)
+	}
+
+	mock.ScriptResponse(apimock.PathGeoLookup, apimock.Response{HTTPStatus: http.StatusInternalServerError, Body: "<html>error</html>"})
+	if _, err := a.GeoLookup(0); err == nil {
+		t.Error("scripted error expected")
+	}
+
+	// the scripted responses are consumed: the normal response expected
+	mock.SetGeoLocation(50.45, 30.52)
+	if geo, err := a.GeoLookup(0); err != nil || geo.Latitude != 50.45 {
+		t.Errorf("unexpected geo-lookup result: %+v (%v)", geo, err)
+	}
+	if len(mock.Requests(apimock.PathGeoLookup)) != 2 {
+		t.Error("unexpected number of received requests")
+	}
+}
+
+func TestServersList(t *testing.T) {
+	a, mock := newMockAPI(t)
+
+	servers, cacheInfo, isNotModified, err := a.DownloadServersListIfModified(api.ServersListCacheInfo{})
+	if err != nil || isNotModified || len(servers.WireguardServers) == 0 {
+		t.Fatalf("unexpected servers list: %v", err)
+	}
+
+	if _, _, isNotModified, err := a.DownloadServersListIfModified(cacheInfo); err != nil || !isNotModified {
+		t.Errorf("servers list expected to be not modified: %v", err)
+	}
+
+	svrs := apimock.DefaultServers()
+	svrs.WireguardServers[0].Hosts[0].PublicKey = "BBECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	mock.SetServers(svrs)
+	servers, _, isNotModified, err = a.DownloadServersListIfModified(cacheInfo)
+	if err != nil || isNotModified || servers.WireguardServers[0].Hosts[0].PublicKey != svrs.WireguardServers[0].Hosts[0].PublicKey {
+		t.Errorf("updated servers list expected: %v", err)
+	}
+
+	// the servers list with bad signature must be rejected
+	mock.ScriptResponse(apimock.PathServersSign, apimock.Response{Body: "AAAA"})
+	if _, _, _, err := a.DownloadServersListIfModified(api.ServersListCacheInfo{}); err == nil {
+		t.Error("servers list with bad signature accepted")
+	}
+	mock.ScriptResponse(apimock.PathServersSign, apimock.Response{HTTPStatus: http.StatusInternalServerError, Body: "error"})
+	if _, _, _, err := a.DownloadServersListIfModified(api.ServersListCacheInfo{}); err == nil {
+		t.Error("servers list accepted when the signature download failed")
+	}
+}
+
+// startHTTPProxy starts simple HTTP proxy (CONNECT method only) which requires basic authentication
+func startHTTPProxy(t *testing.T, user, password string) (addr string, connections *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	connections = new(int32)
+	expectedAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != "CONNECT" {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != expectedAuth {
+					conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+					return
+				}
+				defer target.Close()
+				atomic.AddInt32(connections, 1)
+				conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), connections
+}
+
+func TestProxy(t *testing.T) {
+	a, mock := newMockAPI(t)
+	mock.SetGeoLocation(1, 2)
+
+	proxyAddr, connections := startHTTPProxy(t, "user", "pass")
+
+	if err := a.SetProxy(&api.ProxyConfig{Type: api.ProxyHTTP, Address: proxyAddr, Username: "user", Password: "wrong"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GeoLookup(0); err == nil {
+		t.Error("request through proxy with wrong credentials must fail")
+	}
+
+	if err := a.SetProxy(&api.ProxyConfig{Type: api.ProxyHTTP, Address: proxyAddr, Username: "user", Password: "pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if geo, err := a.GeoLookup(0); err != nil || geo.Latitude != 1 {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	if atomic.LoadInt32(connections) == 0 {
+		t.Error("request was not sent through proxy")
+	}
+
+	if err := a.SetProxy(&api.ProxyConfig{Type: "ftp", Address: proxyAddr}); err == nil {
+		t.Error("unsupported proxy type accepted")
+	}
+}
+
+type reachabilityObserver struct {
+	changes []api.Reachability
+}
+
+func (o *reachabilityObserver) OnApiReachabilityChanged(r api.Reachability) {
+	o.changes = append(o.changes, r)
+}
+
+func TestReachability(t *testing.T) {
+	a, mock := newMockAPI(t)
+	observer := &reachabilityObserver{}
+	a.SetReachabilityObserver(observer)
+
+	if _, err := a.GeoLookup(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GeoLookup(0); err != nil {
+		t.Fatal(err)
+	}
+	if r := a.Reachability(); !r.IsReachable || r.LastSuccess.IsZero() || len(observer.changes) != 1 {
+		t.Fatalf("API must be reachable: %+v (notifications: %d)", r, len(observer.changes))
+	}
+
+	// API error response: the server is still reachable
+	mock.ScriptResponse(apimock.PathGeoLookup, apimock.Response{HTTPStatus: http.StatusInternalServerError, Body: "internal error"})
+	if _, err := a.GeoLookup(0); err == nil {
+		t.Fatal("error expected")
+	}
+	if r := a.Reachability(); !r.IsReachable {
+		t.Fatalf("API must be reachable: %+v", r)
+	}
+
+	mock.Close()
+	if _, err := a.GeoLookup(0); err == nil {
+		t.Fatal("error expected")
+	}
+	r := a.Reachability()
+	if r.IsReachable || r.UnreachableSince.IsZero() || len(r.LastError) == 0 || len(observer.changes) != 2 {
+		t.Fatalf("API must be unreachable: %+v (notifications: %d)", r, len(observer.changes))
+	}
+}
+
+func TestWireGuardPresharedKeyExchangeNotSupported(t *testing.T) {
+	a, _ := newMockAPI(t)
+
+	// the mock server does not provide the exchange (HTTP 404)
+	if _, err := a.WireGuardPresharedKeyExchange("session", "wgPublicKey", "kem", "kemPublicKey"); !errors.Is(err, api.ErrPresharedKeyExchangeNotSupported) {
+		t.Errorf("'not supported' error expected: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Proxy types for API traffic
+const (
+	ProxyHTTP   = "http"   // HTTP proxy (CONNECT method)
+	ProxySocks5 = "socks5" // SOCKS5 proxy
+)
+
+// ProxyConfig - proxy server configuration for API traffic
+type ProxyConfig struct {
+	Type     string // ProxyHTTP or ProxySocks5
+	Address  string // proxy server address: "host:port"
+	Username string // (optional)
+	Password string // (optional)
+}
+
+// SetProxy defines the proxy server for API traffic (nil - direct connection)
+func (a *API) SetProxy(p *ProxyConfig) error {
+	if p != nil {
+		if p.Type != ProxyHTTP && p.Type != ProxySocks5 {
+			return fmt.Errorf("unsupported proxy type '%s'", p.Type)
+		}
+		if _, _, err := net.SplitHostPort(p.Address); err != nil {
+			return fmt.Errorf("bad proxy address '%s': %w", p.Address, err)
+		}
+		cfg := *p
+		p = &cfg
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.proxy = p
+	return nil
+}
+
+func (a *API) getProxy() *ProxyConfig {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.proxy
+}
+
+// dialTCP establishes TCP connection to the address (through the proxy server, if defined)
+func dialTCP(p *ProxyConfig, network, addr string, timeout time.Duration) (net.Conn, error) {
+	if p == nil {
+		return (&net.Dialer{Timeout: timeout}).Dial(network, addr)
+	}
+
+	switch p.Type {
+	case ProxySocks5:
+		var auth *proxy.Auth
+		if len(p.Username) > 0 {
+			auth = &proxy.Auth{User: p.Username, Password: p.Password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", p.Address, auth, &net.Dialer{Timeout: timeout})
+		if err != nil {
+			return nil, fmt.Errorf("SOCKS5 proxy error: %w", err)
+		}
+		conn, err := dialer.Dial(network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("SOCKS5 proxy error: %w", err)
+		}
+		return conn, nil
+
+	case ProxyHTTP:
+		return dialHTTPProxy(p, addr, timeout)
+	}
+	return nil, fmt.Errorf("unsupported proxy type '%s'", p.Type)
+}
+
+// dialHTTPProxy establishes the tunnel through HTTP proxy (CONNECT method)
+func dialHTTPProxy(p *ProxyConfig, addr string, timeout time.Duration) (net.Conn, error) {
+	conn, err := (&net.Dialer{Timeout: timeout}).Dial("tcp", p.Address)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP proxy error: %w", err)
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if len(p.Username) > 0 {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy error: %w", err)
+	}
+	// Note: the proxy sends nothing after the response until the TLS handshake started, so the buffered reader can be dropped
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy error: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("HTTP proxy error: %s", resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,86 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"time"
+)
+
+// Reachability - information about reachability of the API servers
+// (based on the results of the requests made by the daemon)
+type Reachability struct {
+	IsReachable bool
+	LastSuccess time.Time // time of the last successful request (zero - no successful requests yet)
+	LastFailure time.Time // time of the last failed request (zero - no failed requests yet)
+	LastError   string    // error of the last failed request
+
+	UnreachableSince time.Time // time of the first failed request after the last successful one
+}
+
+// IReachabilityObserver receives notifications when reachability of the API servers changes
+type IReachabilityObserver interface {
+	OnApiReachabilityChanged(r Reachability)
+}
+
+// SetReachabilityObserver sets the object to be notified about changes of the API servers reachability
+func (a *API) SetReachabilityObserver(observer IReachabilityObserver) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.reachabilityObserver = observer
+}
+
+// Reachability returns information about reachability of the API servers
+func (a *API) Reachability() Reachability {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.reachability
+}
+
+// updateReachability saves the result of the request to the API servers.
+// 'err' - error of the request when no response was received from the server (nil - the server responded)
+func (a *API) updateReachability(err error) {
+	a.mutex.Lock()
+	prev := a.reachability
+	if err == nil {
+		a.reachability.IsReachable = true
+		a.reachability.LastSuccess = time.Now()
+		a.reachability.UnreachableSince = time.Time{}
+	} else {
+		if a.reachability.IsReachable || a.reachability.UnreachableSince.IsZero() {
+			a.reachability.UnreachableSince = time.Now()
+		}
+		a.reachability.IsReachable = false
+		a.reachability.LastFailure = time.Now()
+		a.reachability.LastError = err.Error()
+	}
+	r := a.reachability
+	observer := a.reachabilityObserver
+	a.mutex.Unlock()
+
+	isChanged := r.IsReachable != prev.IsReachable || (prev.LastSuccess.IsZero() && prev.LastFailure.IsZero())
+	if isChanged && observer != nil {
+		observer.OnApiReachabilityChanged(r)
+	}
+}
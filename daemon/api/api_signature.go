@@ -0,0 +1,104 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// The public key to verify the signatures of the data downloaded from IVPN servers
+// (the same key is in use by UI to verify the update info: see 'updateSign_*' aliases)
+//
+// SIGNING EXAMPLE
+//
+//	sign:
+//	    openssl dgst -sha256 -sign private.pem -out servers.json.sign.sha256 servers.json
+//	encode to base64:
+//	    openssl base64 -in servers.json.sign.sha256 -out servers.json.sign.sha256.base64
+const _signaturePublicKey = `-----BEGIN PUBLIC KEY-----
+MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEA1m7vr8rY10V1ZDIxsP6g
+Bhq+QYRGNt+33NA0+/MUpxioi2t6sfua0ql6Pxs+Q5x10C/Sx8vNlcagOHwXOS6W
+YNnLsqEOHCxgd0M5thEdT5KXjJEbpzjjrTmk2HuD2cnqmI5b9wCYx5GzREMguCAU
+or+PCUEV/TWittG1DYAW3evPUy3VIMer+Oq6L0jLFSDpfGlXBBKmqZwX3nRuzSaI
+iS0qfs39FipVEyuX/ZKNHXx7mFG73RqhU1V6m3dFEdwrMGEqq9rHc/XUXZKMgiwO
+Wvr7qfCXFoYYcYdseQg1g/8MP6ur0WctMfK5PC36MJlSq/gy/W/gRiIrQMCYMHnB
+0yRrGXvm1n8483y0YVorz2WcGt4cal4bCEnOuYam+SOjD+XM81FIXJnlUFpehXbA
+ZNxgu/5woENBPavCkgK0z+d+CdPdF6WAO6mzytAakLyDffOBblVpGouyYr78LhF3
+DfEQSV06n6dAYFyIyxR/jET24MrWwM3KCXTQAyPV1v2eKaMJoh8JMf+4dEVde5om
+LopbFeMGb9xFxQmedNqtBb/DYBcgEh/Fa3s9r+V/8Fq6ULzjeyejC4VMnc8KCST9
+mX57qSlQ3sj9GG7wlW5TvGUnpJ6vuTj50S6ZXfYe7VuvBM9gxtOhJVPwA5Uy/RzX
+C6HXQqBJNLEOqq2b/+q9fHECAwEAAQ==
+-----END PUBLIC KEY-----`
+
+// verifySignature checks the SHA256 RSA signature (base64 encoded) of the data
+func (a *API) verifySignature(data []byte, signatureBase64 []byte) error {
+	keyPem := _signaturePublicKey
+	if e, _ := a.getEndpoints(); len(e.SignaturePublicKey) > 0 {
+		keyPem = e.SignaturePublicKey
+	}
+	pubKey, err := parsePublicKey(keyPem)
+	if err != nil {
+		return err
+	}
+	return verifySignatureWithKey(pubKey, data, signatureBase64)
+}
+
+func verifySignatureWithKey(pubKey *rsa.PublicKey, data []byte, signatureBase64 []byte) error {
+	// 'openssl base64' splits the output into lines
+	signature, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(signatureBase64)), ""))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(signature) == 0 {
+		return fmt.Errorf("signature is empty")
+	}
+
+	hash := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, hash[:], signature); err != nil {
+		return fmt.Errorf("signature mismatch: %w", err)
+	}
+	return nil
+}
+
+func parsePublicKey(pemData string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected public key type")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,66 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	if _, err := parsePublicKey(_signaturePublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"wireguard":[]}`)
+	hash := sha256.Sum256(data)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 'openssl base64' output: split into lines of 64 characters
+	signatureB64 := base64.StdEncoding.EncodeToString(signature)
+	signatureB64 = signatureB64[:64] + "\n" + signatureB64[64:] + "\n"
+
+	if err := verifySignatureWithKey(&key.PublicKey, data, []byte(signatureB64)); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+	if err := verifySignatureWithKey(&key.PublicKey, []byte(`{"wireguard":[{}]}`), []byte(signatureB64)); err == nil {
+		t.Error("modified data accepted")
+	}
+	if err := verifySignatureWithKey(&key.PublicKey, data, []byte("<html>404 Not Found</html>")); err == nil {
+		t.Error("bad signature accepted")
+	}
+	// signature made by another key
+	if err := (&API{}).verifySignature(data, []byte(signatureB64)); err == nil {
+		t.Error("signature of unknown key accepted")
+	}
+}
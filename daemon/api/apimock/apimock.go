@@ -0,0 +1,403 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package apimock implements the mock of IVPN API server (for testing purposes).
+// It serves the session requests ('session/new', 'session/status', 'session/delete', 'session/wg/set'),
+// 'geo-lookup' and signed 'servers.json'. Any response can be replaced by the scripted one (e.g. to test errors handling).
+//
+// Usage example:
+//
+//	mock, _ := apimock.NewServer("")
+//	defer mock.Close()
+//	apiObj, _ := api.CreateAPI()
+//	apiObj.SetEndpoints(mock.Endpoints())
+package apimock
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+// Paths of the API requests served by the mock server
+const (
+	PathSessionNew     = "/v4/session/new"
+	PathSessionStatus  = "/v4/session/status"
+	PathSessionDelete  = "/v4/session/delete"
+	PathWgKeySet       = "/v4/session/wg/set"
+	PathGeoLookup      = "/v4/geo-lookup"
+	PathServers        = "/v5/servers.json"
+	PathServersSign    = "/v5/servers.json.sign.sha256.base64"
+	defaultServerLocal = "127.0.0.1:0"
+)
+
+// Response - the scripted response of the mock server
+type Response struct {
+	HTTPStatus int           // HTTP status code (0 - 200)
+	Body       string        // raw response body
+	Delay      time.Duration // delay before the response (e.g. to test timeouts)
+}
+
+// Server - mock IVPN API server
+type Server struct {
+	mutex sync.Mutex
+
+	httpServer *httptest.Server
+	signKey    *rsa.PrivateKey
+
+	accounts     map[string]types.ServiceStatusAPIResp // [accountID]status
+	sessions     map[string]*session                   // [token]session
+	servers      []byte                                // servers.json
+	serversTime  time.Time                             // time of the last servers.json change
+	geoLocation  types.GeoLookupResponse
+	scripted     map[string][]Response // [path]queue of scripted responses
+	requests     map[string][]string   // [path]bodies of received requests
+	lastClientIP byte
+}
+
+type session struct {
+	accountID   string
+	wgPublicKey string
+	wgLocalIP   string
+}
+
+// NewServer starts the mock server on the specified local address ("" - random port on 127.0.0.1)
+func NewServer(addr string) (*Server, error) {
+	signKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	s := &Server{
+		signKey:  signKey,
+		accounts: make(map[string]types.ServiceStatusAPIResp),
+		sessions: make(map[string]*session),
+		scripted: make(map[string][]Response),
+		requests: make(map[string][]string),
+	}
+	if err := s.SetServers(DefaultServers()); err != nil {
+		return nil, err
+	}
+
+	if len(addr) == 0 {
+		addr = defaultServerLocal
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock API server: %w", err)
+	}
+
+	s.httpServer = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+	s.httpServer.Listener.Close()
+	s.httpServer.Listener = listener
+	s.httpServer.StartTLS()
+	return s, nil
+}
+
+// Close stops the mock server
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// Endpoints returns the API endpoints configuration to access the mock server
+// (the same server is in use as API and update host)
+func (s *Server) Endpoints() api.Endpoints {
+	cert := s.httpServer.Certificate()
+	host := s.httpServer.Listener.Addr().String()
+
+	der, _ := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	hash := sha256.Sum256(der)
+	certHash := base64.StdEncoding.EncodeToString(hash[:])
+
+	pubKeyDer, _ := x509.MarshalPKIXPublicKey(&s.signKey.PublicKey)
+
+	return api.Endpoints{
+		APIHost:            host,
+		UpdateHost:         host,
+		APIHashes:          []string{certHash},
+		UpdateHashes:       []string{certHash},
+		RootCAs:            []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))},
+		SignaturePublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubKeyDer})),
+	}
+}
+
+// AddAccount registers the account (only registered accounts are able to create new session)
+func (s *Server) AddAccount(accountID string, status types.ServiceStatusAPIResp) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accounts[accountID] = status
+}
+
+// SetServers changes the servers list (the list is signed by the mock server key)
+func (s *Server) SetServers(servers types.ServersInfoResponse) error {
+	data, err := json.Marshal(servers)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.servers = data
+	s.serversTime = time.Now().UTC().Truncate(time.Second)
+	return nil
+}
+
+// SetGeoLocation changes the response for 'geo-lookup' request
+func (s *Server) SetGeoLocation(latitude, longitude float32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.geoLocation = types.GeoLookupResponse{Latitude: latitude, Longitude: longitude}
+}
+
+// ScriptResponse adds the responses to the queue of scripted responses for the path.
+// The scripted responses are sent (one per request) instead of the normal ones until the queue is empty.
+func (s *Server) ScriptResponse(path string, responses ...Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.scripted[path] = append(s.scripted[path], responses...)
+}
+
+// ScriptAPIError adds the IVPN API error response (e.g. types.SessionNotFound) to the queue of scripted responses for the path
+func (s *Server) ScriptAPIError(path string, status int, message string) {
+	data, _ := json.Marshal(types.APIErrorResponse{APIResponse: types.APIResponse{Status: status}, Message: message})
+	s.ScriptResponse(path, Response{Body: string(data)})
+}
+
+// Requests returns the bodies of the requests received for the path
+func (s *Server) Requests(path string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.requests[path]...)
+}
+
+// SessionsCount returns the number of active sessions
+func (s *Server) SessionsCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.sessions)
+}
+
+// DefaultServers returns the servers list which is in use by default
+func DefaultServers() types.ServersInfoResponse {
+	var svrs types.ServersInfoResponse
+	svrs.WireguardServers = []types.WireGuardServerInfo{{
+		Gateway: "nl.wg.ivpn.net", CountryCode: "NL", Country: "Netherlands", City: "Amsterdam", Latitude: 52.35, Longitude: 4.9,
+		Hosts: []types.WireGuardServerHostInfo{{
+			HostInfoBase: types.HostInfoBase{Hostname: "nl1.wg.ivpn.net", Host: "127.0.0.10", MultihopPort: 20001, Load: 10},
+			PublicKey:    "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=",
+			LocalIP:      "172.16.0.1/12"}}}}
+	svrs.OpenvpnServers = []types.OpenvpnServerInfo{{
+		Gateway: "nl.gw.ivpn.net", CountryCode: "NL", Country: "Netherlands", City: "Amsterdam", Latitude: 52.35, Longitude: 4.9,
+		Hosts: []types.OpenVPNServerHostInfo{{
+			HostInfoBase: types.HostInfoBase{Hostname: "nl1.gw.ivpn.net", Host: "127.0.0.11", MultihopPort: 20001, Load: 10}}}}}
+	svrs.Config.Ports.WireGuard = []types.PortInfo{{Type: "UDP", Port: 2049}}
+	svrs.Config.Ports.OpenVPN = []types.PortInfo{{Type: "UDP", Port: 2049}, {Type: "TCP", Port: 443}}
+	return svrs
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mutex.Lock()
+	s.requests[r.URL.Path] = append(s.requests[r.URL.Path], string(body))
+	var scripted *Response
+	if queue := s.scripted[r.URL.Path]; len(queue) > 0 {
+		scripted = &queue[0]
+		s.scripted[r.URL.Path] = queue[1:]
+	}
+	s.mutex.Unlock()
+
+	if scripted != nil {
+		time.Sleep(scripted.Delay)
+		if scripted.HTTPStatus > 0 {
+			w.WriteHeader(scripted.HTTPStatus)
+		}
+		w.Write([]byte(scripted.Body))
+		return
+	}
+
+	switch r.URL.Path {
+	case PathSessionNew:
+		var req types.SessionNewRequest
+		json.Unmarshal(body, &req)
+		s.writeJSON(w, s.sessionNew(req))
+	case PathSessionStatus:
+		var req types.SessionStatusRequest
+		json.Unmarshal(body, &req)
+		s.writeJSON(w, s.sessionStatus(req))
+	case PathSessionDelete:
+		var req types.SessionDeleteRequest
+		json.Unmarshal(body, &req)
+		s.writeJSON(w, s.sessionDelete(req))
+	case PathWgKeySet:
+		var req types.SessionWireGuardKeySetRequest
+		json.Unmarshal(body, &req)
+		s.writeJSON(w, s.wgKeySet(req))
+	case PathGeoLookup:
+		s.mutex.Lock()
+		geo := s.geoLocation
+		s.mutex.Unlock()
+		s.writeJSON(w, geo)
+	case PathServers:
+		s.serveServers(w, r)
+	case PathServersSign:
+		s.mutex.Lock()
+		data := s.servers
+		s.mutex.Unlock()
+		hash := sha256.Sum256(data)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, s.signKey, crypto.SHA256, hash[:])
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(base64.StdEncoding.EncodeToString(signature) + "\n"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Server) serveServers(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	data := s.servers
+	modified := s.serversTime
+	s.mutex.Unlock()
+
+	hash := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(hash[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	if match := r.Header.Get("If-None-Match"); len(match) > 0 {
+		if match == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(data)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func apiError(status int, message string) types.APIErrorResponse {
+	return types.APIErrorResponse{APIResponse: types.APIResponse{Status: status}, Message: message}
+}
+
+func (s *Server) sessionNew(req types.SessionNewRequest) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, ok := s.accounts[req.AccountID]
+	if !ok {
+		return apiError(types.Unauthorized, "Invalid Credentials")
+	}
+
+	tokenBytes := make([]byte, 16)
+	rand.Read(tokenBytes)
+	token := hex.EncodeToString(tokenBytes)
+	sn := &session{accountID: req.AccountID, wgPublicKey: req.PublicKey}
+
+	resp := types.SessionNewResponse{
+		APIErrorResponse: apiError(types.CodeSuccess, ""),
+		Token:            token,
+		VpnUsername:      "ivpn" + strings.ToLower(token[:8]),
+		VpnPassword:      token[8:],
+		ServiceStatus:    status,
+	}
+	if len(req.PublicKey) > 0 {
+		sn.wgLocalIP = s.nextClientIP()
+		resp.WireGuard.Status = types.CodeSuccess
+		resp.WireGuard.IPAddress = sn.wgLocalIP
+	}
+	s.sessions[token] = sn
+	return resp
+}
+
+func (s *Server) sessionStatus(req types.SessionStatusRequest) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sn, ok := s.sessions[req.Session]
+	if !ok {
+		return apiError(types.SessionNotFound, "Session not found")
+	}
+	return types.SessionStatusResponse{APIErrorResponse: apiError(types.CodeSuccess, ""), ServiceStatus: s.accounts[sn.accountID]}
+}
+
+func (s *Server) sessionDelete(req types.SessionDeleteRequest) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.sessions[req.Session]; !ok {
+		return apiError(types.SessionNotFound, "Session not found")
+	}
+	delete(s.sessions, req.Session)
+	return apiError(types.CodeSuccess, "")
+}
+
+func (s *Server) wgKeySet(req types.SessionWireGuardKeySetRequest) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sn, ok := s.sessions[req.Session]
+	if !ok {
+		return apiError(types.SessionNotFound, "Session not found")
+	}
+	if len(sn.wgPublicKey) > 0 && len(req.ConnectedPublicKey) > 0 && sn.wgPublicKey != req.ConnectedPublicKey {
+		return apiError(types.WGPublicKeyNotFound, "WireGuard public key not found")
+	}
+	sn.wgPublicKey = req.PublicKey
+	sn.wgLocalIP = s.nextClientIP()
+	return types.SessionsWireGuardResponse{APIErrorResponse: apiError(types.CodeSuccess, ""), IPAddress: sn.wgLocalIP}
+}
+
+func (s *Server) nextClientIP() string {
+	s.lastClientIP++
+	return fmt.Sprintf("172.16.0.%d", s.lastClientIP+1)
+}
@@ -0,0 +1,77 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Standalone mock IVPN API server.
+// Writes the API endpoints configuration to the file which can be used by the daemon debug build:
+//
+//	go run ./api/apimock/cmd -addr 127.0.0.1:8443 -account i-TEST-TEST-TEST -config /tmp/ivpn_api.json
+//	sudo IVPN_API_ENDPOINTS_CONFIG=/tmp/ivpn_api.json ./ivpn-service   (built with '-tags debug')
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ivpn/desktop-app/daemon/api/apimock"
+	"github.com/ivpn/desktop-app/daemon/api/types"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8443", "local address to listen")
+	accounts := flag.String("account", "", "comma separated list of accounts allowed to login")
+	configFile := flag.String("config", "", "file to save API endpoints configuration")
+	flag.Parse()
+
+	mock, err := apimock.NewServer(*addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer mock.Close()
+
+	for _, acc := range strings.Split(*accounts, ",") {
+		if acc = strings.TrimSpace(acc); len(acc) > 0 {
+			mock.AddAccount(acc, types.ServiceStatusAPIResp{Active: true, CurrentPlan: "IVPN Pro", Capabilities: []string{"multihop"}})
+		}
+	}
+
+	data, _ := json.MarshalIndent(mock.Endpoints(), "", "  ")
+	if len(*configFile) > 0 {
+		if err := os.WriteFile(*configFile, data, 0600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("API endpoints configuration saved: %s\n", *configFile)
+	} else {
+		fmt.Println(string(data))
+	}
+	fmt.Printf("Mock API server started: %s (Ctrl+C to stop)\n", *addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+}
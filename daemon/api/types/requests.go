@@ -49,3 +49,11 @@ type SessionWireGuardKeySetRequest struct {
 	PublicKey          string `json:"public_key"`
 	ConnectedPublicKey string `json:"connected_public_key"`
 }
+
+// SessionWireGuardPskRequest request to negotiate post-quantum preshared key for the WG key
+type SessionWireGuardPskRequest struct {
+	Session      string `json:"session_token"`
+	PublicKey    string `json:"public_key"`
+	KemAlgorithm string `json:"kem_algorithm"`
+	KemPublicKey string `json:"kem_public_key"`
+}
@@ -80,6 +80,12 @@ type SessionsWireGuardResponse struct {
 	IPAddress string `json:"ip_address,omitempty"`
 }
 
+// SessionWireGuardPskResponse Sessions WireGuard preshared key negotiation response
+type SessionWireGuardPskResponse struct {
+	APIErrorResponse
+	KemCiphertext string `json:"kem_ciphertext,omitempty"`
+}
+
 // SessionStatusResponse session status response
 type SessionStatusResponse struct {
 	APIErrorResponse
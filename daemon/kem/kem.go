@@ -0,0 +1,167 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package kem implements the hybrid key encapsulation mechanism (X25519 + ML-KEM-768)
+// which is in use to negotiate post-quantum WireGuard preshared keys.
+//
+// The shared secret is resistant to attacks by quantum computers as long as ML-KEM-768 is secure,
+// and remains at least as strong as classic X25519 key exchange otherwise.
+package kem
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/sha3"
+)
+
+// AlgorithmName - name of the hybrid KEM (as it is known by the API)
+const AlgorithmName = "X25519-ML-KEM-768"
+
+const (
+	x25519KeySize = curve25519.PointSize
+
+	mlkemPublicKeySize  = 1184
+	mlkemCiphertextSize = 1088
+
+	// PublicKeySize - size of the encapsulation (public) key: X25519 public key || ML-KEM-768 encapsulation key
+	PublicKeySize = x25519KeySize + mlkemPublicKeySize
+	// CiphertextSize - size of the ciphertext: ephemeral X25519 public key || ML-KEM-768 ciphertext
+	CiphertextSize = x25519KeySize + mlkemCiphertextSize
+	// SharedSecretSize - size of the shared secret (the same as the size of WireGuard preshared key)
+	SharedSecretSize = 32
+)
+
+// domain separation label of the shared secrets combiner
+var combinerLabel = []byte("ivpn-wg-psk-x25519-mlkem768")
+
+// IsSupported returns 'true' when the hybrid KEM is supported by the current build
+// (ML-KEM implementation requires Go 1.24 or later)
+func IsSupported() bool {
+	return mlkemSupported
+}
+
+// PrivateKey - decapsulation (private) key of the hybrid KEM
+type PrivateKey struct {
+	x25519Private [x25519KeySize]byte
+	x25519Public  [x25519KeySize]byte
+	mlkem         mlkemPrivateKey
+}
+
+// GenerateKey generates new decapsulation (private) key
+func GenerateKey() (*PrivateKey, error) {
+	if !IsSupported() {
+		return nil, errNotSupported()
+	}
+
+	k := &PrivateKey{}
+	if _, err := rand.Read(k.x25519Private[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	pub, err := curve25519.X25519(k.x25519Private[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	copy(k.x25519Public[:], pub)
+
+	if k.mlkem, err = mlkemGenerateKey(); err != nil {
+		return nil, fmt.Errorf("failed to generate ML-KEM key: %w", err)
+	}
+	return k, nil
+}
+
+// PublicKey returns the encapsulation (public) key which has to be sent to the peer
+func (k *PrivateKey) PublicKey() []byte {
+	ret := make([]byte, 0, PublicKeySize)
+	ret = append(ret, k.x25519Public[:]...)
+	return append(ret, mlkemPublicKey(k.mlkem)...)
+}
+
+// Decapsulate returns the shared secret from the ciphertext received from the peer
+func (k *PrivateKey) Decapsulate(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != CiphertextSize {
+		return nil, fmt.Errorf("bad ciphertext length")
+	}
+	ephemeralPublic := ciphertext[:x25519KeySize]
+
+	ssX25519, err := curve25519.X25519(k.x25519Private[:], ephemeralPublic)
+	if err != nil {
+		return nil, fmt.Errorf("X25519 failed: %w", err)
+	}
+	ssMlkem, err := mlkemDecapsulate(k.mlkem, ciphertext[x25519KeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("ML-KEM decapsulation failed: %w", err)
+	}
+	return combine(ssMlkem, ssX25519, ephemeralPublic, k.x25519Public[:]), nil
+}
+
+// Encapsulate generates the shared secret and the ciphertext for the peer's encapsulation (public) key
+// (the server side of the key exchange)
+func Encapsulate(publicKey []byte) (ciphertext, sharedSecret []byte, err error) {
+	if !IsSupported() {
+		return nil, nil, errNotSupported()
+	}
+	if len(publicKey) != PublicKeySize {
+		return nil, nil, fmt.Errorf("bad public key length")
+	}
+	peerX25519Public := publicKey[:x25519KeySize]
+
+	var ephemeralPrivate [x25519KeySize]byte
+	if _, err := rand.Read(ephemeralPrivate[:]); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	ephemeralPublic, err := curve25519.X25519(ephemeralPrivate[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	ssX25519, err := curve25519.X25519(ephemeralPrivate[:], peerX25519Public)
+	if err != nil {
+		return nil, nil, fmt.Errorf("X25519 failed: %w", err)
+	}
+
+	ssMlkem, ctMlkem, err := mlkemEncapsulate(publicKey[x25519KeySize:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("ML-KEM encapsulation failed: %w", err)
+	}
+
+	ciphertext = make([]byte, 0, CiphertextSize)
+	ciphertext = append(ciphertext, ephemeralPublic...)
+	ciphertext = append(ciphertext, ctMlkem...)
+	return ciphertext, combine(ssMlkem, ssX25519, ephemeralPublic, peerX25519Public), nil
+}
+
+// combine derives the final shared secret from the secrets of both KEMs
+// SHA3-256(label || ss_mlkem || ss_x25519 || ephemeral_x25519_public || recipient_x25519_public)
+func combine(ssMlkem, ssX25519, ephemeralPublic, recipientPublic []byte) []byte {
+	h := sha3.New256()
+	h.Write(combinerLabel)
+	h.Write(ssMlkem)
+	h.Write(ssX25519)
+	h.Write(ephemeralPublic)
+	h.Write(recipientPublic)
+	return h.Sum(nil)
+}
+
+func errNotSupported() error {
+	return fmt.Errorf("post-quantum key exchange is not supported by this build")
+}
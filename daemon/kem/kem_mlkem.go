@@ -0,0 +1,53 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build go1.24
+// +build go1.24
+
+package kem
+
+import "crypto/mlkem"
+
+const mlkemSupported = true
+
+type mlkemPrivateKey = *mlkem.DecapsulationKey768
+
+func mlkemGenerateKey() (mlkemPrivateKey, error) {
+	return mlkem.GenerateKey768()
+}
+
+func mlkemPublicKey(k mlkemPrivateKey) []byte {
+	return k.EncapsulationKey().Bytes()
+}
+
+func mlkemDecapsulate(k mlkemPrivateKey, ciphertext []byte) ([]byte, error) {
+	return k.Decapsulate(ciphertext)
+}
+
+func mlkemEncapsulate(publicKey []byte) (sharedSecret, ciphertext []byte, err error) {
+	ek, err := mlkem.NewEncapsulationKey768(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, ciphertext = ek.Encapsulate()
+	return sharedSecret, ciphertext, nil
+}
@@ -0,0 +1,100 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build go1.24
+// +build go1.24
+
+package kem_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/kem"
+)
+
+func TestKeyExchange(t *testing.T) {
+	priv, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+	if len(pub) != kem.PublicKeySize {
+		t.Fatalf("unexpected public key size: %d", len(pub))
+	}
+
+	ciphertext, ssServer, err := kem.Encapsulate(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertext) != kem.CiphertextSize {
+		t.Fatalf("unexpected ciphertext size: %d", len(ciphertext))
+	}
+
+	ssClient, err := priv.Decapsulate(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ssClient) != kem.SharedSecretSize || !bytes.Equal(ssClient, ssServer) {
+		t.Fatal("shared secrets do not match")
+	}
+
+	// each encapsulation produces new secret
+	_, ssServer2, err := kem.Encapsulate(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(ssServer, ssServer2) {
+		t.Fatal("shared secret reused")
+	}
+}
+
+func TestDecapsulateTampered(t *testing.T) {
+	priv, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, ss, err := kem.Encapsulate(priv.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ephemeral X25519 key modified
+	tampered := append([]byte{}, ciphertext...)
+	tampered[0] ^= 0xff
+	if ss2, err := priv.Decapsulate(tampered); err == nil && bytes.Equal(ss, ss2) {
+		t.Error("tampered X25519 part not detected")
+	}
+	// ML-KEM ciphertext modified (implicit rejection: different secret)
+	tampered = append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 0xff
+	if ss2, err := priv.Decapsulate(tampered); err == nil && bytes.Equal(ss, ss2) {
+		t.Error("tampered ML-KEM part not detected")
+	}
+
+	if _, err := priv.Decapsulate(ciphertext[:10]); err == nil {
+		t.Error("bad ciphertext length not detected")
+	}
+	if _, _, err := kem.Encapsulate(priv.PublicKey()[:10]); err == nil {
+		t.Error("bad public key length not detected")
+	}
+}
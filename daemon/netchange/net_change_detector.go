@@ -24,6 +24,7 @@ package netchange
 
 import (
 	"net"
+	"sync"
 	"time"
 
 	"github.com/ivpn/desktop-app/daemon/logger"
@@ -50,6 +51,10 @@ type Detector struct {
 	// Signaling when there were some routing changes but 'interfaceToProtect' is still is the default route
 	routingUpdateNotifyChan chan<- struct{}
 
+	// closed to stop the platform-specific detector routine (see doStart())
+	stopChan  chan struct{}
+	stopMutex sync.Mutex
+
 	// Must be implemented (AND USED) in correspond file for concrete platform. Must contain platform-specified properties (or can be empty struct)
 	props osSpecificProperties
 }
@@ -87,14 +92,28 @@ func (d *Detector) Start(routingChangeChan chan<- struct{}, routingUpdateChan ch
 	// save current default interface
 	d.interfaceToProtect = currentDefaultInterface
 
+	// the stop channel is created before the detector routine started: Stop() can be called at any moment after Start()
+	stopChan := make(chan struct{})
+	d.stopMutex.Lock()
+	d.stopChan = stopChan
+	d.stopMutex.Unlock()
+
 	// method should be implemented in platform-specific file
-	go d.doStart()
+	go d.doStart(stopChan)
 }
 
 // Stop - stop route change detector
 func (d *Detector) Stop() {
 	// stop timer
 	d.timerNotifyAfterDelay.Stop()
+
+	d.stopMutex.Lock()
+	if d.stopChan != nil {
+		close(d.stopChan)
+		d.stopChan = nil
+	}
+	d.stopMutex.Unlock()
+
 	// method should be implemented in platform-specific file
 	d.doStop()
 }
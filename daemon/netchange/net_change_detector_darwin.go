@@ -52,7 +52,7 @@ func (d *Detector) isRoutingChanged() (bool, error) {
 	return !isDefaultRoute, nil
 }
 
-func (d *Detector) doStart() {
+func (d *Detector) doStart(stopChan <-chan struct{}) {
 	sock, err := syscall.Socket(syscall.AF_ROUTE, syscall.SOCK_RAW, syscall.AF_UNSPEC)
 	if err != nil {
 		log.Error("Failed to start route change detector:", err)
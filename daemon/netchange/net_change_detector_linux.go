@@ -22,18 +22,85 @@
 
 package netchange
 
-// structure contains properties required for for macOS implementation
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlink socket read timeout (closing the socket does not interrupt blocking read on Linux)
+const socketReadTimeout = time.Second
+
+// structure contains properties required for for Linux implementation
 type osSpecificProperties struct {
 }
 
 func (d *Detector) isRoutingChanged() (bool, error) {
 	// TODO: not implemented
+	// (all detected changes are reported as routing updates)
 	return false, nil
 }
 
-func (d *Detector) doStart() {
-	// TODO: not implemented
+// doStart listens for the route changes (netlink)
+// On Linux the detected changes are reported only as routing updates (see isRoutingChanged()): they never trigger reconnection.
+// The only consumer of them on Linux is the split-tunnel destinations (bypass routes must follow the default gateway);
+// the VPN objects ignore routing updates on this platform.
+func (d *Detector) doStart(stopChan <-chan struct{}) {
+	sock, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return
+	}
+	defer unix.Close(sock)
+
+	addr := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE}
+	if err := unix.Bind(sock, addr); err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return
+	}
+	tv := unix.NsecToTimeval(socketReadTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(sock, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		log.Error("Failed to start route change detector:", err)
+		return
+	}
+
+	log.Info("Route change detector started")
+	defer log.Info("Route change detector stopped")
+
+	// Loop waiting for messages.
+	b := make([]byte, os.Getpagesize())
+	for {
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+
+		nr, _, err := unix.Recvfrom(sock, b, 0)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EINTR {
+				continue
+			}
+			log.Error("Route change detector (error on socket read):", err)
+			return
+		}
+
+		messages, err := syscall.ParseNetlinkMessage(b[:nr])
+		if err != nil {
+			continue
+		}
+
+		for _, msg := range messages {
+			switch msg.Header.Type {
+			case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+				d.routingChangeDetected()
+			}
+		}
+	}
 }
 
 func (d *Detector) doStop() {
+	// the routine is stopped by closing 'stopChan' (see Stop())
 }
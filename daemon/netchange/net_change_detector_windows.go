@@ -76,7 +76,7 @@ func (d *Detector) isRoutingChanged() (bool, error) {
 	return false, nil
 }
 
-func (d *Detector) doStart() {
+func (d *Detector) doStart(stopChan <-chan struct{}) {
 
 	log.Info("Route change detector started")
 	defer func() {
@@ -0,0 +1,199 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// nativeEndian - byte order of the netlink messages (host byte order)
+var nativeEndian binary.ByteOrder
+
+func init() {
+	v := uint16(1)
+	if *(*byte)(unsafe.Pointer(&v)) == 1 {
+		nativeEndian = binary.LittleEndian
+	} else {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// conn is a netlink socket used to send requests to the kernel
+type conn struct {
+	fd  int
+	seq uint32
+}
+
+func dial(netlinkFamily int) (*conn, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, netlinkFamily)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket initialization error: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("netlink socket binding error: %w", err)
+	}
+	return &conn{fd: fd}, nil
+}
+
+func (c *conn) close() {
+	unix.Close(c.fd)
+}
+
+// request sends netlink message and returns all response messages
+// (until NLMSG_DONE, ACK or the single non-multipart message received)
+func (c *conn) request(msgType uint16, flags uint16, data []byte) ([]syscall.NetlinkMessage, error) {
+	c.seq++
+
+	b := make([]byte, unix.NLMSG_HDRLEN, unix.NLMSG_HDRLEN+len(data))
+	nativeEndian.PutUint32(b[0:4], uint32(unix.NLMSG_HDRLEN+len(data)))
+	nativeEndian.PutUint16(b[4:6], msgType)
+	nativeEndian.PutUint16(b[6:8], flags|unix.NLM_F_REQUEST)
+	nativeEndian.PutUint32(b[8:12], c.seq)
+	b = append(b, data...)
+
+	if err := unix.Sendto(c.fd, b, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send error: %w", err)
+	}
+
+	var ret []syscall.NetlinkMessage
+	rb := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(c.fd, rb, 0)
+		if err != nil {
+			return nil, fmt.Errorf("netlink receive error: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(rb[:n])
+		if err != nil {
+			return nil, fmt.Errorf("netlink parse error: %w", err)
+		}
+
+		for _, m := range msgs {
+			if m.Header.Seq != c.seq {
+				continue
+			}
+			switch m.Header.Type {
+			case unix.NLMSG_DONE:
+				return ret, nil
+			case unix.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return nil, fmt.Errorf("netlink: bad error message")
+				}
+				if errno := int32(nativeEndian.Uint32(m.Data[0:4])); errno != 0 {
+					return nil, syscall.Errno(-errno)
+				}
+				return ret, nil // ACK
+			}
+			ret = append(ret, m)
+			if m.Header.Flags&unix.NLM_F_MULTI == 0 && flags&unix.NLM_F_ACK == 0 {
+				return ret, nil
+			}
+		}
+	}
+}
+
+// execute opens netlink socket, sends the request and closes the socket
+func execute(netlinkFamily int, msgType uint16, flags uint16, data []byte) ([]syscall.NetlinkMessage, error) {
+	c, err := dial(netlinkFamily)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+	return c.request(msgType, flags, data)
+}
+
+// ----------------------------------------------------------------------
+// Netlink attributes
+
+type attr struct {
+	typ  uint16
+	data []byte
+}
+
+func attrBytes(typ uint16, data []byte) attr { return attr{typ: typ, data: data} }
+func attrString(typ uint16, v string) attr   { return attr{typ: typ, data: append([]byte(v), 0)} }
+func attrU8(typ uint16, v uint8) attr        { return attr{typ: typ, data: []byte{v}} }
+
+func attrU16(typ uint16, v uint16) attr {
+	b := make([]byte, 2)
+	nativeEndian.PutUint16(b, v)
+	return attr{typ: typ, data: b}
+}
+
+func attrU32(typ uint16, v uint32) attr {
+	b := make([]byte, 4)
+	nativeEndian.PutUint32(b, v)
+	return attr{typ: typ, data: b}
+}
+
+func attrNested(typ uint16, children ...attr) attr {
+	return attr{typ: typ | unix.NLA_F_NESTED, data: encodeAttrs(children...)}
+}
+
+func nlaAlign(l int) int {
+	return (l + unix.NLA_ALIGNTO - 1) & ^(unix.NLA_ALIGNTO - 1)
+}
+
+func encodeAttrs(attrs ...attr) []byte {
+	var ret []byte
+	for _, a := range attrs {
+		l := unix.SizeofNlAttr + len(a.data)
+		b := make([]byte, nlaAlign(l))
+		nativeEndian.PutUint16(b[0:2], uint16(l))
+		nativeEndian.PutUint16(b[2:4], a.typ)
+		copy(b[unix.SizeofNlAttr:], a.data)
+		ret = append(ret, b...)
+	}
+	return ret
+}
+
+func parseAttrs(b []byte) ([]attr, error) {
+	var ret []attr
+	for len(b) >= unix.SizeofNlAttr {
+		l := int(nativeEndian.Uint16(b[0:2]))
+		typ := nativeEndian.Uint16(b[2:4]) & ^uint16(unix.NLA_F_NESTED|unix.NLA_F_NET_BYTEORDER)
+		if l < unix.SizeofNlAttr || l > len(b) {
+			return nil, fmt.Errorf("netlink: bad attribute length")
+		}
+		ret = append(ret, attr{typ: typ, data: b[unix.SizeofNlAttr:l]})
+		if nlaAlign(l) >= len(b) {
+			break
+		}
+		b = b[nlaAlign(l):]
+	}
+	return ret, nil
+}
+
+func structToBytes(p unsafe.Pointer, size int) []byte {
+	b := make([]byte, size)
+	copy(b, unsafe.Slice((*byte)(p), size))
+	return b
+}
@@ -0,0 +1,196 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"fmt"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Routing policy rule attributes (linux/fib_rules.h)
+const (
+	fraPriority          = 6
+	fraFwmark            = 10
+	fraSuppressPrefixlen = 14
+	fraTable             = 15
+
+	frActToTbl    = 1
+	fibRuleInvert = 0x2
+)
+
+// LinkAdd creates new network interface of specified kind (e.g. "wireguard")
+func LinkAdd(name string, kind string) error {
+	msg := unix.IfInfomsg{Family: unix.AF_UNSPEC}
+	data := structToBytes(unsafe.Pointer(&msg), unix.SizeofIfInfomsg)
+	data = append(data, encodeAttrs(
+		attrString(unix.IFLA_IFNAME, name),
+		attrNested(unix.IFLA_LINKINFO, attrString(unix.IFLA_INFO_KIND, kind)))...)
+
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK, unix.NLM_F_CREATE|unix.NLM_F_EXCL|unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to create interface '%s' (%s): %w", name, kind, err)
+	}
+	return nil
+}
+
+// LinkDel removes network interface
+func LinkDel(name string) error {
+	msg := unix.IfInfomsg{Family: unix.AF_UNSPEC}
+	data := structToBytes(unsafe.Pointer(&msg), unix.SizeofIfInfomsg)
+	data = append(data, encodeAttrs(attrString(unix.IFLA_IFNAME, name))...)
+
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_DELLINK, unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to remove interface '%s': %w", name, err)
+	}
+	return nil
+}
+
+// LinkSetUp brings network interface up and sets MTU (if mtu > 0)
+func LinkSetUp(ifIndex int, mtu int) error {
+	msg := unix.IfInfomsg{Family: unix.AF_UNSPEC, Index: int32(ifIndex), Flags: unix.IFF_UP, Change: unix.IFF_UP}
+	data := structToBytes(unsafe.Pointer(&msg), unix.SizeofIfInfomsg)
+	if mtu > 0 {
+		data = append(data, encodeAttrs(attrU32(unix.IFLA_MTU, uint32(mtu)))...)
+	}
+
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK, unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to set interface up (index %d): %w", ifIndex, err)
+	}
+	return nil
+}
+
+// AddrAdd assigns IP address to network interface
+func AddrAdd(ifIndex int, addr net.IPNet) error {
+	family, ip := ipFamily(addr.IP)
+	prefixLen, _ := addr.Mask.Size()
+
+	msg := unix.IfAddrmsg{Family: family, Prefixlen: uint8(prefixLen), Index: uint32(ifIndex)}
+	data := structToBytes(unsafe.Pointer(&msg), unix.SizeofIfAddrmsg)
+	data = append(data, encodeAttrs(
+		attrBytes(unix.IFA_LOCAL, ip),
+		attrBytes(unix.IFA_ADDRESS, ip))...)
+
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_NEWADDR, unix.NLM_F_CREATE|unix.NLM_F_EXCL|unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to add address %s: %w", addr.String(), err)
+	}
+	return nil
+}
+
+// RouteAdd adds route to the destination through network interface
+// (table = 0 means the 'main' routing table)
+func RouteAdd(ifIndex int, dst net.IPNet, table uint32) error {
+	family, ip := ipFamily(dst.IP)
+	dstLen, _ := dst.Mask.Size()
+	if table == 0 {
+		table = unix.RT_TABLE_MAIN
+	}
+
+	msg := unix.RtMsg{
+		Family:   family,
+		Dst_len:  uint8(dstLen),
+		Table:    unix.RT_TABLE_UNSPEC,
+		Protocol: unix.RTPROT_BOOT,
+		Scope:    unix.RT_SCOPE_LINK,
+		Type:     unix.RTN_UNICAST}
+	data := structToBytes(unsafe.Pointer(&msg), unix.SizeofRtMsg)
+	attrs := []attr{attrU32(unix.RTA_OIF, uint32(ifIndex)), attrU32(unix.RTA_TABLE, table)}
+	if dstLen > 0 {
+		attrs = append(attrs, attrBytes(unix.RTA_DST, ip.Mask(dst.Mask)))
+	}
+	data = append(data, encodeAttrs(attrs...)...)
+
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_NEWROUTE, unix.NLM_F_CREATE|unix.NLM_F_REPLACE|unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to add route %s (table %d): %w", dst.String(), table, err)
+	}
+	return nil
+}
+
+// Rule is a routing policy rule
+// Examples:
+//
+//	'not from all fwmark 0xca6c lookup 51820'		- Rule{IsIPv6: false, Table: 51820, FwMark: 0xca6c, Invert: true, SuppressPrefixlen: -1}
+//	'from all lookup main suppress_prefixlength 0'	- Rule{IsIPv6: false, Table: unix.RT_TABLE_MAIN, SuppressPrefixlen: 0}
+type Rule struct {
+	IsIPv6            bool
+	Table             uint32
+	FwMark            uint32 // 0 - not defined
+	Invert            bool
+	SuppressPrefixlen int    // -1 - not defined
+	Priority          uint32 // 0 - not defined
+}
+
+// RuleAdd adds routing policy rule
+func RuleAdd(r Rule) error {
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_NEWRULE, unix.NLM_F_CREATE|unix.NLM_F_EXCL|unix.NLM_F_ACK, r.toBytes()); err != nil {
+		return fmt.Errorf("failed to add routing rule (table %d): %w", r.Table, err)
+	}
+	return nil
+}
+
+// RuleDel removes routing policy rule
+func RuleDel(r Rule) error {
+	if _, err := execute(unix.NETLINK_ROUTE, unix.RTM_DELRULE, unix.NLM_F_ACK, r.toBytes()); err != nil {
+		return fmt.Errorf("failed to remove routing rule (table %d): %w", r.Table, err)
+	}
+	return nil
+}
+
+func (r Rule) toBytes() []byte {
+	// struct fib_rule_hdr
+	hdr := make([]byte, 12)
+	hdr[0] = unix.AF_INET
+	if r.IsIPv6 {
+		hdr[0] = unix.AF_INET6
+	}
+	if r.Table < 256 {
+		hdr[4] = uint8(r.Table)
+	}
+	hdr[7] = frActToTbl
+	if r.Invert {
+		nativeEndian.PutUint32(hdr[8:12], fibRuleInvert)
+	}
+
+	attrs := []attr{attrU32(fraTable, r.Table)}
+	if r.FwMark != 0 {
+		attrs = append(attrs, attrU32(fraFwmark, r.FwMark))
+	}
+	if r.SuppressPrefixlen >= 0 {
+		attrs = append(attrs, attrU32(fraSuppressPrefixlen, uint32(r.SuppressPrefixlen)))
+	}
+	if r.Priority > 0 {
+		attrs = append(attrs, attrU32(fraPriority, r.Priority))
+	}
+	return append(hdr, encodeAttrs(attrs...)...)
+}
+
+func ipFamily(ip net.IP) (family uint8, ipBytes net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return unix.AF_INET, ip4
+	}
+	return unix.AF_INET6, ip.To16()
+}
@@ -0,0 +1,309 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const wgKeyLen = 32
+
+// WgPeerConfig - configuration of WireGuard peer
+type WgPeerConfig struct {
+	PublicKey           []byte
+	PresharedKey        []byte // nil - not defined
+	Endpoint            *net.UDPAddr
+	PersistentKeepalive time.Duration
+	AllowedIPs          []net.IPNet
+}
+
+// WgDeviceConfig - configuration of WireGuard interface
+type WgDeviceConfig struct {
+	PrivateKey []byte
+	ListenPort int
+	FwMark     uint32
+	Peers      []WgPeerConfig // peers configuration replaces all existing peers
+}
+
+// WgPeerInfo - information about WireGuard peer
+type WgPeerInfo struct {
+	PublicKey     []byte
+	Endpoint      *net.UDPAddr
+	LastHandshake time.Time // zero value - no handshake yet
+	RxBytes       uint64
+	TxBytes       uint64
+}
+
+// WgDeviceInfo - information about WireGuard interface
+type WgDeviceInfo struct {
+	ListenPort int
+	FwMark     uint32
+	Peers      []WgPeerInfo
+}
+
+// WgSetDevice configures WireGuard interface (analogue of 'wg setconf')
+func WgSetDevice(ifName string, cfg WgDeviceConfig) error {
+	if len(cfg.PrivateKey) != wgKeyLen {
+		return fmt.Errorf("bad WireGuard private key length")
+	}
+
+	peers := make([]attr, 0, len(cfg.Peers))
+	for i, p := range cfg.Peers {
+		if len(p.PublicKey) != wgKeyLen {
+			return fmt.Errorf("bad WireGuard peer public key length")
+		}
+		peerAttrs := []attr{
+			attrBytes(unix.WGPEER_A_PUBLIC_KEY, p.PublicKey),
+			attrU32(unix.WGPEER_A_FLAGS, unix.WGPEER_F_REPLACE_ALLOWEDIPS),
+			attrU16(unix.WGPEER_A_PERSISTENT_KEEPALIVE_INTERVAL, uint16(p.PersistentKeepalive/time.Second)),
+		}
+		if p.PresharedKey != nil {
+			if len(p.PresharedKey) != wgKeyLen {
+				return fmt.Errorf("bad WireGuard preshared key length")
+			}
+			peerAttrs = append(peerAttrs, attrBytes(unix.WGPEER_A_PRESHARED_KEY, p.PresharedKey))
+		}
+		if p.Endpoint != nil {
+			peerAttrs = append(peerAttrs, attrBytes(unix.WGPEER_A_ENDPOINT, encodeSockaddr(p.Endpoint)))
+		}
+
+		allowedIPs := make([]attr, 0, len(p.AllowedIPs))
+		for j, n := range p.AllowedIPs {
+			family, ip := ipFamily(n.IP)
+			ones, _ := n.Mask.Size()
+			allowedIPs = append(allowedIPs, attrNested(uint16(j),
+				attrU16(unix.WGALLOWEDIP_A_FAMILY, uint16(family)),
+				attrBytes(unix.WGALLOWEDIP_A_IPADDR, ip),
+				attrU8(unix.WGALLOWEDIP_A_CIDR_MASK, uint8(ones))))
+		}
+		peerAttrs = append(peerAttrs, attrNested(unix.WGPEER_A_ALLOWEDIPS, allowedIPs...))
+
+		peers = append(peers, attrNested(uint16(i), peerAttrs...))
+	}
+
+	data := encodeAttrs(
+		attrString(unix.WGDEVICE_A_IFNAME, ifName),
+		attrU32(unix.WGDEVICE_A_FLAGS, unix.WGDEVICE_F_REPLACE_PEERS),
+		attrBytes(unix.WGDEVICE_A_PRIVATE_KEY, cfg.PrivateKey),
+		attrU16(unix.WGDEVICE_A_LISTEN_PORT, uint16(cfg.ListenPort)),
+		attrU32(unix.WGDEVICE_A_FWMARK, cfg.FwMark),
+		attrNested(unix.WGDEVICE_A_PEERS, peers...))
+
+	if _, err := genlRequest(unix.WG_GENL_NAME, unix.WG_CMD_SET_DEVICE, unix.WG_GENL_VERSION, unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to configure WireGuard interface '%s': %w", ifName, err)
+	}
+	return nil
+}
+
+// WgSetPeerPresharedKey updates the preshared key of existing peer (analogue of 'wg set <if> peer <key> preshared-key <file>')
+// The rest of the interface configuration is not modified (the active sessions are not affected)
+func WgSetPeerPresharedKey(ifName string, peerPublicKey []byte, presharedKey []byte) error {
+	if len(peerPublicKey) != wgKeyLen {
+		return fmt.Errorf("bad WireGuard peer public key length")
+	}
+	if len(presharedKey) != wgKeyLen {
+		return fmt.Errorf("bad WireGuard preshared key length")
+	}
+
+	data := encodeAttrs(
+		attrString(unix.WGDEVICE_A_IFNAME, ifName),
+		attrNested(unix.WGDEVICE_A_PEERS, attrNested(0,
+			attrBytes(unix.WGPEER_A_PUBLIC_KEY, peerPublicKey),
+			attrU32(unix.WGPEER_A_FLAGS, unix.WGPEER_F_UPDATE_ONLY),
+			attrBytes(unix.WGPEER_A_PRESHARED_KEY, presharedKey))))
+
+	if _, err := genlRequest(unix.WG_GENL_NAME, unix.WG_CMD_SET_DEVICE, unix.WG_GENL_VERSION, unix.NLM_F_ACK, data); err != nil {
+		return fmt.Errorf("failed to update preshared key of WireGuard interface '%s': %w", ifName, err)
+	}
+	return nil
+}
+
+// WgGetDevice returns information about WireGuard interface (analogue of 'wg show')
+func WgGetDevice(ifName string) (WgDeviceInfo, error) {
+	var ret WgDeviceInfo
+
+	data := encodeAttrs(attrString(unix.WGDEVICE_A_IFNAME, ifName))
+	msgs, err := genlRequest(unix.WG_GENL_NAME, unix.WG_CMD_GET_DEVICE, unix.WG_GENL_VERSION, unix.NLM_F_DUMP, data)
+	if err != nil {
+		return ret, fmt.Errorf("failed to get WireGuard interface '%s' info: %w", ifName, err)
+	}
+
+	// large configuration can be split into multiple messages
+	for _, m := range msgs {
+		if len(m.Data) < unix.GENL_HDRLEN {
+			continue
+		}
+		attrs, err := parseAttrs(m.Data[unix.GENL_HDRLEN:])
+		if err != nil {
+			return ret, err
+		}
+		for _, a := range attrs {
+			switch a.typ {
+			case unix.WGDEVICE_A_LISTEN_PORT:
+				if len(a.data) >= 2 {
+					ret.ListenPort = int(nativeEndian.Uint16(a.data))
+				}
+			case unix.WGDEVICE_A_FWMARK:
+				if len(a.data) >= 4 {
+					ret.FwMark = nativeEndian.Uint32(a.data)
+				}
+			case unix.WGDEVICE_A_PEERS:
+				peers, err := parseAttrs(a.data)
+				if err != nil {
+					return ret, err
+				}
+				for _, p := range peers {
+					peer, err := parseWgPeer(p.data)
+					if err != nil {
+						return ret, err
+					}
+					ret.Peers = append(ret.Peers, peer)
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+func parseWgPeer(b []byte) (WgPeerInfo, error) {
+	var ret WgPeerInfo
+	attrs, err := parseAttrs(b)
+	if err != nil {
+		return ret, err
+	}
+	for _, a := range attrs {
+		switch a.typ {
+		case unix.WGPEER_A_PUBLIC_KEY:
+			ret.PublicKey = append([]byte{}, a.data...)
+		case unix.WGPEER_A_ENDPOINT:
+			ret.Endpoint = decodeSockaddr(a.data)
+		case unix.WGPEER_A_LAST_HANDSHAKE_TIME:
+			// struct __kernel_timespec { s64 tv_sec; s64 tv_nsec; }
+			if len(a.data) >= 16 {
+				sec := int64(nativeEndian.Uint64(a.data[0:8]))
+				nsec := int64(nativeEndian.Uint64(a.data[8:16]))
+				if sec > 0 || nsec > 0 {
+					ret.LastHandshake = time.Unix(sec, nsec)
+				}
+			}
+		case unix.WGPEER_A_RX_BYTES:
+			if len(a.data) >= 8 {
+				ret.RxBytes = nativeEndian.Uint64(a.data)
+			}
+		case unix.WGPEER_A_TX_BYTES:
+			if len(a.data) >= 8 {
+				ret.TxBytes = nativeEndian.Uint64(a.data)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// encodeSockaddr returns 'struct sockaddr_in' or 'struct sockaddr_in6'
+func encodeSockaddr(addr *net.UDPAddr) []byte {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b := make([]byte, unix.SizeofSockaddrInet4)
+		nativeEndian.PutUint16(b[0:2], unix.AF_INET)
+		binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+		copy(b[4:8], ip4)
+		return b
+	}
+	b := make([]byte, unix.SizeofSockaddrInet6)
+	nativeEndian.PutUint16(b[0:2], unix.AF_INET6)
+	binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+	copy(b[8:24], addr.IP.To16())
+	return b
+}
+
+func decodeSockaddr(b []byte) *net.UDPAddr {
+	if len(b) < 4 {
+		return nil
+	}
+	port := int(binary.BigEndian.Uint16(b[2:4]))
+	switch nativeEndian.Uint16(b[0:2]) {
+	case unix.AF_INET:
+		if len(b) >= 8 {
+			return &net.UDPAddr{IP: net.IP(append([]byte{}, b[4:8]...)), Port: port}
+		}
+	case unix.AF_INET6:
+		if len(b) >= 24 {
+			return &net.UDPAddr{IP: net.IP(append([]byte{}, b[8:24]...)), Port: port}
+		}
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------
+// Generic netlink
+
+// genlFamilyID resolves generic netlink family ID by name
+// (returns error when the family is not registered; e.g. kernel module not loaded)
+func genlFamilyID(c *conn, familyName string) (uint16, error) {
+	data := []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}
+	data = append(data, encodeAttrs(attrString(unix.CTRL_ATTR_FAMILY_NAME, familyName))...)
+
+	msgs, err := c.request(unix.GENL_ID_CTRL, 0, data)
+	if err != nil {
+		return 0, fmt.Errorf("generic netlink family '%s' not available: %w", familyName, err)
+	}
+	for _, m := range msgs {
+		if len(m.Data) < unix.GENL_HDRLEN {
+			continue
+		}
+		attrs, err := parseAttrs(m.Data[unix.GENL_HDRLEN:])
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range attrs {
+			if a.typ == unix.CTRL_ATTR_FAMILY_ID && len(a.data) >= 2 {
+				return nativeEndian.Uint16(a.data), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("generic netlink family '%s' not found", familyName)
+}
+
+func genlRequest(familyName string, cmd uint8, version uint8, flags uint16, attrsData []byte) ([]syscall.NetlinkMessage, error) {
+	c, err := dial(unix.NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	familyID, err := genlFamilyID(c, familyName)
+	if err != nil {
+		return nil, err
+	}
+
+	data := append([]byte{cmd, version, 0, 0}, attrsData...)
+	return c.request(familyID, flags, data)
+}
@@ -0,0 +1,53 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package probe checks the reachability of VPN servers on the VPN protocol level
+// (useful in the networks where ICMP is blocked but VPN ports are accessible)
+package probe
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// Mode - the way the host reachability was checked
+type Mode string
+
+const (
+	ModeICMP       Mode = "icmp"         // ICMP echo request
+	ModeWireGuard  Mode = "wg-handshake" // WireGuard handshake initiation (UDP)
+	ModeOpenVpnUDP Mode = "ovpn-udp"     // OpenVPN P_CONTROL_HARD_RESET_CLIENT_V2 (UDP)
+	ModeOpenVpnTCP Mode = "ovpn-tcp"     // TCP connection to the OpenVPN port
+)
+
+// TCPConnect checks the reachability of the TCP port. Returns the time of the connection establishment.
+func TCPConnect(host net.IP, port int, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host.String(), strconv.Itoa(port)), timeout)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return rtt, nil
+}
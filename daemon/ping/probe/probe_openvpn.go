@@ -0,0 +1,147 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package probe
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	opControlHardResetClientV2 = 7
+	opControlHardResetServerV2 = 8
+
+	staticKeySize  = 256 // OpenVPN static key (2048 bits)
+	hmacSHA1Size   = 20
+	sessionIDSize  = 8
+	maxReplyLength = 1500
+)
+
+// ParseStaticKey parses OpenVPN static key file content (in use for 'tls-auth')
+func ParseStaticKey(data []byte) ([]byte, error) {
+	const (
+		beginMarker = "-----BEGIN OpenVPN Static key V1-----"
+		endMarker   = "-----END OpenVPN Static key V1-----"
+	)
+	text := string(data)
+	begin := strings.Index(text, beginMarker)
+	end := strings.Index(text, endMarker)
+	if begin < 0 || end < begin {
+		return nil, fmt.Errorf("OpenVPN static key not found")
+	}
+	hexKey := strings.Join(strings.Fields(text[begin+len(beginMarker):end]), "")
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("bad OpenVPN static key: %w", err)
+	}
+	if len(key) != staticKeySize {
+		return nil, fmt.Errorf("bad OpenVPN static key size (%d bytes)", len(key))
+	}
+	return key, nil
+}
+
+// tlsAuthHmacKey returns the HMAC key for outgoing packets
+// The static key consists of 4 parts (64 bytes each): [cipher dir0][HMAC dir0][cipher dir1][HMAC dir1].
+// The client uses 'key-direction 1': outgoing packets are signed by 'HMAC dir1' key.
+func tlsAuthHmacKey(staticKey []byte, keyDirection int) []byte {
+	offset := 64
+	if keyDirection == 1 {
+		offset = 192
+	}
+	return staticKey[offset : offset+hmacSHA1Size]
+}
+
+// createHardResetClientV2 creates P_CONTROL_HARD_RESET_CLIENT_V2 packet signed by 'tls-auth' HMAC (SHA1)
+//
+//	packet: [opcode|key_id][session_id][HMAC][packet_id][net_time][ack_array_len = 0][message_packet_id = 0]
+//	HMAC is calculated over: [packet_id][net_time][opcode|key_id][session_id][ack_array_len][message_packet_id]
+func createHardResetClientV2(hmacKey []byte, sessionID []byte, now time.Time) []byte {
+	var replay [8]byte
+	binary.BigEndian.PutUint32(replay[0:4], 1) // packet_id
+	binary.BigEndian.PutUint32(replay[4:8], uint32(now.Unix()))
+	opcode := byte(opControlHardResetClientV2 << 3)
+	payload := []byte{0, 0, 0, 0, 0} // no ACKs; message packet_id 0
+
+	mac := hmac.New(sha1.New, hmacKey)
+	mac.Write(replay[:])
+	mac.Write([]byte{opcode})
+	mac.Write(sessionID)
+	mac.Write(payload)
+
+	var packet bytes.Buffer
+	packet.WriteByte(opcode)
+	packet.Write(sessionID)
+	packet.Write(mac.Sum(nil))
+	packet.Write(replay[:])
+	packet.Write(payload)
+	return packet.Bytes()
+}
+
+// OpenVpnHardReset checks the reachability of the OpenVPN server on the UDP port:
+// sends P_CONTROL_HARD_RESET_CLIENT_V2 and waits for P_CONTROL_HARD_RESET_SERVER_V2.
+// Returns the round-trip time.
+// 'staticKey' - the 'tls-auth' key (see ParseStaticKey()); the server ignores the packets with wrong signature.
+func OpenVpnHardReset(host net.IP, port int, staticKey []byte, keyDirection int, timeout time.Duration) (time.Duration, error) {
+	if len(staticKey) != staticKeySize {
+		return 0, fmt.Errorf("OpenVPN static key not defined")
+	}
+
+	sessionID := make([]byte, sessionIDSize)
+	if _, err := rand.Read(sessionID); err != nil {
+		return 0, err
+	}
+	packet := createHardResetClientV2(tlsAuthHmacKey(staticKey, keyDirection), sessionID, time.Now())
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: host, Port: port})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if _, err := conn.Write(packet); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, maxReplyLength)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, fmt.Errorf("no OpenVPN reply: %w", err)
+		}
+		if n > 1+sessionIDSize && buf[0]>>3 == opControlHardResetServerV2 {
+			return time.Since(start), nil
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package probe
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStaticKey() (fileContent []byte, key []byte) {
+	key = make([]byte, staticKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	hexKey := hex.EncodeToString(key)
+	var lines []string
+	for i := 0; i < len(hexKey); i += 32 {
+		lines = append(lines, hexKey[i:i+32])
+	}
+	content := "#\n# 2048 bit OpenVPN static key\n#\n-----BEGIN OpenVPN Static key V1-----\n" +
+		strings.Join(lines, "\n") + "\n-----END OpenVPN Static key V1-----\n"
+	return []byte(content), key
+}
+
+func TestParseStaticKey(t *testing.T) {
+	content, expected := testStaticKey()
+	key, err := ParseStaticKey(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key) != hex.EncodeToString(expected) {
+		t.Fatal("unexpected key")
+	}
+	if _, err := ParseStaticKey([]byte("garbage")); err == nil {
+		t.Fatal("error expected for bad key file")
+	}
+}
+
+func TestOpenVpnHardReset(t *testing.T) {
+	_, key := testStaticKey()
+
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	// server: verify the HMAC ('key-direction 0': incoming packets are verified by 'HMAC dir1' key) and reply
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := server.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		p := buf[:n]
+		hmacOffset := 1 + sessionIDSize
+		mac := hmac.New(sha1.New, key[192:192+hmacSHA1Size])
+		mac.Write(p[hmacOffset+hmacSHA1Size : hmacOffset+hmacSHA1Size+8]) // packet_id + net_time
+		mac.Write(p[:hmacOffset])                                         // opcode + session_id
+		mac.Write(p[hmacOffset+hmacSHA1Size+8:])                          // the rest
+		if p[0]>>3 != opControlHardResetClientV2 || !hmac.Equal(mac.Sum(nil), p[hmacOffset:hmacOffset+hmacSHA1Size]) {
+			return // ignore (as OpenVPN server does)
+		}
+		reply := append([]byte{opControlHardResetServerV2 << 3}, make([]byte, 40)...)
+		server.WriteToUDP(reply, addr)
+	}()
+
+	addr := server.LocalAddr().(*net.UDPAddr)
+	if _, err := OpenVpnHardReset(addr.IP, addr.Port, key, 1, time.Second*5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTCPConnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	// the port is closed now
+	if _, err := TCPConnect(addr.IP, addr.Port, time.Second); err == nil {
+		t.Fatal("error expected for closed port")
+	}
+
+	l, err = net.Listen("tcp", addr.String())
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	if _, err := TCPConnect(addr.IP, addr.Port, time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,55 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package probe
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard/userspace"
+)
+
+// WireGuardHandshake checks the reachability of the WireGuard server: sends the handshake initiation and waits for the reply.
+// Returns the round-trip time.
+// The keys are base64-encoded. The server replies only when the client public key is registered on the server.
+func WireGuardHandshake(host net.IP, port int, serverPublicKey string, clientPrivateKey string, timeout time.Duration) (time.Duration, error) {
+	decodeKey := func(k string) (userspace.Key, error) {
+		b, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return userspace.Key{}, fmt.Errorf("WG key is not base64 string")
+		}
+		return userspace.KeyFromBytes(b)
+	}
+
+	remotePublic, err := decodeKey(serverPublicKey)
+	if err != nil {
+		return 0, err
+	}
+	localPrivate, err := decodeKey(clientPrivateKey)
+	if err != nil {
+		return 0, err
+	}
+	return userspace.ProbeHandshake(&net.UDPAddr{IP: host, Port: port}, localPrivate, remotePublic, timeout)
+}
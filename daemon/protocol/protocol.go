@@ -41,8 +41,11 @@ import (
 	"github.com/ivpn/desktop-app/daemon/protocol/eaa"
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
+	"github.com/ivpn/desktop-app/daemon/service/wgkeys"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
 	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
@@ -78,7 +81,11 @@ type Service interface {
 	// The cached data will be ignored in this case.
 	ServersListForceUpdate() (*apitypes.ServersInfoResponse, error)
 
-	PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAllHostsOnFirstPhase bool, skipSecondPhase bool) (map[string]int, error)
+	PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAllHostsOnFirstPhase bool, skipSecondPhase bool, probeStrategy string) (map[string]types.PingResultType, error)
+	PingHistory(host string, since time.Time) []pinghistory.Sample
+	OfflineStatus() types.OfflineStatus
+	QueryServers(query string) ([]serversquery.Host, error)
+	CurrentLocation() (location *serversquery.Location, source string)
 
 	APIRequest(apiAlias string, ipTypeRequired types.RequiredIPProtocol) (responseData []byte, err error)
 
@@ -91,7 +98,9 @@ type Service interface {
 	SetKillSwitchUserExceptions(exceptions string, ignoreParsingErrors bool) error
 
 	SplitTunnelling_SetConfig(isEnabled bool, reset bool) error
+	SplitTunnelling_SetDestinations(bypassDests []string, vpnDests []string) error
 	SplitTunnelling_GetStatus() (types.SplitTunnelStatus, error)
+	SplitTunnelling_GetLiveStatus() (types.SplitTunnelLiveStatus, error)
 	SplitTunnelling_AddApp(exec string) (cmdToExecute string, isAlreadyRunning bool, err error)
 	SplitTunnelling_RemoveApp(pid int, exec string) (err error)
 	SplitTunnelling_AddedPidInfo(pid int, exec string, cmdToExecute string) error
@@ -103,15 +112,24 @@ type Service interface {
 	SetPreference(key types.ServicePreference, val string) (isChanged bool, err error)
 	SetUserPreferences(userPrefs preferences.UserPreferences) (err error)
 	ResetPreferences() error
+	SetApiProxy(proxy preferences.ApiProxy) error
+	SetServersFavorites(list preferences.ServersList) error
+	SetServersExcluded(list preferences.ServersList) error
+	IsServerHostExcluded(hostname, hostIP string) bool
 
 	SetManualDNS(dns dns.DnsSettings) error
 	ResetManualDNS() error
 
 	IsCanConnectMultiHop() error
-	ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
-	ConnectWireGuard(connectionParams wireguard.ConnectionParams, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+	MultihopExitHostWireGuard(entryHost net.IP, isIPv6 bool, rules types.MultihopExitRules) (apitypes.WireGuardServerHostInfo, error)
+	MultihopExitHostOpenVPN(entryHost net.IP, rules types.MultihopExitRules) (apitypes.OpenVPNServerHostInfo, error)
+	ConnectOpenVPN(connectionParams openvpn.ConnectionParams, exitRules *types.MultihopExitRules, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+	ConnectOpenVPNCustom(id string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+	ConnectWireGuard(connectionParams wireguard.ConnectionParams, exitRules *types.MultihopExitRules, nextHopCustomServerID string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
+	ConnectWireGuardCustom(id string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error
 	Disconnect() error
 	Connected() bool
+	GetConnectionStats() (vpnType vpn.Type, stats vpn.ConnectionStats, err error)
 
 	Pause() error
 	Resume() error
@@ -134,6 +152,18 @@ type Service interface {
 
 	WireGuardGenerateKeys(updateIfNecessary bool) error
 	WireGuardSetKeysRotationInterval(interval int64)
+	WireGuardGetKeysRotationWindow() preferences.TimeWindow
+	WireGuardSetKeysRotationWindow(window preferences.TimeWindow) error
+	WireGuardKeysHistory() []preferences.WgKeysRotationRecord
+	WireGuardKeysNextRotation() time.Time
+
+	WireGuardCustomServerImport(name string, config string) (types.WireGuardCustomServerInfo, error)
+	WireGuardCustomServerDelete(id string) error
+	WireGuardCustomServersGet() []types.WireGuardCustomServerInfo
+
+	OpenVpnCustomProfileImport(name string, profile string, username string, password string) (types.OpenVpnCustomProfileInfo, error)
+	OpenVpnCustomProfileDelete(id string) error
+	OpenVpnCustomProfilesGet() []types.OpenVpnCustomProfileInfo
 
 	GetWiFiCurrentState() (ssid string, isInsecureNetwork bool)
 	GetWiFiAvailableNetworks() []string
@@ -389,10 +419,20 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			"GetVPNState",
 			"GetServers",
 			"PingServers",
+			"PingHistory",
+			"QueryServers",
+			"GetCurrentLocation",
 			"APIRequest",
 			"WiFiAvailableNetworks",
 			"KillSwitchGetStatus",
+			"OfflineGetStatus",
 			"SplitTunnelGetStatus",
+			"SplitTunnelGetLiveStatus",
+			"GetConnectionStats",
+			"WireGuardCustomServersGet",
+			"WireGuardKeysStatus",
+			"WireGuardKeysHistory",
+			"OpenVpnCustomProfilesGet",
 			"GetDnsPredefinedConfigs",
 			"AccountStatus":
 			return true
@@ -526,6 +566,26 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		// send VPN connection  state
 		sendState(reqCmd.Idx, false)
 
+	case "GetConnectionStats":
+		resp := types.ConnectionStatsResp{State: p._lastVPNState.State.String()}
+		if p._service.Connected() {
+			vpnType, stats, err := p._service.GetConnectionStats()
+			if err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				break
+			}
+			resp.VpnType = vpnType
+			resp.StateDetails = stats.StateDetails
+			resp.RxBytes, resp.TxBytes, resp.RxRate, resp.TxRate = stats.RxBytes, stats.TxBytes, stats.RxRate, stats.TxRate
+			if !stats.ConnectedSince.IsZero() {
+				resp.ConnectedSince = stats.ConnectedSince.Unix()
+			}
+			if !stats.LastHandshake.IsZero() {
+				resp.LastHandshake = stats.LastHandshake.Unix()
+			}
+		}
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+
 	case "GetServers":
 		var req types.GetServers
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -566,19 +626,53 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		if req.VpnTypePrioritization {
 			vpnType = req.VpnTypePrioritized
 		}
-		retMap, err := p._service.PingServers(req.TimeOutMs, vpnType, req.PingAllHostsOnFirstPhase, req.SkipSecondPhase)
+		retMap, err := p._service.PingServers(req.TimeOutMs, vpnType, req.PingAllHostsOnFirstPhase, req.SkipSecondPhase, req.ProbeStrategy)
 		if err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
 			break
 		}
 
 		var results []types.PingResultType
-		for k, v := range retMap {
-			results = append(results, types.PingResultType{Host: k, Ping: v})
+		for _, v := range retMap {
+			results = append(results, v)
 		}
 
 		p.sendResponse(conn, &types.PingServersResp{PingResults: results}, req.Idx)
 
+	case "PingHistory":
+		var req types.PingHistory
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		since := time.Time{}
+		if req.SinceUnix > 0 {
+			since = time.Unix(req.SinceUnix, 0)
+		}
+		p.sendResponse(conn, &types.PingHistoryResp{Host: req.Host, Samples: p._service.PingHistory(req.Host, since)}, req.Idx)
+
+	case "QueryServers":
+		var req types.QueryServers
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		hosts, err := p._service.QueryServers(req.Query)
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.QueryServersResp{Hosts: hosts}, req.Idx)
+
+	case "GetCurrentLocation":
+		resp := types.CurrentLocationResp{}
+		if l, source := p._service.CurrentLocation(); l != nil {
+			resp = types.CurrentLocationResp{IsKnown: true, Latitude: l.Latitude, Longitude: l.Longitude, Source: source}
+		}
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+
 	case "APIRequest":
 		var req types.APIRequest
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -603,6 +697,9 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		p.notifyClients(&types.WiFiAvailableNetworksResp{Networks: nets})
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
+	case "OfflineGetStatus":
+		p.sendResponse(conn, &types.OfflineStatusResp{OfflineStatus: p._service.OfflineStatus()}, reqCmd.Idx)
+
 	case "KillSwitchGetStatus":
 		if isEnabled, isPersistant, isAllowLAN, isAllowLanMulticast, isAllowApiServers, fwUserExceptions, err := p._service.KillSwitchState(); err != nil {
 			p.sendErrorResponse(conn, reqCmd, err)
@@ -751,6 +848,56 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
 		}()
 
+	case "SetApiProxy":
+		func() {
+			defer func() {
+				//  notify all connected clients about changed (or not changed!) preferences
+				p.notifyClients(p.createSettingsResponse())
+			}()
+
+			var req types.SetApiProxy
+			if err := json.Unmarshal(messageData, &req); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				return
+			}
+
+			if err := p._service.SetApiProxy(req.Proxy); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				return
+			}
+
+			// notify 'success'
+			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		}()
+
+	case "SetServersFavorites", "SetServersExcluded":
+		func() {
+			defer func() {
+				//  notify all connected clients about changed (or not changed!) preferences
+				p.notifyClients(p.createSettingsResponse())
+			}()
+
+			var req types.SetServersFavorites // the same structure for both requests
+			if err := json.Unmarshal(messageData, &req); err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				return
+			}
+
+			var err error
+			if reqCmd.Command == "SetServersFavorites" {
+				err = p._service.SetServersFavorites(req.List)
+			} else {
+				err = p._service.SetServersExcluded(req.List)
+			}
+			if err != nil {
+				p.sendErrorResponse(conn, reqCmd, err)
+				return
+			}
+
+			// notify 'success'
+			p.sendResponse(conn, &types.EmptyResp{}, req.Idx)
+		}()
+
 	case "SplitTunnelGetStatus":
 		status, err := p._service.SplitTunnelling_GetStatus()
 		if err != nil {
@@ -759,6 +906,14 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		}
 		p.sendResponse(conn, &status, reqCmd.Idx)
 
+	case "SplitTunnelGetLiveStatus":
+		status, err := p._service.SplitTunnelling_GetLiveStatus()
+		if err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &status, reqCmd.Idx)
+
 	case "SplitTunnelSetConfig":
 		var req types.SplitTunnelSetConfig
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -771,6 +926,18 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		}
 		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
 
+	case "SplitTunnelSetDestinations":
+		var req types.SplitTunnelSetDestinations
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.SplitTunnelling_SetDestinations(req.BypassDestinations, req.VpnDestinations); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		// all clients will be notified about configuration change by service in OnSplitTunnelStatusChanged() handler
+
 	case "SplitTunnelAddApp":
 		var req types.SplitTunnelAddApp
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -1035,6 +1202,90 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 		p._service.WireGuardSetKeysRotationInterval(req.Interval)
 		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
 
+	case "WireGuardSetKeysRotationWindow":
+		var req types.WireGuardSetKeysRotationWindow
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+
+		if err := p._service.WireGuardSetKeysRotationWindow(preferences.TimeWindow{StartMinute: req.StartMinute, EndMinute: req.EndMinute}); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.EmptyResp{}, reqCmd.Idx)
+
+	case "WireGuardKeysStatus":
+		resp := types.WireGuardKeysStatusResp{RotationWindow: p._service.WireGuardGetKeysRotationWindow()}
+		if prefs := p._service.Preferences(); prefs.Session.IsLoggedIn() {
+			resp.PublicKeyFingerprint = wgkeys.KeyFingerprint(prefs.Session.WGPublicKey)
+		}
+		if next := p._service.WireGuardKeysNextRotation(); !next.IsZero() {
+			resp.NextRotation = next.Unix()
+		}
+		if history := p._service.WireGuardKeysHistory(); len(history) > 0 {
+			last := history[len(history)-1]
+			resp.LastRotation = &last
+		}
+		p.sendResponse(conn, &resp, reqCmd.Idx)
+
+	case "WireGuardKeysHistory":
+		p.sendResponse(conn, &types.WireGuardKeysHistoryResp{Records: p._service.WireGuardKeysHistory()}, reqCmd.Idx)
+
+	case "WireGuardCustomServersGet":
+		p.sendResponse(conn, &types.WireGuardCustomServersResp{Servers: p._service.WireGuardCustomServersGet()}, reqCmd.Idx)
+
+	case "WireGuardCustomServerImport":
+		var req types.WireGuardCustomServerImport
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if _, err := p._service.WireGuardCustomServerImport(req.Name, req.Config); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.WireGuardCustomServersResp{Servers: p._service.WireGuardCustomServersGet()}, reqCmd.Idx)
+
+	case "WireGuardCustomServerDelete":
+		var req types.WireGuardCustomServerDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.WireGuardCustomServerDelete(req.ID); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.WireGuardCustomServersResp{Servers: p._service.WireGuardCustomServersGet()}, reqCmd.Idx)
+
+	case "OpenVpnCustomProfilesGet":
+		p.sendResponse(conn, &types.OpenVpnCustomProfilesResp{Profiles: p._service.OpenVpnCustomProfilesGet()}, reqCmd.Idx)
+
+	case "OpenVpnCustomProfileImport":
+		var req types.OpenVpnCustomProfileImport
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if _, err := p._service.OpenVpnCustomProfileImport(req.Name, req.Profile, req.Username, req.Password); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.OpenVpnCustomProfilesResp{Profiles: p._service.OpenVpnCustomProfilesGet()}, reqCmd.Idx)
+
+	case "OpenVpnCustomProfileDelete":
+		var req types.OpenVpnCustomProfileDelete
+		if err := json.Unmarshal(messageData, &req); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		if err := p._service.OpenVpnCustomProfileDelete(req.ID); err != nil {
+			p.sendErrorResponse(conn, reqCmd, err)
+			break
+		}
+		p.sendResponse(conn, &types.OpenVpnCustomProfilesResp{Profiles: p._service.OpenVpnCustomProfilesGet()}, reqCmd.Idx)
+
 	case "GetAppIcon":
 		var req types.GetAppIcon
 		if err := json.Unmarshal(messageData, &req); err != nil {
@@ -1152,6 +1403,7 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 				waiter.Done()
 			}()
 
+			var lastConnectedState vpn.StateInfo
 		state_forward_loop:
 			for {
 				select {
@@ -1167,16 +1419,25 @@ func (p *Protocol) processRequest(conn net.Conn, message string) {
 					default:
 					}
 
-					p._lastVPNState = state
+					if state.State == vpn.RECOVERED {
+						// the connection is the same as before DEGRADED state: clients are getting the connection info from the last CONNECTED state
+						p._lastVPNState = lastConnectedState
+					} else {
+						p._lastVPNState = state
+					}
 
 					switch state.State {
 					case vpn.CONNECTED:
+						lastConnectedState = state
 						// Do not send "Connected" notification if we are going to establish new connection immediately
 						if cnt, _ := p.vpnConnectReqCounter(); cnt == 1 || p._disconnectRequested {
 							p.notifyClients(p.createConnectedResponse(state))
 						} else {
 							log.Debug("Skip sending 'Connected' notification. New connection request is awaiting ", cnt)
 						}
+					case vpn.RECOVERED:
+						// the tunnel is working again: clients are getting the same info as for the last CONNECTED state
+						p.notifyClients(p.createConnectedResponse(lastConnectedState))
 					case vpn.EXITING:
 						disconnectAuthError = state.IsAuthError
 					default:
@@ -70,10 +70,10 @@ func (p *Protocol) OnWiFiChanged(ssid string, isInsecureNetwork bool) {
 }
 
 // OnPingStatus - servers ping status
-func (p *Protocol) OnPingStatus(retMap map[string]int) {
+func (p *Protocol) OnPingStatus(retMap map[string]types.PingResultType) {
 	var results []types.PingResultType
-	for k, v := range retMap {
-		results = append(results, types.PingResultType{Host: k, Ping: v})
+	for _, v := range retMap {
+		results = append(results, v)
 	}
 	p.notifyClients(&types.PingServersResp{PingResults: results})
 }
@@ -85,6 +85,14 @@ func (p *Protocol) OnServersUpdated(serv *apitypes.ServersInfoResponse) {
 	p.notifyClients(&types.ServerListResp{VpnServers: *serv})
 }
 
+func (p *Protocol) OnServersChanged(diff types.ServersListDiff) {
+	p.notifyClients(&types.ServersChangedResp{Diff: diff})
+}
+
+func (p *Protocol) OnOfflineStatusChanged(status types.OfflineStatus) {
+	p.notifyClients(&types.OfflineStatusResp{OfflineStatus: status})
+}
+
 func (p *Protocol) OnSplitTunnelStatusChanged() {
 	if p._service == nil {
 		return
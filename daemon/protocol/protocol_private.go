@@ -38,6 +38,7 @@ import (
 	"github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/service/srverrors"
 	"github.com/ivpn/desktop-app/daemon/version"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
@@ -171,6 +172,9 @@ func (p *Protocol) createSettingsResponse() *types.SettingsResp {
 		IsAutoconnectOnLaunch: prefs.IsAutoconnectOnLaunch,
 		UserDefinedOvpnFile:   platform.OpenvpnUserParamsFile(),
 		UserPrefs:             prefs.UserPrefs,
+		ApiProxy:              prefs.ApiProxy.Info(),
+		ServersFavorites:      prefs.ServersFavorites,
+		ServersExcluded:       prefs.ServersExcluded,
 		// TODO: implement the rest of daemon settings
 	}
 }
@@ -228,6 +232,7 @@ func (p *Protocol) createConnectedResponse(state vpn.StateInfo) *types.Connected
 }
 
 // -------------- processing connection request ---------------
+
 func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vpn.StateInfo) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -250,13 +255,24 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 	retManualDNS := r.ManualDNS
 
 	if vpn.Type(r.VpnType) == vpn.OpenVPN {
+		if len(r.OpenVpnParameters.CustomProfileID) > 0 {
+			// user-supplied (imported) OpenVPN profile
+			return p._service.ConnectOpenVPNCustom(r.OpenVpnParameters.CustomProfileID, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		}
+
 		// PARAMETERS VALIDATION
 		// parsing hosts
 		var hosts []net.IP
 		for _, v := range r.OpenVpnParameters.EntryVpnServer.Hosts {
+			if p._service.IsServerHostExcluded(v.Hostname, v.Host) {
+				continue // the excluded hosts are never in use
+			}
 			hosts = append(hosts, net.ParseIP(v.Host))
 		}
 		if len(hosts) < 1 {
+			if len(r.OpenVpnParameters.EntryVpnServer.Hosts) > 0 {
+				return srverrors.ErrorServerExcluded{Server: r.OpenVpnParameters.EntryVpnServer.Hosts[0].Hostname}
+			}
 			return fmt.Errorf("VPN host not defined")
 		}
 		// in case of multiple hosts - take random host from the list
@@ -275,14 +291,38 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 
 		// Multi-Hop
 		var exitHostValue *apitypes.OpenVPNServerHostInfo
+		var exitRules *types.MultihopExitRules
 		multihopExitHosts := r.OpenVpnParameters.MultihopExitServer.Hosts
 		if len(multihopExitHosts) > 0 {
+			n := 0
+			for _, h := range multihopExitHosts {
+				if !p._service.IsServerHostExcluded(h.Hostname, h.Host) {
+					multihopExitHosts[n] = h
+					n++
+				}
+			}
+			if n == 0 {
+				return srverrors.ErrorServerExcluded{Server: multihopExitHosts[0].Hostname}
+			}
+			multihopExitHosts = multihopExitHosts[:n]
+
 			exitHostValue = &multihopExitHosts[0]
 			if len(multihopExitHosts) > 1 {
 				if rnd, err := rand.Int(rand.Reader, big.NewInt(int64(len(multihopExitHosts)))); err == nil {
 					exitHostValue = &multihopExitHosts[rnd.Int64()]
 				}
 			}
+		} else if r.MultihopExitRules.IsDefined() {
+			// the exit server is selected by rules (they are re-applied on reconnection)
+			if mhErr := p._service.IsCanConnectMultiHop(); mhErr != nil {
+				return mhErr
+			}
+			exitHost, err := p._service.MultihopExitHostOpenVPN(host, r.MultihopExitRules)
+			if err != nil {
+				return err
+			}
+			exitHostValue = &exitHost
+			exitRules = &r.MultihopExitRules
 		}
 
 		// only one-line parameter is allowed
@@ -323,12 +363,43 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 				proxyPassword)
 		}
 
-		return p._service.ConnectOpenVPN(connectionParams, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		return p._service.ConnectOpenVPN(connectionParams, exitRules, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
 
 	} else if vpn.Type(r.VpnType) == vpn.WireGuard {
+		if len(r.WireGuardParameters.CustomServerID) > 0 {
+			// custom (imported) WireGuard server
+			return p._service.ConnectWireGuardCustom(r.WireGuardParameters.CustomServerID, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		}
+
 		hosts := r.WireGuardParameters.EntryVpnServer.Hosts
 		multihopExitHosts := r.WireGuardParameters.MultihopExitServer.Hosts
 
+		// the excluded hosts are never in use
+		filterExcluded := func(hosts []apitypes.WireGuardServerHostInfo) ([]apitypes.WireGuardServerHostInfo, error) {
+			if len(hosts) == 0 {
+				return hosts, nil
+			}
+			ret := make([]apitypes.WireGuardServerHostInfo, 0, len(hosts))
+			for _, h := range hosts {
+				if !p._service.IsServerHostExcluded(h.Hostname, h.Host) {
+					ret = append(ret, h)
+				}
+			}
+			if len(ret) == 0 {
+				return nil, srverrors.ErrorServerExcluded{Server: hosts[0].Hostname}
+			}
+			return ret, nil
+		}
+		if hosts, err = filterExcluded(hosts); err != nil {
+			return err
+		}
+		if multihopExitHosts, err = filterExcluded(multihopExitHosts); err != nil {
+			return err
+		}
+		if len(hosts) < 1 {
+			return fmt.Errorf("VPN host not defined")
+		}
+
 		// filter hosts: use IPv6 hosts
 		if r.IPv6 {
 			ipv6Hosts := append(hosts[0:0], hosts...)
@@ -387,6 +458,7 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 		}
 
 		var exitHostValue *apitypes.WireGuardServerHostInfo
+		var exitRules *types.MultihopExitRules
 		if len(multihopExitHosts) > 0 {
 			exitHostValue = &multihopExitHosts[0]
 			if len(multihopExitHosts) > 1 {
@@ -394,6 +466,18 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 					exitHostValue = &multihopExitHosts[rnd.Int64()]
 				}
 			}
+		} else if r.MultihopExitRules.IsDefined() {
+			// the exit server is selected by rules (they are re-applied on reconnection)
+			if mhErr := p._service.IsCanConnectMultiHop(); mhErr != nil {
+				return mhErr
+			}
+			isIPv6 := r.IPv6 && len(hostValue.IPv6.LocalIP) > 0
+			exitHost, err := p._service.MultihopExitHostWireGuard(net.ParseIP(hostValue.Host), isIPv6, r.MultihopExitRules)
+			if err != nil {
+				return err
+			}
+			exitHostValue = &exitHost
+			exitRules = &r.MultihopExitRules
 		}
 
 		// prevent user-defined data injection: ensure that nothing except the base64 public key will be stored in the configuration
@@ -435,7 +519,7 @@ func (p *Protocol) processConnectRequest(messageData []byte, stateChan chan<- vp
 				r.WireGuardParameters.Mtu)
 		}
 
-		return p._service.ConnectWireGuard(connectionParams, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
+		return p._service.ConnectWireGuard(connectionParams, exitRules, r.WireGuardParameters.NextHopCustomServerID, retManualDNS, r.FirewallOn, r.FirewallOnDuringConnection, stateChan)
 
 	}
 
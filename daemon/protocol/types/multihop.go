@@ -0,0 +1,42 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+// MultihopExitRules - rules to select Multi-Hop exit server by the daemon
+// (in use when the exit server is not defined explicitly in the 'Connect' request).
+// The rules are applied on each (re)connection: e.g. the exit server which is not available
+// anymore (or which became excluded) is replaced on reconnection.
+type MultihopExitRules struct {
+	// Country codes of the exit server (empty - any country)
+	Countries []string
+	// The exit server must be in another jurisdiction than the entry server
+	// (the 'Fourteen Eyes' countries are considered as one jurisdiction)
+	OtherJurisdiction bool
+	// Use the exit server with the lowest latency (otherwise - a random exit server satisfying the rules)
+	Fastest bool
+}
+
+// IsDefined returns true when any of the rules is defined
+func (r MultihopExitRules) IsDefined() bool {
+	return len(r.Countries) > 0 || r.OtherJurisdiction || r.Fastest
+}
@@ -0,0 +1,64 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+// OpenVpnCustomProfileImport (request) imports user-supplied OpenVPN profile (.ovpn file)
+// The 'OpenVpnCustomProfilesResp' is sent back to client
+type OpenVpnCustomProfileImport struct {
+	RequestBase
+	Name    string // (optional) human-readable name of the profile
+	Profile string // content of the profile file
+	// credentials (required only when the profile uses 'auth-user-pass' authentication)
+	Username string
+	Password string
+}
+
+// OpenVpnCustomProfileDelete (request) removes user-supplied OpenVPN profile
+// The 'OpenVpnCustomProfilesResp' is sent back to client
+type OpenVpnCustomProfileDelete struct {
+	RequestBase
+	ID string
+}
+
+// OpenVpnCustomProfilesGet (request) requests the list of user-supplied OpenVPN profiles
+type OpenVpnCustomProfilesGet struct {
+	RequestBase
+}
+
+// OpenVpnCustomProfileInfo contains information about user-supplied OpenVPN profile
+// (the keys and password are never sent to clients)
+type OpenVpnCustomProfileInfo struct {
+	ID             string
+	Name           string
+	RemoteHost     string
+	RemotePort     int
+	IsTCP          bool
+	IsAuthUserPass bool
+	Username       string
+}
+
+// OpenVpnCustomProfilesResp (response) contains the list of user-supplied OpenVPN profiles
+type OpenVpnCustomProfilesResp struct {
+	CommandBase
+	Profiles []OpenVpnCustomProfileInfo
+}
@@ -79,6 +79,7 @@ type GetServers struct {
 //		2.2) (when VpnTypePrioritization==true) Ping all hosts for the rest protocols
 // If PingAllHostsOnFirstPhase==true - daemon will ping all hosts for nearest locations on the phase (1)
 // If SkipSecondPhase==true - phase (2) will be skipped
+// ProbeStrategy - the way the hosts reachability is checked (PingProbeAuto, PingProbeICMP or PingProbeProtocol)
 type PingServers struct {
 	RequestBase
 	TimeOutMs                int
@@ -86,6 +87,40 @@ type PingServers struct {
 	VpnTypePrioritization    bool
 	PingAllHostsOnFirstPhase bool
 	SkipSecondPhase          bool
+	ProbeStrategy            string
+}
+
+// Probe strategies for PingServers request
+const (
+	// ICMP; the hosts which are not replying are probed on the VPN protocol level
+	// (when ICMP is blocked by the network - only VPN protocol probes are in use)
+	PingProbeAuto = ""
+	// ICMP only
+	PingProbeICMP = "icmp"
+	// VPN protocol probes only: WireGuard handshake initiation; OpenVPN UDP HARD_RESET or TCP connection
+	PingProbeProtocol = "protocol"
+)
+
+// PingHistory request the history of ping results for the host
+// The 'PingHistoryResp' is sent back to client
+type PingHistory struct {
+	RequestBase
+	Host      string // IP address of the host
+	SinceUnix int64  // return only the samples measured since this time (unix time; 0 - all samples)
+}
+
+// QueryServers request the servers hosts which are matching the query
+// (see 'serversquery' package for the query syntax; e.g. "country in (CH,SE) and load < 50 and proto = wg sort by ping limit 3")
+// The 'QueryServersResp' is sent back to client
+type QueryServers struct {
+	RequestBase
+	Query string
+}
+
+// GetCurrentLocation request the current geographical location which is in use to calculate distance to servers
+// The 'CurrentLocationResp' is sent back to client
+type GetCurrentLocation struct {
+	RequestBase
 }
 
 // KillSwitchSetAllowLANMulticast enable\disable LAN multicast acces for kill-switch
@@ -124,6 +159,11 @@ type KillSwitchGetStatus struct {
 	RequestBase
 }
 
+// OfflineGetStatus get information about offline mode (API servers are unreachable)
+type OfflineGetStatus struct {
+	RequestBase
+}
+
 // KillSwitchSetIsPersistent request to mark kill-switch persistant
 type KillSwitchSetIsPersistent struct {
 	RequestBase
@@ -144,6 +184,26 @@ type SetUserPreferences struct {
 	UserPrefs preferences.UserPreferences
 }
 
+// SetApiProxy request to set proxy server for the daemon API traffic (empty proxy type - direct connection).
+// The proxy password is not sent back to clients; if it is empty, the password currently in use is kept
+// (when the proxy address and username are not changed)
+type SetApiProxy struct {
+	RequestBase
+	Proxy preferences.ApiProxy
+}
+
+// SetServersFavorites request to set the list of favorite servers
+type SetServersFavorites struct {
+	RequestBase
+	List preferences.ServersList
+}
+
+// SetServersExcluded request to set the list of excluded servers (they are never in use for connection)
+type SetServersExcluded struct {
+	RequestBase
+	List preferences.ServersList
+}
+
 // SetAlternateDns request to set custom DNS
 type SetAlternateDns struct {
 	RequestBase
@@ -174,6 +234,11 @@ type Connect struct {
 	// (has effect only if Firewall not enabled before)
 	FirewallOnDuringConnection bool
 
+	// (optional) Multi-Hop exit server selection rules.
+	// Applicable when the exit server is not defined explicitly ('MultihopExitServer.Hosts' is empty):
+	// the daemon selects the exit server satisfying the rules.
+	MultihopExitRules MultihopExitRules
+
 	WireGuardParameters struct {
 		// Port in use only for Single-Hop connections
 		Port struct {
@@ -193,6 +258,15 @@ type Connect struct {
 		}
 
 		Mtu int // Set 0 to use default MTU value
+
+		// (optional) ID of the custom (imported) WireGuard server.
+		// When defined - all other WireGuard parameters are ignored
+		CustomServerID string
+
+		// (optional) ID of the custom (imported) WireGuard server chained after the IVPN (exit) server:
+		// the traffic goes through the IVPN server(s) to the custom server
+		// (currently, supported only on Linux)
+		NextHopCustomServerID string
 	}
 
 	OpenVpnParameters struct {
@@ -200,6 +274,10 @@ type Connect struct {
 			Hosts []types.OpenVPNServerHostInfo
 		}
 
+		// (optional) ID of the user-supplied (imported) OpenVPN profile.
+		// When defined - all other OpenVPN parameters are ignored
+		CustomProfileID string
+
 		//MultihopExitSrvID string
 		MultihopExitServer struct {
 			// ExitSrvID (geteway ID) just in use to keep clients notified about connected MH exit server
@@ -232,6 +310,12 @@ type GetVPNState struct {
 	RequestBase
 }
 
+// GetConnectionStats requests statistics of the active VPN connection (traffic counters, rates, detailed state)
+// The 'ConnectionStatsResp' is sent back to client
+type GetConnectionStats struct {
+	RequestBase
+}
+
 // SessionNew - create new session
 //
 // When force is set to true - all active sessions will be deleted prior to creating a new one if user reached session limit.
@@ -28,7 +28,9 @@ import (
 	"github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/logger"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 )
 
@@ -131,6 +133,12 @@ type SettingsResp struct {
 	UserDefinedOvpnFile string
 
 	UserPrefs preferences.UserPreferences
+
+	// proxy server for the daemon API traffic (without password)
+	ApiProxy preferences.ApiProxy
+
+	ServersFavorites preferences.ServersList
+	ServersExcluded  preferences.ServersList
 }
 
 // HelloResp response on initial request
@@ -203,6 +211,29 @@ type KillSwitchStatusResp struct {
 	UserExceptions    string // Firewall exceptions: comma separated list of IP addresses (masks) in format: x.x.x.x[/xx]
 }
 
+// OfflineStatus - information about offline mode (the API servers are unreachable).
+// Time values are in Unix format (0 - not defined)
+type OfflineStatus struct {
+	IsOffline    bool
+	OfflineSince int64
+	LastError    string // the error of the last failed API request (only in offline mode)
+
+	LastApiSuccess int64 // the last successful request to the API servers
+
+	ServersUpdated    int64 // the last time the servers list was updated (or confirmed as not modified)
+	ServersValidUntil int64 // the cached servers list is reported as stale after this time
+	IsServersStale    bool
+
+	IsWgKeyRotationOverdue bool  // WireGuard key was not rotated in time
+	WgKeyUsableUntil       int64 // WireGuard key can not be used for connection after this time (until rotated)
+}
+
+// OfflineStatusResp - information about offline mode (response to 'OfflineGetStatus' or notification about changes)
+type OfflineStatusResp struct {
+	CommandBase
+	OfflineStatus
+}
+
 // KillSwitchGetIsPestistentResp returns kill-switch persistance status
 type KillSwitchGetIsPestistentResp struct {
 	CommandBase
@@ -247,6 +278,20 @@ type ConnectedResp struct {
 	Mtu             int // (for WireGuard connections)
 }
 
+// ConnectionStatsResp contains statistics of the active VPN connection
+type ConnectionStatsResp struct {
+	CommandBase
+	VpnType        vpn.Type
+	State          string // current VPN state
+	StateDetails   string // detailed description of the current state (e.g. OpenVPN: "TLS negotiation", "Authenticating" ...)
+	ConnectedSince int64  // unix time (seconds); 0 - not connected
+	RxBytes        uint64 // total bytes received
+	TxBytes        uint64 // total bytes sent
+	RxRate         uint64 // bytes per second
+	TxRate         uint64 // bytes per second
+	LastHandshake  int64  // (WireGuard) unix time (seconds) of the latest handshake; 0 - no handshake
+}
+
 // DisconnectionReason - disconnection reason
 type DisconnectionReason int
 
@@ -280,11 +325,66 @@ type ServerListResp struct {
 	VpnServers types.ServersInfoResponse
 }
 
+// ServersChangedResp (event) contains the difference between the previous and the updated servers list
+// (the changed WireGuard public key of a host is a security-relevant event)
+type ServersChangedResp struct {
+	CommandBase
+	Diff ServersListDiff
+}
+
+// ServersListDiff - the difference between two servers lists
+type ServersListDiff struct {
+	Added       []ServerHostChange
+	Removed     []ServerHostChange
+	KeysChanged []ServerHostChange // WireGuard hosts with changed public key
+}
+
+// IsEmpty returns true if there are no changes
+func (d ServersListDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.KeysChanged) == 0
+}
+
+// ServerHostChange - information about the changed host of the servers list (a part of 'ServersListDiff')
+type ServerHostChange struct {
+	VpnType  vpn.Type
+	Gateway  string
+	Hostname string
+	Host     string // IP address
+	// WireGuard public keys (defined only for the hosts with changed keys)
+	OldPublicKey string
+	NewPublicKey string
+}
+
 // PingResultType represents information ping TTL for a host (is a part of 'PingServersResp')
 type PingResultType struct {
 	Host string
-	Ping int
-}
+	Ping int // average round-trip time (ms)
+
+	PingMin    int     // minimum round-trip time (ms)
+	PingMax    int     // maximum round-trip time (ms)
+	Jitter     float64 // standard deviation of the round-trip time (ms)
+	PacketLoss float64 // percentage of lost packets [0-100]
+	Load       float64 // server load [0-100] (as reported by the servers list)
+	// Ranking score which combines latency, jitter, packet loss and server load (lower - better)
+	// (the score weights are defined by user preferences: 'PingScoreWeights')
+	Score float64
+	// The way the host reachability was checked: "icmp", "wg-handshake", "ovpn-udp" or "ovpn-tcp"
+	// (VPN protocol probes are single requests: no jitter and packet loss info)
+	Probe string
+	// The network path the host was measured on: PingPathDirect or PingPathTunnel
+	// (the results measured through the VPN tunnel include the tunnel overhead and are not comparable with direct ones)
+	Path string
+	// Median score of the latest measurements of the host on the same path (including the current one).
+	// It is not affected by occasional spikes, so it is preferable for the servers ranking.
+	StableScore    float64
+	HistorySamples int // number of measurements in use for 'StableScore'
+}
+
+// Network paths of the ping results (see PingResultType.Path)
+const (
+	PingPathDirect = "direct" // measured without VPN connection (or VPN connection is paused)
+	PingPathTunnel = "tunnel" // measured through the VPN tunnel (VPN connected)
+)
 
 // PingServersResp returns average ping time for servers
 type PingServersResp struct {
@@ -292,6 +392,34 @@ type PingServersResp struct {
 	PingResults []PingResultType
 }
 
+// PingHistoryResp contains the history of ping results for the host (the latest sample is the last one)
+type PingHistoryResp struct {
+	CommandBase
+	Host    string
+	Samples []pinghistory.Sample
+}
+
+// QueryServersResp contains the servers hosts which are matching the query (in the order defined by the query)
+type QueryServersResp struct {
+	CommandBase
+	Hosts []serversquery.Host
+}
+
+// Sources of the current location information
+const (
+	LocationSourceHome      = "home"      // home location defined by user
+	LocationSourceGeoLookup = "geolookup" // location detected by the geo-lookup request (the last known one)
+)
+
+// CurrentLocationResp contains the current geographical location which is in use to calculate distance to servers
+type CurrentLocationResp struct {
+	CommandBase
+	IsKnown   bool
+	Latitude  float64
+	Longitude float64
+	Source    string // LocationSourceHome or LocationSourceGeoLookup
+}
+
 // WiFiNetworkInfo - information about WIFI network
 type WiFiNetworkInfo struct {
 	SSID string
@@ -90,6 +90,37 @@ type SplitTunnelStatus struct {
 	// Information about active applications running in Split-Tunnel environment
 	// (applicable for Linux)
 	RunningApps []splittun.RunningApp
+
+	// Destination-based split-tunnel configuration (applicable for Linux)
+	// List of IP addresses, masks (x.x.x.x/xx) or domain names
+	BypassDestinations []string // destinations which always bypass the VPN tunnel
+	VpnDestinations    []string // destinations which always use the VPN tunnel
+	// Last resolved addresses of domain names from destinations configuration (map[<domain>][]<IP>)
+	ResolvedDomains map[string][]string
+}
+
+// SplitTunnelGetLiveStatus (request) requests live information about processes in Split-Tunnel environment
+// (applicable for Linux)
+type SplitTunnelGetLiveStatus struct {
+	RequestBase
+}
+
+// SplitTunnelLiveStatus (response) contains live information about processes in Split-Tunnel environment:
+// all processes (including child processes) with their active sockets and TCP traffic counters
+type SplitTunnelLiveStatus struct {
+	CommandBase
+	IsEnabled   bool
+	RunningApps []splittun.RunningAppTraffic
+}
+
+// SplitTunnelSetDestinations (request) sets the destination-based split-tunnelling configuration
+// (applicable for Linux)
+// Each destination can be IP address, mask (x.x.x.x/xx) or domain name.
+// Domain names are resolved by the daemon (and periodically re-resolved while VPN is connected)
+type SplitTunnelSetDestinations struct {
+	RequestBase
+	BypassDestinations []string // destinations which always bypass the VPN tunnel
+	VpnDestinations    []string // destinations which always use the VPN tunnel (have priority over 'BypassDestinations')
 }
 
 // SplitTunnelAddApp (request) add application to SplitTunneling
@@ -0,0 +1,63 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+// WireGuardCustomServerImport (request) imports third-party WireGuard configuration (in 'wg-quick' format) as a custom server
+// The 'WireGuardCustomServersResp' is sent back to client
+type WireGuardCustomServerImport struct {
+	RequestBase
+	Name   string // (optional) human-readable name of the server
+	Config string // content of the configuration file
+}
+
+// WireGuardCustomServerDelete (request) removes custom WireGuard server
+// The 'WireGuardCustomServersResp' is sent back to client
+type WireGuardCustomServerDelete struct {
+	RequestBase
+	ID string
+}
+
+// WireGuardCustomServersGet (request) requests the list of custom WireGuard servers
+type WireGuardCustomServersGet struct {
+	RequestBase
+}
+
+// WireGuardCustomServerInfo contains information about custom WireGuard server
+// (the private key is never sent to clients)
+type WireGuardCustomServerInfo struct {
+	ID             string
+	Name           string
+	Endpoint       string
+	PublicKey      string
+	IsPresharedKey bool
+	Addresses      []string
+	DNS            []string
+	AllowedIPs     []string
+	Mtu            int
+}
+
+// WireGuardCustomServersResp (response) contains the list of custom WireGuard servers
+type WireGuardCustomServersResp struct {
+	CommandBase
+	Servers []WireGuardCustomServerInfo
+}
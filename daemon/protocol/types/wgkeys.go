@@ -0,0 +1,60 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package types
+
+import "github.com/ivpn/desktop-app/daemon/service/preferences"
+
+// WireGuardSetKeysRotationWindow (request) changes the local time window when automatic WG keys rotation is allowed
+// (time in minutes since midnight; StartMinute == EndMinute - no restrictions)
+type WireGuardSetKeysRotationWindow struct {
+	RequestBase
+	StartMinute int
+	EndMinute   int
+}
+
+// WireGuardKeysStatus (request) requests the status of WG keys rotation
+// The 'WireGuardKeysStatusResp' is sent back to client
+type WireGuardKeysStatus struct {
+	RequestBase
+}
+
+// WireGuardKeysStatusResp (response) contains the status of WG keys rotation
+type WireGuardKeysStatusResp struct {
+	CommandBase
+	PublicKeyFingerprint string
+	NextRotation         int64 // unix time of the next scheduled rotation (0 - rotation not active)
+	RotationWindow       preferences.TimeWindow
+	LastRotation         *preferences.WgKeysRotationRecord // nil - no rotation attempts yet
+}
+
+// WireGuardKeysHistory (request) requests the history of WG keys rotation
+// The 'WireGuardKeysHistoryResp' is sent back to client
+type WireGuardKeysHistory struct {
+	RequestBase
+}
+
+// WireGuardKeysHistoryResp (response) contains the history of WG keys rotation (the latest record is the last one)
+type WireGuardKeysHistoryResp struct {
+	CommandBase
+	Records []preferences.WgKeysRotationRecord
+}
@@ -46,12 +46,18 @@ var (
 	connectedHostIP              net.IP
 	connectedHostPort            int
 	connectedIsTCP               bool
+	connectedNextHopInterfaceIP  net.IP // local IP of the custom hop chained after the VPN server (nil - no chained hop)
 	mutex                        sync.Mutex
 	isClientPaused               bool
 	dnsConfig                    *dns.DnsSettings
 
 	// List of IP masks that are allowed for any communication
+	// (combination of 'userExceptionsCustom' and 'splitTunnelExceptions')
 	userExceptions []net.IPNet
+	// IP masks defined by user (firewall exceptions)
+	userExceptionsCustom []net.IPNet
+	// IP masks which are configured to bypass VPN tunnel (destination-based split-tunnelling)
+	splitTunnelExceptions []net.IPNet
 )
 
 // Initialize is doing initialization stuff
@@ -88,6 +94,11 @@ func SetEnabled(enable bool) error {
 			if e != nil {
 				log.Error(e)
 			}
+			if nextHopAddr := connectedNextHopInterfaceIP; nextHopAddr != nil {
+				if e := implClientNextHopConnected(nextHopAddr); e != nil {
+					log.Error(e)
+				}
+			}
 		}
 	}
 	return err
@@ -154,6 +165,23 @@ func ClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net.IP,
 	return err
 }
 
+// ClientNextHopConnected - allow communication through the interface of the custom hop chained after the VPN server
+// (must be called after ClientConnected())
+func ClientNextHopConnected(nextHopLocalIPAddress net.IP) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	log.Info("Client chained hop connected: ", nextHopLocalIPAddress)
+
+	connectedNextHopInterfaceIP = nextHopLocalIPAddress
+
+	err := implClientNextHopConnected(nextHopLocalIPAddress)
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
+
 // ClientDisconnected - Remove all hosts exceptions
 func ClientDisconnected() error {
 	mutex.Lock()
@@ -164,6 +192,7 @@ func ClientDisconnected() error {
 	if connectedClientInterfaceIP != nil {
 		connectedClientInterfaceIP = nil
 		connectedClientInterfaceIPv6 = nil
+		connectedNextHopInterfaceIP = nil
 		log.Info("Client disconnected")
 		err := implClientDisconnected()
 		if err != nil {
@@ -273,7 +302,7 @@ func OnChangeDNS(newDnsCfg *dns.DnsSettings) error {
 // Parameters:
 //	- exceptions - comma separated list of IP addresses in format: x.x.x.x[/xx]
 func SetUserExceptions(exceptions string, ignoreParseErrors bool) error {
-	userExceptions = []net.IPNet{}
+	exps := []net.IPNet{}
 
 	splitFunc := func(c rune) bool {
 		return !unicode.IsLetter(c) && !unicode.IsNumber(c) && c != rune('/') && c != rune('.') && c != rune(':')
@@ -307,8 +336,37 @@ func SetUserExceptions(exceptions string, ignoreParseErrors bool) error {
 			}
 			continue
 		}
-		userExceptions = append(userExceptions, *n)
+		exps = append(exps, *n)
 	}
 
+	userExceptionsCustom = exps
+	return updateUserExceptions()
+}
+
+// SetSplitTunnelExceptions set ip/mask to be excluded from FW block
+// because the destinations are configured to bypass the VPN tunnel (destination-based split-tunnelling)
+func SetSplitTunnelExceptions(exceptions []net.IPNet) error {
+	if len(exceptions) == len(splitTunnelExceptions) {
+		isEqual := true
+		for i := range exceptions {
+			if exceptions[i].String() != splitTunnelExceptions[i].String() {
+				isEqual = false
+				break
+			}
+		}
+		if isEqual {
+			return nil // nothing changed
+		}
+	}
+	splitTunnelExceptions = exceptions
+	return updateUserExceptions()
+}
+
+func updateUserExceptions() error {
+	exps := make([]net.IPNet, 0, len(userExceptionsCustom)+len(splitTunnelExceptions))
+	exps = append(exps, userExceptionsCustom...)
+	exps = append(exps, splitTunnelExceptions...)
+	userExceptions = exps
+
 	return implOnUserExceptionsUpdated()
 }
@@ -135,6 +135,10 @@ func implClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net
 	return removeHostsFromExceptions([]string{serverIP.String()}, isPersistent)
 }
 
+func implClientNextHopConnected(nextHopLocalIPAddress net.IP) error {
+	return fmt.Errorf("chained hop is not supported on this platform")
+}
+
 // ClientDisconnected - Disable communication for local vpn/client IP address
 func implClientDisconnected() error {
 	// remove all exceptions related to current connection (all non-persistant exceptions)
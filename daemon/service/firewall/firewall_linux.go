@@ -195,6 +195,19 @@ func implClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net
 	return removeHostsFromExceptions([]string{serverIP.String()}, false, false)
 }
 
+// implClientNextHopConnected - allow all communication through the interface of the chained hop
+func implClientNextHopConnected(nextHopLocalIPAddress net.IP) error {
+	inf, err := netinfo.InterfaceByIPAddr(nextHopLocalIPAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get local interface by IP: %w", err)
+	}
+	err = shell.Exec(nil, platform.FirewallScript(), "-connected_iface", inf.Name)
+	if err != nil {
+		return fmt.Errorf("failed to add rule for the chained hop interface: %w", err)
+	}
+	return nil
+}
+
 // ClientDisconnected - Disable communication for local vpn/client IP address
 func implClientDisconnected() error {
 	connectedVpnLocalIP = ""
@@ -372,6 +385,20 @@ func implOnUserExceptionsUpdated() error {
 //---------------------------------------------------------------------
 
 func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP bool) error {
+	hostsIPs, hostsIPv6 := splitHostsByFamily(hostsIPs)
+	if len(hostsIPv6) > 0 {
+		// IPv6 hosts (e.g. endpoint of a custom WireGuard configuration) are supported only for the non-persistent exceptions
+		if onlyForICMP || isPersistant {
+			log.Warning("IPv6 hosts are not supported for this type of firewall exceptions: ", strings.Join(hostsIPv6, ","))
+		} else {
+			ipv6List := strings.Join(hostsIPv6, ",")
+			log.Info("-add_exceptions_ipv6 ", ipv6List)
+			if err := shell.Exec(nil, platform.FirewallScript(), "-add_exceptions_ipv6", ipv6List); err != nil {
+				return err
+			}
+		}
+	}
+
 	ipList := strings.Join(hostsIPs, ",")
 
 	if len(ipList) > 0 {
@@ -395,6 +422,20 @@ func applyAddHostsToExceptions(hostsIPs []string, isPersistant bool, onlyForICMP
 }
 
 func applyRemoveHostsFromExceptions(hostsIPs []string, isPersistant bool, onlyForICMP bool) error {
+	hostsIPs, hostsIPv6 := splitHostsByFamily(hostsIPs)
+	if len(hostsIPv6) > 0 {
+		// IPv6 hosts (e.g. endpoint of a custom WireGuard configuration) are supported only for the non-persistent exceptions
+		if onlyForICMP || isPersistant {
+			log.Warning("IPv6 hosts are not supported for this type of firewall exceptions: ", strings.Join(hostsIPv6, ","))
+		} else {
+			ipv6List := strings.Join(hostsIPv6, ",")
+			log.Info("-remove_exceptions_ipv6 ", ipv6List)
+			if err := shell.Exec(nil, platform.FirewallScript(), "-remove_exceptions_ipv6", ipv6List); err != nil {
+				return err
+			}
+		}
+	}
+
 	ipList := strings.Join(hostsIPs, ",")
 
 	if len(ipList) > 0 {
@@ -417,6 +458,18 @@ func applyRemoveHostsFromExceptions(hostsIPs []string, isPersistant bool, onlyFo
 	return nil
 }
 
+// splitHostsByFamily splits the list of IP addresses to IPv4 and IPv6 addresses
+func splitHostsByFamily(IPs []string) (ipv4 []string, ipv6 []string) {
+	for _, ipStr := range IPs {
+		if ip := net.ParseIP(ipStr); ip != nil && ip.To4() == nil {
+			ipv6 = append(ipv6, ipStr)
+		} else {
+			ipv4 = append(ipv4, ipStr)
+		}
+	}
+	return ipv4, ipv6
+}
+
 func reApplyExceptions() error {
 
 	// Allow LAN communication (if necessary)
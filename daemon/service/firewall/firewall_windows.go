@@ -152,6 +152,10 @@ func implClientConnected(clientLocalIPAddress net.IP, clientLocalIPv6Address net
 	return doAddClientIPFilters(clientLocalIPAddress, clientLocalIPv6Address)
 }
 
+func implClientNextHopConnected(nextHopLocalIPAddress net.IP) error {
+	return fmt.Errorf("chained hop is not supported on this platform")
+}
+
 // ClientDisconnected - Disable communication for local vpn/client IP address
 func implClientDisconnected() (retErr error) {
 	// start / commit transaction
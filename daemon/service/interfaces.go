@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ivpn/desktop-app/daemon/api/types"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/service/wgkeys"
 )
@@ -42,6 +43,8 @@ type IServersUpdater interface {
 	GetServersForceUpdate() (*types.ServersInfoResponse, error)
 	// UpdateNotifierChannel returns channel which is notifying when servers was updated
 	UpdateNotifierChannel() chan struct{}
+	// LastUpdated returns the time when the servers list was updated (or confirmed as not modified) last time
+	LastUpdated() time.Time
 }
 
 // INetChangeDetector - object is detecting routing changes on a PC
@@ -61,6 +64,7 @@ type IWgKeysManager interface {
 	StopKeysRotation()
 	GenerateKeys() error
 	UpdateKeysIfNecessary() (isUpdated bool, retErr error)
+	NextRotation() time.Time
 }
 
 // IServiceEventsReceiver is the receiver for service events (normally, it is protocol object)
@@ -69,7 +73,9 @@ type IServiceEventsReceiver interface {
 	OnAccountStatus(sessionToken string, account preferences.AccountStatus)
 	OnKillSwitchStateChanged()
 	OnWiFiChanged(ssid string, isInsecureNetwork bool)
-	OnPingStatus(retMap map[string]int)
+	OnPingStatus(retMap map[string]protocolTypes.PingResultType)
 	OnServersUpdated(*types.ServersInfoResponse)
+	OnServersChanged(diff protocolTypes.ServersListDiff)
+	OnOfflineStatusChanged(status protocolTypes.OfflineStatus)
 	OnSplitTunnelStatusChanged()
 }
@@ -0,0 +1,203 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pinghistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/logger"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("pnghst")
+}
+
+const (
+	// MaxSamplesPerHost - max number of samples stored for each host (the oldest samples are removed first)
+	MaxSamplesPerHost = 48
+	// MaxSampleAge - samples older than this are removed from the history
+	MaxSampleAge = 7 * 24 * time.Hour
+
+	// StableScoreSamples - the number of the latest samples in use to calculate the stable score
+	StableScoreSamples = 5
+	// StableScoreMaxAge - samples older than this are not in use to calculate the stable score
+	StableScoreMaxAge = 24 * time.Hour
+)
+
+// Sample - the result of a single ping measurement of the host
+type Sample struct {
+	Time       int64   // unix time of the measurement
+	Ping       int     // average round-trip time (ms)
+	Jitter     float64 // standard deviation of the round-trip time (ms)
+	PacketLoss float64 // percentage of lost packets [0-100]
+	Score      float64 // ranking score (lower - better)
+	Probe      string  // the way the host reachability was checked (e.g. "icmp", "wg-handshake" ...)
+	Path       string  // the network path the host was measured on ("direct" or "tunnel")
+}
+
+// IsFailed returns true for the failed attempt (the host did not reply): there is no latency info in this case
+func (s Sample) IsFailed() bool {
+	return s.PacketLoss >= 100
+}
+
+// History - persistent rolling history of ping results for each host
+type History struct {
+	mutex      sync.Mutex
+	file       string
+	hosts      map[string][]Sample // [host IP]samples (the latest sample is the last one)
+	isModified bool
+}
+
+// Load creates history object and loads the data from the file (if exists)
+func Load(file string) *History {
+	h := &History{file: file, hosts: make(map[string][]Sample)}
+	if len(file) == 0 {
+		return h
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning(fmt.Sprintf("failed to read ping history: %s", err))
+		}
+		return h
+	}
+	if err := json.Unmarshal(data, &h.hosts); err != nil {
+		log.Warning(fmt.Sprintf("failed to parse ping history: %s", err))
+		h.hosts = make(map[string][]Sample)
+		return h
+	}
+	h.removeOutdated(time.Now())
+	return h
+}
+
+// Add appends the sample to the history of the host
+func (h *History) Add(host string, s Sample) {
+	if h == nil || len(host) == 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	samples := append(h.hosts[host], s)
+	if len(samples) > MaxSamplesPerHost {
+		samples = samples[len(samples)-MaxSamplesPerHost:]
+	}
+	h.hosts[host] = samples
+	h.isModified = true
+}
+
+// Samples returns the history of the host since the specified time (the latest sample is the last one)
+func (h *History) Samples(host string, since time.Time) []Sample {
+	if h == nil {
+		return nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ret := make([]Sample, 0, len(h.hosts[host]))
+	for _, s := range h.hosts[host] {
+		if s.Time >= since.Unix() {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// StableScore returns the median score of the latest measurements of the host on the specified network path
+// (the failed attempts are taken into account: their score is worse than the score of any replied host)
+// (only StableScoreSamples latest samples which are not older than StableScoreMaxAge are in use).
+// The median is not affected by occasional spikes, so the servers ranking does not flip from run to run.
+// Returns the number of samples in use (0 - no history for the host).
+func (h *History) StableScore(host string, path string, now time.Time) (score float64, samplesCnt int) {
+	if h == nil {
+		return 0, 0
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	scores := make([]float64, 0, StableScoreSamples)
+	minTime := now.Add(-StableScoreMaxAge).Unix()
+	samples := h.hosts[host]
+	for i := len(samples) - 1; i >= 0 && len(scores) < StableScoreSamples; i-- {
+		if samples[i].Time < minTime {
+			break
+		}
+		if samples[i].Path == path {
+			scores = append(scores, samples[i].Score)
+		}
+	}
+
+	if len(scores) == 0 {
+		return 0, 0
+	}
+	sort.Float64s(scores)
+	if len(scores)%2 == 1 {
+		return scores[len(scores)/2], len(scores)
+	}
+	return (scores[len(scores)/2-1] + scores[len(scores)/2]) / 2, len(scores)
+}
+
+// Save writes the history to the file (if it was modified)
+func (h *History) Save() error {
+	if h == nil || len(h.file) == 0 {
+		return nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.isModified {
+		return nil
+	}
+	h.removeOutdated(time.Now())
+
+	data, err := json.Marshal(h.hosts)
+	if err != nil {
+		return fmt.Errorf("failed to save ping history (json marshal error): %w", err)
+	}
+	if err := helpers.WriteFile(h.file, data, 0600); err != nil {
+		return fmt.Errorf("failed to save ping history: %w", err)
+	}
+	h.isModified = false
+	return nil
+}
+
+func (h *History) removeOutdated(now time.Time) {
+	minTime := now.Add(-MaxSampleAge).Unix()
+	for host, samples := range h.hosts {
+		idx := sort.Search(len(samples), func(i int) bool { return samples[i].Time >= minTime })
+		if idx >= len(samples) {
+			delete(h.hosts, host)
+		} else if idx > 0 {
+			h.hosts[host] = samples[idx:]
+		}
+	}
+}
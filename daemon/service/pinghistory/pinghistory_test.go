@@ -0,0 +1,96 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pinghistory
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStableScore(t *testing.T) {
+	h := Load("")
+	now := time.Now()
+
+	// a single spike must not affect the stable score
+	for i, score := range []float64{20, 22, 300, 21, 19} {
+		h.Add("1.1.1.1", Sample{Time: now.Add(time.Duration(i-5) * time.Minute).Unix(), Score: score, Path: "direct"})
+	}
+	// samples measured on other path are ignored
+	h.Add("1.1.1.1", Sample{Time: now.Unix(), Score: 500, Path: "tunnel"})
+
+	score, cnt := h.StableScore("1.1.1.1", "direct", now)
+	if cnt != 5 || score != 21 {
+		t.Errorf("unexpected stable score: %v (samples %d)", score, cnt)
+	}
+
+	// failed attempts worsen the stable score of the host
+	for i := 0; i < 3; i++ {
+		h.Add("3.3.3.3", Sample{Time: now.Add(time.Duration(i-5) * time.Minute).Unix(), Ping: 20, Score: 20, Path: "direct"})
+		h.Add("3.3.3.3", Sample{Time: now.Add(time.Duration(i-4) * time.Minute).Unix(), PacketLoss: 100, Score: 1500, Path: "direct"})
+	}
+	if score, _ := h.StableScore("3.3.3.3", "direct", now); score != 1500 {
+		t.Errorf("unexpected stable score of the unreachable host: %v", score)
+	}
+	if samples := h.Samples("3.3.3.3", time.Time{}); !samples[len(samples)-1].IsFailed() || samples[0].IsFailed() {
+		t.Error("unexpected failed attempt flag")
+	}
+
+	if _, cnt := h.StableScore("2.2.2.2", "direct", now); cnt != 0 {
+		t.Error("no samples expected for unknown host")
+	}
+
+	// outdated samples are not in use
+	if _, cnt := h.StableScore("1.1.1.1", "direct", now.Add(StableScoreMaxAge*2)); cnt != 0 {
+		t.Error("outdated samples must be ignored")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ping_history.json")
+	now := time.Now()
+
+	h := Load(file)
+	for i := 0; i < MaxSamplesPerHost+10; i++ {
+		h.Add("1.1.1.1", Sample{Time: now.Unix() + int64(i), Ping: i})
+	}
+	h.Add("2.2.2.2", Sample{Time: now.Add(-MaxSampleAge * 2).Unix()})
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := Load(file)
+	samples := loaded.Samples("1.1.1.1", time.Time{})
+	if len(samples) != MaxSamplesPerHost {
+		t.Fatalf("expected %d samples, got %d", MaxSamplesPerHost, len(samples))
+	}
+	if samples[len(samples)-1].Ping != MaxSamplesPerHost+9 {
+		t.Error("the latest sample expected to be the last one")
+	}
+	if len(loaded.Samples("2.2.2.2", time.Time{})) != 0 {
+		t.Error("outdated samples must be removed")
+	}
+	if len(loaded.Samples("1.1.1.1", now.Add(time.Second*time.Duration(MaxSamplesPerHost+5)))) != 5 {
+		t.Error("unexpected number of samples since the time")
+	}
+}
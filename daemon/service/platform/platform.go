@@ -260,6 +260,11 @@ func ServersFile() string {
 	return serversFile
 }
 
+// PingHistoryFile path to the file with the history of servers ping results (stored next to servers.json)
+func PingHistoryFile() string {
+	return filepath.Join(filepath.Dir(serversFile), "ping_history.json")
+}
+
 // LogFile path to log-file
 func LogFile() string {
 	return logFile
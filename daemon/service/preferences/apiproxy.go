@@ -0,0 +1,80 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Proxy types for daemon API traffic
+const (
+	ApiProxyHTTP   = "http"
+	ApiProxySocks5 = "socks5"
+)
+
+// ApiProxy - proxy server for the daemon API traffic (login, servers list download, WireGuard keys rotation ...)
+type ApiProxy struct {
+	Type     string // ApiProxyHTTP or ApiProxySocks5 (empty - proxy not in use)
+	Address  string // host name or IP address
+	Port     int
+	Username string // (optional)
+	Password string // (optional; never exposed to clients)
+}
+
+// IsEnabled returns true if the proxy is defined
+func (p ApiProxy) IsEnabled() bool {
+	return len(p.Type) > 0
+}
+
+// HostPort returns the proxy address in "host:port" format
+func (p ApiProxy) HostPort() string {
+	return net.JoinHostPort(p.Address, strconv.Itoa(p.Port))
+}
+
+// Validate checks the proxy parameters
+func (p ApiProxy) Validate() error {
+	if !p.IsEnabled() {
+		return nil
+	}
+	if p.Type != ApiProxyHTTP && p.Type != ApiProxySocks5 {
+		return fmt.Errorf("unsupported proxy type '%s' (expected '%s' or '%s')", p.Type, ApiProxyHTTP, ApiProxySocks5)
+	}
+	if len(p.Address) == 0 {
+		return fmt.Errorf("proxy address not defined")
+	}
+	if p.Port <= 0 || p.Port > 65535 {
+		return fmt.Errorf("bad proxy port %d", p.Port)
+	}
+	if len(p.Username) == 0 && len(p.Password) > 0 {
+		return fmt.Errorf("proxy username not defined")
+	}
+	return nil
+}
+
+// Info returns the proxy parameters which can be exposed to clients (without password)
+func (p ApiProxy) Info() ApiProxy {
+	p.Password = ""
+	return p
+}
@@ -0,0 +1,50 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import "fmt"
+
+// HomeLocation - geographical location defined manually by user.
+// It is in use (instead of the location detected by geo-lookup) to calculate the distance to servers:
+// e.g. when the geo-lookup request is blocked or the detected location is not accurate.
+// Zero coordinates - the home location is not defined.
+type HomeLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// IsDefined returns 'true' when the home location is defined
+func (l HomeLocation) IsDefined() bool {
+	return l != HomeLocation{}
+}
+
+// Validate checks the coordinates values
+func (l HomeLocation) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 {
+		return fmt.Errorf("bad latitude value %v (expected range: [-90, 90])", l.Latitude)
+	}
+	if l.Longitude < -180 || l.Longitude > 180 {
+		return fmt.Errorf("bad longitude value %v (expected range: [-180, 180])", l.Longitude)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import "testing"
+
+func TestHomeLocation(t *testing.T) {
+	if (HomeLocation{}).IsDefined() {
+		t.Fatal("zero location must be not defined")
+	}
+	if !(HomeLocation{Latitude: 46.2}).IsDefined() {
+		t.Fatal("location must be defined")
+	}
+
+	valid := []HomeLocation{{}, {Latitude: 46.2, Longitude: 6.14}, {Latitude: -90, Longitude: 180}, {Latitude: 90, Longitude: -180}}
+	for _, l := range valid {
+		if err := l.Validate(); err != nil {
+			t.Errorf("%v: unexpected error: %v", l, err)
+		}
+	}
+
+	invalid := []HomeLocation{{Latitude: 90.1}, {Latitude: -91}, {Longitude: 180.5}, {Longitude: -181}}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("%v: error expected", l)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+// CustomOvpnProfile - user-supplied OpenVPN profile (imported .ovpn file)
+// Note: the secrets (the profile keys and the credentials) are protected by the permissions of the preferences file
+// (read/write only for privileged user), the same way as the account WireGuard keys and the custom WireGuard configurations
+type CustomOvpnProfile struct {
+	ID       string
+	Name     string
+	Profile  string // original profile text (contains private keys; must never be sent to clients)
+	Username string `json:",omitempty"` // credentials for 'auth-user-pass' authentication
+	Password string `json:",omitempty"`
+}
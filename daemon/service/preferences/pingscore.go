@@ -0,0 +1,78 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import "fmt"
+
+// PingScoreWeights - weights of the servers ranking score components:
+//
+//	score = Latency*avgRtt(ms) + Jitter*jitter(ms) + Loss*packetLoss(%) + Load*serverLoad(%)
+//
+// The lower score - the better server.
+// All weights are zero - the default weights are in use (see DefaultPingScoreWeights()).
+type PingScoreWeights struct {
+	Latency float64
+	Jitter  float64
+	Loss    float64
+	Load    float64
+}
+
+// DefaultPingScoreWeights returns default weights of the servers ranking score
+func DefaultPingScoreWeights() PingScoreWeights {
+	return PingScoreWeights{Latency: 1, Jitter: 1, Loss: 5, Load: 1}
+}
+
+// IsDefined returns 'true' when the weights are defined (otherwise, default weights have to be used)
+func (w PingScoreWeights) IsDefined() bool {
+	return w != PingScoreWeights{}
+}
+
+// Validate checks the weights values
+func (w PingScoreWeights) Validate() error {
+	if w.Latency < 0 || w.Jitter < 0 || w.Loss < 0 || w.Load < 0 {
+		return fmt.Errorf("ping score weights can not be negative")
+	}
+	return nil
+}
+
+// Score calculates the ranking score (lower - better)
+//
+//	avgRttMs, jitterMs - average round-trip time and its standard deviation (milliseconds)
+//	lossPercent - percentage of lost packets [0-100]
+//	loadPercent - server load [0-100]
+func (w PingScoreWeights) Score(avgRttMs, jitterMs, lossPercent, loadPercent float64) float64 {
+	if !w.IsDefined() {
+		w = DefaultPingScoreWeights()
+	}
+	return w.Latency*avgRttMs + w.Jitter*jitterMs + w.Loss*lossPercent + w.Load*loadPercent
+}
+
+// ScoreUnreachable calculates the ranking score of the host which did not reply within the timeout
+// (it is never better than the score of any host which replied within the same timeout)
+//
+//	timeoutMs - the time the reply was waited for (milliseconds)
+//	loadPercent - server load [0-100]
+func (w PingScoreWeights) ScoreUnreachable(timeoutMs, loadPercent float64) float64 {
+	// the round-trip time and its deviation of the replied host never exceed the timeout
+	return w.Score(timeoutMs, timeoutMs, 100, loadPercent)
+}
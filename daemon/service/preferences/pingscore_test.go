@@ -0,0 +1,53 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import "testing"
+
+func TestPingScoreWeights(t *testing.T) {
+	def := PingScoreWeights{}
+	if def.IsDefined() {
+		t.Fatal("zero weights must be not defined")
+	}
+
+	// lossy host must be ranked lower than a bit slower but stable host
+	lossy := def.Score(20, 1, 33.3, 10)
+	stable := def.Score(40, 2, 0, 10)
+	if stable >= lossy {
+		t.Errorf("stable host expected to have better score: stable=%v lossy=%v", stable, lossy)
+	}
+
+	latencyOnly := PingScoreWeights{Latency: 1}
+	if s := latencyOnly.Score(20, 5, 50, 90); s != 20 {
+		t.Errorf("unexpected score %v (expected 20)", s)
+	}
+
+	// unreachable host must be ranked lower than any host replied within the timeout
+	if unreachable, slow := def.ScoreUnreachable(1000, 10), def.Score(999, 499, 99, 10); unreachable <= slow {
+		t.Errorf("unreachable host expected to have worse score: unreachable=%v slow=%v", unreachable, slow)
+	}
+
+	if err := (PingScoreWeights{Loss: -1}).Validate(); err == nil {
+		t.Error("negative weight must be rejected")
+	}
+}
@@ -53,6 +53,9 @@ type LinuxSpecificUserPrefs struct {
 	// If true - use old style DNS management mechanism
 	// by direct modifying file '/etc/resolv.conf'
 	IsDnsMgmtOldStyle bool
+	// If true - use embedded userspace WireGuard implementation even if WireGuard kernel module is available
+	// (by default, userspace implementation is in use only when the kernel module is not available)
+	IsWgUserspace bool
 }
 
 // UserPreferences - IVPN service preferences which can be exposed to client
@@ -60,6 +63,28 @@ type UserPreferences struct {
 	// NOTE: update this type when adding new preferenvces which can be exposed for clients
 	// ...
 
+	// WireGuard: time (seconds) the tunnel may stay stalled (no handshake or no incoming traffic) before reconnection
+	// (0 - use default value)
+	WgStallTimeoutSec int
+
+	// WireGuard: negotiate post-quantum preshared key (hybrid KEM) for each connection to IVPN servers
+	WgQuantumResistance bool
+	// WireGuard: interval (seconds) of the post-quantum preshared key rotation while connected
+	// (0 - the key is negotiated only on connection)
+	WgPskRotationIntervalSec int
+
+	// Weights of the servers ranking score (combination of latency, jitter, packet loss and server load)
+	// (all zero - default weights)
+	PingScoreWeights PingScoreWeights
+
+	// Periodically ping all servers in background to keep the latency history up to date (disabled by default)
+	// (ICMP only; skipped when logged out or when the firewall is enabled while VPN is disconnected)
+	PingHistoryBackgroundCollection bool
+
+	// Manually defined home location: in use to calculate distance to servers instead of the geo-lookup result
+	// (zero coordinates - not defined)
+	HomeLocation HomeLocation
+
 	// The platform-specific preferences
 	Linux LinuxSpecificUserPrefs
 }
@@ -82,6 +107,27 @@ type Preferences struct {
 	// split-tunnelling
 	IsSplitTunnel   bool
 	SplitTunnelApps []string
+	// destination-based split-tunnelling: list of IP addresses, masks (x.x.x.x/xx) or domain names
+	SplitTunnelBypassDests []string // destinations which always bypass the VPN tunnel
+	SplitTunnelVpnDests    []string // destinations which always use the VPN tunnel
+
+	// imported third-party WireGuard servers
+	CustomWgServers []CustomWgServer
+	// imported OpenVPN profiles
+	CustomOvpnProfiles []CustomOvpnProfile
+
+	// proxy server for the daemon API traffic (the proxy password is not exposed to clients)
+	ApiProxy ApiProxy
+
+	// favorite servers
+	ServersFavorites ServersList
+	// excluded servers: never in use for connection (including automatic server selection and Multi-Hop exit server)
+	ServersExcluded ServersList
+
+	// WireGuard keys rotation: local time window when automatic rotation is allowed
+	WgKeysRotationWindow TimeWindow
+	// WireGuard keys rotation history (the latest record is the last one)
+	WgKeysHistory []WgKeysRotationRecord
 
 	// last known account status
 	Session SessionStatus
@@ -0,0 +1,98 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"strings"
+)
+
+// ServersList - list of servers (locations), hosts and countries (in use for favorite and excluded servers)
+type ServersList struct {
+	Servers   []string // server location IDs: the first part of the gateway name (e.g. "ch" for "ch.wg.ivpn.net" and "ch.gw.ivpn.net")
+	Hosts     []string // host names (e.g. "ch1.wg.ivpn.net")
+	Countries []string // country codes (e.g. "CH")
+}
+
+// ServerLocationID returns the server location ID for the gateway name ("ch.wg.ivpn.net" -> "ch").
+// The ID is the same for WireGuard and OpenVPN servers of the location
+func ServerLocationID(gateway string) string {
+	return strings.ToLower(strings.Split(strings.TrimSpace(gateway), ".")[0])
+}
+
+// IsEmpty returns true if the list does not contain any element
+func (l ServersList) IsEmpty() bool {
+	return len(l.Servers) == 0 && len(l.Hosts) == 0 && len(l.Countries) == 0
+}
+
+// IsServerMatch returns true if the server (location) is in the list (by location or by country).
+// Note: the hosts of the server are not taken into account
+func (l ServersList) IsServerMatch(gateway, countryCode string) bool {
+	return containsFold(l.Servers, ServerLocationID(gateway)) || containsFold(l.Countries, countryCode)
+}
+
+// IsHostMatch returns true if the host is in the list (by host name, by server location or by country)
+func (l ServersList) IsHostMatch(gateway, countryCode, hostname string) bool {
+	return l.IsServerMatch(gateway, countryCode) || containsFold(l.Hosts, hostname)
+}
+
+// Normalized returns the copy of the list: values are trimmed and converted to the canonical case, duplicates and empty values are removed
+func (l ServersList) Normalized() ServersList {
+	normalize := func(values []string, toUpper bool) []string {
+		var ret []string
+		for _, v := range values {
+			v = strings.TrimSpace(v)
+			if toUpper {
+				v = strings.ToUpper(v)
+			} else {
+				v = strings.ToLower(v)
+			}
+			if len(v) > 0 && !containsFold(ret, v) {
+				ret = append(ret, v)
+			}
+		}
+		return ret
+	}
+
+	servers := make([]string, 0, len(l.Servers))
+	for _, s := range l.Servers {
+		servers = append(servers, ServerLocationID(s))
+	}
+
+	return ServersList{
+		Servers:   normalize(servers, false),
+		Hosts:     normalize(l.Hosts, false),
+		Countries: normalize(l.Countries, true),
+	}
+}
+
+func containsFold(values []string, v string) bool {
+	if len(v) == 0 {
+		return false
+	}
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
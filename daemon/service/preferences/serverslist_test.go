@@ -0,0 +1,50 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import "testing"
+
+func TestServersList(t *testing.T) {
+	l := ServersList{
+		Servers:   []string{" CH.wg.ivpn.net", "ch"},
+		Hosts:     []string{"SE1.wg.ivpn.net"},
+		Countries: []string{"us", ""},
+	}.Normalized()
+
+	if len(l.Servers) != 1 || l.Servers[0] != "ch" || len(l.Countries) != 1 || l.Countries[0] != "US" {
+		t.Fatalf("unexpected normalized list: %+v", l)
+	}
+
+	if !l.IsServerMatch("ch.gw.ivpn.net", "CH") || !l.IsServerMatch("us-ny.wg.ivpn.net", "US") {
+		t.Error("server expected to match (by location or by country)")
+	}
+	if l.IsServerMatch("se.wg.ivpn.net", "SE") {
+		t.Error("server must not match by its host")
+	}
+	if !l.IsHostMatch("se.wg.ivpn.net", "SE", "se1.wg.ivpn.net") || l.IsHostMatch("se.wg.ivpn.net", "SE", "se2.wg.ivpn.net") {
+		t.Error("unexpected host match result")
+	}
+	if (ServersList{}).IsHostMatch("", "", "") {
+		t.Error("empty list must not match")
+	}
+}
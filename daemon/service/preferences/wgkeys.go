@@ -0,0 +1,101 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxWgKeysHistoryRecords - max number of WireGuard keys rotation records to keep
+const MaxWgKeysHistoryRecords = 50
+
+// The reason of WireGuard keys rotation
+const (
+	WgKeysRotationScheduled = "scheduled" // automatic rotation (rotation interval elapsed)
+	WgKeysRotationOnDemand  = "on-demand" // keys updated because they are outdated (e.g. before connection)
+	WgKeysRotationManual    = "manual"    // rotation requested by the user
+)
+
+// WgKeysRotationRecord - information about WireGuard keys rotation attempt
+type WgKeysRotationRecord struct {
+	Time              time.Time
+	Trigger           string // WgKeysRotationScheduled, WgKeysRotationOnDemand or WgKeysRotationManual
+	OldKeyFingerprint string `json:",omitempty"` // fingerprint of the public key before rotation (empty - no key)
+	NewKeyFingerprint string `json:",omitempty"` // fingerprint of the new public key (empty - rotation failed)
+	Success           bool
+	Error             string `json:",omitempty"`
+}
+
+// TimeWindow - daily time window (local time; minutes since midnight)
+// The window can pass midnight (e.g. 22:00-06:00).
+// StartMinute == EndMinute - the window is not defined (any time allowed)
+type TimeWindow struct {
+	StartMinute int
+	EndMinute   int
+}
+
+// IsDefined returns 'true' when the window restricts the time
+func (w TimeWindow) IsDefined() bool {
+	return w.StartMinute != w.EndMinute
+}
+
+// Validate checks the window boundaries
+func (w TimeWindow) Validate() error {
+	const minutesInDay = 24 * 60
+	if w.StartMinute < 0 || w.StartMinute >= minutesInDay || w.EndMinute < 0 || w.EndMinute >= minutesInDay {
+		return fmt.Errorf("bad time window (the time must be in range 00:00-23:59)")
+	}
+	return nil
+}
+
+// Contains returns 'true' when the time is inside the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	if !w.IsDefined() {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if w.StartMinute < w.EndMinute {
+		return m >= w.StartMinute && m < w.EndMinute
+	}
+	return m >= w.StartMinute || m < w.EndMinute
+}
+
+// NextAllowed returns the earliest time (not before 't') which is inside the window
+func (w TimeWindow) NextAllowed(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.StartMinute/60, w.StartMinute%60, 0, 0, t.Location())
+	if !start.After(t) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start
+}
+
+func (w TimeWindow) String() string {
+	if !w.IsDefined() {
+		return "any time"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.StartMinute/60, w.StartMinute%60, w.EndMinute/60, w.EndMinute%60)
+}
@@ -0,0 +1,60 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package preferences
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2022, 5, 10, h, m, 0, 0, time.Local) }
+
+	tests := []struct {
+		window   TimeWindow
+		t        time.Time
+		contains bool
+		next     time.Time
+	}{
+		{TimeWindow{}, at(3, 0), true, at(3, 0)},
+		{TimeWindow{StartMinute: 9 * 60, EndMinute: 18 * 60}, at(3, 0), false, at(9, 0)},
+		{TimeWindow{StartMinute: 9 * 60, EndMinute: 18 * 60}, at(9, 0), true, at(9, 0)},
+		{TimeWindow{StartMinute: 9 * 60, EndMinute: 18 * 60}, at(18, 0), false, at(9, 0).AddDate(0, 0, 1)},
+		{TimeWindow{StartMinute: 22 * 60, EndMinute: 6 * 60}, at(23, 30), true, at(23, 30)},
+		{TimeWindow{StartMinute: 22 * 60, EndMinute: 6 * 60}, at(5, 59), true, at(5, 59)},
+		{TimeWindow{StartMinute: 22 * 60, EndMinute: 6 * 60}, at(12, 0), false, at(22, 0)},
+	}
+
+	for _, test := range tests {
+		if c := test.window.Contains(test.t); c != test.contains {
+			t.Errorf("%v contains %v: expected %v", test.window, test.t.Format("15:04"), test.contains)
+		}
+		if n := test.window.NextAllowed(test.t); !n.Equal(test.next) {
+			t.Errorf("%v next allowed for %v: expected %v; got %v", test.window, test.t, test.next, n)
+		}
+	}
+
+	if err := (TimeWindow{StartMinute: 24 * 60}).Validate(); err == nil {
+		t.Error("bad window not detected")
+	}
+}
@@ -0,0 +1,97 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// serversListDiff returns the difference between the previous and the updated servers list.
+// The hosts are identified by the VPN type and the host name.
+// Returns empty diff if one of the lists is not defined.
+func serversListDiff(oldSvrs, newSvrs *types.ServersInfoResponse) protocolTypes.ServersListDiff {
+	var diff protocolTypes.ServersListDiff
+	if oldSvrs == nil || newSvrs == nil || oldSvrs == newSvrs {
+		return diff
+	}
+
+	type hostKey struct {
+		vpnType  vpn.Type
+		hostname string
+	}
+
+	collect := func(svrs *types.ServersInfoResponse) ([]hostKey, map[hostKey]protocolTypes.ServerHostChange) {
+		keys := make([]hostKey, 0)
+		hosts := make(map[hostKey]protocolTypes.ServerHostChange)
+		for _, s := range svrs.WireguardServers {
+			for _, h := range s.Hosts {
+				k := hostKey{vpn.WireGuard, h.Hostname}
+				keys = append(keys, k)
+				hosts[k] = protocolTypes.ServerHostChange{VpnType: vpn.WireGuard, Gateway: s.Gateway, Hostname: h.Hostname, Host: h.Host, NewPublicKey: h.PublicKey}
+			}
+		}
+		for _, s := range svrs.OpenvpnServers {
+			for _, h := range s.Hosts {
+				k := hostKey{vpn.OpenVPN, h.Hostname}
+				keys = append(keys, k)
+				hosts[k] = protocolTypes.ServerHostChange{VpnType: vpn.OpenVPN, Gateway: s.Gateway, Hostname: h.Hostname, Host: h.Host}
+			}
+		}
+		return keys, hosts
+	}
+
+	oldKeys, oldHosts := collect(oldSvrs)
+	newKeys, newHosts := collect(newSvrs)
+
+	for _, k := range newKeys {
+		newHost := newHosts[k]
+		oldHost, ok := oldHosts[k]
+		if !ok {
+			newHost.NewPublicKey = ""
+			diff.Added = append(diff.Added, newHost)
+			continue
+		}
+		if k.vpnType == vpn.WireGuard && oldHost.NewPublicKey != newHost.NewPublicKey {
+			newHost.OldPublicKey = oldHost.NewPublicKey
+			diff.KeysChanged = append(diff.KeysChanged, newHost)
+			log.Warning(fmt.Sprintf("WireGuard public key of the host '%s' was changed", k.hostname))
+		}
+	}
+	for _, k := range oldKeys {
+		if _, ok := newHosts[k]; !ok {
+			oldHost := oldHosts[k]
+			oldHost.NewPublicKey = ""
+			diff.Removed = append(diff.Removed, oldHost)
+		}
+	}
+
+	if !diff.IsEmpty() {
+		log.Info(fmt.Sprintf("Servers list changed: %d hosts added; %d hosts removed; %d WireGuard keys changed",
+			len(diff.Added), len(diff.Removed), len(diff.KeysChanged)))
+	}
+	return diff
+}
@@ -38,6 +38,8 @@ import (
 
 type serversUpdater struct {
 	servers           *types.ServersInfoResponse
+	cacheInfo         api.ServersListCacheInfo // validators of the downloaded servers list (for conditional requests)
+	lastUpdated       time.Time                // the last time the servers list was updated (or confirmed as not modified)
 	api               *api.API
 	updatedNotifyChan chan struct{}
 }
@@ -85,6 +87,9 @@ func (s *serversUpdater) GetServers() (*types.ServersInfoResponse, error) {
 
 	if servers != nil && err == nil {
 		s.servers = servers
+		if fi, err := os.Stat(platform.ServersFile()); err == nil {
+			s.lastUpdated = fi.ModTime()
+		}
 		return servers, nil
 	}
 
@@ -121,11 +126,27 @@ func (s *serversUpdater) startUpdater() error {
 }
 
 // UpdateServers - download servers list
+// The list is downloaded only if it was modified since the last download (conditional request);
+// the signature of the downloaded list is verified.
 func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
-	servers, err := s.api.DownloadServersList()
+	cacheInfo := s.cacheInfo
+	if s.servers == nil {
+		cacheInfo = api.ServersListCacheInfo{}
+	}
+
+	servers, newCacheInfo, isNotModified, err := s.api.DownloadServersListIfModified(cacheInfo)
 	if err != nil {
 		return servers, fmt.Errorf("failed to download servers list: %w", err)
 	}
+	if isNotModified {
+		log.Info("Servers info is up to date (not modified)")
+		s.lastUpdated = time.Now()
+		// update modification time of the cache file (it is the time of the last update after the daemon restart)
+		if err := os.Chtimes(platform.ServersFile(), s.lastUpdated, s.lastUpdated); err != nil {
+			log.Warning("failed to update modification time of servers cache file: ", err)
+		}
+		return s.servers, nil
+	}
 
 	if len(servers.Config.Ports.OpenVPN) <= 0 {
 		return servers, fmt.Errorf("no ports info for OpenVPN in servers.json; skipping received data from backend")
@@ -137,6 +158,8 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 	log.Info(fmt.Sprintf("Updated servers info (%d OpenVPN; %d WireGuard)\n", len(servers.OpenvpnServers), len(servers.WireguardServers)))
 
 	s.servers = servers
+	s.cacheInfo = newCacheInfo
+	s.lastUpdated = time.Now()
 	if err := writeServersToCache(servers); err != nil {
 		log.Error("failed to save servers cache file: ", err)
 	}
@@ -151,6 +174,11 @@ func (s *serversUpdater) updateServers() (*types.ServersInfoResponse, error) {
 	return servers, nil
 }
 
+// LastUpdated returns the time when the servers list was updated (or confirmed as not modified) last time
+func (s *serversUpdater) LastUpdated() time.Time {
+	return s.lastUpdated
+}
+
 // UpdateNotifierChannel returns channel which is notifying when servers was updated
 func (s *serversUpdater) UpdateNotifierChannel() chan struct{} {
 	return s.updatedNotifyChan
@@ -0,0 +1,150 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package serversquery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// Location - geographical coordinates
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Host - the server host with the information about its location
+type Host struct {
+	VpnType     vpn.Type
+	Gateway     string
+	CountryCode string
+	Country     string
+	City        string
+	Latitude    float32
+	Longitude   float32
+
+	Hostname string
+	Host     string  // IP address
+	Load     float32 // (%)
+	IPv6     bool    // IPv6 is supported inside the tunnel
+
+	IsFavorite bool
+	IsExcluded bool
+
+	PingMs     int     // the latest measured latency (0 - unknown)
+	DistanceKm float64 // distance from the current location (negative - unknown)
+}
+
+// HostsFromServers returns the list of all hosts from the servers list.
+// 'origin' - the current location to calculate distance to the hosts (nil - distance unknown)
+func HostsFromServers(servers *types.ServersInfoResponse, origin *Location) []Host {
+	if servers == nil {
+		return nil
+	}
+
+	distance := func(lat, lon float32) float64 {
+		if origin == nil {
+			return -1
+		}
+		return helpers.GetDistanceFromLatLonInKm(origin.Latitude, origin.Longitude, float64(lat), float64(lon))
+	}
+
+	var ret []Host
+	for _, s := range servers.WireguardServers {
+		for _, h := range s.Hosts {
+			ret = append(ret, Host{
+				VpnType: vpn.WireGuard, Gateway: s.Gateway, CountryCode: s.CountryCode, Country: s.Country, City: s.City, Latitude: s.Latitude, Longitude: s.Longitude,
+				Hostname: h.Hostname, Host: strings.TrimSpace(h.Host), Load: h.Load, IPv6: len(h.IPv6.LocalIP) > 0,
+				DistanceKm: distance(s.Latitude, s.Longitude)})
+		}
+	}
+	for _, s := range servers.OpenvpnServers {
+		for _, h := range s.Hosts {
+			ret = append(ret, Host{
+				VpnType: vpn.OpenVPN, Gateway: s.Gateway, CountryCode: s.CountryCode, Country: s.Country, City: s.City, Latitude: s.Latitude, Longitude: s.Longitude,
+				Hostname: h.Hostname, Host: strings.TrimSpace(h.Host), Load: h.Load,
+				DistanceKm: distance(s.Latitude, s.Longitude)})
+		}
+	}
+	return ret
+}
+
+// text returns the values of the text field (the condition is satisfied if any of them matches)
+func (h Host) text(field string) []string {
+	switch field {
+	case FieldCountry:
+		return []string{h.CountryCode, h.Country}
+	case FieldCountryCode:
+		return []string{h.CountryCode}
+	case FieldCity:
+		return []string{h.City}
+	case FieldGateway:
+		return []string{h.Gateway}
+	case FieldHostname:
+		return []string{h.Hostname}
+	case FieldHost:
+		return []string{h.Host}
+	case FieldProto:
+		return []string{h.VpnType.String()}
+	}
+	return nil
+}
+
+// number returns the value of the numeric field ('false' - the value is unknown)
+func (h Host) number(field string) (float64, bool) {
+	switch field {
+	case FieldLoad:
+		return float64(h.Load), true
+	case FieldPing:
+		return float64(h.PingMs), h.PingMs > 0
+	case FieldDistance:
+		return h.DistanceKm, h.DistanceKm >= 0
+	}
+	return 0, false
+}
+
+func (h Host) boolean(field string) bool {
+	switch field {
+	case FieldIPv6:
+		return h.IPv6
+	case FieldFavorite:
+		return h.IsFavorite
+	case FieldExcluded:
+		return h.IsExcluded
+	}
+	return false
+}
+
+func parseProto(proto string) (string, error) {
+	switch strings.ToLower(proto) {
+	case "wg", "wireguard":
+		return vpn.WireGuard.String(), nil
+	case "ovpn", "openvpn":
+		return vpn.OpenVPN.String(), nil
+	}
+	return "", fmt.Errorf("unknown protocol '%s' (expected: wg, wireguard, ovpn or openvpn)", proto)
+}
@@ -0,0 +1,384 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package serversquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString   // quoted string
+	tokenOperator // = != < <= > >= ~
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // position in the query text (for error messages)
+}
+
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	isWordChar := func(c byte) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte("_.-:", c) >= 0
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(text[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("query syntax error at position %d: unterminated string", i+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case strings.IndexByte("=!<>~", c) >= 0:
+			op := string(c)
+			if i+1 < len(text) && text[i+1] == '=' && c != '=' && c != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("query syntax error at position %d: unknown operator '!'", i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		case isWordChar(c):
+			start := i
+			for i < len(text) && isWordChar(text[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: text[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("query syntax error at position %d: unexpected character '%c'", i+1, c)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(text)}), nil
+}
+
+type parser struct {
+	tokens []token
+	idx    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.idx]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.idx]
+	if t.kind != tokenEnd {
+		p.idx++
+	}
+	return t
+}
+
+func (p *parser) isEnd() bool {
+	return p.peek().kind == tokenEnd
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) errorAt(t token, message string) error {
+	if t.kind == tokenEnd {
+		return fmt.Errorf("query syntax error: %s (unexpected end of query)", message)
+	}
+	return fmt.Errorf("query syntax error at position %d: %s", t.pos+1, message)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (node, error) {
+	if p.isKeyword("not") {
+		p.next()
+		n, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n: n}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, p.errorAt(t, "')' expected")
+		}
+		return n, nil
+	}
+
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (node, error) {
+	t := p.next()
+	field := normalizeField(t.text)
+	kind, ok := fields[field]
+	if t.kind != tokenWord || !ok {
+		return nil, p.errorAt(t, fmt.Sprintf("unknown field '%s'", t.text))
+	}
+
+	if kind == kindBool {
+		// boolean field can be used without operator ("ipv6" is the same as "ipv6 = true")
+		if p.peek().kind != tokenOperator {
+			return &conditionNode{field: field, op: "=", values: []value{{text: "true", isTrue: true}}}, nil
+		}
+	}
+
+	var op string
+	if p.isKeyword("in") {
+		p.next()
+		op = "in"
+	} else if t := p.next(); t.kind == tokenOperator {
+		op = t.text
+	} else {
+		return nil, p.errorAt(t, fmt.Sprintf("operator expected after '%s'", field))
+	}
+
+	switch kind {
+	case kindText:
+		if op != "=" && op != "!=" && op != "~" && op != "in" {
+			return nil, p.errorAt(t, fmt.Sprintf("operator '%s' is not applicable to text field '%s'", op, field))
+		}
+	case kindNumber:
+		if op == "~" {
+			return nil, p.errorAt(t, fmt.Sprintf("operator '%s' is not applicable to numeric field '%s'", op, field))
+		}
+	case kindBool:
+		if op != "=" && op != "!=" {
+			return nil, p.errorAt(t, fmt.Sprintf("operator '%s' is not applicable to boolean field '%s'", op, field))
+		}
+	}
+
+	var valTokens []token
+	if op == "in" {
+		if t := p.next(); t.kind != tokenLParen {
+			return nil, p.errorAt(t, "'(' expected after 'in'")
+		}
+		for {
+			valTokens = append(valTokens, p.next())
+			t := p.next()
+			if t.kind == tokenRParen {
+				break
+			}
+			if t.kind != tokenComma {
+				return nil, p.errorAt(t, "',' or ')' expected")
+			}
+		}
+	} else {
+		valTokens = append(valTokens, p.next())
+	}
+
+	cond := &conditionNode{field: field, op: op}
+	for _, vt := range valTokens {
+		v, err := p.parseValue(vt, field, kind)
+		if err != nil {
+			return nil, err
+		}
+		cond.values = append(cond.values, v)
+	}
+	return cond, nil
+}
+
+func (p *parser) parseValue(t token, field string, kind fieldKind) (value, error) {
+	if t.kind != tokenWord && t.kind != tokenString {
+		return value{}, p.errorAt(t, fmt.Sprintf("value expected for '%s'", field))
+	}
+
+	v := value{text: t.text}
+	switch kind {
+	case kindNumber:
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return value{}, p.errorAt(t, fmt.Sprintf("numeric value expected for '%s' (got '%s')", field, t.text))
+		}
+		v.number = num
+	case kindBool:
+		b, err := strconv.ParseBool(t.text)
+		if err != nil {
+			return value{}, p.errorAt(t, fmt.Sprintf("boolean value expected for '%s' (got '%s')", field, t.text))
+		}
+		v.isTrue = b
+	case kindText:
+		if field == FieldProto {
+			proto, err := parseProto(t.text)
+			if err != nil {
+				return value{}, p.errorAt(t, err.Error())
+			}
+			v.text = proto
+		}
+	}
+	return v, nil
+}
+
+type value struct {
+	text   string
+	number float64
+	isTrue bool
+}
+
+type node interface {
+	match(h Host) bool
+	isUsingField(field string) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) match(h Host) bool { return n.left.match(h) && n.right.match(h) }
+func (n *andNode) isUsingField(f string) bool {
+	return n.left.isUsingField(f) || n.right.isUsingField(f)
+}
+
+type orNode struct{ left, right node }
+
+func (n *orNode) match(h Host) bool { return n.left.match(h) || n.right.match(h) }
+func (n *orNode) isUsingField(f string) bool {
+	return n.left.isUsingField(f) || n.right.isUsingField(f)
+}
+
+type notNode struct{ n node }
+
+func (n *notNode) match(h Host) bool          { return !n.n.match(h) }
+func (n *notNode) isUsingField(f string) bool { return n.n.isUsingField(f) }
+
+type conditionNode struct {
+	field  string
+	op     string
+	values []value
+}
+
+func (n *conditionNode) isUsingField(f string) bool { return n.field == f }
+
+func (n *conditionNode) match(h Host) bool {
+	switch fields[n.field] {
+	case kindNumber:
+		hv, ok := h.number(n.field)
+		if !ok {
+			return false // unknown value (e.g. the host was not pinged): the condition is not satisfied
+		}
+		for _, v := range n.values {
+			if compareNumbers(hv, n.op, v.number) {
+				return true
+			}
+		}
+		return false
+
+	case kindBool:
+		isEq := h.boolean(n.field) == n.values[0].isTrue
+		if n.op == "!=" {
+			return !isEq
+		}
+		return isEq
+
+	default:
+		texts := h.text(n.field)
+		isMatch := false
+		for _, v := range n.values {
+			for _, t := range texts {
+				if n.op == "~" && strings.Contains(strings.ToLower(t), strings.ToLower(v.text)) {
+					isMatch = true
+				} else if n.op != "~" && strings.EqualFold(t, v.text) {
+					isMatch = true
+				}
+			}
+		}
+		if n.op == "!=" {
+			return !isMatch
+		}
+		return isMatch
+	}
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "=", "in":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
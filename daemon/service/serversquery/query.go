@@ -0,0 +1,210 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+// Package serversquery implements a small query language to select servers (hosts) from the servers list.
+//
+// Query syntax:
+//
+//	QUERY     := [CONDITION] ["sort" ["by"] SORTFIELD ["asc"|"desc"]] ["limit" NUMBER]
+//	CONDITION := TERM {"or" TERM}
+//	TERM      := FACTOR {"and" FACTOR}
+//	FACTOR    := "not" FACTOR | "(" CONDITION ")" | FIELD OPERATOR VALUE | FIELD "in" "(" VALUE {"," VALUE} ")" | BOOLFIELD
+//	OPERATOR  := "=" | "!=" | "<" | "<=" | ">" | ">=" | "~" (contains; only for text fields)
+//
+// Example:
+//
+//	country in (CH,SE) and load < 50 and proto = wg and ipv6 sort by ping limit 3
+package serversquery
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field names
+const (
+	FieldCountry     = "country"      // country code or country name
+	FieldCountryCode = "country_code" // (alias "cc")
+	FieldCity        = "city"
+	FieldGateway     = "gateway" // server location (alias "location")
+	FieldHostname    = "hostname"
+	FieldHost        = "host" // host IP address
+	FieldProto       = "proto"
+	FieldLoad        = "load"     // host load (%)
+	FieldPing        = "ping"     // the latest measured latency of the host (ms)
+	FieldDistance    = "distance" // distance from the current location (km)
+	FieldIPv6        = "ipv6"     // IPv6 is supported inside the tunnel
+	FieldFavorite    = "favorite" // the host (or its server/country) is in the favorites list
+	FieldExcluded    = "excluded" // the host (or its server/country) is in the excluded list
+)
+
+type fieldKind int
+
+const (
+	kindText fieldKind = iota
+	kindNumber
+	kindBool
+)
+
+var fields = map[string]fieldKind{
+	FieldCountry:     kindText,
+	FieldCountryCode: kindText,
+	FieldCity:        kindText,
+	FieldGateway:     kindText,
+	FieldHostname:    kindText,
+	FieldHost:        kindText,
+	FieldProto:       kindText,
+	FieldLoad:        kindNumber,
+	FieldPing:        kindNumber,
+	FieldDistance:    kindNumber,
+	FieldIPv6:        kindBool,
+	FieldFavorite:    kindBool,
+	FieldExcluded:    kindBool,
+}
+
+var fieldAliases = map[string]string{
+	"cc":       FieldCountryCode,
+	"location": FieldGateway,
+	"protocol": FieldProto,
+	"latency":  FieldPing,
+}
+
+// sortable fields (lower value - better host)
+var sortFields = map[string]bool{
+	FieldPing:     true,
+	FieldLoad:     true,
+	FieldDistance: true,
+}
+
+// Query - the parsed query
+type Query struct {
+	condition node // nil - all hosts match
+	SortBy    string
+	SortDesc  bool
+	Limit     int // 0 - no limit
+}
+
+// IsUsingField returns true when the field is in use by the query (condition or sorting).
+// It allows to skip obtaining the data which is expensive to get (e.g. ping or distance)
+func (q *Query) IsUsingField(field string) bool {
+	if q.SortBy == field {
+		return true
+	}
+	return q.condition != nil && q.condition.isUsingField(field)
+}
+
+// Match returns true if the host matches the query condition
+func (q *Query) Match(h Host) bool {
+	return q.condition == nil || q.condition.match(h)
+}
+
+// Apply returns the hosts which are matching the query (sorted and limited according to the query)
+func (q *Query) Apply(hosts []Host) []Host {
+	ret := make([]Host, 0, len(hosts))
+	for _, h := range hosts {
+		if q.Match(h) {
+			ret = append(ret, h)
+		}
+	}
+
+	if len(q.SortBy) > 0 {
+		sort.SliceStable(ret, func(i, j int) bool {
+			vi, okI := ret[i].number(q.SortBy)
+			vj, okJ := ret[j].number(q.SortBy)
+			if okI != okJ {
+				return okI // hosts with unknown value are always the last
+			}
+			if q.SortDesc {
+				return vi > vj
+			}
+			return vi < vj
+		})
+	}
+
+	if q.Limit > 0 && len(ret) > q.Limit {
+		ret = ret[:q.Limit]
+	}
+	return ret
+}
+
+// Parse parses the query text
+func Parse(text string) (*Query, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	q := &Query{}
+
+	if !p.isKeyword("sort") && !p.isKeyword("limit") && !p.isEnd() {
+		if q.condition, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("sort") {
+		p.next()
+		if p.isKeyword("by") {
+			p.next()
+		}
+		t := p.next()
+		field := normalizeField(t.text)
+		if t.kind != tokenWord || !sortFields[field] {
+			return nil, p.errorAt(t, fmt.Sprintf("unable to sort by '%s' (expected: %s, %s or %s)", t.text, FieldPing, FieldLoad, FieldDistance))
+		}
+		q.SortBy = field
+		if p.isKeyword("asc") {
+			p.next()
+		} else if p.isKeyword("desc") {
+			p.next()
+			q.SortDesc = true
+		}
+	}
+
+	if p.isKeyword("limit") {
+		p.next()
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if err != nil || t.kind != tokenWord || limit <= 0 {
+			return nil, p.errorAt(t, fmt.Sprintf("bad limit value '%s' (positive number expected)", t.text))
+		}
+		q.Limit = limit
+	}
+
+	if !p.isEnd() {
+		t := p.peek()
+		return nil, p.errorAt(t, fmt.Sprintf("unexpected '%s'", t.text))
+	}
+
+	return q, nil
+}
+
+func normalizeField(name string) string {
+	name = strings.ToLower(name)
+	if f, ok := fieldAliases[name]; ok {
+		return f
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package serversquery
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+var testHosts = []Host{
+	{VpnType: vpn.WireGuard, Gateway: "ch.wg.ivpn.net", CountryCode: "CH", Country: "Switzerland", City: "Zurich", Hostname: "ch1.wg.ivpn.net", Host: "10.0.0.1", Load: 30, IPv6: true, PingMs: 40, DistanceKm: 900, IsFavorite: true},
+	{VpnType: vpn.WireGuard, Gateway: "se.wg.ivpn.net", CountryCode: "SE", Country: "Sweden", City: "Stockholm", Hostname: "se1.wg.ivpn.net", Host: "10.0.0.2", Load: 60, IPv6: true, PingMs: 20, DistanceKm: 500},
+	{VpnType: vpn.WireGuard, Gateway: "se.wg.ivpn.net", CountryCode: "SE", Country: "Sweden", City: "Stockholm", Hostname: "se2.wg.ivpn.net", Host: "10.0.0.3", Load: 10, DistanceKm: 500},
+	{VpnType: vpn.OpenVPN, Gateway: "ch.gw.ivpn.net", CountryCode: "CH", Country: "Switzerland", City: "Zurich", Hostname: "ch1.gw.ivpn.net", Host: "10.0.1.1", Load: 20, PingMs: 35, DistanceKm: 900},
+	{VpnType: vpn.OpenVPN, Gateway: "us-ny.gw.ivpn.net", CountryCode: "US", Country: "United States", City: "New York, NY", Hostname: "us-ny1.gw.ivpn.net", Host: "10.0.1.2", Load: 5, PingMs: 120, DistanceKm: -1},
+}
+
+func queryHostnames(t *testing.T, text string) string {
+	q, err := Parse(text)
+	if err != nil {
+		t.Fatalf("'%s': %v", text, err)
+	}
+	var names []string
+	for _, h := range q.Apply(testHosts) {
+		names = append(names, h.Hostname)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"", "ch1.wg.ivpn.net,se1.wg.ivpn.net,se2.wg.ivpn.net,ch1.gw.ivpn.net,us-ny1.gw.ivpn.net"},
+		{"country in (CH,SE) and load < 50 and proto = wg and ipv6", "ch1.wg.ivpn.net"},
+		{"country = sweden and not ipv6", "se2.wg.ivpn.net"},
+		{"cc = ch or city ~ 'new york'", "ch1.wg.ivpn.net,ch1.gw.ivpn.net,us-ny1.gw.ivpn.net"},
+		{"proto = openvpn and (load <= 5 or hostname ~ ch)", "ch1.gw.ivpn.net,us-ny1.gw.ivpn.net"},
+		{"ping < 50", "ch1.wg.ivpn.net,se1.wg.ivpn.net,ch1.gw.ivpn.net"}, // hosts with unknown ping do not match
+		{"country != SE sort by distance", "ch1.wg.ivpn.net,ch1.gw.ivpn.net,us-ny1.gw.ivpn.net"},
+		{"sort ping limit 2", "se1.wg.ivpn.net,ch1.gw.ivpn.net"},
+		{"proto = wg sort by load desc", "se1.wg.ivpn.net,ch1.wg.ivpn.net,se2.wg.ivpn.net"},
+		{"ipv6 = false limit 1", "se2.wg.ivpn.net"},
+		{"favorite or load < 10", "ch1.wg.ivpn.net,us-ny1.gw.ivpn.net"},
+	}
+
+	for _, test := range tests {
+		if res := queryHostnames(t, test.query); res != test.expected {
+			t.Errorf("'%s': expected '%s', got '%s'", test.query, test.expected, res)
+		}
+	}
+}
+
+func TestQuerySyntaxErrors(t *testing.T) {
+	for _, text := range []string{
+		"unknown = 1",
+		"country <",
+		"country < CH",
+		"load ~ 5",
+		"load < abc",
+		"proto = l2tp",
+		"country in (CH, SE",
+		"(country = CH",
+		"country = 'CH",
+		"ipv6 > true",
+		"sort by city",
+		"limit 0",
+		"limit 3x",
+		"limit 3.5",
+		"country = CH limit 1 extra",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("'%s': syntax error expected", text)
+		}
+	}
+}
+
+func TestIsUsingField(t *testing.T) {
+	q, err := Parse("country = CH and not (ping < 20) sort by distance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.IsUsingField(FieldPing) || !q.IsUsingField(FieldDistance) || q.IsUsingField(FieldLoad) {
+		t.Error("unexpected result of IsUsingField")
+	}
+}
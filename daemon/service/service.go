@@ -43,6 +43,7 @@ import (
 	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
 	"github.com/ivpn/desktop-app/daemon/service/firewall"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 	"github.com/ivpn/desktop-app/daemon/service/platform/filerights"
 	"github.com/ivpn/desktop-app/daemon/service/preferences"
@@ -104,6 +105,16 @@ type Service struct {
 
 	_serversPingProgressSemaphore *syncSemaphore.Weighted
 
+	// rolling history of servers ping results (persistent)
+	_pingHistory *pinghistory.History
+
+	// firewall exceptions for the API proxy server (IP addresses of the proxy)
+	_apiProxyFwExceptions []net.IP
+
+	// the last known geographical location (detected when the traffic is not going through the VPN tunnel)
+	_geoLocation      *types.GeoLookupResponse
+	_geoLocationMutex sync.Mutex
+
 	// nil - when session checker stopped
 	// to stop -> write to channel (it is synchronous channel)
 	_sessionCheckerStopChn chan struct{}
@@ -136,10 +147,12 @@ func CreateService(evtReceiver IServiceEventsReceiver, api *api.API, updater ISe
 		_netChangeDetector:            netChDetector,
 		_wgKeysMgr:                    wgKeysMgr,
 		_serversPingProgressSemaphore: syncSemaphore.NewWeighted(1),
+		_pingHistory:                  pinghistory.Load(platform.PingHistoryFile()),
 	}
 
 	// register the current service as a 'Connectivity checker' for API object
 	serv._api.SetConnectivityChecker(serv)
+	serv._api.SetReachabilityObserver(serv)
 
 	if err := serv.init(); err != nil {
 		return nil, fmt.Errorf("service initialization error : %w", err)
@@ -169,10 +182,12 @@ func (s *Service) init() error {
 	// initialize split-tunnel functionality
 	if err := splittun.Initialize(); err != nil {
 		log.Warning(fmt.Errorf("Split-Tunnelling initialization error : %w", err))
-	} else {
-		// apply Split Tunneling configuration
-		s.splitTunnelling_ApplyConfig()
 	}
+	// apply Split Tunneling configuration
+	// (destination-based configuration is applicable even if application-based Split-Tunnelling not available)
+	s.splitTunnelling_ApplyConfig()
+	// start periodical resolving of domain names from destination-based Split-Tunnel configuration
+	go s.splitTunnelling_domainsResolver()
 
 	// Logging mus be already initialized (by launcher). Do nothing here.
 	// Init logger (if not initialized before)
@@ -188,6 +203,11 @@ func (s *Service) init() error {
 		log.Error("Failed to apply firewall exceptions: ", err)
 	}
 
+	// API proxy (it also allows the proxy server in firewall)
+	if err := s.applyApiProxy(); err != nil {
+		log.Error("Failed to apply API proxy configuration: ", err)
+	}
+
 	if s._preferences.IsFwPersistant {
 		log.Info("Enabling firewal (persistant configuration)")
 		if err := firewall.SetPersistant(true); err != nil {
@@ -214,6 +234,8 @@ func (s *Service) init() error {
 	s.startSessionChecker()
 
 	s.updateAPIAddrInFWExceptions()
+	// periodically ping servers to keep the latency history up to date
+	go s.pingHistoryCollector()
 	// servers updated notifier
 	go func() {
 		defer func() {
@@ -226,12 +248,18 @@ func (s *Service) init() error {
 		}()
 
 		log.Info("Servers update notifier started")
+		prevSvrs, _ := s.ServersList()
 		for {
 			// wait for 'servers updated' event
 			<-s._serversUpdater.UpdateNotifierChannel()
 			// notify clients
 			svrs, _ := s.ServersList()
 			s._evtReceiver.OnServersUpdated(svrs)
+			// notify clients about changes in the servers list (added/removed hosts; changed WireGuard keys)
+			if diff := serversListDiff(prevSvrs, svrs); !diff.IsEmpty() {
+				s._evtReceiver.OnServersChanged(diff)
+			}
+			prevSvrs = svrs
 			// update firewall rules: notify firewall about new IP addresses of IVPN API
 			s.updateAPIAddrInFWExceptions()
 		}
@@ -360,6 +388,11 @@ func (s *Service) GetDisabledFunctions() protocolTypes.DisabledFunctionality {
 		}
 	}
 
+	if wgErr != nil && wireguard.IsNativeConfigSupported() {
+		// WireGuard tools are not required when WG interface can be configured natively
+		wgErr = nil
+	}
+
 	// returns non-nil error object if Split-Tunneling functionality not available
 	splitTunErr = splittun.GetFuncNotAvailableError()
 
@@ -398,11 +431,24 @@ func (s *Service) IsCanConnectMultiHop() error {
 }
 
 // ConnectOpenVPN start OpenVPN connection
-func (s *Service) ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+// exitRules - (optional) the rules the Multi-Hop exit server was selected by: they are re-applied on reconnection (nil - not in use)
+func (s *Service) ConnectOpenVPN(connectionParams openvpn.ConnectionParams, exitRules *protocolTypes.MultihopExitRules, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
 
-	createVpnObjfunc := func() (vpn.Process, error) {
+	createVpnObjfunc := func(reconnectReason error) (vpn.Process, error) {
 		prefs := s.Preferences()
 
+		if exitRules != nil && reconnectReason != nil {
+			if exit, err := s.multihopExitHostOpenVPN(connectionParams.HostIP(), *exitRules, connectionParams.MultihopExitHostname()); err != nil {
+				log.Warning(fmt.Sprintf("Multi-Hop exit server selection failed (%s). Reconnecting to the same exit server...", err))
+			} else {
+				connectionParams = connectionParams.WithMultihopExit(exit.Hostname, exit.MultihopPort)
+			}
+		}
+
+		if err := s.checkServerHostsNotExcluded(connectionParams.HostIP(), connectionParams.MultihopExitHostname()); err != nil {
+			return nil, err
+		}
+
 		// checking if functionality accessible
 		disabledFuncs := s.GetDisabledFunctions()
 		if len(disabledFuncs.OpenVPNError) > 0 {
@@ -478,16 +524,26 @@ func (s *Service) ConnectOpenVPN(connectionParams openvpn.ConnectionParams, manu
 		return vpnObj, nil
 	}
 
+	if prefs := s.Preferences(); !prefs.Session.IsLoggedIn() {
+		return srverrors.ErrorNotLoggedIn{}
+	}
+
 	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
 }
 
 // ConnectWireGuard start WireGuard connection
-func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+// exitRules - (optional) the rules the Multi-Hop exit server was selected by: they are re-applied on reconnection (nil - not in use)
+// nextHopCustomServerID - (optional) ID of the custom WireGuard server chained after the IVPN server (empty - not in use)
+func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams, exitRules *protocolTypes.MultihopExitRules, nextHopCustomServerID string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
 	// stop active connection (if exists)
 	if err := s.Disconnect(); err != nil {
 		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
 	}
 
+	if prefs := s.Preferences(); !prefs.Session.IsLoggedIn() {
+		return srverrors.ErrorNotLoggedIn{}
+	}
+
 	// checking if functionality accessible
 	disabledFuncs := s.GetDisabledFunctions()
 	if len(disabledFuncs.WireGuardError) > 0 {
@@ -498,19 +554,56 @@ func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams,
 	err := s.WireGuardGenerateKeys(true)
 	if err != nil {
 		// If new WG keys regeneration failed but we still have active keys - keep connecting
-		// (this could happen, for example, when FW is enabled and we even not tried to make API request)
-		// Return error only if the keys had to be regenerated more than 3 days ago.
-		_, activePublicKey, _, _, lastUpdate, interval := s.WireGuardGetKeys()
-
-		if len(activePublicKey) > 0 && lastUpdate.Add(interval).Add(time.Hour*24*3).After(time.Now()) {
+		// (this could happen, for example, when FW is enabled or API servers are unreachable and we even not tried to make API request)
+		// Return error only if the grace period for the key is over (see 'wgKeyGracePeriod' and 'offlineWgKeyGracePeriod').
+		if usableUntil := s.wgKeyUsableUntil(); time.Now().Before(usableUntil) {
 			// continue connection
-			log.Warning(fmt.Errorf("WG KEY generation failed (%w). But we keep connecting (will try to regenerate it next 3 days)", err))
+			log.Warning(fmt.Errorf("WG KEY generation failed (%w). But we keep connecting (the key can be used until %s)", err, usableUntil.Format(time.RFC3339)))
 		} else {
 			return err
 		}
 	}
 
-	createVpnObjfunc := func() (vpn.Process, error) {
+	var nextHopConfig *wireguard.Config
+	if len(nextHopCustomServerID) > 0 {
+		srv, cfg, err := s.wireGuardCustomServerConfig(nextHopCustomServerID)
+		if err != nil {
+			return err
+		}
+		log.Info(fmt.Sprintf("Chaining custom WireGuard server '%s' (%s) after the exit server", srv.Name, cfg.Endpoint))
+		nextHopConfig = &cfg
+	}
+
+	stallsOnServer := 0
+	createVpnObjfunc := func(reconnectReason error) (vpn.Process, error) {
+		// the tunnel stalled: fail over to another server if the tunnel stalls repeatedly
+		var stalledErr *wireguard.TunnelStalledError
+		if errors.As(reconnectReason, &stalledErr) {
+			stallsOnServer++
+			if stallsOnServer >= wgFailoverAfterStalls {
+				if nextHopConfig != nil {
+					log.Info("Failover is not applicable for the connections with chained custom WireGuard server. Reconnecting to the same server...")
+				} else if failoverParams, err := s.wireGuardFailoverParams(connectionParams); err != nil {
+					log.Warning(fmt.Sprintf("Failover to another server is not possible (%s). Reconnecting to the same server...", err))
+				} else {
+					connectionParams = failoverParams
+					stallsOnServer = 0
+				}
+			}
+		}
+
+		if exitRules != nil && reconnectReason != nil {
+			if exit, err := s.multihopExitHostWireGuard(connectionParams.HostIP(), connectionParams.IsIPv6(), *exitRules, connectionParams.MultihopExitHostname()); err != nil {
+				log.Warning(fmt.Sprintf("Multi-Hop exit server selection failed (%s). Reconnecting to the same exit server...", err))
+			} else {
+				connectionParams = connectionParams.WithMultihopExit(exit.Hostname, exit.MultihopPort, exit.PublicKey)
+			}
+		}
+
+		if err := s.checkServerHostsNotExcluded(connectionParams.HostIP(), connectionParams.MultihopExitHostname()); err != nil {
+			return nil, err
+		}
+
 		session := s.Preferences().Session
 
 		if !session.IsWGCredentialsOk() {
@@ -522,6 +615,22 @@ func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams,
 			return nil, fmt.Errorf("error updating WG connection preferences (failed parsing local IP for WG connection)")
 		}
 		connectionParams.SetCredentials(session.WGPrivateKey, localip)
+		connectionParams.SetUserspaceForced(s.Preferences().UserPrefs.Linux.IsWgUserspace)
+		connectionParams.SetStallTimeout(time.Duration(s.Preferences().UserPrefs.WgStallTimeoutSec) * time.Second)
+
+		// post-quantum preshared key (new key for each connection)
+		if err := s.wireGuardApplyPresharedKeyParams(&connectionParams); err != nil {
+			return nil, err
+		}
+
+		if nextHopConfig != nil {
+			// the endpoint host name is resolved on each (re)connection
+			hopParams, err := nextHopConfig.CreateConnectionParams()
+			if err != nil {
+				return nil, fmt.Errorf("chained custom WireGuard server: %w", err)
+			}
+			connectionParams.SetNextHop(hopParams)
+		}
 
 		vpnObj, err := wireguard.NewWireGuardObject(
 			platform.WgBinaryPath(),
@@ -538,12 +647,10 @@ func (s *Service) ConnectWireGuard(connectionParams wireguard.ConnectionParams,
 	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
 }
 
-func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
-	prefs := s.Preferences()
-	if !prefs.Session.IsLoggedIn() {
-		return srverrors.ErrorNotLoggedIn{}
-	}
-
+// keepConnection connects and keeps the connection alive (reconnects when required)
+// 'createVpnObj' is called before each (re)connection; 'reconnectReason' is the error of the previous connection (nil - on first connection)
+// Note: the caller is responsible for checking the account session (custom servers do not require it)
+func (s *Service) keepConnection(createVpnObj func(reconnectReason error) (vpn.Process, error), manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
 	s._manualDNS = manualDNS
 
 	// Not necessary to keep connection until we are not connected
@@ -554,9 +661,10 @@ func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manua
 	delayBeforeReconnect := 0 * time.Second
 
 	stateChan <- vpn.NewStateInfo(vpn.CONNECTING, "Connecting")
+	var lastConnErr error
 	for {
 		// create new VPN object
-		vpnObj, err := createVpnObj()
+		vpnObj, err := createVpnObj(lastConnErr)
 		if err != nil {
 			return fmt.Errorf("failed to create VPN object: %w", err)
 		}
@@ -565,6 +673,7 @@ func (s *Service) keepConnection(createVpnObj func() (vpn.Process, error), manua
 
 		// start connection
 		connErr := s.connect(vpnObj, s._manualDNS, firewallOn, firewallDuringConnection, stateChan)
+		lastConnErr = connErr
 		if connErr != nil {
 			log.Error(fmt.Sprintf("Connection error: %s", connErr))
 			if s._requiredVpnState == Connect {
@@ -776,6 +885,9 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS dns.DnsSettings, firewa
 						state.ClientPort,
 						state.ServerIP, state.ServerPort,
 						state.IsTCP)
+					if state.NextHopClientIP != nil {
+						firewall.ClientNextHopConnected(state.NextHopClientIP)
+					}
 
 					// Ensure firewall is configured to allow DNS communication
 					// At this moment, firewall must be already configured for custom DNS
@@ -834,6 +946,10 @@ func (s *Service) connect(vpnProc vpn.Process, manualDNS dns.DnsSettings, firewa
 				}
 			case <-routingUpdateChan: // there were some routing changes but 'interfaceToProtect' is still is the default route
 				s._vpn.OnRoutingChanged()
+				// default gateway could be changed: bypass routes of split-tunnel destinations must follow it
+				if err := splittun.OnRoutingChanged(); err != nil {
+					log.Error(fmt.Errorf("failed to update split-tunnel destinations routes: %w", err))
+				}
 			case <-stopChannel: // triggered when the stopChannel is closed
 				isRuning = false
 			}
@@ -995,6 +1111,16 @@ func (s *Service) ConnectedType() (isConnected bool, connectedVpnType vpn.Type)
 	return true, vpnObj.Type()
 }
 
+// GetConnectionStats returns statistics of the active VPN connection
+func (s *Service) GetConnectionStats() (vpnType vpn.Type, stats vpn.ConnectionStats, err error) {
+	vpnObj := s._vpn
+	if vpnObj == nil {
+		return 0, stats, fmt.Errorf("VPN is not connected")
+	}
+	stats, err = vpnObj.Stats()
+	return vpnObj.Type(), stats, err
+}
+
 // FirewallEnabled returns firewall state (enabled\disabled)
 // (in use, for example, by WireGuard keys manager, to know is it have sense to make API requests.)
 func (s *Service) FirewallEnabled() (bool, error) {
@@ -1204,6 +1330,19 @@ func (s *Service) SetPreference(key protocolTypes.ServicePreference, val string)
 
 // SetPreference set preference value
 func (s *Service) SetUserPreferences(userPrefs preferences.UserPreferences) error {
+	if userPrefs.WgStallTimeoutSec < 0 {
+		return fmt.Errorf("WireGuard stall timeout can not be negative")
+	}
+	if err := checkWireGuardPskPreferences(userPrefs); err != nil {
+		return err
+	}
+	if err := userPrefs.PingScoreWeights.Validate(); err != nil {
+		return err
+	}
+	if err := userPrefs.HomeLocation.Validate(); err != nil {
+		return err
+	}
+
 	// platform-specific check if we can apply this preferences
 	if err := s.implIsCanApplyUserPreferences(userPrefs); err != nil {
 		return err
@@ -1224,6 +1363,11 @@ func (s *Service) Preferences() preferences.Preferences {
 func (s *Service) ResetPreferences() error {
 	s._preferences = *preferences.Create()
 
+	// API proxy is not defined anymore
+	if err := s.applyApiProxy(); err != nil {
+		log.Error("Failed to apply API proxy configuration: ", err)
+	}
+
 	// erase ST config
 	s.SplitTunnelling_SetConfig(false, true)
 	return nil
@@ -1253,12 +1397,36 @@ func (s *Service) SplitTunnelling_GetStatus() (protocolTypes.SplitTunnelStatus,
 		IsEnabled:                   prefs.IsSplitTunnel,
 		IsCanGetAppIconForBinary:    oshelpers.IsCanGetAppIconForBinary(),
 		SplitTunnelApps:             prefs.SplitTunnelApps,
-		RunningApps:                 runningProcesses}
+		RunningApps:                 runningProcesses,
+		BypassDestinations:          prefs.SplitTunnelBypassDests,
+		VpnDestinations:             prefs.SplitTunnelVpnDests,
+		ResolvedDomains:             s.splitTunnelling_GetResolvedDomains()}
 
 	return ret, nil
 }
 
+func (s *Service) SplitTunnelling_GetLiveStatus() (protocolTypes.SplitTunnelLiveStatus, error) {
+	ret := protocolTypes.SplitTunnelLiveStatus{IsEnabled: s.Preferences().IsSplitTunnel}
+	if !ret.IsEnabled {
+		return ret, nil
+	}
+
+	apps, err := splittun.GetRunningAppsTraffic()
+	if err != nil {
+		return ret, err
+	}
+	ret.RunningApps = apps
+	return ret, nil
+}
+
 func (s *Service) SplitTunnelling_SetConfig(isEnabled bool, reset bool) error {
+	if reset {
+		// erase destination-based configuration
+		prefs := s._preferences
+		prefs.SplitTunnelBypassDests = make([]string, 0)
+		prefs.SplitTunnelVpnDests = make([]string, 0)
+		s.setPreferences(prefs)
+	}
 	if reset || splittun.GetFuncNotAvailableError() != nil {
 		return s.splitTunnelling_Reset()
 	}
@@ -1283,11 +1451,6 @@ func (s *Service) splitTunnelling_ApplyConfig() error {
 	// notify changed ST configuration status (even if functionality not available)
 	defer s._evtReceiver.OnSplitTunnelStatusChanged()
 
-	if splittun.GetFuncNotAvailableError() != nil {
-		// Split-Tunneling not accessable (not able to connect to a driver or not implemented for current platform)
-		return nil
-	}
-
 	prefs := s.Preferences()
 	sInf := s.GetVpnSessionInfo()
 
@@ -1297,7 +1460,13 @@ func (s *Service) splitTunnelling_ApplyConfig() error {
 		IPv6Tunnel: sInf.VpnLocalIPv6,
 		IPv6Public: sInf.OutboundIPv6}
 
-	return splittun.ApplyConfig(prefs.IsSplitTunnel, s.Connected(), addressesCfg, prefs.SplitTunnelApps)
+	// destination-based configuration (domain names resolved to IP addresses)
+	destCfg := s.splitTunnelling_GetDestinations()
+	s.splitTunnelling_applyDestinationsFirewall(destCfg)
+
+	// Note: application-based configuration will be skipped by splittun package
+	// if Split-Tunneling not accessable (not able to connect to a driver or not implemented for current platform)
+	return splittun.ApplyConfig(prefs.IsSplitTunnel, s.Connected(), addressesCfg, prefs.SplitTunnelApps, destCfg)
 }
 
 func (s *Service) SplitTunnelling_AddApp(exec string) (cmdToExecute string, isAlreadyRunning bool, err error) {
@@ -1702,6 +1871,61 @@ func (s *Service) WireGuardSetKeysRotationInterval(interval int64) {
 	s._evtReceiver.OnServiceSessionChanged()
 }
 
+// WireGuardGetKeysRotationWindow returns the local time window when automatic WG keys rotation is allowed
+func (s *Service) WireGuardGetKeysRotationWindow() preferences.TimeWindow {
+	return s._preferences.WgKeysRotationWindow
+}
+
+// WireGuardSetKeysRotationWindow change the local time window when automatic WG keys rotation is allowed
+// (StartMinute == EndMinute - no restrictions)
+func (s *Service) WireGuardSetKeysRotationWindow(window preferences.TimeWindow) error {
+	if err := window.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	prefs.WgKeysRotationWindow = window
+	s.setPreferences(prefs)
+	log.Info(fmt.Sprintf("WG keys rotation window: %s", window))
+
+	// restart WG keys rotation
+	if err := s._wgKeysMgr.StartKeysRotation(); err != nil {
+		log.Error(err)
+	}
+
+	// notify clients about session (wg keys) update
+	s._evtReceiver.OnServiceSessionChanged()
+	return nil
+}
+
+// WireGuardKeysRotationLog saves the WG keys rotation attempt into the history
+func (s *Service) WireGuardKeysRotationLog(record preferences.WgKeysRotationRecord) {
+	prefs := s._preferences
+
+	history := make([]preferences.WgKeysRotationRecord, 0, len(prefs.WgKeysHistory)+1)
+	history = append(history, prefs.WgKeysHistory...)
+	history = append(history, record)
+	if len(history) > preferences.MaxWgKeysHistoryRecords {
+		history = history[len(history)-preferences.MaxWgKeysHistoryRecords:]
+	}
+
+	prefs.WgKeysHistory = history
+	s.setPreferences(prefs)
+}
+
+// WireGuardKeysHistory returns the history of WG keys rotation (the latest record is the last one)
+func (s *Service) WireGuardKeysHistory() []preferences.WgKeysRotationRecord {
+	return s._preferences.WgKeysHistory
+}
+
+// WireGuardKeysNextRotation returns the time of the next scheduled WG keys rotation (zero - rotation not active)
+func (s *Service) WireGuardKeysNextRotation() time.Time {
+	if !s._preferences.Session.IsLoggedIn() {
+		return time.Time{}
+	}
+	return s._wgKeysMgr.NextRotation()
+}
+
 // WireGuardGetKeys get WG keys
 func (s *Service) WireGuardGetKeys() (session, wgPublicKey, wgPrivateKey, wgLocalIP string, generatedTime time.Time, updateInterval time.Duration) {
 	p := s._preferences
@@ -0,0 +1,115 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/service/firewall"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+)
+
+// SetApiProxy changes the proxy server for the daemon API traffic (empty proxy type - direct connection)
+func (s *Service) SetApiProxy(proxy preferences.ApiProxy) error {
+	if err := proxy.Validate(); err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	if !proxy.IsEnabled() {
+		proxy = preferences.ApiProxy{}
+	} else if len(proxy.Password) == 0 && proxy.Username == prefs.ApiProxy.Username && proxy.HostPort() == prefs.ApiProxy.HostPort() {
+		// the password is not exposed to clients: keep the current one if it was not specified
+		proxy.Password = prefs.ApiProxy.Password
+	}
+	prefs.ApiProxy = proxy
+	s.setPreferences(prefs)
+
+	return s.applyApiProxy()
+}
+
+// applyApiProxy applies the proxy configuration (from preferences) to the API object
+// and allows the communication with the proxy server in the firewall
+func (s *Service) applyApiProxy() error {
+	proxy := s._preferences.ApiProxy
+
+	var proxyIPs []net.IP
+	if proxy.IsEnabled() {
+		ips, err := resolveHost(proxy.Address, time.Second*5)
+		if err != nil {
+			log.Warning(fmt.Sprintf("Unable to resolve API proxy address (firewall exception not added): %s", err))
+		}
+		proxyIPs = ips
+	}
+	s.updateApiProxyFwExceptions(proxyIPs)
+
+	if !proxy.IsEnabled() {
+		log.Info("API proxy: not in use")
+		return s._api.SetProxy(nil)
+	}
+
+	log.Info(fmt.Sprintf("API proxy: %s://%s", proxy.Type, proxy.HostPort()))
+	// preferences.ApiProxyXXX and api.ProxyXXX constants have the same values
+	return s._api.SetProxy(&api.ProxyConfig{
+		Type:     proxy.Type,
+		Address:  proxy.HostPort(),
+		Username: proxy.Username,
+		Password: proxy.Password})
+}
+
+// updateApiProxyFwExceptions replaces the firewall exceptions for the API proxy server
+func (s *Service) updateApiProxyFwExceptions(proxyIPs []net.IP) {
+	const onlyForICMP = false
+	const isPersistent = true
+
+	if len(s._apiProxyFwExceptions) > 0 {
+		firewall.RemoveHostsFromExceptions(s._apiProxyFwExceptions, onlyForICMP, isPersistent)
+	}
+	s._apiProxyFwExceptions = proxyIPs
+	if len(proxyIPs) > 0 {
+		firewall.AddHostsToExceptions(proxyIPs, onlyForICMP, isPersistent)
+	}
+}
+
+// resolveHost returns IP addresses of the host (host name or IP address)
+func resolveHost(host string, timeout time.Duration) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ips, nil
+}
@@ -65,13 +65,11 @@ func (s *Service) implGetDisabledFuncForPlatform() protocolTypes.DisabledFunctio
 	return protocolTypes.DisabledFunctionalityForPlatform{Linux: linuxFuncs}
 }
 
-func (s *Service) implPingServersStarting(hosts []net.IP) error {
-	const onlyForICMP = true
+func (s *Service) implPingServersStarting(hosts []net.IP, onlyForICMP bool) error {
 	const isPersistent = false
 	return firewall.AddHostsToExceptions(hosts, onlyForICMP, isPersistent)
 }
-func (s *Service) implPingServersStopped(hosts []net.IP) error {
-	const onlyForICMP = true
+func (s *Service) implPingServersStopped(hosts []net.IP, onlyForICMP bool) error {
 	const isPersistent = false
 	return firewall.RemoveHostsFromExceptions(hosts, onlyForICMP, isPersistent)
 }
@@ -0,0 +1,233 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// Multi-Hop exit server selection by rules (see protocolTypes.MultihopExitRules).
+// The exit server is selected by the daemon on connection and the rules are re-applied on each reconnection.
+// Note: Multi-Hop chain consists of two IVPN servers (entry and exit); longer IVPN chains are out of scope.
+// The custom WireGuard server can be chained after the exit server (see ConnectWireGuard()).
+
+// max time to measure latency of the exit servers (when it is unknown)
+const multihopExitPingTimeout = 5 * time.Second
+
+// jurisdiction14Eyes - countries of the 'Fourteen Eyes' intelligence sharing alliance.
+// All of them are considered as one jurisdiction when selecting Multi-Hop exit server.
+var jurisdiction14Eyes = map[string]struct{}{
+	"US": {}, "GB": {}, "CA": {}, "AU": {}, "NZ": {}, // Five Eyes
+	"DK": {}, "FR": {}, "NL": {}, "NO": {}, // Nine Eyes
+	"DE": {}, "BE": {}, "IT": {}, "ES": {}, "SE": {}, // Fourteen Eyes
+}
+
+// jurisdiction returns the jurisdiction identifier of the country
+func jurisdiction(countryCode string) string {
+	cc := strings.ToUpper(countryCode)
+	if _, ok := jurisdiction14Eyes[cc]; ok {
+		return "14-eyes"
+	}
+	return cc
+}
+
+// MultihopExitHostWireGuard selects the WireGuard Multi-Hop exit host which satisfies the rules
+// 'entryHost' - IP address of the entry server host
+// 'isIPv6' - the exit host must support IPv6 inside the tunnel
+func (s *Service) MultihopExitHostWireGuard(entryHost net.IP, isIPv6 bool, rules protocolTypes.MultihopExitRules) (types.WireGuardServerHostInfo, error) {
+	return s.multihopExitHostWireGuard(entryHost, isIPv6, rules, "")
+}
+
+// MultihopExitHostOpenVPN selects the OpenVPN Multi-Hop exit host which satisfies the rules
+// 'entryHost' - IP address of the entry server host
+func (s *Service) MultihopExitHostOpenVPN(entryHost net.IP, rules protocolTypes.MultihopExitRules) (types.OpenVPNServerHostInfo, error) {
+	return s.multihopExitHostOpenVPN(entryHost, rules, "")
+}
+
+// 'currentExitHostname' - the exit host in use (on reconnection): it is kept when it still satisfies the rules
+// (except the 'Fastest' rule: the fastest exit host is selected on each reconnection)
+func (s *Service) multihopExitHostWireGuard(entryHost net.IP, isIPv6 bool, rules protocolTypes.MultihopExitRules, currentExitHostname string) (types.WireGuardServerHostInfo, error) {
+	servers, candidates, err := s.multihopExitCandidates(vpn.WireGuard, entryHost, isIPv6, rules, currentExitHostname)
+	if err != nil {
+		return types.WireGuardServerHostInfo{}, err
+	}
+	for _, c := range candidates {
+		for _, svr := range servers.WireguardServers {
+			for _, h := range svr.Hosts {
+				if h.Hostname == c.Hostname && h.MultihopPort > 0 && helpers.ValidateBase64(h.PublicKey) {
+					return h, nil
+				}
+			}
+		}
+	}
+	return types.WireGuardServerHostInfo{}, fmt.Errorf("no Multi-Hop exit server found which satisfies the rules")
+}
+
+func (s *Service) multihopExitHostOpenVPN(entryHost net.IP, rules protocolTypes.MultihopExitRules, currentExitHostname string) (types.OpenVPNServerHostInfo, error) {
+	servers, candidates, err := s.multihopExitCandidates(vpn.OpenVPN, entryHost, false, rules, currentExitHostname)
+	if err != nil {
+		return types.OpenVPNServerHostInfo{}, err
+	}
+	for _, c := range candidates {
+		for _, svr := range servers.OpenvpnServers {
+			for _, h := range svr.Hosts {
+				if h.Hostname == c.Hostname && h.MultihopPort > 0 {
+					return h, nil
+				}
+			}
+		}
+	}
+	return types.OpenVPNServerHostInfo{}, fmt.Errorf("no Multi-Hop exit server found which satisfies the rules")
+}
+
+// multihopExitCandidates returns the hosts which satisfy the Multi-Hop exit rules for the entry host (the preferred host is the first).
+// The exit server is always from another country than the entry server. The excluded servers are never in use.
+func (s *Service) multihopExitCandidates(vpnType vpn.Type, entryHost net.IP, isIPv6 bool, rules protocolTypes.MultihopExitRules, currentExitHostname string) (*types.ServersInfoResponse, []serversquery.Host, error) {
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		return nil, nil, fmt.Errorf("servers list is not available")
+	}
+
+	var entry *serversquery.Host
+	for _, h := range serversquery.HostsFromServers(servers, nil) {
+		if h.VpnType == vpnType && net.ParseIP(h.Host).Equal(entryHost) {
+			entry = &h
+			break
+		}
+	}
+	if entry == nil {
+		return nil, nil, fmt.Errorf("entry server not found in the servers list")
+	}
+
+	countries := make(map[string]struct{})
+	for _, cc := range rules.Countries {
+		if cc = strings.ToUpper(strings.TrimSpace(cc)); len(cc) > 0 {
+			countries[cc] = struct{}{}
+		}
+	}
+
+	query := func(sorting string) ([]serversquery.Host, error) {
+		q := "proto = " + strings.ToLower(vpnType.String())
+		if isIPv6 {
+			q += " and ipv6"
+		}
+		hosts, err := s.QueryServers(q + sorting)
+		if err != nil {
+			return nil, err
+		}
+		ret := hosts[:0]
+		for _, h := range hosts {
+			if h.Gateway == entry.Gateway || strings.EqualFold(h.CountryCode, entry.CountryCode) {
+				continue
+			}
+			if _, ok := countries[strings.ToUpper(h.CountryCode)]; len(countries) > 0 && !ok {
+				continue
+			}
+			if rules.OtherJurisdiction && jurisdiction(h.CountryCode) == jurisdiction(entry.CountryCode) {
+				continue
+			}
+			ret = append(ret, h)
+		}
+		return ret, nil
+	}
+
+	if !rules.Fastest {
+		candidates, err := query("")
+		if err != nil {
+			return nil, nil, err
+		}
+		// random host satisfying the rules (the current exit host is preferred)
+		for i := range candidates {
+			if candidates[i].Hostname == currentExitHostname {
+				candidates[0], candidates[i] = candidates[i], candidates[0]
+				return servers, candidates, nil
+			}
+		}
+		if len(candidates) > 1 {
+			if rnd, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates)))); err == nil {
+				candidates[0], candidates[rnd.Int64()] = candidates[rnd.Int64()], candidates[0]
+			}
+		}
+		return servers, candidates, nil
+	}
+
+	// the fastest host (the latest measured latency)
+	candidates, err := query(" sort by ping")
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) > 0 && candidates[0].PingMs <= 0 && s._vpn == nil {
+		// latency of the hosts is unknown: measure it (the nearest hosts first)
+		s.multihopExitPing(query)
+		if candidates, err = query(" sort by ping"); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(candidates) > 0 && candidates[0].PingMs <= 0 {
+		// the nearest host is a better guess than a random one
+		log.Info("(Multi-Hop) latency of the exit servers is unknown: using the nearest exit server")
+		if candidates, err = query(" sort by distance"); err != nil {
+			return nil, nil, err
+		}
+	}
+	return servers, candidates, nil
+}
+
+// multihopExitPing measures latency of the Multi-Hop exit hosts (one host for each server; the nearest servers first)
+// The results are stored in the latency history.
+func (s *Service) multihopExitPing(query func(sorting string) ([]serversquery.Host, error)) {
+	if !s._serversPingProgressSemaphore.TryAcquire(1) {
+		return // pinging is in progress: the latency history will be updated by it
+	}
+	defer s._serversPingProgressSemaphore.Release(1)
+
+	hosts, err := query(" sort by distance")
+	if err != nil {
+		return
+	}
+	ips := make([]net.IP, 0, len(hosts))
+	gateways := make(map[string]struct{})
+	for _, h := range hosts {
+		if _, ok := gateways[h.Gateway]; ok {
+			continue
+		}
+		if ip := net.ParseIP(h.Host); ip != nil {
+			gateways[h.Gateway] = struct{}{}
+			ips = append(ips, ip)
+		}
+	}
+
+	timeout := time.Now().Add(multihopExitPingTimeout)
+	s.pingIteration(ips, make(map[string]protocolTypes.PingResultType), 300, &timeout, protocolTypes.PingProbeAuto, true)
+}
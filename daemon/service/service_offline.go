@@ -0,0 +1,140 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// Offline mode: the daemon is in offline mode when the last request to the API servers failed (no response received).
+// The mode is over as soon as any API request succeeds (servers list update, session status check, keys rotation ...).
+//
+// Policies in offline mode:
+//   - Servers list: the cached list is in use. It is considered up to date during 'offlineServersCacheValidity'
+//     after the last successful update; after that it is reported as stale but still in use for connections (there is no other data).
+//   - WireGuard key: the scheduled (and on-connect) rotation is postponed while the key is in the grace period
+//     ('offlineWgKeyGracePeriod' after the rotation time; it is longer than 'wgKeyGracePeriod' which is in use when the API servers are reachable).
+//     When the grace period is over, WireGuard connection is not possible until the key is rotated.
+//   - Kill switch: the firewall state is never changed because of API unavailability. The enabled firewall stays enabled
+//     (the API servers are reachable only when allowed by user configuration); the session is not logged out.
+const (
+	offlineServersCacheValidity = time.Hour * 24 * 30
+	// the time the WireGuard key can be in use after the rotation time when the key rotation failed
+	// (e.g. the firewall blocks the API request)
+	wgKeyGracePeriod = time.Hour * 24 * 3
+	// the same as 'wgKeyGracePeriod' but in offline mode
+	offlineWgKeyGracePeriod = time.Hour * 24 * 7
+)
+
+// OnApiReachabilityChanged - (api.IReachabilityObserver) reachability of the API servers changed
+func (s *Service) OnApiReachabilityChanged(r api.Reachability) {
+	if r.IsReachable {
+		log.Info("API servers are reachable (online mode)")
+	} else {
+		log.Warning("API servers are unreachable (offline mode): ", r.LastError)
+	}
+
+	// notify clients asynchronously (we are in the context of the API request)
+	go s._evtReceiver.OnOfflineStatusChanged(s.OfflineStatus())
+}
+
+// IsApiOffline returns true when the API servers are unreachable (offline mode)
+func (s *Service) IsApiOffline() bool {
+	r := s._api.Reachability()
+	return !r.IsReachable && !r.LastFailure.IsZero()
+}
+
+// OfflineStatus returns information about offline mode
+func (s *Service) OfflineStatus() protocolTypes.OfflineStatus {
+	toUnix := func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	}
+
+	now := time.Now()
+	r := s._api.Reachability()
+	ret := protocolTypes.OfflineStatus{
+		IsOffline:      s.IsApiOffline(),
+		LastApiSuccess: toUnix(r.LastSuccess),
+	}
+	if ret.IsOffline {
+		ret.OfflineSince = toUnix(r.UnreachableSince)
+		ret.LastError = r.LastError
+	}
+
+	if updated := s._serversUpdater.LastUpdated(); !updated.IsZero() {
+		validUntil := updated.Add(offlineServersCacheValidity)
+		ret.ServersUpdated = updated.Unix()
+		ret.ServersValidUntil = validUntil.Unix()
+		ret.IsServersStale = now.After(validUntil)
+	}
+
+	if rotationTime := s.wgKeyRotationTime(); !rotationTime.IsZero() {
+		ret.WgKeyUsableUntil = s.wgKeyUsableUntil().Unix()
+		ret.IsWgKeyRotationOverdue = now.After(rotationTime)
+	}
+
+	return ret
+}
+
+// WireGuardIsRotationDeferred - (wgkeys.IWgKeysChangeReceiver) returns true when the WireGuard key rotation must be postponed:
+// the API servers are unreachable (offline mode) and the active key is still in the grace period.
+// The rotation is not postponed when WireGuard is connected (the failed rotation does not erase the active key in this case).
+func (s *Service) WireGuardIsRotationDeferred() bool {
+	if !s.IsApiOffline() {
+		return false
+	}
+	if isConnected, vpnType := s.ConnectedType(); isConnected && vpnType == vpn.WireGuard {
+		return false
+	}
+	return time.Now().Before(s.wgKeyUsableUntil())
+}
+
+// wgKeyRotationTime returns the time the active WireGuard key have to be rotated. Zero - no active key.
+func (s *Service) wgKeyRotationTime() time.Time {
+	_, activePublicKey, _, _, lastUpdate, interval := s.WireGuardGetKeys()
+	if len(activePublicKey) == 0 {
+		return time.Time{}
+	}
+	return lastUpdate.Add(interval)
+}
+
+// wgKeyUsableUntil returns the time until the active WireGuard key can be used for connection
+// without rotation (the rotation time + grace period). Zero - no active key.
+// The longer grace period is in use only in offline mode (see 'offlineWgKeyGracePeriod').
+func (s *Service) wgKeyUsableUntil() time.Time {
+	rotationTime := s.wgKeyRotationTime()
+	if rotationTime.IsZero() {
+		return rotationTime
+	}
+	if s.IsApiOffline() {
+		return rotationTime.Add(offlineWgKeyGracePeriod)
+	}
+	return rotationTime.Add(wgKeyGracePeriod)
+}
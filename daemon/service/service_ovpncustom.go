@@ -0,0 +1,176 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+	"github.com/ivpn/desktop-app/daemon/vpn/openvpn"
+)
+
+// Custom OpenVPN profiles: user-supplied OpenVPN profiles (.ovpn files) imported by the user.
+// Connection with a custom profile does not require IVPN account.
+
+const maxCustomOvpnProfileSize = 256 * 1024
+
+// OpenVpnCustomProfileImport imports user-supplied OpenVPN profile
+func (s *Service) OpenVpnCustomProfileImport(name string, profile string, username string, password string) (protocolTypes.OpenVpnCustomProfileInfo, error) {
+	if len(profile) > maxCustomOvpnProfileSize {
+		return protocolTypes.OpenVpnCustomProfileInfo{}, fmt.Errorf("profile is too big")
+	}
+
+	p, err := openvpn.ParseProfile(profile)
+	if err != nil {
+		return protocolTypes.OpenVpnCustomProfileInfo{}, fmt.Errorf("failed to parse OpenVPN profile: %w", err)
+	}
+
+	username = strings.TrimSpace(username)
+	if len(username) == 0 && len(password) == 0 {
+		// the credentials defined inline in the profile ('<auth-user-pass>' block)
+		username, password = p.Username, p.Password
+	}
+	if p.IsAuthUserPass && (len(username) == 0 || len(password) == 0) {
+		return protocolTypes.OpenVpnCustomProfileInfo{}, fmt.Errorf("the profile requires username/password authentication: credentials not defined")
+	}
+	if !p.IsAuthUserPass {
+		username, password = "", ""
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		name = p.RemoteHost
+	}
+
+	cp := preferences.CustomOvpnProfile{ID: uuid.New().String(), Name: name, Profile: profile, Username: username, Password: password}
+
+	prefs := s._preferences
+	prefs.CustomOvpnProfiles = append(append([]preferences.CustomOvpnProfile{}, prefs.CustomOvpnProfiles...), cp)
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Custom OpenVPN profile imported: '%s' (%s:%d)", cp.Name, p.RemoteHost, p.RemotePort))
+	return customOvpnProfileInfo(cp, p), nil
+}
+
+// OpenVpnCustomProfileDelete removes user-supplied OpenVPN profile
+func (s *Service) OpenVpnCustomProfileDelete(id string) error {
+	prefs := s._preferences
+
+	profiles := make([]preferences.CustomOvpnProfile, 0, len(prefs.CustomOvpnProfiles))
+	for _, p := range prefs.CustomOvpnProfiles {
+		if p.ID != id {
+			profiles = append(profiles, p)
+		}
+	}
+	if len(profiles) == len(prefs.CustomOvpnProfiles) {
+		return fmt.Errorf("custom OpenVPN profile '%s' not found", id)
+	}
+
+	prefs.CustomOvpnProfiles = profiles
+	s.setPreferences(prefs)
+	return nil
+}
+
+// OpenVpnCustomProfilesGet returns information about user-supplied OpenVPN profiles
+// (the keys and passwords are not included)
+func (s *Service) OpenVpnCustomProfilesGet() []protocolTypes.OpenVpnCustomProfileInfo {
+	prefs := s.Preferences()
+
+	ret := make([]protocolTypes.OpenVpnCustomProfileInfo, 0, len(prefs.CustomOvpnProfiles))
+	for _, cp := range prefs.CustomOvpnProfiles {
+		p, err := openvpn.ParseProfile(cp.Profile)
+		if err != nil {
+			log.Warning(fmt.Sprintf("failed to parse custom OpenVPN profile '%s': %s", cp.Name, err))
+		}
+		ret = append(ret, customOvpnProfileInfo(cp, p))
+	}
+	return ret
+}
+
+// ConnectOpenVPNCustom start OpenVPN connection with user-supplied profile
+func (s *Service) ConnectOpenVPNCustom(id string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+	var cp *preferences.CustomOvpnProfile
+	for _, p := range s.Preferences().CustomOvpnProfiles {
+		if p.ID == id {
+			cp = &p
+			break
+		}
+	}
+	if cp == nil {
+		return fmt.Errorf("custom OpenVPN profile '%s' not found", id)
+	}
+
+	profile, err := openvpn.ParseProfile(cp.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to parse custom OpenVPN profile '%s': %w", cp.Name, err)
+	}
+
+	log.Info(fmt.Sprintf("Connecting with custom OpenVPN profile '%s' (%s:%d)", cp.Name, profile.RemoteHost, profile.RemotePort))
+
+	createVpnObjfunc := func(reconnectReason error) (vpn.Process, error) {
+		// checking if functionality accessible
+		disabledFuncs := s.GetDisabledFunctions()
+		if len(disabledFuncs.OpenVPNError) > 0 {
+			return nil, fmt.Errorf(disabledFuncs.OpenVPNError)
+		}
+
+		// the remote host name is resolved on each (re)connection
+		connectionParams, err := profile.CreateConnectionParams(cp.Username, cp.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		vpnObj, err := openvpn.NewOpenVpnObject(
+			platform.OpenVpnBinaryPath(),
+			platform.OpenvpnConfigFile(),
+			"",
+			false,
+			"",
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new openVPN object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
+}
+
+func customOvpnProfileInfo(cp preferences.CustomOvpnProfile, p openvpn.Profile) protocolTypes.OpenVpnCustomProfileInfo {
+	return protocolTypes.OpenVpnCustomProfileInfo{
+		ID:             cp.ID,
+		Name:           cp.Name,
+		RemoteHost:     p.RemoteHost,
+		RemotePort:     p.RemotePort,
+		IsTCP:          p.IsTCP,
+		IsAuthUserPass: p.IsAuthUserPass,
+		Username:       cp.Username,
+	}
+}
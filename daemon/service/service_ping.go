@@ -33,9 +33,24 @@ import (
 	"github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/helpers"
 	"github.com/ivpn/desktop-app/daemon/ping"
+	"github.com/ivpn/desktop-app/daemon/ping/probe"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 )
 
+const (
+	// Number of ICMP requests sent to each host (more than one required to detect jitter and packet loss)
+	pingPacketsCount = 3
+	// Interval between ICMP requests sent to the host
+	pingPacketsInterval = 50 * time.Millisecond
+
+	// Interval of the background servers pinging (to keep the latency history up to date)
+	pingHistoryInterval = time.Hour
+	// Delay of the first background servers pinging after the daemon start
+	pingHistoryFirstDelay = 5 * time.Minute
+)
+
 // PingServers ping vpn servers.
 //
 // Pinging operation separated on few phases:
@@ -53,10 +68,21 @@ import (
 // 		In some cases the multiple (and simultaneous pings) are leading to OS crash on macOS and Windows.
 // 		It happens when installed some third-party 'security' software.
 // 		Therefore, we using ping algorithm which avoids simultaneous pings and doing it one-by-one
-func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAllHostsOnFirstPhase bool, skipSecondPhase bool) (map[string]int, error) {
+//
+// The result contains statistics for each pinged host (latency, jitter, packet loss) and the ranking score
+// which combines them with the server load (see 'PingScoreWeights' user preference).
+//
+// Probe strategy (see protocolTypes.PingProbeXXX constants):
+// by default, the hosts which are not replying to ICMP are probed on the VPN protocol level
+// (WireGuard handshake initiation; OpenVPN UDP HARD_RESET or TCP connection to the OpenVPN port).
+//
+// Pinging is allowed in connected state: the hosts are measured through the VPN tunnel in this case
+// (each result is marked by the network path it was measured on: see PingResultType.Path).
+// The pinging iteration stops when the path changes (connected, disconnected, paused or resumed).
+func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAllHostsOnFirstPhase bool, skipSecondPhase bool, probeStrategy string) (map[string]protocolTypes.PingResultType, error) {
 
-	if s._vpn != nil {
-		return nil, fmt.Errorf("servers pinging skipped due to connected state")
+	if s._vpn != nil && s.GetVpnSessionInfo().VpnLocalIPv4 == nil {
+		return nil, fmt.Errorf("servers pinging skipped: VPN connection is being established")
 	}
 
 	if timeoutMs <= 0 {
@@ -75,10 +101,16 @@ func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAl
 	timeoutTime := time.Now().Add(time.Millisecond * time.Duration(timeoutMs))
 
 	var geoLocation *types.GeoLookupResponse = nil
-	if timeoutMs >= 3000 {
+	if home := s.Preferences().UserPrefs.HomeLocation; home.IsDefined() {
+		// the home location defined by user: no need to request geo-location
+		geoLocation = &types.GeoLookupResponse{Latitude: float32(home.Latitude), Longitude: float32(home.Longitude)}
+	} else if timeoutMs >= 3000 {
+		isDirect := s.pingPath() == protocolTypes.PingPathDirect
 		l, err := s._api.GeoLookup(1500)
 		if err != nil {
 			log.Warning("(pinging) unable to obtain geo-location (fastest server detection could be not accurate):", err)
+		} else if isDirect {
+			s.saveGeoLocation(l)
 		}
 		geoLocation = l
 	} else {
@@ -95,8 +127,14 @@ func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAl
 		}
 	}()
 
-	// return value [host]latency
-	result := make(map[string]int)
+	switch probeStrategy {
+	case protocolTypes.PingProbeAuto, protocolTypes.PingProbeICMP, protocolTypes.PingProbeProtocol:
+	default:
+		return nil, fmt.Errorf("unknown probe strategy '%s'", probeStrategy)
+	}
+
+	// return value [host]statistics
+	result := make(map[string]protocolTypes.PingResultType)
 
 	allWgHosts, _ := s.getHostsToPing(geoLocation, false, vpn.WireGuard)
 	allOvpnHosts, _ := s.getHostsToPing(geoLocation, false, vpn.OpenVPN)
@@ -116,7 +154,7 @@ func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAl
 		return nil, err
 	}
 	// First ping iteration. Doing it fast. 300ms max for each server
-	s.pingIteration(hosts, result, 300, &timeoutTime)
+	s.pingIteration(hosts, result, 300, &timeoutTime, probeStrategy, true)
 
 	if !skipSecondPhase {
 		// The first ping result already received.
@@ -146,7 +184,7 @@ func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAl
 					return
 				}
 
-				s.pingIteration(hosts, result, 1000, nil)
+				s.pingIteration(hosts, result, 1000, nil, probeStrategy, true)
 				s._evtReceiver.OnPingStatus(result)
 			}
 
@@ -172,25 +210,58 @@ func (s *Service) PingServers(timeoutMs int, vpnTypePrioritized vpn.Type, pingAl
 	return result, nil
 }
 
-func (s *Service) pingIteration(hostsToPing []net.IP, pingedResult map[string]int, onePingTimeoutMs int, timeout *time.Time) /* map[string]int*/ {
+// pingPath returns the network path the hosts are currently reachable through (PingPathDirect or PingPathTunnel)
+func (s *Service) pingPath() string {
+	if s._vpn == nil || s.IsPaused() {
+		return protocolTypes.PingPathDirect
+	}
+	return protocolTypes.PingPathTunnel
+}
+
+// 'allowFirewallExceptions' - when VPN is disconnected, the servers are temporarily added to the firewall exceptions (to be reachable when the firewall is enabled)
+func (s *Service) pingIteration(hostsToPing []net.IP, pingedResult map[string]protocolTypes.PingResultType, onePingTimeoutMs int, timeout *time.Time, probeStrategy string, allowFirewallExceptions bool) {
+	path := s.pingPath()
+	// When VPN is connected (or paused) the firewall exceptions are not required:
+	// the traffic through the tunnel is allowed (and the firewall allows everything in paused state).
+	// Moreover, removing the exceptions after pinging may break the rules for the current VPN server.
+	isDisconnected := s._vpn == nil
+	isFirewallExceptionsRequired := isDisconnected && allowFirewallExceptions
+
 	// OS-specific preparations (e.g. we need to add servers IPs to firewall exceptions list)
-	if err := s.implPingServersStarting(hostsToPing); err != nil {
-		log.Error("implPingServersStarting failed: " + err.Error())
+	if isFirewallExceptionsRequired {
+		if err := s.implPingServersStarting(hostsToPing, true); err != nil {
+			log.Error("implPingServersStarting failed: " + err.Error())
+		}
 	}
 
+	prober := s.newHostProber(hostsToPing, isFirewallExceptionsRequired)
 	defer func() {
-		if err := s.implPingServersStopped(hostsToPing); err != nil {
-			log.Error("implPingServersStopped failed: " + err.Error())
+		if err := s._pingHistory.Save(); err != nil {
+			log.Error(err)
+		}
+		prober.close()
+		if isFirewallExceptionsRequired {
+			if err := s.implPingServersStopped(hostsToPing, true); err != nil {
+				log.Error("implPingServersStopped failed: " + err.Error())
+			}
 		}
 	}()
 
+	// the replies for all requests are waited 'onePingTimeoutMs' after the last request sent
+	hostPingTimeout := time.Millisecond*time.Duration(onePingTimeoutMs) + pingPacketsInterval*(pingPacketsCount-1)
+	scoreWeights := s.Preferences().UserPrefs.PingScoreWeights
+	toMs := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+	isIcmpInUse := probeStrategy != protocolTypes.PingProbeProtocol
+	icmpRepliedHosts, icmpFailedHosts := 0, 0
+
 	lastUpdateSentTime := time.Now()
 	for _, h := range hostsToPing {
-		if s._vpn != nil {
-			log.Info("Servers pinging stopped due to connected state")
+		if s.pingPath() != path || (s._vpn == nil) != isDisconnected {
+			log.Info("Servers pinging stopped due to VPN connection state change")
 			break
 		}
-		if timeout != nil && time.Now().Add(time.Millisecond*time.Duration(onePingTimeoutMs)).After(*timeout) {
+		if timeout != nil && time.Now().Add(hostPingTimeout).After(*timeout) {
 			log.Info("Servers pinging stopped due max-timeout for this operation")
 			break
 		}
@@ -208,21 +279,88 @@ func (s *Service) pingIteration(hostsToPing []net.IP, pingedResult map[string]in
 			continue
 		}
 
-		pinger, err := ping.NewPinger(ipStr)
-		if err != nil {
-			log.Error("Pinger creation error: " + err.Error())
-			continue
+		isReachable := false
+		failedProbe := "" // the way the unreachable host was checked (empty - the host was not checked)
+		// ICMP is in use also for the hosts which can not be probed on the VPN protocol level
+		// (e.g. WireGuard servers while WireGuard is connected)
+		if isIcmpInUse || !prober.isProbeAvailable(h) {
+			pinger, err := ping.NewPinger(ipStr)
+			if err != nil {
+				log.Error("Pinger creation error: " + err.Error())
+				continue
+			}
+
+			pinger.SetPrivileged(true)
+			pinger.Count = pingPacketsCount
+			pinger.Interval = pingPacketsInterval
+			pinger.Timeout = hostPingTimeout
+			pinger.Run()
+			stat := pinger.Statistics()
+
+			if stat.PacketsRecv > 0 && stat.AvgRtt > 0 {
+				isReachable = true
+				icmpRepliedHosts++
+				// the requests which were not sent (timeout) are considered as lost
+				loss := float64(pingPacketsCount-stat.PacketsRecv) * 100 / pingPacketsCount
+				load := float64(prober.load(ipStr))
+
+				pingedResult[ipStr] = s.pingHistoryAdd(protocolTypes.PingResultType{
+					Host:       ipStr,
+					Ping:       int(stat.AvgRtt / time.Millisecond),
+					PingMin:    int(stat.MinRtt / time.Millisecond),
+					PingMax:    int(stat.MaxRtt / time.Millisecond),
+					Jitter:     toMs(stat.StdDevRtt),
+					PacketLoss: loss,
+					Load:       load,
+					Score:      scoreWeights.Score(toMs(stat.AvgRtt), toMs(stat.StdDevRtt), loss, load),
+					Probe:      string(probe.ModeICMP),
+					Path:       path,
+				})
+			} else {
+				failedProbe = string(probe.ModeICMP)
+				icmpFailedHosts++
+				if probeStrategy == protocolTypes.PingProbeAuto && icmpRepliedHosts == 0 && icmpFailedHosts >= icmpBlockedThreshold {
+					log.Info("(pinging) no ICMP replies received (ICMP seems to be blocked): using VPN protocol probes only")
+					isIcmpInUse = false
+				}
+			}
 		}
 
-		pinger.SetPrivileged(true)
-		pinger.Count = 1
-		pinger.Timeout = time.Millisecond * time.Duration(onePingTimeoutMs)
-		pinger.Run()
-		stat := pinger.Statistics()
+		// fallback: probe the host on VPN protocol level
+		if !isReachable && probeStrategy != protocolTypes.PingProbeICMP && prober.isProbeAvailable(h) {
+			if rtt, mode, err := prober.probe(h, time.Millisecond*time.Duration(onePingTimeoutMs)); err != nil {
+				if len(mode) > 0 {
+					failedProbe = string(mode)
+				}
+			} else {
+				isReachable = true
+				// single probe: no jitter and packet loss info
+				load := float64(prober.load(ipStr))
+				pingedResult[ipStr] = s.pingHistoryAdd(protocolTypes.PingResultType{
+					Host:    ipStr,
+					Ping:    int(rtt / time.Millisecond),
+					PingMin: int(rtt / time.Millisecond),
+					PingMax: int(rtt / time.Millisecond),
+					Load:    load,
+					Score:   scoreWeights.Score(toMs(rtt), 0, 0, load),
+					Probe:   string(mode),
+					Path:    path,
+				})
+			}
+		}
 
-		if stat.AvgRtt > 0 {
-			ttl := int(stat.AvgRtt / time.Millisecond)
-			pingedResult[ipStr] = ttl
+		if !isReachable && len(failedProbe) > 0 {
+			// the failed attempt is stored into the history: it worsens the stable score of the unreachable host
+			// (the result is not reported to clients: there is no latency info)
+			load := float64(prober.load(ipStr))
+			s.pingHistoryAdd(protocolTypes.PingResultType{
+				Host:       ipStr,
+				PacketLoss: 100,
+				Load:       load,
+				Score:      scoreWeights.ScoreUnreachable(toMs(hostPingTimeout), load),
+				Probe:      failedProbe,
+				Path:       path,
+			})
 		}
 
 		if timeout == nil && time.Now().After(lastUpdateSentTime.Add(time.Second*2)) && len(pingedResult) > 0 {
@@ -233,6 +371,81 @@ func (s *Service) pingIteration(hostsToPing []net.IP, pingedResult map[string]in
 	}
 }
 
+// pingHistoryAdd stores the ping result into the latency history and updates the result with the stable score
+func (s *Service) pingHistoryAdd(res protocolTypes.PingResultType) protocolTypes.PingResultType {
+	now := time.Now()
+	s._pingHistory.Add(res.Host, pinghistory.Sample{
+		Time:       now.Unix(),
+		Ping:       res.Ping,
+		Jitter:     res.Jitter,
+		PacketLoss: res.PacketLoss,
+		Score:      res.Score,
+		Probe:      res.Probe,
+		Path:       res.Path,
+	})
+	res.StableScore, res.HistorySamples = s._pingHistory.StableScore(res.Host, res.Path, now)
+	return res
+}
+
+// PingHistory returns the history of ping results for the host (the latest sample is the last one)
+func (s *Service) PingHistory(host string, since time.Time) []pinghistory.Sample {
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return s._pingHistory.Samples(host, since)
+}
+
+// pingHistoryCollector periodically pings all servers in background to keep the latency history up to date
+// (the results are also collected opportunistically: each time the servers are pinged by client request)
+// The background pinging is opt-in (UserPreferences.PingHistoryBackgroundCollection) and it is skipped when:
+// the user is logged out; the firewall is enabled while VPN is disconnected (no firewall exceptions are added for background pinging).
+// Only ICMP is in use: no VPN protocol probes (e.g. WireGuard handshakes with the user's key) are sent in background.
+func (s *Service) pingHistoryCollector() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("PANIC in ping history collector!: ", r)
+			if err, ok := r.(error); ok {
+				log.ErrorTrace(err)
+			}
+		}
+	}()
+
+	for delay := pingHistoryFirstDelay; ; delay = pingHistoryInterval {
+		time.Sleep(delay)
+
+		prefs := s.Preferences()
+		if !prefs.UserPrefs.PingHistoryBackgroundCollection || !prefs.Session.IsLoggedIn() {
+			continue
+		}
+		if s._vpn != nil && s.GetVpnSessionInfo().VpnLocalIPv4 == nil {
+			log.Info("(pinging) background pinging skipped: VPN connection is being established")
+			continue
+		}
+		if s._vpn == nil {
+			if fwEnabled, err := s.FirewallEnabled(); err != nil || fwEnabled {
+				log.Info("(pinging) background pinging skipped: firewall is enabled")
+				continue
+			}
+		}
+		// skip if the servers are pinging by client request at the moment
+		if !s._serversPingProgressSemaphore.TryAcquire(1) {
+			continue
+		}
+
+		hosts, err := s.getHostsToPing(nil, false, vpn.Type(-1))
+		if err != nil {
+			log.Info("(pinging) background pinging failed: " + err.Error())
+		} else {
+			result := make(map[string]protocolTypes.PingResultType)
+			s.pingIteration(hosts, result, 1000, nil, protocolTypes.PingProbeICMP, false)
+			if len(result) > 0 {
+				s._evtReceiver.OnPingStatus(result)
+			}
+		}
+		s._serversPingProgressSemaphore.Release(1)
+	}
+}
+
 // if 'currentLocation' defined - the output hosts list will be sorted by distance to current location
 func (s *Service) getHostsToPing(currentLocation *types.GeoLookupResponse, onlyOneHostPerServer bool, vpnTypePrioritized vpn.Type) ([]net.IP, error) {
 	// get servers info
@@ -0,0 +1,215 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	"github.com/ivpn/desktop-app/daemon/ping/probe"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// Number of hosts which did not reply to ICMP (while no host replied) to consider ICMP blocked by the network.
+// Afterwards, only VPN protocol probes are in use (for the current pinging iteration).
+const icmpBlockedThreshold = 3
+
+// pingTarget - information about the host required for reachability probing
+type pingTarget struct {
+	load        float32
+	isWireGuard bool
+	wgPublicKey string
+}
+
+// hostProber checks the reachability of VPN servers on the VPN protocol level
+// (in use when ICMP is not accessible: e.g. blocked in hotel or airport networks)
+type hostProber struct {
+	service *Service
+	hosts   []net.IP
+	targets map[string]pingTarget
+
+	wgPort        int    // UDP
+	ovpnUDPPort   int    // 0 - not defined
+	ovpnTCPPort   int    // 0 - not defined
+	wgPrivateKey  string // empty - WireGuard probes are not possible (not logged in or WireGuard is connected)
+	ovpnStaticKey []byte // 'tls-auth' key; nil - OpenVPN UDP probes are not possible
+
+	isFirewallRequired   bool // false - the firewall exceptions are not required (e.g. probing through the VPN tunnel)
+	isFirewallConfigured bool
+}
+
+// newHostProber initializes the prober for the hosts
+func (s *Service) newHostProber(hosts []net.IP, isFirewallRequired bool) *hostProber {
+	p := &hostProber{service: s, hosts: hosts, targets: make(map[string]pingTarget), isFirewallRequired: isFirewallRequired}
+
+	servers, err := s._serversUpdater.GetServers()
+	if err != nil {
+		return p
+	}
+
+	for _, svr := range servers.OpenvpnServers {
+		for _, h := range svr.Hosts {
+			if ip := net.ParseIP(h.Host); ip != nil {
+				p.targets[ip.String()] = pingTarget{load: h.Load}
+			}
+		}
+	}
+	for _, svr := range servers.WireguardServers {
+		for _, h := range svr.Hosts {
+			if ip := net.ParseIP(h.Host); ip != nil {
+				p.targets[ip.String()] = pingTarget{load: h.Load, isWireGuard: true, wgPublicKey: h.PublicKey}
+			}
+		}
+	}
+
+	firstPort := func(ports []types.PortInfo, isTCP bool) int {
+		for _, pi := range ports {
+			if pi.Port > 0 && pi.IsTCP() == isTCP {
+				return pi.Port
+			}
+		}
+		return 0
+	}
+	p.wgPort = firstPort(servers.Config.Ports.WireGuard, false)
+	p.ovpnUDPPort = firstPort(servers.Config.Ports.OpenVPN, false)
+	p.ovpnTCPPort = firstPort(servers.Config.Ports.OpenVPN, true)
+
+	// No WireGuard probes while WireGuard is connected: the handshake initiation with the account's key
+	// makes the server to switch the endpoint of the active peer to the probe socket (the tunnel stalls)
+	isWgConnected := false
+	if vpnObj := s._vpn; vpnObj != nil && vpnObj.Type() == vpn.WireGuard {
+		isWgConnected = true
+	}
+	if session := s.Preferences().Session; session.IsLoggedIn() && session.IsWGCredentialsOk() && !isWgConnected {
+		p.wgPrivateKey = session.WGPrivateKey
+	}
+	return p
+}
+
+// isProbeAvailable returns 'false' when the host can not be probed on the VPN protocol level
+// (e.g. WireGuard servers while WireGuard is connected): only ICMP is applicable for such hosts
+func (p *hostProber) isProbeAvailable(host net.IP) bool {
+	target, ok := p.targets[host.String()]
+	if !ok {
+		return false
+	}
+	if target.isWireGuard {
+		return len(p.wgPrivateKey) > 0 && p.wgPort > 0
+	}
+	return true
+}
+
+// load returns the host load (as reported by the servers list)
+func (p *hostProber) load(host string) float32 {
+	return p.targets[host].load
+}
+
+// probe checks the reachability of the host on the VPN protocol level. Returns the round-trip time and the probe mode.
+// Fallback order: WireGuard servers - handshake initiation; OpenVPN servers - UDP HARD_RESET, then TCP connect
+func (p *hostProber) probe(host net.IP, timeout time.Duration) (time.Duration, probe.Mode, error) {
+	target, ok := p.targets[host.String()]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown host")
+	}
+
+	p.ensureFirewallConfigured()
+
+	if target.isWireGuard {
+		if len(p.wgPrivateKey) == 0 || p.wgPort <= 0 {
+			return 0, "", fmt.Errorf("WireGuard probe is not possible (WireGuard credentials or port not defined, or WireGuard is connected)")
+		}
+		rtt, err := probe.WireGuardHandshake(host, p.wgPort, target.wgPublicKey, p.wgPrivateKey, timeout)
+		return rtt, probe.ModeWireGuard, err
+	}
+
+	var retErr error
+	var retMode probe.Mode // the last probe attempted (empty - no probes possible)
+	if p.ovpnUDPPort > 0 {
+		if staticKey := p.openVpnStaticKey(); staticKey != nil {
+			const keyDirection = 1 // the same as in use for OpenVPN connection ('tls-auth <file> 1')
+			rtt, err := probe.OpenVpnHardReset(host, p.ovpnUDPPort, staticKey, keyDirection, timeout)
+			if err == nil {
+				return rtt, probe.ModeOpenVpnUDP, nil
+			}
+			retErr, retMode = err, probe.ModeOpenVpnUDP
+		}
+	}
+	if p.ovpnTCPPort > 0 {
+		rtt, err := probe.TCPConnect(host, p.ovpnTCPPort, timeout)
+		if err == nil {
+			return rtt, probe.ModeOpenVpnTCP, nil
+		}
+		retErr, retMode = err, probe.ModeOpenVpnTCP
+	}
+	if retErr == nil {
+		retErr = fmt.Errorf("OpenVPN probe is not possible (ports not defined)")
+	}
+	return 0, retMode, retErr
+}
+
+// openVpnStaticKey returns OpenVPN 'tls-auth' key (nil - not available)
+func (p *hostProber) openVpnStaticKey() []byte {
+	if p.ovpnStaticKey == nil {
+		data, err := os.ReadFile(platform.OpenvpnTaKeyFile())
+		if err != nil {
+			log.Warning(fmt.Sprintf("(pinging) OpenVPN TLS auth key not available: %s", err))
+			p.ovpnStaticKey = []byte{}
+			return nil
+		}
+		if p.ovpnStaticKey, err = probe.ParseStaticKey(data); err != nil {
+			log.Warning(fmt.Sprintf("(pinging) %s", err))
+			p.ovpnStaticKey = []byte{}
+			return nil
+		}
+	}
+	if len(p.ovpnStaticKey) == 0 {
+		return nil
+	}
+	return p.ovpnStaticKey
+}
+
+// ensureFirewallConfigured allows all communication with the hosts (not only ICMP) during probing
+func (p *hostProber) ensureFirewallConfigured() {
+	if p.isFirewallConfigured || !p.isFirewallRequired {
+		return
+	}
+	p.isFirewallConfigured = true
+	if err := p.service.implPingServersStarting(p.hosts, false); err != nil {
+		log.Error("implPingServersStarting failed: " + err.Error())
+	}
+}
+
+// close removes the firewall exceptions (if were added)
+func (p *hostProber) close() {
+	if !p.isFirewallConfigured {
+		return
+	}
+	p.isFirewallConfigured = false
+	if err := p.service.implPingServersStopped(p.hosts, false); err != nil {
+		log.Error("implPingServersStopped failed: " + err.Error())
+	}
+}
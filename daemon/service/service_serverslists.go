@@ -0,0 +1,98 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/service/srverrors"
+)
+
+// SetServersFavorites sets the list of favorite servers
+func (s *Service) SetServersFavorites(list preferences.ServersList) error {
+	prefs := s._preferences
+	prefs.ServersFavorites = list.Normalized()
+	s.setPreferences(prefs)
+	return nil
+}
+
+// SetServersExcluded sets the list of excluded servers.
+// The excluded servers (hosts) are never in use for connection.
+func (s *Service) SetServersExcluded(list preferences.ServersList) error {
+	prefs := s._preferences
+	prefs.ServersExcluded = list.Normalized()
+	s.setPreferences(prefs)
+	return nil
+}
+
+// IsServerHostExcluded returns true if the host is in the excluded list (by host name, by its server location or by country).
+// The host is defined by the host name or by IP address.
+func (s *Service) IsServerHostExcluded(hostname, hostIP string) bool {
+	excluded := s.Preferences().ServersExcluded
+	if excluded.IsEmpty() {
+		return false
+	}
+
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		log.Warning(fmt.Sprintf("unable to check if the host '%s' is excluded: servers list not available", hostname))
+		return excluded.IsHostMatch("", "", hostname)
+	}
+
+	isHost := func(hName, hIP string) bool {
+		return (len(hostname) > 0 && strings.EqualFold(hName, hostname)) || (len(hostIP) > 0 && strings.TrimSpace(hIP) == hostIP)
+	}
+
+	for _, svr := range servers.WireguardServers {
+		for _, h := range svr.Hosts {
+			if isHost(h.Hostname, h.Host) {
+				return excluded.IsHostMatch(svr.Gateway, svr.CountryCode, h.Hostname)
+			}
+		}
+	}
+	for _, svr := range servers.OpenvpnServers {
+		for _, h := range svr.Hosts {
+			if isHost(h.Hostname, h.Host) {
+				return excluded.IsHostMatch(svr.Gateway, svr.CountryCode, h.Hostname)
+			}
+		}
+	}
+
+	return excluded.IsHostMatch("", "", hostname)
+}
+
+// checkServerHostsNotExcluded returns srverrors.ErrorServerExcluded when the entry host (defined by IP address)
+// or the Multi-Hop exit host (defined by host name; empty - Single-Hop) is in the excluded list.
+// It is called on each (re)connection, so the excluded servers are never in use (whatever client requested the connection).
+func (s *Service) checkServerHostsNotExcluded(entryHost net.IP, exitHostname string) error {
+	if entryHost != nil && s.IsServerHostExcluded("", entryHost.String()) {
+		return srverrors.ErrorServerExcluded{Server: entryHost.String()}
+	}
+	if len(exitHostname) > 0 && s.IsServerHostExcluded(exitHostname, "") {
+		return srverrors.ErrorServerExcluded{Server: exitHostname}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api/types"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/pinghistory"
+	"github.com/ivpn/desktop-app/daemon/service/serversquery"
+)
+
+// QueryServers returns the servers hosts which are matching the query (see 'serversquery' package for the query syntax)
+// The excluded hosts are skipped unless the query explicitly checks the 'excluded' field.
+func (s *Service) QueryServers(query string) ([]serversquery.Host, error) {
+	q, err := serversquery.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	servers, err := s.ServersList()
+	if err != nil {
+		return nil, err
+	}
+
+	// the data which is expensive to get is obtained only when it is in use by the query
+	var origin *serversquery.Location
+	if q.IsUsingField(serversquery.FieldDistance) {
+		if origin, _ = s.CurrentLocation(); origin == nil {
+			log.Warning("(servers query) the current location is unknown: distance to servers can not be calculated (the home location can be defined by user)")
+		}
+	}
+
+	hosts := serversquery.HostsFromServers(servers, origin)
+
+	prefs := s.Preferences()
+	isSkipExcluded := !q.IsUsingField(serversquery.FieldExcluded)
+	filtered := hosts[:0]
+	for _, h := range hosts {
+		h.IsFavorite = prefs.ServersFavorites.IsHostMatch(h.Gateway, h.CountryCode, h.Hostname)
+		h.IsExcluded = prefs.ServersExcluded.IsHostMatch(h.Gateway, h.CountryCode, h.Hostname)
+		if h.IsExcluded && isSkipExcluded {
+			continue
+		}
+		filtered = append(filtered, h)
+	}
+	hosts = filtered
+
+	if q.IsUsingField(serversquery.FieldPing) {
+		// the latest measured latency of the host
+		since := time.Now().Add(-pinghistory.StableScoreMaxAge)
+		for i := range hosts {
+			if samples := s._pingHistory.Samples(hosts[i].Host, since); len(samples) > 0 {
+				hosts[i].PingMs = samples[len(samples)-1].Ping
+			}
+		}
+	}
+
+	return q.Apply(hosts), nil
+}
+
+// CurrentLocation returns the current geographical location (nil - unknown) and the source of this information.
+// The home location defined by user (if any) has priority over the geo-lookup result.
+// The location is requested from the API only when the traffic is not going through the VPN tunnel
+// (otherwise, the location of the VPN server is detected); the last known location is in use in this case.
+func (s *Service) CurrentLocation() (location *serversquery.Location, source string) {
+	if home := s.Preferences().UserPrefs.HomeLocation; home.IsDefined() {
+		return &serversquery.Location{Latitude: home.Latitude, Longitude: home.Longitude}, protocolTypes.LocationSourceHome
+	}
+
+	if s.pingPath() == protocolTypes.PingPathDirect {
+		if l, err := s._api.GeoLookup(1500); err != nil {
+			log.Warning("unable to obtain geo-location: ", err)
+		} else {
+			s.saveGeoLocation(l)
+		}
+	}
+
+	s._geoLocationMutex.Lock()
+	defer s._geoLocationMutex.Unlock()
+	if s._geoLocation == nil {
+		return nil, ""
+	}
+	return &serversquery.Location{Latitude: float64(s._geoLocation.Latitude), Longitude: float64(s._geoLocation.Longitude)}, protocolTypes.LocationSourceGeoLookup
+}
+
+// saveGeoLocation keeps the last known geographical location
+// (must be called only for the location detected when the traffic is not going through the VPN tunnel)
+func (s *Service) saveGeoLocation(l *types.GeoLookupResponse) {
+	if l == nil || (l.Latitude == 0 && l.Longitude == 0) {
+		return
+	}
+	s._geoLocationMutex.Lock()
+	defer s._geoLocationMutex.Unlock()
+	s._geoLocation = l
+}
@@ -0,0 +1,275 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/service/firewall"
+	"github.com/ivpn/desktop-app/daemon/splittun"
+)
+
+const (
+	// interval to re-resolve domain names from destination-based split-tunnel configuration
+	splitTunDomainsResolveInterval = time.Minute * 5
+	// max time to wait for domain name resolving
+	splitTunDomainResolveTimeout = time.Second * 3
+)
+
+var (
+	// resolved domain names from destination-based split-tunnel configuration (map[<domain>][]<IP>)
+	splitTunResolvedDomains      = map[string][]net.IP{}
+	splitTunResolvedDomainsMutex sync.Mutex
+	// triggers immediate resolving of domain names (e.g. when configuration changed)
+	splitTunResolveTrigger = make(chan struct{}, 1)
+
+	regexpDomainName = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,63}\.?$`)
+)
+
+// SplitTunnelling_SetDestinations saves destination-based split-tunnel configuration
+// Parameters:
+//   - bypassDests - destinations which always bypass the VPN tunnel
+//   - vpnDests 	 - destinations which always use the VPN tunnel
+//
+// Each destination can be IP address, mask in format x.x.x.x/xx or domain name
+func (s *Service) SplitTunnelling_SetDestinations(bypassDests []string, vpnDests []string) error {
+	bypassDests, err := normalizeSplitTunDestinations(bypassDests)
+	if err != nil {
+		return err
+	}
+	vpnDests, err = normalizeSplitTunDestinations(vpnDests)
+	if err != nil {
+		return err
+	}
+
+	prefs := s._preferences
+	prefs.SplitTunnelBypassDests = bypassDests
+	prefs.SplitTunnelVpnDests = vpnDests
+	s.setPreferences(prefs)
+
+	// new domains (if any) will be applied after they are resolved
+	triggerSplitTunDomainsResolve()
+
+	return s.splitTunnelling_ApplyConfig()
+}
+
+// splitTunnelling_GetDestinations returns destination-based split-tunnel configuration
+// with domain names converted to already resolved IP addresses
+// (it does not block on DNS requests: not yet resolved domains are skipped until splitTunnelling_domainsResolver() resolves them)
+func (s *Service) splitTunnelling_GetDestinations() splittun.ConfigDestinations {
+	prefs := s.Preferences()
+	return splittun.ConfigDestinations{
+		Bypass: resolveSplitTunDestinations(prefs.SplitTunnelBypassDests),
+		Tunnel: resolveSplitTunDestinations(prefs.SplitTunnelVpnDests),
+	}
+}
+
+// splitTunnelling_GetResolvedDomains returns last resolved IP addresses of domain names from destination-based split-tunnel configuration
+func (s *Service) splitTunnelling_GetResolvedDomains() map[string][]string {
+	splitTunResolvedDomainsMutex.Lock()
+	defer splitTunResolvedDomainsMutex.Unlock()
+
+	ret := make(map[string][]string, len(splitTunResolvedDomains))
+	for domain, ips := range splitTunResolvedDomains {
+		ipStrings := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			ipStrings = append(ipStrings, ip.String())
+		}
+		ret[domain] = ipStrings
+	}
+	return ret
+}
+
+// splitTunnelling_applyDestinationsFirewall allows 'bypass' destinations in firewall
+func (s *Service) splitTunnelling_applyDestinationsFirewall(destCfg splittun.ConfigDestinations) {
+	if err := firewall.SetSplitTunnelExceptions(destCfg.Bypass); err != nil {
+		log.Error(fmt.Errorf("failed to apply split-tunnel destinations to firewall: %w", err))
+	}
+}
+
+// splitTunnelling_domainsResolver resolves domain names from destination-based split-tunnel configuration
+// (on start, when configuration changed and periodically when connected)
+// and re-applies split-tunnel configuration when resolved addresses changed
+// (some domains - e.g. CDN - can change their addresses frequently)
+func (s *Service) splitTunnelling_domainsResolver() {
+	isTriggered := true // resolve immediately on start
+	for {
+		if !isTriggered && !s.Connected() {
+			isTriggered = waitSplitTunDomainsResolve()
+			continue
+		}
+
+		prefs := s.Preferences()
+		dests := append(append([]string{}, prefs.SplitTunnelBypassDests...), prefs.SplitTunnelVpnDests...)
+
+		isChanged := false
+		for _, d := range dests {
+			if _, isDomain, err := parseSplitTunDestination(d); err != nil || !isDomain {
+				continue
+			}
+			if resolveSplitTunDomain(d) {
+				isChanged = true
+			}
+		}
+
+		if isChanged {
+			log.Info("Split Tunnel destinations: resolved addresses changed. Applying configuration...")
+			s.splitTunnelling_ApplyConfig()
+		}
+
+		isTriggered = waitSplitTunDomainsResolve()
+	}
+}
+
+// waitSplitTunDomainsResolve blocks until next domains resolving required
+// Returns 'true' when resolving was triggered explicitly (not by timeout)
+func waitSplitTunDomainsResolve() bool {
+	select {
+	case <-splitTunResolveTrigger:
+		return true
+	case <-time.After(splitTunDomainsResolveInterval):
+		return false
+	}
+}
+
+func triggerSplitTunDomainsResolve() {
+	select {
+	case splitTunResolveTrigger <- struct{}{}:
+	default: // already triggered
+	}
+}
+
+// normalizeSplitTunDestinations checks and normalizes destinations
+// (single IP addresses converted to masks; domain names converted to lower case; duplicates removed)
+func normalizeSplitTunDestinations(dests []string) ([]string, error) {
+	ret := make([]string, 0, len(dests))
+	exists := make(map[string]struct{}, len(dests))
+	for _, d := range dests {
+		d = strings.TrimSpace(d)
+		if len(d) == 0 {
+			continue
+		}
+
+		n, isDomain, err := parseSplitTunDestination(d)
+		if err != nil {
+			return nil, err
+		}
+		if isDomain {
+			d = strings.TrimSuffix(strings.ToLower(d), ".")
+		} else {
+			d = n.String()
+		}
+
+		if _, ok := exists[d]; ok {
+			continue
+		}
+		exists[d] = struct{}{}
+		ret = append(ret, d)
+	}
+	return ret, nil
+}
+
+// parseSplitTunDestination parses destination: IP address, mask (x.x.x.x/xx) or domain name
+func parseSplitTunDestination(dest string) (n *net.IPNet, isDomain bool, err error) {
+	if strings.Contains(dest, "/") {
+		_, n, err = net.ParseCIDR(dest)
+		if err != nil {
+			return nil, false, fmt.Errorf("bad split-tunnel destination '%s': %w", dest, err)
+		}
+		return n, false, nil
+	}
+
+	if ip := net.ParseIP(dest); ip != nil {
+		return ipToNet(ip), false, nil
+	}
+
+	if !regexpDomainName.MatchString(dest) {
+		return nil, false, fmt.Errorf("bad split-tunnel destination '%s': expected IP address, mask (x.x.x.x/xx) or domain name", dest)
+	}
+	return nil, true, nil
+}
+
+// resolveSplitTunDestinations converts destinations to list of masks
+// (the cached addresses are used for domains)
+func resolveSplitTunDestinations(dests []string) []net.IPNet {
+	ret := make([]net.IPNet, 0, len(dests))
+	for _, d := range dests {
+		n, isDomain, err := parseSplitTunDestination(d)
+		if err != nil {
+			log.Warning(err)
+			continue
+		}
+		if !isDomain {
+			ret = append(ret, *n)
+			continue
+		}
+
+		splitTunResolvedDomainsMutex.Lock()
+		ips := splitTunResolvedDomains[d]
+		splitTunResolvedDomainsMutex.Unlock()
+
+		for _, ip := range ips {
+			ret = append(ret, *ipToNet(ip))
+		}
+	}
+	return ret
+}
+
+// resolveSplitTunDomain resolves domain name and updates cached addresses
+// Returns 'true' when resolved addresses changed
+func resolveSplitTunDomain(domain string) (isChanged bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), splitTunDomainResolveTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", domain)
+	if err != nil {
+		// keep using previously resolved addresses (if any)
+		log.Warning(fmt.Sprintf("Split Tunnel destinations: failed to resolve '%s': %s", domain, err))
+		return false
+	}
+	// sorting: to be able to detect changes in resolved addresses
+	sort.Slice(ips, func(i, j int) bool { return ips[i].String() < ips[j].String() })
+
+	splitTunResolvedDomainsMutex.Lock()
+	defer splitTunResolvedDomainsMutex.Unlock()
+
+	if reflect.DeepEqual(splitTunResolvedDomains[domain], ips) {
+		return false
+	}
+	splitTunResolvedDomains[domain] = ips
+	return true
+}
+
+func ipToNet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
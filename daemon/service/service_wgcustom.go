@@ -0,0 +1,182 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	protocolTypes "github.com/ivpn/desktop-app/daemon/protocol/types"
+	"github.com/ivpn/desktop-app/daemon/service/dns"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+// Custom WireGuard servers: third-party WireGuard configurations (in 'wg-quick' format) imported by the user.
+// Connection to a custom server does not require IVPN account.
+
+const maxCustomWgConfigSize = 64 * 1024
+
+// WireGuardCustomServerImport imports third-party WireGuard configuration as a custom server
+func (s *Service) WireGuardCustomServerImport(name string, config string) (protocolTypes.WireGuardCustomServerInfo, error) {
+	if len(config) > maxCustomWgConfigSize {
+		return protocolTypes.WireGuardCustomServerInfo{}, fmt.Errorf("configuration is too big")
+	}
+
+	cfg, err := wireguard.ParseConfig(config)
+	if err != nil {
+		return protocolTypes.WireGuardCustomServerInfo{}, fmt.Errorf("failed to parse WireGuard configuration: %w", err)
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		name = cfg.Endpoint
+	}
+
+	srv := preferences.CustomWgServer{ID: uuid.New().String(), Name: name, Config: config}
+
+	prefs := s._preferences
+	prefs.CustomWgServers = append(append([]preferences.CustomWgServer{}, prefs.CustomWgServers...), srv)
+	s.setPreferences(prefs)
+
+	log.Info(fmt.Sprintf("Custom WireGuard server imported: '%s' (%s)", srv.Name, cfg.Endpoint))
+	return customWgServerInfo(srv.ID, srv.Name, cfg), nil
+}
+
+// WireGuardCustomServerDelete removes custom WireGuard server
+func (s *Service) WireGuardCustomServerDelete(id string) error {
+	prefs := s._preferences
+
+	servers := make([]preferences.CustomWgServer, 0, len(prefs.CustomWgServers))
+	for _, srv := range prefs.CustomWgServers {
+		if srv.ID != id {
+			servers = append(servers, srv)
+		}
+	}
+	if len(servers) == len(prefs.CustomWgServers) {
+		return fmt.Errorf("custom WireGuard server '%s' not found", id)
+	}
+
+	prefs.CustomWgServers = servers
+	s.setPreferences(prefs)
+	return nil
+}
+
+// WireGuardCustomServersGet returns information about custom WireGuard servers
+// (the private keys are not included)
+func (s *Service) WireGuardCustomServersGet() []protocolTypes.WireGuardCustomServerInfo {
+	prefs := s.Preferences()
+
+	ret := make([]protocolTypes.WireGuardCustomServerInfo, 0, len(prefs.CustomWgServers))
+	for _, srv := range prefs.CustomWgServers {
+		cfg, err := wireguard.ParseConfig(srv.Config)
+		if err != nil {
+			log.Warning(fmt.Sprintf("failed to parse configuration of custom WireGuard server '%s': %s", srv.Name, err))
+		}
+		ret = append(ret, customWgServerInfo(srv.ID, srv.Name, cfg))
+	}
+	return ret
+}
+
+// ConnectWireGuardCustom start WireGuard connection to the custom (imported) server
+func (s *Service) ConnectWireGuardCustom(id string, manualDNS dns.DnsSettings, firewallOn bool, firewallDuringConnection bool, stateChan chan<- vpn.StateInfo) error {
+	srv, cfg, err := s.wireGuardCustomServerConfig(id)
+	if err != nil {
+		return err
+	}
+
+	// stop active connection (if exists)
+	if err := s.Disconnect(); err != nil {
+		return fmt.Errorf("failed to connect. Unable to stop active connection: %w", err)
+	}
+
+	// checking if functionality accessible
+	disabledFuncs := s.GetDisabledFunctions()
+	if len(disabledFuncs.WireGuardError) > 0 {
+		return fmt.Errorf(disabledFuncs.WireGuardError)
+	}
+
+	log.Info(fmt.Sprintf("Connecting to custom WireGuard server '%s' (%s)", srv.Name, cfg.Endpoint))
+
+	createVpnObjfunc := func(reconnectReason error) (vpn.Process, error) {
+		// the endpoint host name is resolved on each (re)connection
+		connectionParams, err := cfg.CreateConnectionParams()
+		if err != nil {
+			return nil, err
+		}
+		connectionParams.SetUserspaceForced(s.Preferences().UserPrefs.Linux.IsWgUserspace)
+		connectionParams.SetStallTimeout(time.Duration(s.Preferences().UserPrefs.WgStallTimeoutSec) * time.Second)
+
+		vpnObj, err := wireguard.NewWireGuardObject(
+			platform.WgBinaryPath(),
+			platform.WgToolBinaryPath(),
+			platform.WGConfigFilePath(),
+			connectionParams)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new WireGuard object: %w", err)
+		}
+		return vpnObj, nil
+	}
+
+	return s.keepConnection(createVpnObjfunc, manualDNS, firewallOn, firewallDuringConnection, stateChan)
+}
+
+// wireGuardCustomServerConfig returns the custom (imported) WireGuard server and its parsed configuration
+func (s *Service) wireGuardCustomServerConfig(id string) (preferences.CustomWgServer, wireguard.Config, error) {
+	for _, srv := range s.Preferences().CustomWgServers {
+		if srv.ID == id {
+			cfg, err := wireguard.ParseConfig(srv.Config)
+			if err != nil {
+				return srv, wireguard.Config{}, fmt.Errorf("failed to parse configuration of custom WireGuard server '%s': %w", srv.Name, err)
+			}
+			return srv, cfg, nil
+		}
+	}
+	return preferences.CustomWgServer{}, wireguard.Config{}, fmt.Errorf("custom WireGuard server '%s' not found", id)
+}
+
+func customWgServerInfo(id string, name string, cfg wireguard.Config) protocolTypes.WireGuardCustomServerInfo {
+	ret := protocolTypes.WireGuardCustomServerInfo{
+		ID:             id,
+		Name:           name,
+		Endpoint:       cfg.Endpoint,
+		PublicKey:      cfg.PublicKey,
+		IsPresharedKey: len(cfg.PresharedKey) > 0,
+		Mtu:            cfg.MTU,
+	}
+	for _, ip := range cfg.Addresses {
+		ret.Addresses = append(ret.Addresses, ip.String())
+	}
+	for _, ip := range cfg.DNS {
+		ret.DNS = append(ret.DNS, ip.String())
+	}
+	for _, n := range cfg.AllowedIPs {
+		ret.AllowedIPs = append(ret.AllowedIPs, n.String())
+	}
+	return ret
+}
@@ -0,0 +1,99 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ivpn/desktop-app/daemon/helpers"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+// WireGuard failover.
+// When the tunnel stays stalled longer than the stall timeout, the watchdog stops the connection (wireguard.TunnelStalledError)
+// and the service reconnects. If the tunnel stalls again ('wgFailoverAfterStalls' times in a row on the same server),
+// the connection fails over to another server: the nearest one to the current location (see CurrentLocation())
+// which is not in the excluded list.
+// Note: failover is not applicable for Multi-Hop connections and for connections with chained custom WireGuard server
+// (the route is explicitly defined by user).
+const wgFailoverAfterStalls = 2
+
+// wireGuardFailoverParams returns the connection parameters for the nearest server other than the one in 'params'
+func (s *Service) wireGuardFailoverParams(params wireguard.ConnectionParams) (wireguard.ConnectionParams, error) {
+	if params.IsMultiHop() {
+		return params, fmt.Errorf("not applicable for Multi-Hop connection")
+	}
+
+	servers, err := s.ServersList()
+	if err != nil || servers == nil {
+		return params, fmt.Errorf("servers list is not available")
+	}
+
+	// the server (location) of the stalled connection
+	failedGateway := ""
+	for _, svr := range servers.WireguardServers {
+		for _, h := range svr.Hosts {
+			if net.ParseIP(strings.TrimSpace(h.Host)).Equal(params.HostIP()) {
+				failedGateway = svr.Gateway
+			}
+		}
+	}
+
+	query := "proto = wg"
+	if params.IsIPv6() {
+		query += " and ipv6"
+	}
+	candidates, err := s.QueryServers(query + " sort by distance")
+	if err != nil {
+		return params, err
+	}
+
+	for _, c := range candidates {
+		if c.Gateway == failedGateway || net.ParseIP(c.Host).Equal(params.HostIP()) {
+			continue
+		}
+		for _, svr := range servers.WireguardServers {
+			for _, h := range svr.Hosts {
+				if strings.TrimSpace(h.Host) != c.Host || !helpers.ValidateBase64(h.PublicKey) {
+					continue
+				}
+				hostIP := net.ParseIP(c.Host)
+				hostLocalIP := net.ParseIP(strings.Split(h.LocalIP, "/")[0])
+				if hostIP == nil || hostLocalIP == nil {
+					continue
+				}
+				ipv6Prefix := ""
+				if params.IsIPv6() {
+					ipv6Prefix = strings.Split(h.IPv6.LocalIP, "/")[0]
+				}
+
+				log.Info(fmt.Sprintf("Failover: the tunnel stalled repeatedly; switching from '%s' to '%s' (%s)", failedGateway, svr.Gateway, h.Hostname))
+				return params.WithHost(hostIP, h.PublicKey, hostLocalIP, ipv6Prefix), nil
+			}
+		}
+	}
+
+	return params, fmt.Errorf("no other server available")
+}
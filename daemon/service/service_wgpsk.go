@@ -0,0 +1,112 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/api"
+	"github.com/ivpn/desktop-app/daemon/kem"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
+)
+
+// Post-quantum WireGuard preshared keys.
+// The client generates hybrid KEM key pair (X25519 + ML-KEM-768) and sends the public key to the API.
+// The server encapsulates new shared secret, configures it as a preshared key for the client's WG public key
+// and returns the KEM ciphertext. The client decapsulates the secret and uses it as the peer preshared key.
+// A new key is negotiated for each connection and (optionally) periodically while connected.
+// The feature is opt-in (UserPreferences.WgQuantumResistance). When the API server does not provide the exchange
+// (api.ErrPresharedKeyExchangeNotSupported) the connection fails with a clear error: it never silently falls back
+// to the connection without the requested protection; the rotation while connected just keeps the current key.
+
+// minimal interval of the preshared key rotation while connected
+const minWgPskRotationInterval = time.Minute * 5
+
+func checkWireGuardPskPreferences(userPrefs preferences.UserPreferences) error {
+	if userPrefs.WgPskRotationIntervalSec < 0 {
+		return fmt.Errorf("WireGuard preshared key rotation interval can not be negative")
+	}
+	if userPrefs.WgPskRotationIntervalSec > 0 && time.Duration(userPrefs.WgPskRotationIntervalSec)*time.Second < minWgPskRotationInterval {
+		return fmt.Errorf("WireGuard preshared key rotation interval can not be less than %v", minWgPskRotationInterval)
+	}
+	if userPrefs.WgQuantumResistance && !kem.IsSupported() {
+		return fmt.Errorf("post-quantum key exchange is not supported by this version of the daemon")
+	}
+	return nil
+}
+
+// wireGuardApplyPresharedKeyParams negotiates new post-quantum preshared key for the connection
+// and configures its rotation (if enabled by preferences)
+func (s *Service) wireGuardApplyPresharedKeyParams(connectionParams *wireguard.ConnectionParams) error {
+	userPrefs := s.Preferences().UserPrefs
+	if !userPrefs.WgQuantumResistance {
+		connectionParams.SetPresharedKey("")
+		connectionParams.SetPresharedKeyRotation(0, nil)
+		return nil
+	}
+
+	psk, err := s.wireGuardNegotiatePresharedKey()
+	if err != nil {
+		// do not connect without requested protection
+		if errors.Is(err, api.ErrPresharedKeyExchangeNotSupported) {
+			return fmt.Errorf("%w (disable the post-quantum protection option to connect)", err)
+		}
+		return fmt.Errorf("failed to negotiate post-quantum preshared key: %w", err)
+	}
+	connectionParams.SetPresharedKey(psk)
+	connectionParams.SetPresharedKeyRotation(time.Duration(userPrefs.WgPskRotationIntervalSec)*time.Second, s.wireGuardNegotiatePresharedKey)
+	return nil
+}
+
+// wireGuardNegotiatePresharedKey negotiates new post-quantum preshared key (base64) for the active WireGuard key
+func (s *Service) wireGuardNegotiatePresharedKey() (string, error) {
+	session := s.Preferences().Session
+	if !session.IsWGCredentialsOk() {
+		return "", fmt.Errorf("WireGuard credentials are not defined")
+	}
+
+	privateKey, err := kem.GenerateKey()
+	if err != nil {
+		return "", err
+	}
+
+	ciphertextB64, err := s._api.WireGuardPresharedKeyExchange(session.Session, session.WGPublicKey, kem.AlgorithmName, base64.StdEncoding.EncodeToString(privateKey.PublicKey()))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return "", fmt.Errorf("KEM ciphertext is not base64 string")
+	}
+
+	psk, err := privateKey.Decapsulate(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	log.Info("Post-quantum preshared key negotiated")
+	return base64.StdEncoding.EncodeToString(psk), nil
+}
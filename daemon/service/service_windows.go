@@ -41,12 +41,12 @@ func (s *Service) implGetDisabledFuncForPlatform() protocolTypes.DisabledFunctio
 	return protocolTypes.DisabledFunctionalityForPlatform{}
 }
 
-func (s *Service) implPingServersStarting(hosts []net.IP) error {
+func (s *Service) implPingServersStarting(hosts []net.IP, onlyForICMP bool) error {
 	// nothing to do for Windows implementation
 	// firewall configured to allow all connectivity for service
 	return nil
 }
-func (s *Service) implPingServersStopped(hosts []net.IP) error {
+func (s *Service) implPingServersStopped(hosts []net.IP, onlyForICMP bool) error {
 	// nothing to do for Windows implementation
 	// firewall configured to allow all connectivity for service
 	return nil
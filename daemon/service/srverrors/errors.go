@@ -22,6 +22,8 @@
 
 package srverrors
 
+import "fmt"
+
 // ErrorNotLoggedIn - error, user not logged in into account
 type ErrorNotLoggedIn struct {
 }
@@ -29,3 +31,12 @@ type ErrorNotLoggedIn struct {
 func (e ErrorNotLoggedIn) Error() string {
 	return "not logged in; please visit https://www.ivpn.net/ to Sign Up or Log In to get info about your Account ID"
 }
+
+// ErrorServerExcluded - error, the server is in the list of excluded servers (by host, by server location or by country)
+type ErrorServerExcluded struct {
+	Server string // host name or IP address of the server
+}
+
+func (e ErrorServerExcluded) Error() string {
+	return fmt.Sprintf("the server '%s' is in the list of excluded servers; connection to it is not allowed", e.Server)
+}
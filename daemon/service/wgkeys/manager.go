@@ -23,6 +23,9 @@
 package wgkeys
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"sync"
@@ -31,6 +34,7 @@ import (
 	"github.com/ivpn/desktop-app/daemon/api"
 	"github.com/ivpn/desktop-app/daemon/api/types"
 	"github.com/ivpn/desktop-app/daemon/logger"
+	"github.com/ivpn/desktop-app/daemon/service/preferences"
 	"github.com/ivpn/desktop-app/daemon/vpn"
 	"github.com/ivpn/desktop-app/daemon/vpn/wireguard"
 )
@@ -43,14 +47,21 @@ func init() {
 
 //HardExpirationIntervalDays = 40;
 
+// ErrOffline - the keys were not rotated because the API servers are unreachable (offline mode).
+// The active key is still in use (the service decides how long it can be used past the rotation time)
+var ErrOffline = errors.New("WG keys not updated: API servers are unreachable (offline mode)")
+
 // IWgKeysChangeReceiver WG key update handler
 type IWgKeysChangeReceiver interface {
 	WireGuardSaveNewKeys(wgPublicKey string, wgPrivateKey string, wgLocalIP string)
 	WireGuardGetKeys() (session, wgPublicKey, wgPrivateKey, wgLocalIP string, generatedTime time.Time, updateInterval time.Duration)
+	WireGuardGetKeysRotationWindow() preferences.TimeWindow
+	WireGuardKeysRotationLog(record preferences.WgKeysRotationRecord)
 	FirewallEnabled() (bool, error)
 	Connected() bool
 	ConnectedType() (isConnected bool, connectedVpnType vpn.Type)
 	IsConnectivityBlocked() (isBlocked bool, reasonDescription string, err error)
+	WireGuardIsRotationDeferred() bool
 	OnSessionNotFound()
 }
 
@@ -69,6 +80,35 @@ type KeysManager struct {
 	api              *api.API
 	wgToolBinPath    string
 	stopKeysRotation chan struct{}
+
+	nextRotationMutex sync.Mutex
+	nextRotation      time.Time // time of the next scheduled rotation (zero - rotation not active)
+}
+
+// KeyFingerprint returns short fingerprint of WireGuard public key (first 8 bytes of SHA-256 hash; hex)
+func KeyFingerprint(publicKey string) string {
+	if len(publicKey) == 0 {
+		return ""
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
+
+// NextRotation returns the time of the next scheduled keys rotation (zero - rotation not active)
+func (m *KeysManager) NextRotation() time.Time {
+	m.nextRotationMutex.Lock()
+	defer m.nextRotationMutex.Unlock()
+	return m.nextRotation
+}
+
+func (m *KeysManager) setNextRotation(t time.Time) {
+	m.nextRotationMutex.Lock()
+	defer m.nextRotationMutex.Unlock()
+	m.nextRotation = t
 }
 
 // Init - initialize master service
@@ -95,6 +135,7 @@ func (m *KeysManager) StartKeysRotation() error {
 	}
 
 	if len(activePublicKey) == 0 {
+		m.setNextRotation(time.Time{})
 		log.Info("Active public WG key is not defined. WG key rotation disabled.")
 		return nil
 	}
@@ -131,6 +172,14 @@ func (m *KeysManager) StartKeysRotation() error {
 				waitInterval = time.Second
 			}
 
+			// automatic rotation is allowed only inside the time window (if defined)
+			nextRotation := time.Now().Add(waitInterval)
+			if allowed := m.service.WireGuardGetKeysRotationWindow().NextAllowed(nextRotation); allowed.After(nextRotation) {
+				nextRotation = allowed
+				waitInterval = time.Until(allowed)
+			}
+			m.setNextRotation(nextRotation)
+
 			if waitInterval > maxCheckInterval && !isLastUpdateFailed {
 				// We can not trust "time.After()" that it will be triggered in exact time.
 				// If the computer fall to sleep on a long time, after wake up the "time.After()"
@@ -141,7 +190,10 @@ func (m *KeysManager) StartKeysRotation() error {
 
 			select {
 			case <-time.After(waitInterval):
-				_, err := m.UpdateKeysIfNecessary()
+				if !m.service.WireGuardGetKeysRotationWindow().Contains(time.Now()) {
+					continue // outside the time window: just re-calculate the waiting time
+				}
+				_, err := m.generateKeys(true, preferences.WgKeysRotationScheduled)
 				if err != nil {
 					isLastUpdateFailed = true
 					isLastUpdateFailedCnt += 1
@@ -169,7 +221,7 @@ func (m *KeysManager) StopKeysRotation() {
 
 // GenerateKeys generate keys
 func (m *KeysManager) GenerateKeys() error {
-	isUpdated, err := m.generateKeys(false)
+	isUpdated, err := m.generateKeys(false, preferences.WgKeysRotationManual)
 	if err == nil && !isUpdated {
 		err = fmt.Errorf("WG keys were not updated")
 	}
@@ -179,13 +231,18 @@ func (m *KeysManager) GenerateKeys() error {
 // UpdateKeysIfNecessary generate or update keys
 // 1) If no active WG keys defined - new keys will be generated + key rotation will be started
 // 2) If active WG key defined - key will be updated only if it is a time to do it
+// Note: the rotation time window is not taken into account (the keys are required right now, e.g. for connection)
 func (m *KeysManager) UpdateKeysIfNecessary() (isUpdated bool, retErr error) {
-	return m.generateKeys(true)
+	return m.generateKeys(true, preferences.WgKeysRotationOnDemand)
 }
 
-func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (isUpdated bool, retErr error) {
+// generateKeys generates new keys and registers them on the API
+// 'trigger' - the reason of rotation (for the rotation history)
+func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool, trigger string) (isUpdated bool, retErr error) {
 	defer func() {
-		if retErr != nil {
+		if errors.Is(retErr, ErrOffline) {
+			log.Info(retErr)
+		} else if retErr != nil {
 			log.Error("Failed to update WG keys: ", retErr)
 		}
 	}()
@@ -230,11 +287,39 @@ func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (isUpdated bool,
 		return false, err
 	}
 
+	// Offline mode: the service can postpone the rotation while the active key is still usable
+	// (if the API request fails when WireGuard is not connected, the active key must be erased).
+	// The manual rotation is always attempted.
+	if trigger != preferences.WgKeysRotationManual && len(activePublicKey) > 0 && m.service.WireGuardIsRotationDeferred() {
+		return false, ErrOffline
+	}
+
 	isRotationStopped := false
 	if len(activePublicKey) == 0 {
 		isRotationStopped = true
 	}
 
+	// save the rotation attempt into history
+	var newPublicKey string
+	var detailedErr error // the error description for the history (can be more detailed than 'retErr')
+	oldKeyFingerprint := KeyFingerprint(activePublicKey)
+	defer func() {
+		rec := preferences.WgKeysRotationRecord{
+			Time:              time.Now(),
+			Trigger:           trigger,
+			OldKeyFingerprint: oldKeyFingerprint,
+			Success:           retErr == nil}
+		if retErr != nil {
+			if detailedErr == nil {
+				detailedErr = retErr
+			}
+			rec.Error = detailedErr.Error()
+		} else {
+			rec.NewKeyFingerprint = KeyFingerprint(newPublicKey)
+		}
+		m.service.WireGuardKeysRotationLog(rec)
+	}()
+
 	log.Info("Updating WG keys...")
 
 	if isBlocked, reasonDescription, err := m.service.IsConnectivityBlocked(); err == nil && isBlocked {
@@ -257,6 +342,7 @@ func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (isUpdated bool,
 	// trying to update WG keys with notifying API about current active public key (if it exists)
 	localIP, err := m.api.WireGuardKeySet(session, pub, activePublicKey)
 	if err != nil {
+		detailedErr = fmt.Errorf("API error: %w", err)
 		if len(activePublicKey) == 0 {
 			// IMPORTANT! As soon as server receive request with empty 'activePublicKey' - it clears all keys
 			// Therefore, we have to ensure that local keys are not using anymore (we have to clear them independently from we received response or not)
@@ -278,6 +364,7 @@ func (m *KeysManager) generateKeys(onlyUpdateIfNecessary bool) (isUpdated bool,
 
 	// notify service about new keys
 	m.service.WireGuardSaveNewKeys(pub, priv, localIP.String())
+	newPublicKey = pub
 
 	if isRotationStopped {
 		// If there was no public key defined - start keys rotation
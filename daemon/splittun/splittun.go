@@ -46,6 +46,17 @@ type ConfigAddresses struct {
 	IPv6Tunnel net.IP // VpnLocalIPv6
 }
 
+// ConfigDestinations - destination-based split-tunnel configuration
+// (applied only when VPN is connected; independent of the application-based split-tunnel configuration)
+type ConfigDestinations struct {
+	Bypass []net.IPNet // destinations which always bypass the VPN tunnel
+	Tunnel []net.IPNet // destinations which always use the VPN tunnel (have priority over 'Bypass')
+}
+
+func (d ConfigDestinations) IsEmpty() bool {
+	return len(d.Bypass) == 0 && len(d.Tunnel) == 0
+}
+
 // Information about running application
 // https://man7.org/linux/man-pages/man5/proc.5.html
 type RunningApp struct {
@@ -57,6 +68,32 @@ type RunningApp struct {
 	ExtModifiedCmdLine string
 }
 
+// Information about network socket which belongs to the process running in Split-Tunnel environment
+type SocketInfo struct {
+	Protocol      string // "tcp" or "udp"
+	LocalAddr     string // ip:port
+	RemoteAddr    string // ip:port
+	State         string // e.g. "ESTAB", "LISTEN", "UNCONN"
+	// bytes counters of the socket (since the socket was opened)
+	// Note: applicable only for TCP sockets (always 0 for UDP)
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// Live information about the process which belongs to Split-Tunnel environment
+type RunningAppTraffic struct {
+	RunningApp
+	// false - when the process was started by the command in Split-Tunnel environment
+	// but the process is not in Split-Tunnel environment anymore (its traffic is NOT excluded from the VPN tunnel)
+	IsExcluded bool
+	Sockets    []SocketInfo
+	// TCP traffic of the process: bytes of the open TCP sockets plus bytes of the TCP sockets
+	// which were closed since the daemon started to observe the process.
+	// Note: UDP traffic is not counted (the kernel has no bytes counters for UDP sockets)
+	TcpBytesSent     uint64
+	TcpBytesReceived uint64
+}
+
 // Initialize must be called first (before accessing any ST functionality)
 // Normally, it should check if the ST functionality available
 // Returns non-nil error object if Split-Tunneling functionality not available
@@ -87,7 +124,7 @@ func Reset() error {
 }
 
 // ApplyConfig control split-tunnel functionality
-func ApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string) error {
+func ApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddresses, splitTunnelApps []string, destConfig ConfigDestinations) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -96,7 +133,19 @@ func ApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddresses
 		addrConfig.IPv6Tunnel = nil
 	}
 
-	return implApplyConfig(isStEnabled, isVpnEnabled, addrConfig, splitTunnelApps)
+	var retErr error
+	if implFuncNotAvailableError() == nil {
+		retErr = implApplyConfig(isStEnabled, isVpnEnabled, addrConfig, splitTunnelApps)
+	}
+
+	// destination-based configuration does not depend on application-based split-tunnel functionality
+	if err := applyDestinations(isStEnabled, isVpnEnabled, addrConfig, destConfig); err != nil {
+		log.Error(err)
+		if retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
 }
 
 // AddPid add process to Split-Tunnel environment
@@ -116,3 +165,9 @@ func RemovePid(pid int) error {
 func GetRunningApps() (allProcesses []RunningApp, err error) {
 	return implGetRunningApps()
 }
+
+// Get live information (active sockets, TCP traffic) about applications running in Split-Tunnel environment
+// (applicable for Linux)
+func GetRunningAppsTraffic() ([]RunningAppTraffic, error) {
+	return implGetRunningAppsTraffic()
+}
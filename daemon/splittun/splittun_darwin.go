@@ -24,6 +24,10 @@ package splittun
 
 import (
 	"fmt"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
 func implInitialize() error {
@@ -42,6 +46,43 @@ func implApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddre
 	return fmt.Errorf("Split-Tunnelling is not implemented for macOS")
 }
 
+func implDestRoutesSplitTunnelTable() string {
+	return "" // application-based Split-Tunnelling is not implemented for macOS
+}
+
+// implDestRouteAdd adds the route with longer prefix than default routes of VPN connection ('0/1' and '128.0/1'),
+// so it has priority over VPN routes
+func implDestRouteAdd(r destRoute) error {
+	gateway := r.gateway
+	if len(gateway) == 0 {
+		gateway = r.devIP // route via interface: the local IP of VPN interface is in use as gateway (the same as VPN routes)
+	}
+	return shell.Exec(log, platform.RouteCommand(), "-n", "add", destRouteFamilyArg(r), "-net", r.dest, gateway)
+}
+
+func implDestRouteDel(r destRoute) error {
+	return shell.Exec(nil, platform.RouteCommand(), "-n", "delete", destRouteFamilyArg(r), "-net", r.dest)
+}
+
+func destRouteFamilyArg(r destRoute) string {
+	if r.isIPv6 {
+		return "-inet6"
+	}
+	return "-inet"
+}
+
+// implDefaultRoute returns gateway of the default route
+func implDefaultRoute(isIPv6 bool) (gateway string, dev string, err error) {
+	if isIPv6 {
+		return "", "", fmt.Errorf("bypass destinations are not supported for IPv6 on macOS")
+	}
+	gw, err := netinfo.DefaultGatewayIP()
+	if err != nil {
+		return "", "", err
+	}
+	return gw.String(), "", nil
+}
+
 func implAddPid(pid int, commandToExecute string) error {
 	return fmt.Errorf("Split-Tunnelling is not implemented for macOS")
 }
@@ -53,3 +94,7 @@ func implRemovePid(pid int) error {
 func implGetRunningApps() ([]RunningApp, error) {
 	return nil, fmt.Errorf("Split-Tunnelling is not implemented for macOS")
 }
+
+func implGetRunningAppsTraffic() ([]RunningAppTraffic, error) {
+	return nil, fmt.Errorf("Split-Tunnelling is not implemented for macOS")
+}
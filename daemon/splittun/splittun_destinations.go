@@ -0,0 +1,181 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package splittun
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+)
+
+// Destination-based split tunnelling is implemented by the routes with longer prefixes than default routes of VPN connection
+// (so they have priority over VPN routes):
+//   - 'Bypass' destinations are routed via the default gateway;
+//   - 'Tunnel' destinations are routed via the VPN interface.
+// The 'Bypass' routes depend on the default gateway, so they are re-applied when the default gateway changes (see OnRoutingChanged()).
+
+// destRoute - the route added by applyDestinations()
+type destRoute struct {
+	isIPv6  bool
+	dest    string // CIDR
+	gateway string // empty - the route is via interface 'dev'
+	dev     string // interface name (optional when the gateway is defined)
+	devIP   string // local IP of the interface 'dev' (defined for the routes via interface)
+	table   string // empty - the default routing table
+}
+
+// defaultRoute - the default route in use for 'Bypass' destinations
+type defaultRoute struct {
+	gateway string
+	dev     string
+}
+
+var (
+	// routes added by applyDestinations() (to be able to remove them on configuration change)
+	_appliedDestRoutes []destRoute
+	// default routes in use for 'Bypass' destinations (key: isIPv6)
+	_appliedDefaultRoutes = map[bool]defaultRoute{}
+	// the last applied configuration (in use to re-apply the routes on routing change)
+	_lastDestIsStEnabled  bool
+	_lastDestIsVpnEnabled bool
+	_lastDestAddrConfig   ConfigAddresses
+	_lastDestConfig       ConfigDestinations
+)
+
+// OnRoutingChanged must be called when the system routing configuration changed.
+// The 'Bypass' routes of destination-based split-tunnel configuration are re-applied when the default gateway changed.
+func OnRoutingChanged() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(_lastDestConfig.Bypass) == 0 || !_lastDestIsVpnEnabled {
+		return nil
+	}
+
+	for _, isIPv6 := range []bool{false, true} {
+		if !hasDestinations(_lastDestConfig.Bypass, isIPv6) {
+			continue
+		}
+		gw, dev, err := implDefaultRoute(isIPv6)
+		if err != nil {
+			gw, dev = "", ""
+		}
+		if _appliedDefaultRoutes[isIPv6] != (defaultRoute{gateway: gw, dev: dev}) {
+			log.Info("Default route changed. Re-applying split-tunnel destinations...")
+			return applyDestinations(_lastDestIsStEnabled, _lastDestIsVpnEnabled, _lastDestAddrConfig, _lastDestConfig)
+		}
+	}
+	return nil
+}
+
+// applyDestinations applies destination-based split-tunnel configuration
+// (must be called under locked 'mutex')
+func applyDestinations(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddresses, destConfig ConfigDestinations) error {
+	_lastDestIsStEnabled, _lastDestIsVpnEnabled, _lastDestAddrConfig, _lastDestConfig = isStEnabled, isVpnEnabled, addrConfig, destConfig
+
+	// remove routes from previous configuration
+	for _, r := range _appliedDestRoutes {
+		if err := implDestRouteDel(r); err != nil {
+			log.Warning(fmt.Sprintf("failed to remove route to '%s': %s", r.dest, err))
+		}
+	}
+	_appliedDestRoutes = nil
+	_appliedDefaultRoutes = map[bool]defaultRoute{}
+
+	if !isVpnEnabled || destConfig.IsEmpty() {
+		return nil
+	}
+
+	var retErr error
+	addRoute := func(r destRoute) {
+		if err := implDestRouteAdd(r); err != nil {
+			if retErr == nil {
+				retErr = fmt.Errorf("failed to add route to '%s': %w", r.dest, err)
+			}
+			return
+		}
+		_appliedDestRoutes = append(_appliedDestRoutes, r)
+	}
+
+	// 'Bypass' destinations: route via default gateway
+	for _, isIPv6 := range []bool{false, true} {
+		if !hasDestinations(destConfig.Bypass, isIPv6) {
+			continue
+		}
+		gw, dev, err := implDefaultRoute(isIPv6)
+		if err != nil {
+			log.Warning(err)
+			continue
+		}
+		_appliedDefaultRoutes[isIPv6] = defaultRoute{gateway: gw, dev: dev}
+		for _, n := range destConfig.Bypass {
+			if (n.IP.To4() == nil) == isIPv6 {
+				addRoute(destRoute{isIPv6: isIPv6, dest: n.String(), gateway: gw, dev: dev})
+			}
+		}
+	}
+
+	// 'Tunnel' destinations: route via VPN interface
+	for _, tunnelIP := range []net.IP{addrConfig.IPv4Tunnel, addrConfig.IPv6Tunnel} {
+		if tunnelIP == nil {
+			continue
+		}
+		isIPv6 := tunnelIP.To4() == nil
+		if !hasDestinations(destConfig.Tunnel, isIPv6) {
+			continue
+		}
+		inf, err := netinfo.InterfaceByIPAddr(tunnelIP)
+		if err != nil || inf == nil {
+			log.Warning(fmt.Sprintf("unable to find VPN interface (%s): %v", tunnelIP, err))
+			continue
+		}
+		stTable := ""
+		if isStEnabled {
+			stTable = implDestRoutesSplitTunnelTable()
+		}
+		for _, n := range destConfig.Tunnel {
+			if (n.IP.To4() == nil) != isIPv6 {
+				continue
+			}
+			r := destRoute{isIPv6: isIPv6, dest: n.String(), dev: inf.Name, devIP: tunnelIP.String()}
+			addRoute(r)
+			if len(stTable) > 0 {
+				// the excluded applications are also using VPN tunnel for such destinations
+				r.table = stTable
+				addRoute(r)
+			}
+		}
+	}
+
+	return retErr
+}
+
+func hasDestinations(dests []net.IPNet, isIPv6 bool) bool {
+	for _, n := range dests {
+		if (n.IP.To4() == nil) == isIPv6 {
+			return true
+		}
+	}
+	return false
+}
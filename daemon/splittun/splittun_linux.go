@@ -32,6 +32,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ivpn/desktop-app/daemon/service/platform"
 	"github.com/ivpn/desktop-app/daemon/shell"
@@ -49,6 +51,9 @@ var _addedRootProcesses map[int]string = map[int]string{}
 
 const stPidsFile = "/sys/fs/cgroup/net_cls/ivpn-exclude/cgroup.procs"
 
+// Routing table for packets coming from Split-Tunneling environment (must be the same as in splittun.sh)
+const stRoutingTable = "ivpn-exclude-tbl"
+
 func implInitialize() error {
 	funcNotAvailableError = nil
 
@@ -101,6 +106,83 @@ func implApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddre
 	return err
 }
 
+// implDestRoutesSplitTunnelTable returns the routing table for packets coming from Split-Tunneling environment
+// ('Tunnel' destinations are also added to this table, so the excluded applications are also using VPN tunnel for such destinations)
+func implDestRoutesSplitTunnelTable() string {
+	if funcNotAvailableError != nil {
+		return ""
+	}
+	return stRoutingTable
+}
+
+// implDestRouteAdd adds the route to the 'main' routing table (or to the 'r.table').
+// The routes have longer prefixes than default routes of VPN connection, so they have priority:
+//   - OpenVPN: uses routes '0.0.0.0/1' and '128.0.0.0/1' in the 'main' table;
+//   - WireGuard: uses rule 'from all lookup main suppress_prefixlength 0' which ignores only default routes in the 'main' table.
+func implDestRouteAdd(r destRoute) error {
+	args := append(destRouteIpArgs(r, "replace"), destRouteNextHopArgs(r)...)
+	if len(r.table) > 0 {
+		args = append(args, "table", r.table)
+	}
+	return shell.Exec(log, "ip", args...)
+}
+
+func implDestRouteDel(r destRoute) error {
+	args := destRouteIpArgs(r, "del")
+	if len(r.table) > 0 {
+		args = append(args, "table", r.table)
+	}
+	return shell.Exec(nil, "ip", args...)
+}
+
+func destRouteIpArgs(r destRoute, operation string) []string {
+	if r.isIPv6 {
+		return []string{"-6", "route", operation, r.dest}
+	}
+	return []string{"-4", "route", operation, r.dest}
+}
+
+func destRouteNextHopArgs(r destRoute) []string {
+	args := []string{}
+	if len(r.gateway) > 0 {
+		args = append(args, "via", r.gateway)
+	}
+	if len(r.dev) > 0 {
+		args = append(args, "dev", r.dev)
+	}
+	return args
+}
+
+// implDefaultRoute returns gateway and interface name of the default route
+// (parsing output of 'ip route show default': "default via 192.168.1.1 dev eth0 proto dhcp metric 100")
+func implDefaultRoute(isIPv6 bool) (gateway string, dev string, err error) {
+	ipVer := "-4"
+	if isIPv6 {
+		ipVer = "-6"
+	}
+	outText, _, _, err := shell.ExecAndGetOutput(nil, 1024, "", "ip", ipVer, "route", "show", "default")
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get default route: %w", err)
+	}
+
+	for _, line := range strings.Split(outText, "\n") {
+		cols := strings.Fields(line)
+		gateway, dev = "", ""
+		for i := 0; i+1 < len(cols); i++ {
+			switch cols[i] {
+			case "via":
+				gateway = cols[i+1]
+			case "dev":
+				dev = cols[i+1]
+			}
+		}
+		if len(gateway) > 0 && len(dev) > 0 {
+			return gateway, dev, nil
+		}
+	}
+	return "", "", fmt.Errorf("default route (%s) not found", ipVer)
+}
+
 func implAddPid(pid int, commandToExecute string) error {
 	if pid <= 0 {
 		return fmt.Errorf("PID is not defined")
@@ -315,6 +397,234 @@ func implGetRunningApps() (allProcesses []RunningApp, err error) {
 	return retAll, nil
 }
 
+// TCP traffic of the process accumulated by the daemon.
+// The kernel provides bytes counters only for open TCP sockets, so the last known
+// counters of the closed sockets are kept here.
+type procTraffic struct {
+	sockets        map[string]SocketInfo // last known info about open TCP sockets of the process (map[<inode>]SocketInfo)
+	closedSent     uint64
+	closedReceived uint64
+}
+
+const (
+	trafficSampleInterval = time.Second
+	// the traffic counters are sampled in background until this period elapsed since the last live status request
+	trafficSamplerTimeout = time.Minute
+)
+
+var (
+	_trafficMutex          sync.Mutex
+	_traffic               map[int]*procTraffic = map[int]*procTraffic{} // map[<PID>]
+	_trafficLastRequest    time.Time
+	_trafficSamplerRunning bool
+)
+
+// update registers the current TCP sockets of the process and returns the total TCP traffic of the process
+func (t *procTraffic) update(openTcp map[string]SocketInfo) (sent, received uint64) {
+	for inode, old := range t.sockets {
+		if cur, ok := openTcp[inode]; !ok || cur.BytesSent < old.BytesSent || cur.BytesReceived < old.BytesReceived {
+			// the socket is closed (or the inode is reused by a new socket): keep its last known counters
+			t.closedSent += old.BytesSent
+			t.closedReceived += old.BytesReceived
+		}
+	}
+	t.sockets = openTcp
+
+	sent, received = t.closedSent, t.closedReceived
+	for _, si := range openTcp {
+		sent += si.BytesSent
+		received += si.BytesReceived
+	}
+	return sent, received
+}
+
+func implGetRunningAppsTraffic() ([]RunningAppTraffic, error) {
+	_trafficMutex.Lock()
+	defer _trafficMutex.Unlock()
+
+	ret, err := getRunningAppsTraffic()
+	if err != nil {
+		return nil, err
+	}
+
+	// Closed sockets are detected by periodic sampling. Keep sampling while the live status is in use
+	// (e.g. 'ivpn splittun -status -watch') so the traffic of short-living sockets is not lost.
+	_trafficLastRequest = time.Now()
+	if !_trafficSamplerRunning {
+		_trafficSamplerRunning = true
+		go trafficSampler()
+	}
+
+	return ret, nil
+}
+
+func trafficSampler() {
+	for {
+		time.Sleep(trafficSampleInterval)
+
+		_trafficMutex.Lock()
+		if time.Since(_trafficLastRequest) > trafficSamplerTimeout {
+			_trafficSamplerRunning = false
+			_trafficMutex.Unlock()
+			return
+		}
+		if _, err := getRunningAppsTraffic(); err != nil {
+			log.Warning(fmt.Sprintf("failed to update traffic counters of processes in Split Tunnel environment: %v", err))
+		}
+		_trafficMutex.Unlock()
+	}
+}
+
+// getRunningAppsTraffic collects live information about applications running in Split-Tunnel environment
+// and updates the traffic counters of the processes.
+// Must be called under '_trafficMutex' lock
+func getRunningAppsTraffic() ([]RunningAppTraffic, error) {
+	apps, err := implGetRunningApps()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]RunningAppTraffic, 0, len(apps))
+	stPids := make(map[int]struct{}, len(apps))
+	for _, app := range apps {
+		stPids[app.Pid] = struct{}{}
+		ret = append(ret, RunningAppTraffic{RunningApp: app, IsExcluded: true})
+	}
+
+	// Looking for processes which were started by a command in ST environment (have environment variable 'IVPN_STARTED_ST_ID')
+	// but are not in ST environment anymore (e.g. the process moved itself to another cgroup)
+	if procDirs, err := os.ReadDir("/proc"); err == nil {
+		for _, d := range procDirs {
+			pid, err := strconv.Atoi(d.Name())
+			if err != nil || !d.IsDir() {
+				continue
+			}
+			if _, ok := stPids[pid]; ok {
+				continue
+			}
+			idEnv, err := readProcEnvVarIvpnId(pid)
+			if err != nil || idEnv <= 0 {
+				continue
+			}
+			app := RunningApp{Pid: pid, ExtIvpnRootPid: idEnv}
+			if b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+				app.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(b), "\x00", " "))
+			}
+			if sl, err := filepath.EvalSymlinks(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+				app.Exe = sl
+			}
+			ret = append(ret, RunningAppTraffic{RunningApp: app, IsExcluded: false})
+		}
+	}
+
+	// sockets info (map[<inode>]SocketInfo)
+	sockets, err := getSocketsInfo()
+	if err != nil {
+		// without sockets info all the sockets would be considered as closed
+		return nil, err
+	}
+
+	alivePids := make(map[int]struct{}, len(ret))
+	for i, app := range ret {
+		openTcp := make(map[string]SocketInfo)
+		for _, inode := range getProcSocketInodes(app.Pid) {
+			si, ok := sockets[inode]
+			if !ok {
+				continue
+			}
+			app.Sockets = append(app.Sockets, si)
+			if si.Protocol == "tcp" {
+				openTcp[inode] = si
+			}
+		}
+
+		t, ok := _traffic[app.Pid]
+		if !ok {
+			t = &procTraffic{}
+			_traffic[app.Pid] = t
+		}
+		app.TcpBytesSent, app.TcpBytesReceived = t.update(openTcp)
+		alivePids[app.Pid] = struct{}{}
+
+		ret[i] = app
+	}
+
+	// forget the processes which are not in Split-Tunnel environment anymore
+	for pid := range _traffic {
+		if _, ok := alivePids[pid]; !ok {
+			delete(_traffic, pid)
+		}
+	}
+
+	return ret, nil
+}
+
+// getProcSocketInodes returns inodes of sockets opened by the process
+// (reading links from '/proc/<PID>/fd': "socket:[<inode>]")
+func getProcSocketInodes(pid int) []string {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	fds, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+
+	ret := make([]string, 0)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(link, "socket:[") && strings.HasSuffix(link, "]") {
+			ret = append(ret, link[len("socket:["):len(link)-1])
+		}
+	}
+	return ret
+}
+
+// getSocketsInfo returns information about all TCP/UDP sockets (map[<inode>]SocketInfo)
+// (parsing output of 'ss -tuaineHO')
+// Example of output line:
+// tcp ESTAB 0 0 192.168.1.2:60678 1.1.1.1:443 ino:26228 sk:3 <-> ts sack cubic ... bytes_acked:7678915 bytes_received:914337 ...
+func getSocketsInfo() (map[string]SocketInfo, error) {
+	outText, _, _, err := shell.ExecAndGetOutput(nil, 1024*1024, "", "ss", "-tuaineHO")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sockets info: %w", err)
+	}
+
+	ret := make(map[string]SocketInfo)
+	for _, line := range strings.Split(outText, "\n") {
+		cols := strings.Fields(line)
+		if len(cols) < 6 {
+			continue
+		}
+		si := SocketInfo{
+			Protocol:   cols[0],
+			State:      cols[1],
+			LocalAddr:  cols[4],
+			RemoteAddr: cols[5],
+		}
+		inode := ""
+		for _, c := range cols[6:] {
+			kv := strings.SplitN(c, ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "ino":
+				inode = kv[1]
+			case "bytes_acked":
+				si.BytesSent, _ = strconv.ParseUint(kv[1], 10, 64)
+			case "bytes_received":
+				si.BytesReceived, _ = strconv.ParseUint(kv[1], 10, 64)
+			}
+		}
+		if len(inode) > 0 && inode != "0" {
+			ret[inode] = si
+		}
+	}
+	return ret, nil
+}
+
 func isEnabled() (bool, error) {
 	err := shell.Exec(nil, stScriptPath, "status")
 	if err != nil {
@@ -27,11 +27,14 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"syscall"
 	"unsafe"
 
+	"github.com/ivpn/desktop-app/daemon/netinfo"
 	"github.com/ivpn/desktop-app/daemon/service/platform"
+	"github.com/ivpn/desktop-app/daemon/shell"
 )
 
 var (
@@ -169,6 +172,55 @@ func implApplyConfig(isStEnabled bool, isVpnEnabled bool, addrConfig ConfigAddre
 	return nil
 }
 
+func implDestRoutesSplitTunnelTable() string {
+	return "" // the Split-Tunnel driver does not use routing tables
+}
+
+// implDestRouteAdd adds the route with longer prefix than default routes of VPN connection ('0.0.0.0/1' and '128.0.0.0/1'),
+// so it has priority over VPN routes
+func implDestRouteAdd(r destRoute) error {
+	dest, mask, err := destRouteWinArgs(r)
+	if err != nil {
+		return err
+	}
+	gateway := r.gateway
+	if len(gateway) == 0 {
+		gateway = r.devIP // route via interface: the local IP of VPN interface is in use as gateway
+	}
+	return shell.Exec(log, platform.RouteCommand(), "ADD", dest, "MASK", mask, gateway)
+}
+
+func implDestRouteDel(r destRoute) error {
+	dest, mask, err := destRouteWinArgs(r)
+	if err != nil {
+		return err
+	}
+	return shell.Exec(nil, platform.RouteCommand(), "DELETE", dest, "MASK", mask)
+}
+
+func destRouteWinArgs(r destRoute) (dest, mask string, err error) {
+	if r.isIPv6 {
+		return "", "", fmt.Errorf("destination-based Split-Tunnelling is not supported for IPv6 on Windows")
+	}
+	_, n, err := net.ParseCIDR(r.dest)
+	if err != nil {
+		return "", "", err
+	}
+	return n.IP.String(), net.IP(n.Mask).String(), nil
+}
+
+// implDefaultRoute returns gateway of the default route
+func implDefaultRoute(isIPv6 bool) (gateway string, dev string, err error) {
+	if isIPv6 {
+		return "", "", fmt.Errorf("destination-based Split-Tunnelling is not supported for IPv6 on Windows")
+	}
+	gw, err := netinfo.DefaultGatewayIP()
+	if err != nil {
+		return "", "", err
+	}
+	return gw.String(), "", nil
+}
+
 func implAddPid(pid int, commandToExecute string) error {
 	return fmt.Errorf("operation not applicable for current platform")
 }
@@ -181,6 +233,10 @@ func implGetRunningApps() ([]RunningApp, error) {
 	return nil, fmt.Errorf("operation not applicable for current platform")
 }
 
+func implGetRunningAppsTraffic() ([]RunningAppTraffic, error) {
+	return nil, fmt.Errorf("operation not applicable for current platform")
+}
+
 func catchPanic(err *error) {
 	if r := recover(); r != nil {
 		log.Error("PANIC (recovered): ", r)
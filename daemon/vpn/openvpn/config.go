@@ -49,6 +49,8 @@ type ConnectionParams struct {
 	proxyPort            int
 	proxyUsername        string
 	proxyPassword        string
+
+	profile *Profile // user-supplied OpenVPN profile (nil - configuration for IVPN server)
 }
 
 // SetCredentials update WG credentials
@@ -57,6 +59,23 @@ func (c *ConnectionParams) SetCredentials(username, password string) {
 	c.username = username
 }
 
+// HostIP returns the IP address of the server host (entry server in case of Multi-Hop)
+func (c *ConnectionParams) HostIP() net.IP {
+	return c.hostIP
+}
+
+// MultihopExitHostname returns the host name of the Multi-Hop exit server (empty - Single-Hop connection)
+func (c *ConnectionParams) MultihopExitHostname() string {
+	return c.multihopExitHostname
+}
+
+// WithMultihopExit returns the copy of Multi-Hop connection parameters for another exit server host
+func (c ConnectionParams) WithMultihopExit(exitHostname string, exitMultihopPort int) ConnectionParams {
+	c.multihopExitHostname = exitHostname
+	c.hostPort = exitMultihopPort
+	return c
+}
+
 // CreateConnectionParams creates OpenVPN connection parameters object
 func CreateConnectionParams(
 	multihopExitHostname string,
@@ -104,10 +123,15 @@ func (c *ConnectionParams) WriteConfigFile(
 		return fmt.Errorf("failed to save OpenVPN configuration into a file: %w", err)
 	}
 
-	log.Info("Configuring OpenVPN...\n",
-		"=====================\n",
-		configText,
-		"\n=====================\n")
+	if c.profile != nil {
+		// do not write private keys from the user-supplied profile into the log
+		log.Info(fmt.Sprintf("Configuring OpenVPN (user-supplied profile; remote %s:%d)...", c.hostIP, c.hostPort))
+	} else {
+		log.Info("Configuring OpenVPN...\n",
+			"=====================\n",
+			configText,
+			"\n=====================\n")
+	}
 
 	return nil
 }
@@ -121,6 +145,10 @@ func (c *ConnectionParams) generateConfiguration(
 	extraParameters string,
 	isCanUseV24Params bool) (cfg []string, err error) {
 
+	if c.profile != nil {
+		return c.generateProfileConfiguration(localPort, miAddr, miPort, logFile)
+	}
+
 	if obfsproxyPort > 0 {
 		c.tcp = true
 		if len(c.multihopExitHostname) > 0 {
@@ -275,6 +303,71 @@ func (c *ConnectionParams) generateConfiguration(
 	return cfg, nil
 }
 
+// generateProfileConfiguration generates configuration based on user-supplied OpenVPN profile
+// (the parameters required for the daemon are defined by the daemon; all the rest - taken from the profile)
+func (c *ConnectionParams) generateProfileConfiguration(
+	localPort int,
+	miAddr string,
+	miPort int,
+	logFile string) (cfg []string, err error) {
+
+	cfg = make([]string, 0, 32+len(c.profile.lines))
+
+	cfg = append(cfg, "client")
+	cfg = append(cfg, fmt.Sprintf("management %s %d", miAddr, miPort))
+	cfg = append(cfg, "management-client")
+	cfg = append(cfg, "management-hold")
+	if c.profile.IsAuthUserPass {
+		cfg = append(cfg, "auth-user-pass")
+	}
+	cfg = append(cfg, "auth-nocache")
+	cfg = append(cfg, "management-query-passwords")
+	cfg = append(cfg, "management-signal")
+
+	if len(logFile) > 0 && logger.IsEnabled() {
+		cfg = append(cfg, fmt.Sprintf(`log "%s"`, logFile))
+	}
+
+	cfg = append(cfg, "dev tun")
+
+	if c.tcp {
+		cfg = append(cfg, "proto tcp-client")
+	} else {
+		cfg = append(cfg, "proto udp")
+	}
+
+	if c.hostIP == nil || c.hostIP.IsUnspecified() {
+		return nil, errors.New("unable to connect. Host IP not defined")
+	}
+	if c.hostPort <= 0 || c.hostPort > 65535 {
+		return nil, errors.New("unable to connect. Invalid port")
+	}
+	// the remote host is always defined by IP: only this IP is allowed by the firewall
+	cfg = append(cfg, fmt.Sprintf("remote %s %d", c.hostIP, c.hostPort))
+
+	cfg = append(cfg, "resolv-retry infinite")
+	if localPort > 0 {
+		cfg = append(cfg, fmt.Sprintf("lport %d", localPort))
+	} else {
+		cfg = append(cfg, "nobind")
+	}
+
+	cfg = append(cfg, "verb 4")
+
+	if upCmd := platform.OpenvpnUpScript(); upCmd != "" {
+		cfg = append(cfg, "up \""+upCmd+" "+platform.OpenvpnUpDownScriptArg()+"\"")
+	}
+	if downCmd := platform.OpenvpnDownScript(); downCmd != "" {
+		cfg = append(cfg, "down \""+downCmd+" "+platform.OpenvpnUpDownScriptArg()+"\"")
+	}
+	cfg = append(cfg, "script-security 2")
+
+	cfg = append(cfg, "# ---- user-supplied profile ----")
+	cfg = append(cfg, c.profile.lines...)
+
+	return cfg, nil
+}
+
 // merge current parameters with user-defined parameters
 func addUserDefinedParameters(currParams []string, userParams string) ([]string, error) {
 	if len(userParams) <= 0 {
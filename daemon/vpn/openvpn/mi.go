@@ -60,6 +60,67 @@ type ManagementInterface struct {
 
 	pushReplyCmds []string
 	pushReplyDNS  net.IP
+
+	// connection statistics
+	trafficCounter    vpn.TrafficCounter // bytes received/sent ('>BYTECOUNT' notifications)
+	stateDetailsMutex sync.Mutex
+	stateDetails      string // detailed description of the current state
+}
+
+// interval (seconds) of the '>BYTECOUNT' notifications from OpenVPN
+const miBytecountIntervalSec = 2
+
+// human-readable descriptions of the OpenVPN states ('>STATE' notifications)
+var miStateDetails = map[string]string{
+	"CONNECTING":   "Initializing",
+	"RESOLVE":      "Resolving remote host name",
+	"TCP_CONNECT":  "Establishing TCP connection",
+	"WAIT":         "TLS negotiation (waiting for initial response from server)",
+	"AUTH":         "Authenticating",
+	"AUTH_PENDING": "Authentication pending (additional steps required)",
+	"GET_CONFIG":   "Downloading configuration from server",
+	"ASSIGN_IP":    "Assigning IP address to virtual network interface",
+	"ADD_ROUTES":   "Adding routes",
+	"CONNECTED":    "Connected",
+	"RECONNECTING": "Reconnecting",
+	"EXITING":      "Exiting",
+}
+
+// GetTrafficStats returns traffic counters (bytes in/out on the OpenVPN connection) and rates (bytes per second)
+func (i *ManagementInterface) GetTrafficStats() (rx, tx, rxRate, txRate uint64) {
+	return i.trafficCounter.Get()
+}
+
+// GetStateDetails returns detailed description of the current OpenVPN state
+func (i *ManagementInterface) GetStateDetails() string {
+	i.stateDetailsMutex.Lock()
+	defer i.stateDetailsMutex.Unlock()
+	return i.stateDetails
+}
+
+func (i *ManagementInterface) setStateDetails(details string) {
+	i.stateDetailsMutex.Lock()
+	defer i.stateDetailsMutex.Unlock()
+	i.stateDetails = details
+}
+
+// parseStateDetails returns detailed description of the OpenVPN state
+// (params - parameters of the '>STATE' notification: <time>,<state>,<description>,...)
+func parseStateDetails(params []string) string {
+	if len(params) < 2 {
+		return ""
+	}
+	stateName := strings.TrimSpace(params[1])
+	details, ok := miStateDetails[stateName]
+	if !ok {
+		details = stateName
+	}
+	if len(params) > 2 && (stateName == "RECONNECTING" || stateName == "EXITING" || stateName == "AUTH_PENDING") {
+		if reason := strings.TrimSpace(params[2]); len(reason) > 0 {
+			details += " (" + reason + ")"
+		}
+	}
+	return details
 }
 
 // StartManagementInterface - starts TCP interface to communicate with IVPN application (server to listen incoming connections)
@@ -273,6 +334,20 @@ func (i *ManagementInterface) miCommunication() {
 			continue
 		}
 
+		if strings.HasPrefix(message, ">BYTECOUNT:") {
+			// >BYTECOUNT:{BYTES_IN},{BYTES_OUT}
+			// (too frequent notification to be logged)
+			cols := strings.Split(strings.TrimSpace(strings.TrimPrefix(message, ">BYTECOUNT:")), ",")
+			if len(cols) == 2 {
+				in, err1 := strconv.ParseUint(cols[0], 10, 64)
+				out, err2 := strconv.ParseUint(cols[1], 10, 64)
+				if err1 == nil && err2 == nil {
+					i.trafficCounter.Update(in, out)
+				}
+			}
+			continue
+		}
+
 		i.log.Info("[<-]: ", message)
 
 		columns := mesRegexp.FindStringSubmatch(message)
@@ -310,6 +385,13 @@ func (i *ManagementInterface) miCommunication() {
 				if len(cols) == 2 {
 					i.onPushReplyCommands(strings.Split(cols[1], ","))
 				}
+
+				// TLS negotiation progress (the details are not available in '>STATE' notifications)
+				if strings.Contains(msgText, "TLS: Initial packet from") {
+					i.setStateDetails("TLS negotiation (initial packet received from server)")
+				} else if strings.Contains(msgText, "Peer Connection Initiated") {
+					i.setStateDetails("TLS negotiation completed")
+				}
 			}
 
 			break
@@ -361,6 +443,8 @@ func (i *ManagementInterface) miCommunication() {
 			}
 			stateStr := params[1]
 
+			i.setStateDetails(parseStateDetails(params))
+
 			state, err := vpn.ParseState(stateStr)
 			if err != nil {
 				i.log.Error("Unable to parse VPN state:", err.Error())
@@ -374,6 +458,9 @@ func (i *ManagementInterface) miCommunication() {
 
 				// If state is Connected - save local and server IP addresses
 				if state == vpn.CONNECTED {
+					// enable traffic statistics notifications
+					i.sendResponse(fmt.Sprintf("bytecount %d", miBytecountIntervalSec))
+
 					if len(params) > 3 {
 						clientIP = net.ParseIP(strings.TrimSpace(params[3]))
 					}
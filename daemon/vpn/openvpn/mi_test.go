@@ -0,0 +1,44 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStateDetails(t *testing.T) {
+	tests := map[string]string{
+		"1563526742,WAIT,,,,,,":                       "TLS negotiation (waiting for initial response from server)",
+		"1563526742,GET_CONFIG,,,,,,":                 "Downloading configuration from server",
+		"1563526742,RECONNECTING,tls-error,,,,,":      "Reconnecting (tls-error)",
+		"1563526742,EXITING,auth-failure,,,,,":        "Exiting (auth-failure)",
+		"1563526742,CONNECTED,SUCCESS,10.0.0.2,1.2.3": "Connected",
+		"1563526742,SOME_NEW_STATE,,,,,,":             "SOME_NEW_STATE",
+	}
+	for state, expected := range tests {
+		if details := parseStateDetails(strings.Split(state, ",")); details != expected {
+			t.Errorf("'%s': unexpected details '%s' (expected '%s')", state, details, expected)
+		}
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ivpn/desktop-app/daemon/logger"
 	"github.com/ivpn/desktop-app/daemon/obfsproxy"
@@ -92,9 +93,10 @@ type OpenVPN struct {
 	obfsproxy           *obfsproxy.Obfsproxy
 
 	// current VPN state
-	state     vpn.State
-	clientIP  net.IP // applicable only for 'CONNECTED' state
-	localPort int
+	state          vpn.State
+	clientIP       net.IP    // applicable only for 'CONNECTED' state
+	connectedSince time.Time // applicable only for 'CONNECTED' state
+	localPort      int
 
 	// platform-specific properties (for macOS, Windows etc. ...)
 	psProps platformSpecificProperties
@@ -119,9 +121,13 @@ func NewOpenVpnObject(
 	extraParameters string,
 	connectionParams ConnectionParams) (*OpenVPN, error) {
 
-	if len(connectionParams.username) == 0 || len(connectionParams.password) == 0 {
+	isCredentialsRequired := connectionParams.profile == nil || connectionParams.profile.IsAuthUserPass
+	if isCredentialsRequired && (len(connectionParams.username) == 0 || len(connectionParams.password) == 0) {
 		return nil, fmt.Errorf("OpenVPN user credentials not defined")
 	}
+	if connectionParams.profile != nil && (isObfsProxy || len(extraParameters) > 0) {
+		return nil, fmt.Errorf("obfsproxy and user-defined parameters are not applicable for user-supplied OpenVPN profiles")
+	}
 
 	return &OpenVPN{
 			state:           vpn.DISCONNECTED,
@@ -228,6 +234,7 @@ func (o *OpenVPN) Connect(stateChan chan<- vpn.StateInfo) (retErr error) {
 
 					// notify about correct local IP in VPN network
 					o.clientIP = stateInf.ClientIP
+					o.connectedSince = time.Now()
 
 					if o.isObfsProxy {
 						// in case of obfsproxy - 'stateInf.ServerIP' returns local IP (IP of obfsproxy 127.0.0.1)
@@ -245,6 +252,7 @@ func (o *OpenVPN) Connect(stateChan chan<- vpn.StateInfo) (retErr error) {
 					}
 				} else {
 					o.clientIP = nil
+					o.connectedSince = time.Time{}
 				}
 
 				// forward state
@@ -548,3 +556,19 @@ func (o *OpenVPN) OnRoutingChanged() error {
 func (o *OpenVPN) IsIPv6InTunnel() bool {
 	return false
 }
+
+// Stats returns statistics of the active connection
+// Note: the traffic counters are the bytes in/out on the OpenVPN connection (including the protocol overhead)
+func (o *OpenVPN) Stats() (vpn.ConnectionStats, error) {
+	mi := o.managementInterface
+	if mi == nil || !mi.isConnected {
+		return vpn.ConnectionStats{}, errors.New("OpenVPN management interface is not connected")
+	}
+
+	ret := vpn.ConnectionStats{StateDetails: mi.GetStateDetails()}
+	if o.state == vpn.CONNECTED {
+		ret.ConnectedSince = o.connectedSince
+	}
+	ret.RxBytes, ret.TxBytes, ret.RxRate, ret.TxRate = mi.GetTrafficStats()
+	return ret, nil
+}
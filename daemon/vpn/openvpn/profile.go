@@ -0,0 +1,309 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsing of the user-supplied OpenVPN profiles (.ovpn files).
+//
+// The profile must be self-contained: certificates and keys must be inline (<ca>...</ca>, <cert>...</cert>, etc.).
+// The parameters which are controlled by the daemon (management interface, logging, scripts, remote, etc.)
+// are removed from the profile and generated by the daemon.
+// For security reasons, only the known client-side parameters are accepted (see 'profileAllowedParams'):
+// the profiles with the script hooks, library loading or references to the local files are not accepted.
+
+const profileRemoteResolveTimeout = time.Second * 5
+
+// parameters controlled by the daemon (removed from the profile)
+var profileControlledParams = map[string]struct{}{
+	"client": {}, "tls-client": {}, "pull": {},
+	"remote": {}, "proto": {}, "port": {}, "rport": {}, "remote-random": {}, "resolv-retry": {}, "nobind": {}, "lport": {}, "bind": {},
+	"auth-user-pass": {}, "auth-nocache": {}, "auth-retry": {},
+	"management": {}, "management-client": {}, "management-hold": {}, "management-query-passwords": {}, "management-signal": {},
+	"log": {}, "log-append": {}, "verb": {}, "mute": {}, "status": {}, "syslog": {},
+	"dev": {}, "dev-type": {}, "script-security": {},
+}
+
+// client-side parameters which are allowed in the profile (all the rest are not accepted)
+// Note: the allowlist must never contain the parameters which allow to run external programs, load libraries
+// (e.g. 'plugin', 'engine', 'providers', 'pkcs11-providers'), access local files or change the daemon's behaviour
+var profileAllowedParams = map[string]struct{}{
+	// TLS and data channel crypto (keys and certificates - only inline)
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {}, "pkcs12": {},
+	"extra-certs": {}, "secret": {}, "crl-verify": {}, "dh": {}, "key-direction": {},
+	"cipher": {}, "data-ciphers": {}, "data-ciphers-fallback": {}, "ncp-ciphers": {}, "ncp-disable": {}, "auth": {},
+	"tls-cipher": {}, "tls-ciphersuites": {}, "tls-groups": {}, "tls-version-min": {}, "tls-version-max": {}, "tls-cert-profile": {},
+	"tls-timeout": {}, "tls-exit": {}, "hand-window": {}, "tran-window": {}, "key-method": {},
+	"reneg-sec": {}, "reneg-bytes": {}, "reneg-pkts": {},
+	"remote-cert-tls": {}, "remote-cert-ku": {}, "remote-cert-eku": {}, "ns-cert-type": {}, "verify-x509-name": {}, "verify-hash": {},
+	"compress": {}, "comp-lzo": {}, "comp-noadapt": {}, "allow-compression": {},
+	// connection
+	"persist-key": {}, "persist-tun": {}, "persist-remote-ip": {}, "persist-local-ip": {}, "float": {},
+	"keepalive": {}, "ping": {}, "ping-restart": {}, "ping-exit": {}, "ping-timer-rem": {}, "inactive": {}, "explicit-exit-notify": {},
+	"connect-retry": {}, "connect-retry-max": {}, "connect-timeout": {}, "server-poll-timeout": {},
+	"replay-window": {}, "mute-replay-warnings": {}, "no-replay": {}, "push-peer-info": {},
+	// MTU and buffers
+	"tun-mtu": {}, "tun-mtu-extra": {}, "link-mtu": {}, "fragment": {}, "mssfix": {}, "mtu-disc": {}, "mtu-test": {},
+	"sndbuf": {}, "rcvbuf": {}, "txqueuelen": {}, "fast-io": {},
+	// routing and options pushed by the server
+	"topology": {}, "tun-ipv6": {}, "route": {}, "route-ipv6": {}, "route-gateway": {}, "route-ipv6-gateway": {}, "route-metric": {},
+	"route-delay": {}, "route-nopull": {}, "redirect-gateway": {}, "redirect-private": {}, "block-ipv6": {}, "pull-filter": {},
+	"dhcp-option": {}, "block-outside-dns": {},
+}
+
+// parameters which may refer to a file (only inline content is allowed)
+var profileFileParams = map[string]struct{}{
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {}, "pkcs12": {},
+	"extra-certs": {}, "secret": {}, "crl-verify": {}, "dh": {}, "auth-gen-token-secret": {},
+}
+
+// allowed inline blocks
+var profileInlineBlocks = map[string]struct{}{
+	"ca": {}, "cert": {}, "key": {}, "tls-auth": {}, "tls-crypt": {}, "tls-crypt-v2": {}, "pkcs12": {},
+	"extra-certs": {}, "secret": {}, "crl-verify": {}, "dh": {},
+}
+
+// inline block with credentials for 'auth-user-pass' authentication (username and password lines)
+const profileAuthUserPassBlock = "auth-user-pass"
+
+// Profile - user-supplied OpenVPN profile
+type Profile struct {
+	RemoteHost     string // host name or IP address of the first 'remote' entry
+	RemotePort     int
+	IsTCP          bool
+	IsAuthUserPass bool // true when the profile requires username/password authentication
+
+	// credentials defined inline in the profile ('<auth-user-pass>' block; empty - not defined)
+	Username string
+	Password string
+
+	// profile lines (without the parameters controlled by the daemon)
+	lines []string
+}
+
+// ParseProfile parses OpenVPN profile
+func ParseProfile(text string) (Profile, error) {
+	var p Profile
+	defaultPort := 1194
+	defaultTCP := false
+	remotePort := 0
+	remoteProto := ""
+	inlineBlock := ""
+	authLines := 0
+	authBlockFound := false
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// inline credentials: the first line - username, the second - password
+		// (the block is removed from the profile: the daemon provides the credentials through the management interface)
+		if inlineBlock == profileAuthUserPassBlock {
+			if strings.EqualFold(line, "</"+inlineBlock+">") {
+				inlineBlock = ""
+			} else if len(line) > 0 {
+				authLines++
+				switch authLines {
+				case 1:
+					p.Username = line
+				case 2:
+					p.Password = line
+				}
+			}
+			continue
+		}
+
+		// inline block content
+		if len(inlineBlock) > 0 {
+			p.lines = append(p.lines, line)
+			if strings.EqualFold(line, "</"+inlineBlock+">") {
+				inlineBlock = ""
+			}
+			continue
+		}
+
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">") {
+			tag := strings.ToLower(line[1 : len(line)-1])
+			if tag == profileAuthUserPassBlock {
+				if authBlockFound {
+					return p, fmt.Errorf("line %d: duplicate inline block '%s'", lineNum, line)
+				}
+				authBlockFound = true
+				p.IsAuthUserPass = true
+				inlineBlock = tag
+				continue
+			}
+			if _, ok := profileInlineBlocks[tag]; !ok {
+				return p, fmt.Errorf("line %d: unsupported inline block '%s'", lineNum, line)
+			}
+			inlineBlock = tag
+			p.lines = append(p.lines, line)
+			continue
+		}
+
+		words := strings.Fields(line)
+		param := strings.ToLower(strings.TrimPrefix(words[0], "--"))
+		args := words[1:]
+
+		_, isAllowed := profileAllowedParams[param]
+		_, isControlled := profileControlledParams[param]
+		if !isAllowed && !isControlled {
+			return p, fmt.Errorf("line %d: parameter '%s' is not supported", lineNum, param)
+		}
+		if _, ok := profileFileParams[param]; ok {
+			if len(args) == 0 || args[0] != "[inline]" {
+				return p, fmt.Errorf("line %d: references to local files are not supported ('%s'); use inline content", lineNum, param)
+			}
+		}
+
+		if !isControlled {
+			p.lines = append(p.lines, line)
+			continue
+		}
+
+		var err error
+		switch param {
+		case "remote":
+			// remote <host> [port] [proto]
+			// Note: only the first 'remote' entry is in use
+			if len(args) == 0 {
+				return p, fmt.Errorf("line %d: remote host not defined", lineNum)
+			}
+			if len(p.RemoteHost) > 0 {
+				continue
+			}
+			p.RemoteHost = args[0]
+			if len(args) > 1 {
+				if remotePort, err = parseProfilePort(args[1]); err != nil {
+					return p, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+			}
+			if len(args) > 2 {
+				remoteProto = args[2]
+			}
+		case "port", "rport":
+			if len(args) > 0 {
+				if defaultPort, err = parseProfilePort(args[0]); err != nil {
+					return p, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+			}
+		case "proto":
+			if len(args) > 0 {
+				if defaultTCP, err = parseProfileProto(args[0]); err != nil {
+					return p, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+			}
+		case "dev", "dev-type":
+			if len(args) == 0 || !strings.HasPrefix(strings.ToLower(args[0]), "tun") {
+				return p, fmt.Errorf("line %d: only 'tun' devices are supported", lineNum)
+			}
+		case "auth-user-pass":
+			// the credentials from the file are not in use: the daemon provides them through the management interface
+			p.IsAuthUserPass = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	if len(inlineBlock) > 0 {
+		return p, fmt.Errorf("inline block '<%s>' is not closed", inlineBlock)
+	}
+	if authBlockFound && (len(p.Username) == 0 || len(p.Password) == 0) {
+		return p, fmt.Errorf("inline block '<%s>' must contain username and password", profileAuthUserPassBlock)
+	}
+
+	if len(p.RemoteHost) == 0 {
+		return p, fmt.Errorf("remote host not defined")
+	}
+	p.RemotePort = defaultPort
+	if remotePort > 0 {
+		p.RemotePort = remotePort
+	}
+	p.IsTCP = defaultTCP
+	if len(remoteProto) > 0 {
+		var err error
+		if p.IsTCP, err = parseProfileProto(remoteProto); err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// CreateConnectionParams creates connection parameters for the profile
+// (the remote host name is resolved to IP address)
+func (p *Profile) CreateConnectionParams(username, password string) (ConnectionParams, error) {
+	if p.IsAuthUserPass && (len(username) == 0 || len(password) == 0) {
+		return ConnectionParams{}, fmt.Errorf("the profile requires username/password authentication but credentials not defined")
+	}
+
+	hostIP := net.ParseIP(p.RemoteHost)
+	if hostIP == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), profileRemoteResolveTimeout)
+		defer cancel()
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", p.RemoteHost)
+		if err != nil {
+			return ConnectionParams{}, fmt.Errorf("failed to resolve remote host '%s': %w", p.RemoteHost, err)
+		}
+		if len(ips) == 0 {
+			return ConnectionParams{}, fmt.Errorf("failed to resolve remote host '%s'", p.RemoteHost)
+		}
+		hostIP = ips[0]
+	}
+
+	params := CreateConnectionParams("", p.IsTCP, p.RemotePort, hostIP, "", nil, 0, "", "")
+	params.SetCredentials(username, password)
+	params.profile = p
+	return params, nil
+}
+
+func parseProfilePort(val string) (int, error) {
+	port, err := strconv.Atoi(val)
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("bad port '%s'", val)
+	}
+	return port, nil
+}
+
+func parseProfileProto(val string) (isTCP bool, err error) {
+	val = strings.ToLower(val)
+	switch {
+	case strings.HasPrefix(val, "udp"):
+		return false, nil
+	case strings.HasPrefix(val, "tcp"):
+		return true, nil
+	}
+	return false, fmt.Errorf("unsupported protocol '%s'", val)
+}
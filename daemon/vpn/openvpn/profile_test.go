@@ -0,0 +1,270 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package openvpn
+
+import (
+	"strings"
+	"testing"
+)
+
+const testProfile = `
+client
+dev tun
+proto tcp
+remote 198.51.100.7 443
+remote backup.example.com 1194 udp
+auth-user-pass /etc/openvpn/creds.txt
+cipher AES-256-GCM
+key-direction 1
+<ca>
+-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----
+</ca>
+<tls-auth>
+-----BEGIN OpenVPN Static key V1-----
+0011
+-----END OpenVPN Static key V1-----
+</tls-auth>
+`
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile(testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.RemoteHost != "198.51.100.7" || p.RemotePort != 443 || !p.IsTCP || !p.IsAuthUserPass {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+
+	params, err := p.CreateConnectionParams("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := params.generateConfiguration(0, "127.0.0.1", 1234, "", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgText := strings.Join(cfg, "\n")
+	for _, expected := range []string{"management 127.0.0.1 1234", "remote 198.51.100.7 443", "cipher AES-256-GCM", "</tls-auth>"} {
+		if !strings.Contains(cfgText, expected) {
+			t.Errorf("'%s' not found in configuration", expected)
+		}
+	}
+	if strings.Contains(cfgText, "creds.txt") || strings.Contains(cfgText, "backup.example.com") {
+		t.Error("parameters controlled by the daemon must be removed from the profile")
+	}
+
+	if _, err := p.CreateConnectionParams("", ""); err == nil {
+		t.Error("error expected: credentials not defined")
+	}
+
+	bad := map[string]string{
+		"script hook":    testProfile + "up /tmp/script.sh\n",
+		"file reference": testProfile + "ca /etc/ssl/ca.crt\n",
+		"tap device":     strings.Replace(testProfile, "dev tun", "dev tap", 1),
+		"no remote":      strings.Replace(testProfile, "remote ", "#remote ", -1),
+		"unclosed block": testProfile + "<key>\n",
+		"unknown block":  testProfile + "<connection>\n</connection>\n",
+		"proxy (bypass)": testProfile + "http-proxy 10.0.0.1 8080\n",
+	}
+	for name, text := range bad {
+		if _, err := ParseProfile(text); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
+
+func TestParseProfileRejectsNotAllowedDirectives(t *testing.T) {
+	dangerous := []string{
+		"engine dynamic",
+		"providers legacy default",
+		"pkcs11-providers /tmp/provider.so",
+		"pkcs11-id 'id'",
+		"plugin /tmp/plugin.so",
+		"up /tmp/script.sh",
+		"down /tmp/script.sh",
+		"route-up /tmp/script.sh",
+		"ipchange /tmp/script.sh",
+		"learn-address /tmp/script.sh",
+		"client-connect /tmp/script.sh",
+		"tls-verify /tmp/script.sh",
+		"auth-user-pass-verify /tmp/script.sh via-env",
+		"setenv opt block-outside-dns",
+		"setenv-safe X Y",
+		"config /tmp/other.ovpn",
+		"cd /tmp",
+		"chroot /tmp",
+		"iproute /tmp/ip",
+		"dev-node /dev/net/tun",
+		"askpass /etc/passwd",
+		"management-external-key",
+		"ignore-unknown-option engine",
+		"cryptoapicert SUBJ:test",
+		"daemon",
+		"writepid /tmp/pid",
+		"user nobody",
+		"http-proxy 10.0.0.1 8080",
+		"socks-proxy 10.0.0.1 1080",
+		"some-unknown-directive 1",
+	}
+	for _, line := range dangerous {
+		if _, err := ParseProfile(testProfile + line + "\n"); err == nil {
+			t.Errorf("'%s': error expected", line)
+		}
+	}
+
+	// the parameters controlled by the daemon are removed from the profile
+	controlled := []string{"script-security 3", "management 0.0.0.0 7505", "log-append /etc/passwd", "status /tmp/status.log"}
+	for _, line := range controlled {
+		p, err := ParseProfile(testProfile + line + "\n")
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %v", line, err)
+			continue
+		}
+		for _, l := range p.lines {
+			if l == line {
+				t.Errorf("'%s': parameter controlled by the daemon must be removed from the profile", line)
+			}
+		}
+	}
+}
+
+func TestParseProfileAllowedDirectives(t *testing.T) {
+	allowed := []string{
+		"data-ciphers AES-256-GCM:AES-128-GCM",
+		"remote-cert-tls server",
+		"verify-x509-name server name-prefix",
+		"persist-key",
+		"persist-tun",
+		"keepalive 10 60",
+		"compress lz4-v2",
+		"redirect-gateway def1",
+		"pull-filter ignore \"dhcp-option\"",
+		"route 10.0.0.0 255.0.0.0",
+		"# comment",
+		"; comment",
+	}
+	for _, line := range allowed {
+		if _, err := ParseProfile(testProfile + line + "\n"); err != nil {
+			t.Errorf("'%s': unexpected error: %v", line, err)
+		}
+	}
+}
+
+func TestProfileConfigurationCredentials(t *testing.T) {
+	// profile with username/password authentication
+	p, err := ParseProfile(testProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.CreateConnectionParams("user", ""); err == nil {
+		t.Error("error expected: password not defined")
+	}
+	params, err := p.CreateConnectionParams("user", "secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := params.generateConfiguration(0, "127.0.0.1", 1234, "", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgText := strings.Join(cfg, "\n")
+	// credentials are passed only via the management interface
+	for _, line := range cfg {
+		if strings.HasPrefix(line, "auth-user-pass") && line != "auth-user-pass" {
+			t.Errorf("credentials file must not be used: '%s'", line)
+		}
+	}
+	for _, expected := range []string{"auth-user-pass", "auth-nocache", "management-query-passwords"} {
+		if !strings.Contains(cfgText, expected) {
+			t.Errorf("'%s' not found in configuration", expected)
+		}
+	}
+	if strings.Contains(cfgText, "secret-password") {
+		t.Error("password must not be a part of configuration")
+	}
+
+	// profile without authentication
+	noAuth, err := ParseProfile(strings.Replace(testProfile, "auth-user-pass /etc/openvpn/creds.txt\n", "", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noAuth.IsAuthUserPass {
+		t.Error("the profile does not require authentication")
+	}
+	params, err = noAuth.CreateConnectionParams("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = params.generateConfiguration(0, "127.0.0.1", 1234, "", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range cfg {
+		if strings.HasPrefix(line, "auth-user-pass") {
+			t.Errorf("unexpected '%s' in configuration", line)
+		}
+	}
+}
+
+func TestParseProfileInlineCredentials(t *testing.T) {
+	text := strings.Replace(testProfile, "auth-user-pass /etc/openvpn/creds.txt\n", "auth-user-pass\n<auth-user-pass>\nuser1\nsecret-password\n</auth-user-pass>\n", 1)
+	p, err := ParseProfile(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsAuthUserPass || p.Username != "user1" || p.Password != "secret-password" {
+		t.Fatalf("unexpected credentials: %+v", p)
+	}
+
+	params, err := p.CreateConnectionParams(p.Username, p.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := params.generateConfiguration(0, "127.0.0.1", 1234, "", 0, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgText := strings.Join(cfg, "\n")
+	if strings.Contains(cfgText, "secret-password") || strings.Contains(cfgText, "<auth-user-pass>") {
+		t.Error("inline credentials must be removed from the configuration")
+	}
+
+	// the block without the 'auth-user-pass' parameter also means username/password authentication
+	if p, err := ParseProfile(testProfile + "<auth-user-pass>\nuser1\npass\n</auth-user-pass>\n"); err != nil || !p.IsAuthUserPass {
+		t.Errorf("inline credentials expected: %+v (%v)", p, err)
+	}
+
+	bad := map[string]string{
+		"no password": testProfile + "<auth-user-pass>\nuser1\n</auth-user-pass>\n",
+		"empty block": testProfile + "<auth-user-pass>\n</auth-user-pass>\n",
+		"not closed":  testProfile + "<auth-user-pass>\nuser1\npass\n",
+		"duplicate":   testProfile + "<auth-user-pass>\nu\np\n</auth-user-pass>\n<auth-user-pass>\nu\np\n</auth-user-pass>\n",
+	}
+	for name, text := range bad {
+		if _, err := ParseProfile(text); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vpn
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnectionStats - statistics of the active VPN connection
+type ConnectionStats struct {
+	ConnectedSince time.Time // zero - not connected
+	RxBytes        uint64    // total bytes received
+	TxBytes        uint64    // total bytes sent
+	RxRate         uint64    // bytes per second (for the last measuring period)
+	TxRate         uint64    // bytes per second (for the last measuring period)
+	LastHandshake  time.Time // (WireGuard) time of the latest handshake with the server; zero - no handshake
+	StateDetails   string    // detailed description of the current state (e.g. OpenVPN sub-state: "TLS negotiation", "Authenticating" ...)
+}
+
+// minimal period between counters samples to calculate traffic rates
+const trafficRateMinPeriod = time.Second
+
+// TrafficCounter keeps total traffic counters and calculates the traffic rates
+// (the counters are sampled periodically; the rate is calculated between samples)
+type TrafficCounter struct {
+	mutex          sync.Mutex
+	rx, tx         uint64
+	rxRate, txRate uint64
+	lastSampleTime time.Time
+}
+
+// Update saves new values of the total counters
+func (c *TrafficCounter) Update(rx, tx uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if c.lastSampleTime.IsZero() {
+		c.rx, c.tx, c.lastSampleTime = rx, tx, now
+		return
+	}
+
+	period := now.Sub(c.lastSampleTime)
+	if period < trafficRateMinPeriod {
+		return
+	}
+
+	c.rxRate, c.txRate = 0, 0
+	if rx >= c.rx && tx >= c.tx { // the counters could be reset (e.g. on reconnection)
+		c.rxRate = uint64(float64(rx-c.rx) / period.Seconds())
+		c.txRate = uint64(float64(tx-c.tx) / period.Seconds())
+	}
+	c.rx, c.tx, c.lastSampleTime = rx, tx, now
+}
+
+// Get returns last values of the total counters and the traffic rates (bytes per second)
+func (c *TrafficCounter) Get() (rx, tx, rxRate, txRate uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rx, c.tx, c.rxRate, c.txRate
+}
+
+// Reset erases all counters
+func (c *TrafficCounter) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rx, c.tx, c.rxRate, c.txRate, c.lastSampleTime = 0, 0, 0, 0, time.Time{}
+}
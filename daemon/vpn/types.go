@@ -72,10 +72,12 @@ const (
 	RECONNECTING State = iota // A restart has occurred.
 	TCP_CONNECT  State = iota // TCP_CONNECT
 	EXITING      State = iota // A graceful exit is in progress.
+	DEGRADED     State = iota // (WireGuard) Connected but the tunnel is stalled (no handshake or no incoming traffic)
+	RECOVERED    State = iota // (WireGuard) The stalled tunnel is working again (the connection is the same as before DEGRADED state)
 )
 
 func (s State) String() string {
-	if s < DISCONNECTED || s > EXITING {
+	if s < DISCONNECTED || s > RECOVERED {
 		return "<Unknown>"
 	}
 
@@ -90,18 +92,20 @@ func (s State) String() string {
 		"CONNECTED",
 		"RECONNECTING",
 		"TCP_CONNECT",
-		"EXITING"}[s]
+		"EXITING",
+		"DEGRADED",
+		"RECOVERED"}[s]
 }
 
 // ParseState - Converts string representation of OpenVPN state to vpn.State
 func ParseState(stateStr string) (State, error) {
 	stateStr = strings.Trim(stateStr, " \t;,.")
 	switch stateStr {
-	case "CONNECTING":
+	case "CONNECTING", "RESOLVE":
 		return CONNECTING, nil
 	case "WAIT":
 		return WAIT, nil
-	case "AUTH":
+	case "AUTH", "AUTH_PENDING":
 		return AUTH, nil
 	case "GET_CONFIG":
 		return GETCONFIG, nil
@@ -141,6 +145,8 @@ type StateInfo struct {
 	Mtu          int    // applicable only for 'CONNECTED' state (WireGuard)
 	IsAuthError  bool   // applicable only for 'EXITING' state
 
+	NextHopClientIP net.IP // applicable only for 'CONNECTED' state (WireGuard). Local IP of the custom hop chained after the VPN server (nil - no chained hop)
+
 	// TODO: try to avoid using this protocol-specific parameter in future
 	// Currently, in use by OpenVPN connection to inform about "RECONNECTING" reason (e.g. "tls-error", "init_instance"...)
 	// UI client using this info in order to determine is it necessary to try to connect with another port
@@ -201,6 +207,9 @@ type Process interface {
 	IsIPv6InTunnel() bool
 
 	OnRoutingChanged() error
+
+	// Stats returns statistics of the active connection (traffic counters, rates, detailed state ...)
+	Stats() (ConnectionStats, error)
 }
 
 // ReconnectionRequiredError object can be returned by vpn.Process.Connect() function
@@ -0,0 +1,207 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+// Package userspace is an embedded userspace implementation of the WireGuard protocol (based on wireguard-go).
+// It is in use when the WireGuard kernel module is not available (e.g. containers, old kernels).
+// Only one peer is supported (the VPN server).
+package userspace
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/logger"
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+var log *logger.Logger
+
+func init() {
+	log = logger.NewLogger("wgusr")
+}
+
+// PeerConfig - configuration of the peer
+type PeerConfig struct {
+	PublicKey    Key
+	PresharedKey Key
+	// Endpoint of the peer. When defined - the device initiates handshakes (client);
+	// otherwise - the device only responds to the handshakes of the peer and the endpoint is learned from incoming packets
+	Endpoint            *net.UDPAddr
+	PersistentKeepalive time.Duration
+	// Packets are routed to the peer (and accepted from the peer) only when the address belongs to one of AllowedIPs
+	// (when empty - all the addresses are allowed)
+	AllowedIPs []net.IPNet
+}
+
+// Config - configuration of the device
+type Config struct {
+	PrivateKey Key
+	ListenPort int // 0 - use any free port
+	FwMark     int // firewall mark of the outgoing UDP packets (0 - not in use)
+	Peer       PeerConfig
+}
+
+// Stats - the peer statistics
+type Stats struct {
+	Endpoint      *net.UDPAddr
+	LastHandshake time.Time
+	RxBytes       uint64
+	TxBytes       uint64
+}
+
+// Device - userspace WireGuard device
+type Device struct {
+	dev           *device.Device
+	name          string
+	peerPublicKey Key
+}
+
+// NewDevice creates new userspace WireGuard device
+// The device must be started by Start() and it takes ownership of 'tun' (it is closed on device Close())
+func NewDevice(tunDev tun.Device, cfg Config) (*Device, error) {
+	if tunDev == nil {
+		return nil, fmt.Errorf("TUN interface not defined")
+	}
+	name, err := tunDev.Name()
+	if err != nil {
+		tunDev.Close()
+		return nil, fmt.Errorf("failed to get TUN interface name: %w", err)
+	}
+
+	wgLogger := &device.Logger{
+		Verbosef: device.DiscardLogf,
+		Errorf: func(format string, args ...interface{}) {
+			log.Warning(fmt.Sprintf(format, args...))
+		},
+	}
+	dev := device.NewDevice(tunDev, conn.NewDefaultBind(), wgLogger) // the TUN interface is closed together with the device
+
+	if err := dev.IpcSet(uapiConfig(cfg)); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("failed to configure WireGuard device: %w", err)
+	}
+
+	return &Device{dev: dev, name: name, peerPublicKey: cfg.Peer.PublicKey}, nil
+}
+
+// Start starts processing of the packets
+func (d *Device) Start() error {
+	if err := d.dev.Up(); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Started (interface: %s; local port: %d)", d.name, d.LocalPort()))
+	return nil
+}
+
+// Close stops the device and closes the TUN interface
+func (d *Device) Close() error {
+	d.dev.Close()
+	log.Info(fmt.Sprintf("Stopped (interface: %s)", d.name))
+	return nil
+}
+
+// LocalPort returns the UDP port the device is listening on
+func (d *Device) LocalPort() int {
+	port, _ := strconv.Atoi(d.ipcGet()["listen_port"])
+	return port
+}
+
+// Stats returns the peer statistics
+func (d *Device) Stats() Stats {
+	var ret Stats
+	v := d.ipcGet()
+	if sec, _ := strconv.ParseInt(v["last_handshake_time_sec"], 10, 64); sec > 0 {
+		nsec, _ := strconv.ParseInt(v["last_handshake_time_nsec"], 10, 64)
+		ret.LastHandshake = time.Unix(sec, nsec)
+	}
+	ret.RxBytes, _ = strconv.ParseUint(v["rx_bytes"], 10, 64)
+	ret.TxBytes, _ = strconv.ParseUint(v["tx_bytes"], 10, 64)
+	if ep, ok := v["endpoint"]; ok {
+		ret.Endpoint, _ = net.ResolveUDPAddr("udp", ep)
+	}
+	return ret
+}
+
+// SetPresharedKey updates the preshared key of the peer
+// The new key is in use starting from the next handshake (the active session keys stay valid)
+func (d *Device) SetPresharedKey(psk Key) error {
+	return d.dev.IpcSet(fmt.Sprintf("public_key=%s\nupdate_only=true\npreshared_key=%s\n", d.peerPublicKey.hex(), psk.hex()))
+}
+
+// ipcGet returns the device configuration (the keys of the device and of the single peer are merged)
+func (d *Device) ipcGet() map[string]string {
+	ret := make(map[string]string)
+	cfg, err := d.dev.IpcGet()
+	if err != nil {
+		log.Warning(fmt.Sprintf("failed to get WireGuard device configuration: %s", err))
+		return ret
+	}
+	scanner := bufio.NewScanner(strings.NewReader(cfg))
+	for scanner.Scan() {
+		if kv := strings.SplitN(scanner.Text(), "=", 2); len(kv) == 2 {
+			ret[kv[0]] = kv[1]
+		}
+	}
+	return ret
+}
+
+// uapiConfig converts configuration to the WireGuard configuration protocol format
+// (https://www.wireguard.com/xplatform/#configuration-protocol)
+func uapiConfig(cfg Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "private_key=%s\n", cfg.PrivateKey.hex())
+	fmt.Fprintf(&b, "listen_port=%d\n", cfg.ListenPort)
+	if cfg.FwMark != 0 {
+		fmt.Fprintf(&b, "fwmark=%d\n", cfg.FwMark)
+	}
+	b.WriteString("replace_peers=true\n")
+
+	peer := cfg.Peer
+	fmt.Fprintf(&b, "public_key=%s\n", peer.PublicKey.hex())
+	if !peer.PresharedKey.IsZero() {
+		fmt.Fprintf(&b, "preshared_key=%s\n", peer.PresharedKey.hex())
+	}
+	if peer.Endpoint != nil {
+		fmt.Fprintf(&b, "endpoint=%s\n", peer.Endpoint.String())
+	}
+	if peer.PersistentKeepalive > 0 {
+		fmt.Fprintf(&b, "persistent_keepalive_interval=%d\n", int(peer.PersistentKeepalive/time.Second))
+	}
+	b.WriteString("replace_allowed_ips=true\n")
+	allowedIPs := peer.AllowedIPs
+	if len(allowedIPs) == 0 {
+		allowedIPs = []net.IPNet{{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}, {IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}}
+	}
+	for _, n := range allowedIPs {
+		fmt.Fprintf(&b, "allowed_ip=%s\n", n.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,356 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package userspace_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard/userspace"
+	"golang.org/x/sys/unix"
+	"golang.zx2c4.com/wireguard/tun/tuntest"
+)
+
+// newDevice creates and starts the device on in-memory TUN interface
+func newDevice(t *testing.T, cfg userspace.Config) (*userspace.Device, *tuntest.ChannelTUN) {
+	tun := tuntest.NewChannelTUN()
+	dev, err := userspace.NewDevice(tun.TUN(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.Start(); err != nil {
+		dev.Close()
+		t.Fatal(err)
+	}
+	return dev, tun
+}
+
+func ipv4Packet(src, dst net.IP, payload []byte) []byte {
+	p := make([]byte, 20+len(payload))
+	p[0] = 0x45
+	binary.BigEndian.PutUint16(p[2:4], uint16(len(p)))
+	p[8] = 64
+	p[9] = 17
+	copy(p[12:16], src.To4())
+	copy(p[16:20], dst.To4())
+	copy(p[20:], payload)
+	return p
+}
+
+func newKeys(t *testing.T) (private, public userspace.Key) {
+	private, err := userspace.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, private.PublicKey()
+}
+
+func TestDeviceMemTun(t *testing.T) {
+	privA, pubA := newKeys(t)
+	privB, pubB := newKeys(t)
+	psk, _ := userspace.NewPrivateKey()
+
+	devB, tunB := newDevice(t, userspace.Config{
+		PrivateKey: privB,
+		Peer: userspace.PeerConfig{
+			PublicKey:    pubA,
+			PresharedKey: psk,
+			AllowedIPs:   []net.IPNet{{IP: net.IPv4(10, 0, 0, 1), Mask: net.CIDRMask(32, 32)}},
+		},
+	})
+	defer devB.Close()
+
+	devA, tunA := newDevice(t, userspace.Config{
+		PrivateKey: privA,
+		Peer: userspace.PeerConfig{
+			PublicKey:    pubB,
+			PresharedKey: psk,
+			Endpoint:     &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: devB.LocalPort()},
+		},
+	})
+	defer devA.Close()
+
+	ipA, ipB := net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2)
+
+	// A -> B (the packet is queued until the handshake is finished)
+	request := ipv4Packet(ipA, ipB, []byte("request"))
+	tunA.Outbound <- request
+	select {
+	case p := <-tunB.Inbound:
+		if !bytes.Equal(p, request) {
+			t.Fatalf("unexpected packet received: %x", p)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("packet not received by responder")
+	}
+
+	// B -> A
+	response := ipv4Packet(ipB, ipA, []byte("response (with padding)"))
+	tunB.Outbound <- response
+	select {
+	case p := <-tunA.Inbound:
+		if !bytes.Equal(p, response) {
+			t.Fatalf("unexpected packet received: %x", p)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("packet not received by initiator")
+	}
+
+	// not allowed source address must be dropped by B
+	tunA.Outbound <- ipv4Packet(net.IPv4(10, 0, 0, 3), ipB, []byte("spoofed"))
+	select {
+	case p := <-tunB.Inbound:
+		t.Fatalf("packet with not allowed source address received: %x", p)
+	case <-time.After(time.Millisecond * 500):
+	}
+
+	stats := devA.Stats()
+	if stats.LastHandshake.IsZero() || stats.RxBytes == 0 || stats.TxBytes == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestDeviceBadPeerKey(t *testing.T) {
+	privA, _ := newKeys(t)
+	privB, pubB := newKeys(t)
+	_, pubOther := newKeys(t)
+
+	// B does not know A's public key: no session must be established
+	devB, tunB := newDevice(t, userspace.Config{PrivateKey: privB, Peer: userspace.PeerConfig{PublicKey: pubOther}})
+	defer devB.Close()
+
+	devA, tunA := newDevice(t, userspace.Config{
+		PrivateKey: privA,
+		Peer:       userspace.PeerConfig{PublicKey: pubB, Endpoint: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: devB.LocalPort()}},
+	})
+	defer devA.Close()
+
+	tunA.Outbound <- ipv4Packet(net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), []byte("request"))
+	select {
+	case p := <-tunB.Inbound:
+		t.Fatalf("packet from unknown peer received: %x", p)
+	case <-time.After(time.Second):
+	}
+	if !devA.Stats().LastHandshake.IsZero() {
+		t.Fatal("handshake with unknown peer must fail")
+	}
+}
+
+// TestDeviceNetns runs two devices with real TUN interfaces in separate network namespaces
+// (connected by veth pair) and checks UDP traffic through the tunnel.
+// Root privileges required.
+func TestDeviceNetns(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("root privileges required")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("'ip' utility not found")
+	}
+	if !userspace.IsSupported() {
+		t.Skip("TUN not supported")
+	}
+
+	nsA := fmt.Sprintf("wgusr-a-%d", os.Getpid())
+	nsB := fmt.Sprintf("wgusr-b-%d", os.Getpid())
+	const tunName = "wgusr0"
+
+	ip := func(args ...string) {
+		if out, err := exec.Command("ip", args...).CombinedOutput(); err != nil {
+			t.Fatalf("ip %v: %s (%s)", args, err, out)
+		}
+	}
+
+	ip("netns", "add", nsA)
+	defer exec.Command("ip", "netns", "del", nsA).Run()
+	ip("netns", "add", nsB)
+	defer exec.Command("ip", "netns", "del", nsB).Run()
+
+	ip("link", "add", "wgusr-va", "netns", nsA, "type", "veth", "peer", "name", "wgusr-vb", "netns", nsB)
+	ip("-n", nsA, "addr", "add", "192.168.231.1/24", "dev", "wgusr-va")
+	ip("-n", nsA, "link", "set", "wgusr-va", "up")
+	ip("-n", nsB, "addr", "add", "192.168.231.2/24", "dev", "wgusr-vb")
+	ip("-n", nsB, "link", "set", "wgusr-vb", "up")
+
+	privA, pubA := newKeys(t)
+	privB, pubB := newKeys(t)
+
+	// the devices must be started inside the namespace: the UDP sockets are created on start
+	var devA, devB *userspace.Device
+	inNetns(t, nsB, func() {
+		tun, err := userspace.CreateTUN(tunName, 1420)
+		if err != nil {
+			t.Fatal(err)
+		}
+		devB, err = userspace.NewDevice(tun, userspace.Config{PrivateKey: privB, Peer: userspace.PeerConfig{PublicKey: pubA}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := devB.Start(); err != nil {
+			devB.Close()
+			t.Fatal(err)
+		}
+	})
+	defer devB.Close()
+	ip("-n", nsB, "addr", "add", "10.231.0.2/24", "dev", tunName)
+	ip("-n", nsB, "link", "set", tunName, "mtu", "1420", "up")
+
+	inNetns(t, nsA, func() {
+		tun, err := userspace.CreateTUN(tunName, 1420)
+		if err != nil {
+			t.Fatal(err)
+		}
+		devA, err = userspace.NewDevice(tun, userspace.Config{
+			PrivateKey: privA,
+			FwMark:     0xca6c,
+			Peer: userspace.PeerConfig{
+				PublicKey:           pubB,
+				Endpoint:            &net.UDPAddr{IP: net.IPv4(192, 168, 231, 2), Port: devB.LocalPort()},
+				PersistentKeepalive: time.Second * 25,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := devA.Start(); err != nil {
+			devA.Close()
+			t.Fatal(err)
+		}
+	})
+	defer devA.Close()
+	ip("-n", nsA, "addr", "add", "10.231.0.1/24", "dev", tunName)
+	ip("-n", nsA, "link", "set", tunName, "mtu", "1420", "up")
+
+	// UDP echo server in namespace B (listening on the tunnel address)
+	var server *net.UDPConn
+	inNetns(t, nsB, func() {
+		var err error
+		if server, err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(10, 231, 0, 2), Port: 5555}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			server.WriteToUDP(buf[:n], addr)
+		}
+	}()
+
+	var client *net.UDPConn
+	inNetns(t, nsA, func() {
+		var err error
+		if client, err = net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(10, 231, 0, 2), Port: 5555}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer client.Close()
+
+	request := bytes.Repeat([]byte("ivpn"), 300)
+	buf := make([]byte, 1500)
+	received := false
+	for i := 0; i < 5 && !received; i++ {
+		if _, err := client.Write(request); err != nil {
+			t.Fatal(err)
+		}
+		client.SetReadDeadline(time.Now().Add(time.Second * 2))
+		n, err := client.Read(buf)
+		if err == nil {
+			if !bytes.Equal(buf[:n], request) {
+				t.Fatalf("unexpected echo response (%d bytes)", n)
+			}
+			received = true
+		}
+	}
+	if !received {
+		t.Fatal("no response through the tunnel")
+	}
+
+	stats := devA.Stats()
+	if stats.LastHandshake.IsZero() || stats.RxBytes == 0 || stats.TxBytes == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+// inNetns executes 'f' in the network namespace (sockets and TUN interfaces created by 'f' belong to the namespace)
+func inNetns(t *testing.T, name string, f func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	orig, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orig.Close()
+	ns, err := os.Open("/run/netns/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); err != nil {
+			panic(fmt.Sprintf("failed to restore network namespace: %s", err))
+		}
+	}()
+	f()
+}
+
+func TestProbeHandshake(t *testing.T) {
+	privA, pubA := newKeys(t)
+	privB, pubB := newKeys(t)
+
+	devB, _ := newDevice(t, userspace.Config{
+		PrivateKey: privB,
+		Peer:       userspace.PeerConfig{PublicKey: pubA},
+	})
+	defer devB.Close()
+
+	endpoint := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: devB.LocalPort()}
+
+	if rtt, err := userspace.ProbeHandshake(endpoint, privA, pubB, time.Second*5); err != nil || rtt <= 0 {
+		t.Fatalf("probe of the known peer failed: rtt=%v err=%v", rtt, err)
+	}
+
+	// the responder does not reply to unknown peers
+	privUnknown, _ := newKeys(t)
+	if _, err := userspace.ProbeHandshake(endpoint, privUnknown, pubB, time.Millisecond*500); err == nil {
+		t.Fatal("probe of the unknown peer expected to fail")
+	}
+}
@@ -0,0 +1,118 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package userspace
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/tuntest"
+)
+
+// ProbeHandshake checks the reachability of WireGuard server on the UDP port:
+// sends the handshake initiation to the endpoint and waits for the reply (handshake response or cookie reply).
+// Returns the round-trip time.
+// Note: the server replies only when the local public key is known by the server (the peer is registered).
+func ProbeHandshake(endpoint *net.UDPAddr, localPrivate, remotePublic Key, timeout time.Duration) (time.Duration, error) {
+	msg, err := createInitiation(localPrivate, remotePublic)
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := net.DialUDP("udp", nil, endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if _, err := c.Write(msg.packet); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, device.MessageInitiationSize)
+	for {
+		n, err := c.Read(buf)
+		if err != nil {
+			return 0, fmt.Errorf("no handshake reply: %w", err)
+		}
+		reply := buf[:n]
+		// The receiver index is enough to ensure that it is the reply to our initiation.
+		// (the handshake response is not validated: the server may expect a preshared key which is unknown here)
+		switch {
+		case n == device.MessageResponseSize && binary.LittleEndian.Uint32(reply[0:4]) == device.MessageResponseType:
+			if binary.LittleEndian.Uint32(reply[8:12]) == msg.sender {
+				return time.Since(start), nil
+			}
+		case n == device.MessageCookieReplySize && binary.LittleEndian.Uint32(reply[0:4]) == device.MessageCookieReplyType:
+			// the server is under load: the cookie reply is an evidence of reachability as well
+			if binary.LittleEndian.Uint32(reply[4:8]) == msg.sender {
+				return time.Since(start), nil
+			}
+		}
+	}
+}
+
+type initiation struct {
+	packet []byte
+	sender uint32 // the local index of the handshake
+}
+
+// createInitiation creates the handshake initiation message (by the wireguard-go device which is not started)
+func createInitiation(localPrivate, remotePublic Key) (initiation, error) {
+	// the device is not brought up: neither the TUN interface nor the network is in use
+	dev := device.NewDevice(tuntest.NewChannelTUN().TUN(), conn.NewDefaultBind(), device.NewLogger(device.LogLevelSilent, ""))
+	defer dev.Close()
+
+	if err := dev.IpcSet(fmt.Sprintf("private_key=%s\npublic_key=%s\n", localPrivate.hex(), remotePublic.hex())); err != nil {
+		return initiation{}, fmt.Errorf("bad WireGuard keys: %w", err)
+	}
+	peer := dev.LookupPeer(device.NoisePublicKey(remotePublic))
+	if peer == nil {
+		return initiation{}, fmt.Errorf("bad WireGuard keys")
+	}
+
+	msg, err := dev.CreateMessageInitiation(peer)
+	if err != nil {
+		return initiation{}, err
+	}
+	var b bytes.Buffer
+	if err := binary.Write(&b, binary.LittleEndian, msg); err != nil {
+		return initiation{}, err
+	}
+	packet := b.Bytes()
+
+	var cookieGen device.CookieGenerator
+	cookieGen.Init(device.NoisePublicKey(remotePublic))
+	cookieGen.AddMacs(packet)
+
+	return initiation{packet: packet, sender: msg.Sender}, nil
+}
@@ -28,6 +28,8 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ivpn/desktop-app/daemon/helpers"
 	"github.com/ivpn/desktop-app/daemon/logger"
@@ -51,11 +53,30 @@ type ConnectionParams struct {
 	hostPublicKey        string
 	hostLocalIP          net.IP
 	ipv6Prefix           string
-	multihopExitHostname string // (e.g.: "nl4.wg.ivpn.net") we need it only for informing clients about connection status
-	mtu                  int    // Set 0 to use default MTU value
+	multihopExitHostname string        // (e.g.: "nl4.wg.ivpn.net") we need it only for informing clients about connection status
+	mtu                  int           // Set 0 to use default MTU value
+	isUserspaceForced    bool          // (Linux) use embedded userspace WireGuard implementation even if kernel module is available
+	stallTimeout         time.Duration // time the tunnel may stay stalled before reconnection (0 - use DefaultStallTimeout)
+
+	// post-quantum preshared key rotation while connected (see SetPresharedKeyRotation())
+	pskRotationInterval time.Duration
+	pskNegotiate        func() (string, error)
+
+	// parameters of custom (third-party) WireGuard configurations (see Config.CreateConnectionParams())
+	isCustomConfig      bool
+	presharedKey        string      // base64 (empty - not in use); also in use for IVPN servers (see SetPresharedKey())
+	clientLocalIPv6     net.IP      // IPv6 address of the interface (when defined - 'ipv6Prefix' is ignored)
+	allowedIPs          []net.IPNet // networks routed into the tunnel (empty - all IPv4 and IPv6 (if supported) traffic)
+	persistentKeepalive int         // seconds (0 - default value; KeepaliveOff - disabled)
+
+	// custom WireGuard hop chained after the server (the hop's endpoint is reachable only through this tunnel)
+	nextHop *ConnectionParams
 }
 
 func (cp *ConnectionParams) GetIPv6ClientLocalIP() net.IP {
+	if cp.clientLocalIPv6 != nil {
+		return cp.clientLocalIPv6
+	}
 	if len(cp.ipv6Prefix) <= 0 {
 		return nil
 	}
@@ -68,12 +89,106 @@ func (cp *ConnectionParams) GetIPv6HostLocalIP() net.IP {
 	return net.ParseIP(cp.ipv6Prefix + cp.hostLocalIP.String())
 }
 
+// getAllowedIPs returns the networks routed into the tunnel
+func (cp *ConnectionParams) getAllowedIPs() []net.IPNet {
+	if len(cp.allowedIPs) > 0 {
+		return cp.allowedIPs
+	}
+	ret := []net.IPNet{{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}}
+	if cp.GetIPv6ClientLocalIP() != nil {
+		ret = append(ret, net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)})
+	}
+	return ret
+}
+
+// getPersistentKeepalive returns the keepalive interval in seconds (0 - disabled)
+func (cp *ConnectionParams) getPersistentKeepalive() int {
+	switch {
+	case cp.persistentKeepalive == KeepaliveOff:
+		return 0
+	case cp.persistentKeepalive > 0:
+		return cp.persistentKeepalive
+	}
+	return defaultKeepalive
+}
+
 // SetCredentials update WG credentials
 func (cp *ConnectionParams) SetCredentials(privateKey string, localIP net.IP) {
 	cp.clientPrivateKey = privateKey
 	cp.clientLocalIP = localIP
 }
 
+// HostIP returns the IP address of the server host (entry server in case of Multi-Hop)
+func (cp *ConnectionParams) HostIP() net.IP {
+	return cp.hostIP
+}
+
+// IsMultiHop returns true for Multi-Hop connection parameters
+func (cp *ConnectionParams) IsMultiHop() bool {
+	return len(cp.multihopExitHostname) > 0
+}
+
+// MultihopExitHostname returns the host name of the Multi-Hop exit server (empty - Single-Hop connection)
+func (cp *ConnectionParams) MultihopExitHostname() string {
+	return cp.multihopExitHostname
+}
+
+// WithMultihopExit returns the copy of Multi-Hop connection parameters for another exit server host
+func (cp ConnectionParams) WithMultihopExit(exitHostname string, exitMultihopPort int, exitPublicKey string) ConnectionParams {
+	cp.multihopExitHostname = exitHostname
+	cp.hostPort = exitMultihopPort
+	cp.hostPublicKey = exitPublicKey
+	cp.presharedKey = "" // the preshared key is negotiated for each connection
+	return cp
+}
+
+// IsIPv6 returns true when IPv6 is in use inside the tunnel
+func (cp *ConnectionParams) IsIPv6() bool {
+	return len(cp.ipv6Prefix) > 0 || cp.clientLocalIPv6 != nil
+}
+
+// WithHost returns the copy of connection parameters for another IVPN server host (e.g. for failover)
+func (cp ConnectionParams) WithHost(hostIP net.IP, hostPublicKey string, hostLocalIP net.IP, ipv6Prefix string) ConnectionParams {
+	cp.hostIP = hostIP
+	cp.hostPublicKey = hostPublicKey
+	cp.hostLocalIP = hostLocalIP
+	cp.ipv6Prefix = ipv6Prefix
+	cp.presharedKey = "" // the preshared key is negotiated for each connection
+	return cp
+}
+
+// SetUserspaceForced - (Linux) force using embedded userspace WireGuard implementation
+// (by default, it is in use only when the WireGuard kernel module is not available)
+func (cp *ConnectionParams) SetUserspaceForced(isForced bool) {
+	cp.isUserspaceForced = isForced
+}
+
+// SetStallTimeout - the time the tunnel may stay stalled (no handshake or no incoming traffic) before reconnection
+// (0 - use DefaultStallTimeout)
+func (cp *ConnectionParams) SetStallTimeout(timeout time.Duration) {
+	cp.stallTimeout = timeout
+}
+
+// SetPresharedKey - set the preshared key of the peer (base64; empty - not in use)
+func (cp *ConnectionParams) SetPresharedKey(psk string) {
+	cp.presharedKey = psk
+}
+
+// SetPresharedKeyRotation - negotiate new preshared key each 'interval' while connected
+// 'negotiate' returns new preshared key (base64) which is already accepted by the server
+// (interval <= 0 or nil 'negotiate' - no rotation)
+func (cp *ConnectionParams) SetPresharedKeyRotation(interval time.Duration, negotiate func() (string, error)) {
+	cp.pskRotationInterval = interval
+	cp.pskNegotiate = negotiate
+}
+
+// SetNextHop - chain the custom WireGuard hop after the server: the traffic goes through the server to the hop
+// (currently, applicable only for Linux with native WireGuard configuration)
+func (cp *ConnectionParams) SetNextHop(hop ConnectionParams) {
+	hop.nextHop = nil
+	cp.nextHop = &hop
+}
+
 // CreateConnectionParams initializing connection parameters object
 func CreateConnectionParams(
 	multihopExitHostName string,
@@ -95,6 +210,12 @@ func CreateConnectionParams(
 	}
 }
 
+// IsNativeConfigSupported returns 'true' when WireGuard interface can be configured natively
+// (without external WireGuard tools; currently, applicable only for Linux)
+func IsNativeConfigSupported() bool {
+	return isNativeConfigSupported()
+}
+
 // WireGuard structure represents all data of wireguard connection
 type WireGuard struct {
 	binaryPath     string
@@ -104,6 +225,18 @@ type WireGuard struct {
 	localPort      int
 	isDisconnected bool
 
+	// protects 'connectParams.presharedKey' (it is updated by the preshared key rotation while connected)
+	pskMutex sync.Mutex
+
+	// connection statistics (initialized by the watchdog)
+	stats struct {
+		mutex          sync.Mutex
+		getPeerStats   func() (peerStats, error) // platform-specific; nil - not connected
+		connectedSince time.Time
+		stallReason    error // not nil when the tunnel is stalled (DEGRADED state)
+		trafficCounter vpn.TrafficCounter
+	}
+
 	// Must be implemented (AND USED) in correspond file for concrete platform. Must contain platform-specified properties (or can be empty struct)
 	internals internalVariables
 }
@@ -131,6 +264,9 @@ func (wg *WireGuard) DefaultDNS() net.IP {
 		return nil
 	}
 
+	if hop := wg.connectParams.nextHop; hop != nil && hop.hostLocalIP != nil {
+		return hop.hostLocalIP
+	}
 	return wg.connectParams.hostLocalIP
 }
 
@@ -166,6 +302,10 @@ func (wg *WireGuard) Connect(stateChan chan<- vpn.StateInfo) error {
 			}
 		}
 
+		if wg.connectParams.nextHop != nil && !isNativeConfigSupported() {
+			return fmt.Errorf("chained WireGuard hop is not supported on this platform")
+		}
+
 		return wg.connect(stateChan)
 	}()
 
@@ -223,9 +363,13 @@ func (wg *WireGuard) generateAndSaveConfigFile(cfgFilePath string) error {
 		return fmt.Errorf("failed to save WireGuard configuration into a file: %w", err)
 	}
 
+	logText := strings.ReplaceAll(configText, wg.connectParams.clientPrivateKey, "***")
+	if psk := wg.getPresharedKey(); len(psk) > 0 {
+		logText = strings.ReplaceAll(logText, psk, "***")
+	}
 	log.Info("WireGuard  configuration:",
 		"\n=====================\n",
-		strings.ReplaceAll(configText, wg.connectParams.clientPrivateKey, "***"),
+		logText,
 		"\n=====================\n")
 
 	return nil
@@ -256,7 +400,14 @@ func (wg *WireGuard) generateConfig() ([]string, error) {
 		"[Peer]",
 		"PublicKey = " + wg.connectParams.hostPublicKey,
 		"Endpoint = " + wg.connectParams.hostIP.String() + ":" + strconv.Itoa(wg.connectParams.hostPort),
-		"PersistentKeepalive = 25"}
+		"PersistentKeepalive = " + strconv.Itoa(wg.connectParams.getPersistentKeepalive())}
+
+	if psk := wg.getPresharedKey(); len(psk) > 0 {
+		if !helpers.ValidateBase64(psk) {
+			return nil, fmt.Errorf("WG preshared key is not base64 string")
+		}
+		peerCfg = append(peerCfg, "PresharedKey = "+psk)
+	}
 
 	// add some OS-specific configurations (if necessary)
 	iCfg, pCgf := wg.getOSSpecificConfigParams()
@@ -281,6 +432,9 @@ func (wg *WireGuard) notifyConnectedStat(stateChan chan<- vpn.StateInfo) {
 		wg.connectParams.mtu)
 
 	si.ExitHostname = wg.connectParams.multihopExitHostname
+	if hop := wg.connectParams.nextHop; hop != nil {
+		si.NextHopClientIP = hop.clientLocalIP
+	}
 
 	stateChan <- si
 }
@@ -292,3 +446,28 @@ func (wg *WireGuard) OnRoutingChanged() error {
 func (wg *WireGuard) IsIPv6InTunnel() bool {
 	return len(wg.connectParams.GetIPv6ClientLocalIP()) > 0
 }
+
+// Stats returns statistics of the active connection
+func (wg *WireGuard) Stats() (vpn.ConnectionStats, error) {
+	wg.stats.mutex.Lock()
+	getPeerStats := wg.stats.getPeerStats
+	ret := vpn.ConnectionStats{ConnectedSince: wg.stats.connectedSince}
+	if wg.stats.stallReason != nil {
+		ret.StateDetails = "Tunnel stalled: " + wg.stats.stallReason.Error()
+	}
+	wg.stats.mutex.Unlock()
+
+	if getPeerStats == nil || wg.isDisconnected {
+		return vpn.ConnectionStats{}, fmt.Errorf("WireGuard is not connected")
+	}
+
+	ps, err := getPeerStats()
+	if err != nil {
+		return vpn.ConnectionStats{}, fmt.Errorf("unable to get WireGuard statistics: %w", err)
+	}
+	wg.stats.trafficCounter.Update(ps.rxBytes, ps.txBytes)
+
+	ret.RxBytes, ret.TxBytes, ret.RxRate, ret.TxRate = wg.stats.trafficCounter.Get()
+	ret.LastHandshake = ps.lastHandshake
+	return ret, nil
+}
@@ -0,0 +1,338 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsing of the third-party WireGuard configurations (in 'wg-quick' format).
+// Only one peer is supported.
+// For security reasons, the configurations with the script hooks (PreUp, PostUp, PreDown, PostDown) are not accepted.
+
+const (
+	endpointResolveTimeout = time.Second * 5
+	defaultKeepalive       = 25 // seconds
+	// KeepaliveOff - the persistent keepalive is disabled ('PersistentKeepalive = off' or 'PersistentKeepalive = 0')
+	KeepaliveOff = -1
+)
+
+// Config - WireGuard configuration (parsed 'wg-quick' configuration file)
+type Config struct {
+	// [Interface]
+	PrivateKey string
+	Addresses  []net.IP
+	DNS        []net.IP
+	MTU        int
+
+	// [Peer]
+	PublicKey           string
+	PresharedKey        string
+	Endpoint            string // host:port (host can be a domain name)
+	AllowedIPs          []net.IPNet
+	PersistentKeepalive int // seconds (0 - not defined: default value is in use; KeepaliveOff - disabled)
+}
+
+// ParseConfig parses WireGuard configuration in 'wg-quick' format
+func ParseConfig(text string) (Config, error) {
+	var cfg Config
+	section := ""
+	peersCount := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			switch section {
+			case "interface":
+			case "peer":
+				peersCount++
+				if peersCount > 1 {
+					return cfg, fmt.Errorf("only one [Peer] is supported")
+				}
+			default:
+				return cfg, fmt.Errorf("line %d: unknown section '%s'", lineNum, line)
+			}
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return cfg, fmt.Errorf("line %d: unexpected format", lineNum)
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		value := strings.TrimSpace(kv[1])
+
+		var err error
+		switch section {
+		case "interface":
+			err = cfg.parseInterfaceValue(key, value)
+		case "peer":
+			err = cfg.parsePeerValue(key, value)
+		default:
+			err = fmt.Errorf("value outside of section")
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("line %d ('%s'): %w", lineNum, strings.TrimSpace(kv[0]), err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.validate()
+}
+
+func (c *Config) parseInterfaceValue(key, value string) error {
+	switch key {
+	case "privatekey":
+		if err := validateKey(value); err != nil {
+			return err
+		}
+		c.PrivateKey = value
+	case "address":
+		for _, v := range splitList(value) {
+			ip, _, err := net.ParseCIDR(v)
+			if err != nil {
+				if ip = net.ParseIP(v); ip == nil {
+					return fmt.Errorf("bad address '%s'", v)
+				}
+			}
+			c.Addresses = append(c.Addresses, ip)
+		}
+	case "dns":
+		for _, v := range splitList(value) {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				// DNS search domains are not supported
+				return fmt.Errorf("bad DNS server IP '%s'", v)
+			}
+			c.DNS = append(c.DNS, ip)
+		}
+	case "mtu":
+		mtu, err := strconv.Atoi(value)
+		if err != nil || mtu <= 0 {
+			return fmt.Errorf("bad MTU value")
+		}
+		c.MTU = mtu
+	case "listenport", "fwmark", "table", "saveconfig":
+		// ignored: the values are defined by the daemon
+	case "preup", "postup", "predown", "postdown":
+		return fmt.Errorf("script hooks are not supported")
+	default:
+		return fmt.Errorf("unsupported parameter")
+	}
+	return nil
+}
+
+func (c *Config) parsePeerValue(key, value string) error {
+	switch key {
+	case "publickey":
+		if err := validateKey(value); err != nil {
+			return err
+		}
+		c.PublicKey = value
+	case "presharedkey":
+		if err := validateKey(value); err != nil {
+			return err
+		}
+		c.PresharedKey = value
+	case "endpoint":
+		host, port, err := net.SplitHostPort(value)
+		if err != nil {
+			return fmt.Errorf("bad endpoint: %w", err)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			return fmt.Errorf("bad endpoint port")
+		}
+		if len(host) == 0 {
+			return fmt.Errorf("bad endpoint host")
+		}
+		c.Endpoint = value
+	case "allowedips":
+		for _, v := range splitList(value) {
+			_, n, err := net.ParseCIDR(v)
+			if err != nil {
+				return fmt.Errorf("bad allowed IP '%s'", v)
+			}
+			c.AllowedIPs = append(c.AllowedIPs, *n)
+		}
+	case "persistentkeepalive":
+		if value == "off" {
+			c.PersistentKeepalive = KeepaliveOff
+			return nil
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 || v > 65535 {
+			return fmt.Errorf("bad keepalive value")
+		}
+		if v == 0 {
+			v = KeepaliveOff
+		}
+		c.PersistentKeepalive = v
+	default:
+		return fmt.Errorf("unsupported parameter")
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if len(c.PrivateKey) == 0 {
+		return fmt.Errorf("private key not defined")
+	}
+	if len(c.PublicKey) == 0 {
+		return fmt.Errorf("peer not defined")
+	}
+	if len(c.Endpoint) == 0 {
+		return fmt.Errorf("peer endpoint not defined")
+	}
+	if len(c.AllowedIPs) == 0 {
+		return fmt.Errorf("peer allowed IPs not defined")
+	}
+	if c.LocalIPv4() == nil {
+		return fmt.Errorf("IPv4 interface address not defined")
+	}
+	// all the DNS requests must go through the tunnel (the firewall blocks other DNS servers)
+	if c.DNSv4() == nil {
+		return fmt.Errorf("IPv4 DNS server not defined")
+	}
+	if c.MTU > 0 && (c.MTU < 1280 || c.MTU > 65535) {
+		return fmt.Errorf("bad MTU value (acceptable interval is: [1280 - 65535])")
+	}
+	return nil
+}
+
+// LocalIPv4 returns IPv4 address of the interface
+func (c *Config) LocalIPv4() net.IP {
+	for _, ip := range c.Addresses {
+		if ip.To4() != nil {
+			return ip.To4()
+		}
+	}
+	return nil
+}
+
+// LocalIPv6 returns IPv6 address of the interface (nil - not defined)
+func (c *Config) LocalIPv6() net.IP {
+	for _, ip := range c.Addresses {
+		if ip.To4() == nil {
+			return ip
+		}
+	}
+	return nil
+}
+
+// DNSv4 returns the first IPv4 DNS server
+func (c *Config) DNSv4() net.IP {
+	for _, ip := range c.DNS {
+		if ip.To4() != nil {
+			return ip.To4()
+		}
+	}
+	return nil
+}
+
+// CreateConnectionParams creates connection parameters for the configuration
+// (the endpoint host name is resolved to IP address)
+func (c *Config) CreateConnectionParams() (ConnectionParams, error) {
+	host, portStr, err := net.SplitHostPort(c.Endpoint)
+	if err != nil {
+		return ConnectionParams{}, fmt.Errorf("bad endpoint: %w", err)
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	hostIP := net.ParseIP(host)
+	if hostIP == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), endpointResolveTimeout)
+		defer cancel()
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return ConnectionParams{}, fmt.Errorf("failed to resolve endpoint '%s': %w", host, err)
+		}
+		if hostIP = preferredEndpointIP(ips); hostIP == nil {
+			return ConnectionParams{}, fmt.Errorf("failed to resolve endpoint '%s'", host)
+		}
+	}
+
+	keepalive := c.PersistentKeepalive
+	if keepalive == 0 {
+		keepalive = defaultKeepalive
+	}
+
+	params := CreateConnectionParams("", port, hostIP, c.PublicKey, c.DNSv4(), "", c.MTU)
+	params.SetCredentials(c.PrivateKey, c.LocalIPv4())
+	params.presharedKey = c.PresharedKey
+	params.clientLocalIPv6 = c.LocalIPv6()
+	params.allowedIPs = c.AllowedIPs
+	params.persistentKeepalive = keepalive
+	params.isCustomConfig = true
+	return params, nil
+}
+
+// preferredEndpointIP returns the first IPv4 address from the list
+// (or the first IPv6 address when the host has no IPv4 addresses)
+func preferredEndpointIP(ips []net.IP) net.IP {
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.To4()
+		}
+	}
+	if len(ips) > 0 {
+		return ips[0]
+	}
+	return nil
+}
+
+func validateKey(key string) error {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(b) != 32 {
+		return fmt.Errorf("bad key (base64-encoded 32 bytes value expected)")
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var ret []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
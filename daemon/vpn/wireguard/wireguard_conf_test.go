@@ -0,0 +1,120 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	const cfgText = `
+# office gateway
+[Interface]
+PrivateKey = cIyZ2uUjnVRqH5bZ3VUyu0ELhbVApYuP3wTfBcE3+lY=
+Address = 10.8.0.2/32, fd00::2/128
+DNS = 10.8.0.1
+ListenPort = 51820
+
+[Peer]
+PublicKey = mTrcCv2Tu8w4Mzmwy0KPBhAy9ysNNrSzdjYmBHMlsn4=
+PresharedKey = w2eTBy8Jgs0xW3lZBY5bEKfQ8HREJOrZv+ixdKWBMQs=
+AllowedIPs = 0.0.0.0/0, ::/0
+Endpoint = 198.51.100.1:51820
+`
+	cfg, err := ParseConfig(cfgText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LocalIPv4().String() != "10.8.0.2" || cfg.LocalIPv6().String() != "fd00::2" || cfg.DNSv4().String() != "10.8.0.1" {
+		t.Errorf("unexpected addresses: %v %v", cfg.Addresses, cfg.DNS)
+	}
+	if len(cfg.AllowedIPs) != 2 || cfg.Endpoint != "198.51.100.1:51820" || len(cfg.PresharedKey) == 0 {
+		t.Errorf("unexpected peer configuration: %+v", cfg)
+	}
+
+	params, err := cfg.CreateConnectionParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.hostIP.String() != "198.51.100.1" || params.hostPort != 51820 || params.persistentKeepalive != defaultKeepalive {
+		t.Errorf("unexpected connection parameters: %+v", params)
+	}
+
+	// the persistent keepalive can be disabled explicitly
+	for _, v := range []string{"off", "0"} {
+		cfg, err := ParseConfig(cfgText + "PersistentKeepalive = " + v + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		params, err := cfg.CreateConnectionParams()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.getPersistentKeepalive() != 0 {
+			t.Errorf("PersistentKeepalive = %s: keepalive must be disabled (%d)", v, params.getPersistentKeepalive())
+		}
+	}
+
+	// IPv6 endpoint
+	cfg, err = ParseConfig(strings.Replace(cfgText, "198.51.100.1:51820", "[2001:db8::1]:51820", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, err = cfg.CreateConnectionParams(); err != nil {
+		t.Fatal(err)
+	}
+	if params.hostIP.String() != "2001:db8::1" || params.hostPort != 51820 {
+		t.Errorf("unexpected connection parameters for IPv6 endpoint: %+v", params)
+	}
+
+	bad := map[string]string{
+		"script hook":   strings.Replace(cfgText, "ListenPort = 51820", "PostUp = iptables -F", 1),
+		"no DNS":        strings.Replace(cfgText, "DNS = 10.8.0.1", "", 1),
+		"two peers":     cfgText + "[Peer]\n",
+		"bad key":       strings.Replace(cfgText, "PublicKey = mTrc", "PublicKey = ", 1),
+		"unknown param": cfgText + "Foo = bar\n",
+	}
+	for name, text := range bad {
+		if _, err := ParseConfig(text); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
+
+func TestPreferredEndpointIP(t *testing.T) {
+	ipv4 := net.ParseIP("198.51.100.1")
+	ipv6 := net.ParseIP("2001:db8::1")
+
+	if ip := preferredEndpointIP([]net.IP{ipv6, ipv4}); !ip.Equal(ipv4) {
+		t.Errorf("IPv4 address expected: %v", ip)
+	}
+	// IPv6-only host
+	if ip := preferredEndpointIP([]net.IP{ipv6}); !ip.Equal(ipv6) {
+		t.Errorf("IPv6 address expected: %v", ip)
+	}
+	if ip := preferredEndpointIP(nil); ip != nil {
+		t.Errorf("nil expected: %v", ip)
+	}
+}
@@ -60,6 +60,7 @@ type internalVariables struct {
 
 	isPaused      bool
 	omResumedChan chan struct{} // channel for 'On Resume' events
+	stallState    stallState    // the reason of the connection stop by watchdog (tunnel stalled)
 }
 
 var logWgOut *logger.Logger
@@ -89,6 +90,11 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) (err error) {
 
 // connect - SYNCHRONOUSLY execute openvpn process (wait until it finished)
 func (wg *WireGuard) internalConnect(stateChan chan<- vpn.StateInfo) error {
+	if wg.connectParams.isCustomConfig {
+		// the routing configuration relies on IVPN servers configuration (the server local IP is in use as a gateway;
+		// the tunnel interface is configured with a fixed subnet mask), so the custom AllowedIPs can not be applied
+		return fmt.Errorf("custom WireGuard configurations are not supported on macOS")
+	}
 
 	var routineStopWaiter sync.WaitGroup
 
@@ -188,6 +194,7 @@ func (wg *WireGuard) internalConnect(stateChan chan<- vpn.StateInfo) error {
 	}
 
 	var initError error = nil
+	processStoppedChan := make(chan struct{})
 
 	// waiting to start and initialize
 	routineStopWaiter.Add(1)
@@ -206,6 +213,21 @@ func (wg *WireGuard) internalConnect(stateChan chan<- vpn.StateInfo) error {
 				log.Info("Started")
 				// CONNECTED
 				wg.notifyConnectedStat(stateChan)
+
+				// tunnel health monitor (works until the process stopped)
+				wd := wg.startWatchdog(stateChan,
+					func() (peerStats, error) { return wg.getPeerStatsByTool(utunName) },
+					func(reason error) {
+						wg.internals.stallState.set(reason)
+						if err := wg.internalDisconnect(); err != nil {
+							log.Error(err)
+						}
+					})
+				// post-quantum preshared key rotation (if enabled)
+				stopPskRotation := wg.startPresharedKeyRotation(func(psk string) error { return wg.setPresharedKeyByTool(utunName, psk) })
+				<-processStoppedChan
+				stopPskRotation()
+				wd.Stop()
 			}
 
 		case <-time.After(time.Second * 5):
@@ -226,7 +248,15 @@ func (wg *WireGuard) internalConnect(stateChan chan<- vpn.StateInfo) error {
 		wg.disconnect()
 	}
 
-	if err := wg.internals.command.Wait(); err != nil {
+	err = wg.internals.command.Wait()
+	close(processStoppedChan)
+
+	// the tunnel was stalled: request the service to reconnect
+	if stallReason := wg.internals.stallState.pop(); stallReason != nil && !wg.internals.isGoingToStop {
+		return newStalledReconnectionError(stallReason)
+	}
+
+	if err != nil {
 		// error will be received anyway. We are logging it only if process was stopped unexpectedly
 		if !wg.internals.isGoingToStop {
 			log.Error(err.Error())
@@ -546,3 +576,7 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 
 	return interfaceCfg, peerCfg
 }
+
+func isNativeConfigSupported() bool {
+	return false
+}
@@ -23,13 +23,24 @@
 package wireguard
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+
+	"golang.org/x/crypto/curve25519"
 )
 
 // GenerateKeys generates new WireGuard keys pair
+// If WireGuard tools binary is not available - the keys are generated natively
 func GenerateKeys(wgToolBinaryPath string) (publicKey string, privateKey string, err error) {
+	if _, err := os.Stat(wgToolBinaryPath); err != nil {
+		return generateKeysNative()
+	}
+
 	// private key
 	privCmd := exec.Command(wgToolBinaryPath, "genkey")
 	out, err1 := privCmd.Output()
@@ -57,3 +68,22 @@ func GenerateKeys(wgToolBinaryPath string) (publicKey string, privateKey string,
 
 	return strings.TrimSpace(publicKey), strings.TrimSpace(privateKey), nil
 }
+
+// generateKeysNative generates new WireGuard keys pair without using external binaries
+// (analogue of 'wg genkey' and 'wg pubkey')
+func generateKeysNative() (publicKey string, privateKey string, err error) {
+	var priv [curve25519.ScalarSize]byte
+	if _, err := rand.Read(priv[:]); err != nil {
+		return "", "", fmt.Errorf("failed to generate private key: %w", err)
+	}
+	// clamping (https://cr.yp.to/ecdh.html)
+	priv[0] &= 248
+	priv[31] = (priv[31] & 127) | 64
+
+	pub, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate public key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv[:]), nil
+}
@@ -23,18 +23,20 @@
 package wireguard
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/ivpn/desktop-app/daemon/oshelpers/linux/netlink"
 	"github.com/ivpn/desktop-app/daemon/service/dns"
 	"github.com/ivpn/desktop-app/daemon/shell"
 	"github.com/ivpn/desktop-app/daemon/vpn"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard/userspace"
 )
 
 type operation int
@@ -49,7 +51,11 @@ type internalVariables struct {
 	manualDNS            dns.DnsSettings
 	isRunning            bool
 	isPaused             bool
-	resumeDisconnectChan chan operation // control connection pause\resume or disconnect from paused state
+	resumeDisconnectChan chan operation    // control connection pause\resume or disconnect from paused state
+	isNativeConfig       bool              // true - WG interface configured natively (netlink); false - by 'wg-quick'
+	userspaceDevice      *userspace.Device // embedded userspace WireGuard implementation (nil - not in use)
+	hopUserspaceDevice   *userspace.Device // userspace WireGuard device of the chained hop (nil - not in use)
+	stallState           stallState        // the reason of the connection stop by watchdog (tunnel stalled)
 }
 
 func (wg *WireGuard) init() error {
@@ -57,19 +63,19 @@ func (wg *WireGuard) init() error {
 	// (e.g. process was terminated)
 	// In such situation, the 'wgivpn' keeps active.
 	// We should close it in this case. Otherwise, new connection would not be established
-	wgInterfaceName := filepath.Base(wg.configFilePath)
-	wgInterfaceName = strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
 	// stop current WG connection (if exists)
-	i, _ := net.InterfaceByName(wgInterfaceName)
-	if i != nil {
-		log.Info(fmt.Sprintf("Stopping WireGuard interface ('%s' expected to be stopped before the new connection)...", wgInterfaceName))
-		err := shell.Exec(log, "ip", "link", "set", "down", wgInterfaceName) // command: sudo ip link set down wgivpn
-		if err != nil {
-			log.Warning(err)
-		}
-		err = shell.Exec(log, "ip", "link", "delete", wgInterfaceName) // command: sudo ip link delete wgivpn
-		if err != nil {
-			log.Warning(err)
+	for _, wgInterfaceName := range []string{wg.hopInterfaceName(), wg.interfaceName()} {
+		i, _ := net.InterfaceByName(wgInterfaceName)
+		if i != nil {
+			log.Info(fmt.Sprintf("Stopping WireGuard interface ('%s' expected to be stopped before the new connection)...", wgInterfaceName))
+			err := shell.Exec(log, "ip", "link", "set", "down", wgInterfaceName) // command: sudo ip link set down wgivpn
+			if err != nil {
+				log.Warning(err)
+			}
+			err = shell.Exec(log, "ip", "link", "delete", wgInterfaceName) // command: sudo ip link delete wgivpn
+			if err != nil {
+				log.Warning(err)
+			}
 		}
 	}
 
@@ -83,8 +89,11 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 	defer func() {
 		wg.internals.isRunning = false
 		// do not forget to remove config file after finishing configuration
-		if err := os.Remove(wg.configFilePath); err != nil {
-			log.Warning(fmt.Sprintf("failed to remove WG configuration: %s", err))
+		// (the file is not created when WG interface configured natively)
+		if _, err := os.Stat(wg.configFilePath); err == nil {
+			if err := os.Remove(wg.configFilePath); err != nil {
+				log.Warning(fmt.Sprintf("failed to remove WG configuration: %s", err))
+			}
 		}
 	}()
 
@@ -94,21 +103,10 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 	// on 'pause' - we stopping WG interface but not exiting this (connect) method
 	// (method 'connect' is synchronous, must NOT exit on pause)
 	for {
-		// generate configuration
-		err := wg.generateAndSaveConfigFile(wg.configFilePath)
-		if err != nil {
-			return fmt.Errorf("failed to save WG config file: %w", err)
-		}
-
 		// start WG
-		log.Info("Shell exec: ", wg.binaryPath, " up ", wg.configFilePath)
-		cmd := exec.Command(wg.binaryPath, "up", wg.configFilePath)
-		outBytes, err := cmd.CombinedOutput()
+		err := wg.up()
 		if err != nil {
-			if len(outBytes) > 0 {
-				log.Error(fmt.Sprintf("'%s' error. Output: %s", wg.binaryPath, string(outBytes)))
-			}
-			return fmt.Errorf("failed to start WireGuard: %w", err)
+			return err
 		}
 
 		err = func() error {
@@ -135,8 +133,20 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 			// notify connected
 			wg.notifyConnectedStat(stateChan)
 
-			wgInterfaceName := filepath.Base(wg.configFilePath)
-			wgInterfaceName = strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
+			// start tunnel health monitor
+			wd := wg.startWatchdog(stateChan, wg.getPeerStats, func(reason error) {
+				wg.internals.stallState.set(reason)
+				if err := wg.internalDisconnect(); err != nil {
+					log.Error(err)
+				}
+			})
+			defer wd.Stop()
+
+			// post-quantum preshared key rotation (if enabled)
+			stopPskRotation := wg.startPresharedKeyRotation(wg.setPresharedKey)
+			defer stopPskRotation()
+
+			wgInterfaceName := wg.interfaceName()
 			// wait until wireguard interface is available
 			for {
 				time.Sleep(time.Millisecond * 500)
@@ -156,6 +166,11 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 			return err
 		}
 
+		// the tunnel was stalled: request the service to reconnect
+		if stallReason := wg.internals.stallState.pop(); stallReason != nil {
+			return newStalledReconnectionError(stallReason)
+		}
+
 		// if connection not PAUSED - exit
 		if wg.isPaused() {
 			log.Info("Paused")
@@ -186,7 +201,73 @@ func (wg *WireGuard) disconnect() error {
 	return wg.internalDisconnect()
 }
 
+// up starts WireGuard interface
+// The interface is configured natively (netlink) if possible; otherwise - using 'wg-quick' (fallback).
+// When the WireGuard kernel module is not available (or userspace implementation forced by preferences)
+// the embedded userspace WireGuard implementation is in use.
+func (wg *WireGuard) up() error {
+	var errNative error
+	if wg.connectParams.isUserspaceForced {
+		log.Info("Userspace WireGuard implementation forced by preferences")
+	} else if !isKernelModuleAvailable() {
+		errNative = fmt.Errorf("WireGuard kernel module not available")
+		log.Info(errNative)
+	} else {
+		if errNative = wg.nativeUp(false); errNative == nil {
+			wg.internals.isNativeConfig = true
+			return nil
+		}
+		log.Warning(fmt.Sprintf("Unable to configure WireGuard natively: %s", errNative))
+	}
+
+	if !userspace.IsSupported() {
+		if errNative == nil {
+			errNative = fmt.Errorf("userspace WireGuard implementation not supported (TUN not available)")
+		}
+	} else if errUserspace := wg.nativeUp(true); errUserspace != nil {
+		log.Warning(fmt.Sprintf("Unable to start userspace WireGuard implementation: %s", errUserspace))
+		if errNative == nil {
+			errNative = errUserspace
+		}
+	} else {
+		wg.internals.isNativeConfig = true
+		return nil
+	}
+
+	if wg.connectParams.nextHop != nil {
+		// the chained hop requires two interfaces and custom routing which 'wg-quick' is not able to configure
+		return fmt.Errorf("failed to start WireGuard with chained hop (native configuration required): %w", errNative)
+	}
+
+	log.Info(fmt.Sprintf("Using '%s'...", filepath.Base(wg.binaryPath)))
+	wg.internals.isNativeConfig = false
+
+	// generate configuration
+	err := wg.generateAndSaveConfigFile(wg.configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to save WG config file: %w", err)
+	}
+
+	log.Info("Shell exec: ", wg.binaryPath, " up ", wg.configFilePath)
+	cmd := exec.Command(wg.binaryPath, "up", wg.configFilePath)
+	outBytes, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(outBytes) > 0 {
+			log.Error(fmt.Sprintf("'%s' error. Output: %s", wg.binaryPath, string(outBytes)))
+		}
+		return fmt.Errorf("failed to start WireGuard: %w (native configuration error: %s)", err, errNative)
+	}
+	return nil
+}
+
 func (wg *WireGuard) internalDisconnect() error {
+	if wg.internals.isNativeConfig {
+		if err := wg.nativeDown(); err != nil {
+			return fmt.Errorf("failed to stop WireGuard: %w", err)
+		}
+		return nil
+	}
+
 	err := shell.Exec(log, wg.binaryPath, "down", wg.configFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to stop WireGuard: %w", err)
@@ -194,6 +275,55 @@ func (wg *WireGuard) internalDisconnect() error {
 	return nil
 }
 
+// getPeerStats returns statistics of the WireGuard peer (in use by the watchdog)
+func (wg *WireGuard) getPeerStats() (peerStats, error) {
+	if dev := wg.internals.userspaceDevice; dev != nil {
+		s := dev.Stats()
+		return peerStats{lastHandshake: s.LastHandshake, rxBytes: s.RxBytes, txBytes: s.TxBytes}, nil
+	}
+
+	if !wg.internals.isNativeConfig {
+		return wg.getPeerStatsByTool(wg.interfaceName())
+	}
+
+	info, err := netlink.WgGetDevice(wg.interfaceName())
+	if err != nil {
+		return peerStats{}, err
+	}
+	for _, p := range info.Peers {
+		if base64.StdEncoding.EncodeToString(p.PublicKey) == wg.connectParams.hostPublicKey {
+			return peerStats{lastHandshake: p.LastHandshake, rxBytes: p.RxBytes, txBytes: p.TxBytes}, nil
+		}
+	}
+	return peerStats{}, fmt.Errorf("peer not found")
+}
+
+// setPresharedKey updates the preshared key (base64) of the peer on the active interface
+func (wg *WireGuard) setPresharedKey(psk string) error {
+	pskBytes, err := base64.StdEncoding.DecodeString(psk)
+	if err != nil {
+		return fmt.Errorf("WG preshared key is not base64 string")
+	}
+
+	if dev := wg.internals.userspaceDevice; dev != nil {
+		key, err := userspace.KeyFromBytes(pskBytes)
+		if err != nil {
+			return fmt.Errorf("bad WG preshared key: %w", err)
+		}
+		return dev.SetPresharedKey(key)
+	}
+
+	if !wg.internals.isNativeConfig {
+		return wg.setPresharedKeyByTool(wg.interfaceName(), psk)
+	}
+
+	hostPublicKey, err := base64.StdEncoding.DecodeString(wg.connectParams.hostPublicKey)
+	if err != nil {
+		return fmt.Errorf("WG public key is not base64 string")
+	}
+	return netlink.WgSetPeerPresharedKey(wg.interfaceName(), hostPublicKey, pskBytes)
+}
+
 func (wg *WireGuard) isPaused() bool {
 	return wg.internals.isPaused
 }
@@ -245,10 +375,8 @@ func (wg *WireGuard) resetManualDNS() error {
 func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg []string) {
 	ipv6LocalIP := wg.connectParams.GetIPv6ClientLocalIP()
 	ipv6LocalIPStr := ""
-	allowedIPsV6 := ""
 	if ipv6LocalIP != nil {
 		ipv6LocalIPStr = ", " + ipv6LocalIP.String()
-		allowedIPsV6 = ", ::/0"
 	}
 
 	if wg.connectParams.mtu > 0 {
@@ -257,7 +385,11 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	interfaceCfg = append(interfaceCfg, "Address = "+wg.connectParams.clientLocalIP.String()+"/32"+ipv6LocalIPStr)
 	interfaceCfg = append(interfaceCfg, "SaveConfig = true")
 
-	peerCfg = append(peerCfg, "AllowedIPs = 0.0.0.0/0"+allowedIPsV6)
+	var allowedIPs []string
+	for _, n := range wg.connectParams.getAllowedIPs() {
+		allowedIPs = append(allowedIPs, n.String())
+	}
+	peerCfg = append(peerCfg, "AllowedIPs = "+strings.Join(allowedIPs, ", "))
 	return interfaceCfg, peerCfg
 }
 
@@ -0,0 +1,390 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+//go:build linux
+// +build linux
+
+package wireguard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/netinfo"
+	"github.com/ivpn/desktop-app/daemon/oshelpers/linux/netlink"
+	"github.com/ivpn/desktop-app/daemon/vpn/wireguard/userspace"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Firewall mark of the WireGuard packets and the routing table number (the same values as used by 'wg-quick')
+	// IMPORTANT! The Split-Tunnel functionality (splittun.sh) relies on this mark value.
+	wgFwMark       = 0xca6c
+	wgRoutingTable = 51820
+	// Default MTU value ('wg-quick' uses: <MTU of default interface> - 80)
+	wgDefaultMtu = 1420
+	// Max number of attempts to remove duplicated routing rules
+	wgMaxRulesToRemove = 10
+	// MTU reduction of the chained hop interface (WireGuard encapsulation overhead: IPv6 header + UDP + WireGuard)
+	wgHopOverhead = 80
+)
+
+// hopInterfaceName returns name of the interface of the custom WireGuard hop chained after the server (e.g. 'wgivpnhop')
+func (wg *WireGuard) hopInterfaceName() string {
+	return wg.interfaceName() + "hop"
+}
+
+// interfaceName returns name of WireGuard interface (e.g. 'wgivpn')
+// The name is the same as configuration file name (without extension) since 'wg-quick' uses the file name as interface name
+func (wg *WireGuard) interfaceName() string {
+	wgInterfaceName := filepath.Base(wg.configFilePath)
+	return strings.TrimSuffix(wgInterfaceName, path.Ext(wgInterfaceName))
+}
+
+// isNativeConfigSupported returns 'true' when the WireGuard interface can be configured natively, without 'wg-quick'/'wg' tools
+// (the WireGuard kernel module is available or the embedded userspace implementation can be used)
+func isNativeConfigSupported() bool {
+	return isKernelModuleAvailable() || userspace.IsSupported()
+}
+
+// isKernelModuleAvailable returns 'true' when WireGuard kernel module is available
+func isKernelModuleAvailable() bool {
+	if _, err := os.Stat("/sys/module/wireguard"); err == nil {
+		return true // module loaded (or built into kernel)
+	}
+
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return false
+	}
+	release := unix.ByteSliceToString(uname.Release[:])
+	modulesDir := filepath.Join("/lib/modules", release)
+
+	if _, err := os.Stat(filepath.Join(modulesDir, "kernel/drivers/net/wireguard")); err == nil {
+		return true // module will be loaded automatically on interface creation
+	}
+	if builtin, err := os.ReadFile(filepath.Join(modulesDir, "modules.builtin")); err == nil {
+		return strings.Contains(string(builtin), "/wireguard.ko")
+	}
+	return false
+}
+
+// nativeUp configures WireGuard interface via netlink
+// (do the same as 'wg-quick up' for the configuration generated by generateConfig())
+// When 'isUserspace' is true - the embedded userspace WireGuard implementation is in use (TUN interface);
+// otherwise - the WireGuard kernel module.
+// Note: no configuration file with private key saved to disk
+func (wg *WireGuard) nativeUp(isUserspace bool) (retErr error) {
+	ifName := wg.interfaceName()
+
+	localPort, err := netinfo.GetFreeUDPPort()
+	if err != nil {
+		return fmt.Errorf("unable to obtain free local port: %w", err)
+	}
+	wg.localPort = localPort
+
+	cfg, err := newNativeInterfaceConfig(&wg.connectParams, wg.getPresharedKey(), wg.localPort, wgFwMark)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if retErr != nil {
+			if err := wg.nativeDown(); err != nil {
+				log.Warning(err)
+			}
+		}
+	}()
+
+	mtu := wg.connectParams.mtu
+	if mtu <= 0 {
+		mtu = wgDefaultMtu
+	}
+
+	dev, inf, err := nativeInterfaceUp(ifName, cfg, &wg.connectParams, mtu, isUserspace)
+	wg.internals.userspaceDevice = dev
+	if err != nil {
+		return err
+	}
+
+	// Routing (the same as 'wg-quick' does for 'AllowedIPs = 0.0.0.0/0, ::/0'):
+	//	ip route add 0.0.0.0/0 dev wgivpn table 51820
+	//	ip rule add not fwmark 51820 table 51820
+	//	ip rule add table main suppress_prefixlength 0
+	// (for custom configurations - a route for each allowed network; the rules are added once per address family)
+	type route struct {
+		inf *net.Interface
+		net net.IPNet
+	}
+	var routes []route
+
+	hop := wg.connectParams.nextHop
+	if hop == nil {
+		for _, n := range cfg.allowedIPs {
+			routes = append(routes, route{inf, n})
+		}
+	} else {
+		// Chained hop: only the hop endpoint (and the server DNS) are routed directly into the server tunnel;
+		// the rest of the traffic goes through the hop interface (its encrypted packets - through the server tunnel)
+		hopPort, err := netinfo.GetFreeUDPPort()
+		if err != nil {
+			return fmt.Errorf("unable to obtain free local port: %w", err)
+		}
+		hopCfg, err := newNativeInterfaceConfig(hop, hop.presharedKey, hopPort, 0)
+		if err != nil {
+			return fmt.Errorf("chained hop: %w", err)
+		}
+		hopMtu := mtu - wgHopOverhead
+		if hop.mtu > 0 && hop.mtu < hopMtu {
+			hopMtu = hop.mtu
+		}
+
+		hopDev, hopInf, err := nativeInterfaceUp(wg.hopInterfaceName(), hopCfg, hop, hopMtu, isUserspace)
+		wg.internals.hopUserspaceDevice = hopDev
+		if err != nil {
+			return fmt.Errorf("chained hop: %w", err)
+		}
+
+		routes = append(routes, route{inf, hostNetwork(hop.hostIP)})
+		if wg.connectParams.hostLocalIP != nil {
+			routes = append(routes, route{inf, hostNetwork(wg.connectParams.hostLocalIP)})
+		}
+		for _, n := range hopCfg.allowedIPs {
+			routes = append(routes, route{hopInf, n})
+		}
+
+		log.Info(fmt.Sprintf("Chained WireGuard hop '%s' configured (endpoint: %s:%d; local port: %d; MTU: %d)",
+			wg.hopInterfaceName(), hop.hostIP, hop.hostPort, hopPort, hopMtu))
+	}
+
+	isFamilyRouted := map[bool]bool{}
+	for _, r := range routes {
+		isIPv6 := r.net.IP.To4() == nil
+		if err := netlink.RouteAdd(r.inf.Index, r.net, wgRoutingTable); err != nil {
+			return err
+		}
+		isFamilyRouted[isIPv6] = true
+	}
+	for isIPv6 := range isFamilyRouted {
+		if err := netlink.RuleAdd(netlink.Rule{IsIPv6: isIPv6, Table: wgRoutingTable, FwMark: wgFwMark, Invert: true, SuppressPrefixlen: -1}); err != nil {
+			return err
+		}
+		if err := netlink.RuleAdd(netlink.Rule{IsIPv6: isIPv6, Table: unix.RT_TABLE_MAIN, SuppressPrefixlen: 0}); err != nil {
+			return err
+		}
+	}
+
+	// the reverse path filtering must take into account the packets mark
+	if err := os.WriteFile("/proc/sys/net/ipv4/conf/all/src_valid_mark", []byte("1"), 0644); err != nil {
+		log.Warning(fmt.Sprintf("failed to set 'src_valid_mark': %s", err))
+	}
+
+	log.Info(fmt.Sprintf("WireGuard interface '%s' configured natively (endpoint: %s:%d; local port: %d; MTU: %d; IPv6: %v; userspace: %v)",
+		ifName, wg.connectParams.hostIP, wg.connectParams.hostPort, wg.localPort, mtu, wg.connectParams.GetIPv6ClientLocalIP() != nil, isUserspace))
+
+	return nil
+}
+
+// nativeDown removes WireGuard interface and routing rules created by nativeUp()
+func (wg *WireGuard) nativeDown() error {
+	// TUN interfaces are removed together with the devices
+	for _, dev := range []**userspace.Device{&wg.internals.hopUserspaceDevice, &wg.internals.userspaceDevice} {
+		if *dev != nil {
+			if err := (*dev).Close(); err != nil {
+				log.Warning(fmt.Sprintf("failed to stop userspace WireGuard device: %s", err))
+			}
+			*dev = nil
+		}
+	}
+
+	for _, isIPv6 := range []bool{false, true} {
+		for i := 0; i < wgMaxRulesToRemove; i++ {
+			if netlink.RuleDel(netlink.Rule{IsIPv6: isIPv6, Table: wgRoutingTable, FwMark: wgFwMark, Invert: true, SuppressPrefixlen: -1}) != nil {
+				break
+			}
+		}
+		for i := 0; i < wgMaxRulesToRemove; i++ {
+			if netlink.RuleDel(netlink.Rule{IsIPv6: isIPv6, Table: unix.RT_TABLE_MAIN, SuppressPrefixlen: 0}) != nil {
+				break
+			}
+		}
+	}
+
+	// routes in the WG routing table are removed automatically together with the interface
+	if _, err := net.InterfaceByName(wg.hopInterfaceName()); err == nil {
+		if err := netlink.LinkDel(wg.hopInterfaceName()); err != nil {
+			log.Warning(fmt.Sprintf("failed to remove chained hop interface: %s", err))
+		}
+	}
+	if _, err := net.InterfaceByName(wg.interfaceName()); err != nil {
+		return nil // interface already removed
+	}
+	return netlink.LinkDel(wg.interfaceName())
+}
+
+// nativeInterfaceConfig - decoded WireGuard interface configuration
+type nativeInterfaceConfig struct {
+	privateKey    []byte
+	peerPublicKey []byte
+	presharedKey  []byte
+	endpoint      *net.UDPAddr
+	listenPort    int
+	fwMark        int // 0 - not in use
+	keepalive     time.Duration
+	allowedIPs    []net.IPNet
+}
+
+// newNativeInterfaceConfig - 'psk' is the actual preshared key of the peer (base64; empty - not in use)
+func newNativeInterfaceConfig(params *ConnectionParams, psk string, listenPort int, fwMark int) (nativeInterfaceConfig, error) {
+	privateKey, err := base64.StdEncoding.DecodeString(params.clientPrivateKey)
+	if err != nil {
+		return nativeInterfaceConfig{}, fmt.Errorf("WG private key is not base64 string")
+	}
+	hostPublicKey, err := base64.StdEncoding.DecodeString(params.hostPublicKey)
+	if err != nil {
+		return nativeInterfaceConfig{}, fmt.Errorf("WG public key is not base64 string")
+	}
+	var presharedKey []byte
+	if len(psk) > 0 {
+		if presharedKey, err = base64.StdEncoding.DecodeString(psk); err != nil {
+			return nativeInterfaceConfig{}, fmt.Errorf("WG preshared key is not base64 string")
+		}
+	}
+
+	return nativeInterfaceConfig{
+		privateKey:    privateKey,
+		peerPublicKey: hostPublicKey,
+		presharedKey:  presharedKey,
+		endpoint:      &net.UDPAddr{IP: params.hostIP, Port: params.hostPort},
+		listenPort:    listenPort,
+		fwMark:        fwMark,
+		keepalive:     time.Second * time.Duration(params.getPersistentKeepalive()),
+		allowedIPs:    params.getAllowedIPs(),
+	}, nil
+}
+
+// nativeInterfaceUp creates WireGuard interface (kernel or userspace), sets its addresses and brings it up
+// Returns the userspace device (if in use); it must be closed by caller even if an error returned
+func nativeInterfaceUp(ifName string, cfg nativeInterfaceConfig, params *ConnectionParams, mtu int, isUserspace bool) (*userspace.Device, *net.Interface, error) {
+	var dev *userspace.Device
+	if isUserspace {
+		var err error
+		if dev, err = userspaceDeviceStart(ifName, cfg, mtu); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if err := netlink.LinkAdd(ifName, "wireguard"); err != nil {
+			return nil, nil, err
+		}
+		err := netlink.WgSetDevice(ifName, netlink.WgDeviceConfig{
+			PrivateKey: cfg.privateKey,
+			ListenPort: cfg.listenPort,
+			FwMark:     uint32(cfg.fwMark),
+			Peers: []netlink.WgPeerConfig{{
+				PublicKey:           cfg.peerPublicKey,
+				PresharedKey:        cfg.presharedKey,
+				Endpoint:            cfg.endpoint,
+				PersistentKeepalive: cfg.keepalive,
+				AllowedIPs:          cfg.allowedIPs,
+			}},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	inf, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return dev, nil, fmt.Errorf("failed to get WireGuard interface: %w", err)
+	}
+
+	if err := netlink.AddrAdd(inf.Index, net.IPNet{IP: params.clientLocalIP, Mask: net.CIDRMask(32, 32)}); err != nil {
+		return dev, nil, err
+	}
+	if ipv6LocalIP := params.GetIPv6ClientLocalIP(); ipv6LocalIP != nil {
+		if err := netlink.AddrAdd(inf.Index, net.IPNet{IP: ipv6LocalIP, Mask: net.CIDRMask(128, 128)}); err != nil {
+			return dev, nil, err
+		}
+	}
+
+	if err := netlink.LinkSetUp(inf.Index, mtu); err != nil {
+		return dev, nil, err
+	}
+	return dev, inf, nil
+}
+
+// userspaceDeviceStart creates TUN interface and starts the embedded userspace WireGuard implementation on it
+func userspaceDeviceStart(ifName string, cfg nativeInterfaceConfig, mtu int) (*userspace.Device, error) {
+	privKey, err := userspace.KeyFromBytes(cfg.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("bad WG private key: %w", err)
+	}
+	hostKey, err := userspace.KeyFromBytes(cfg.peerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("bad WG public key: %w", err)
+	}
+	var psk userspace.Key
+	if len(cfg.presharedKey) > 0 {
+		if psk, err = userspace.KeyFromBytes(cfg.presharedKey); err != nil {
+			return nil, fmt.Errorf("bad WG preshared key: %w", err)
+		}
+	}
+
+	tun, err := userspace.CreateTUN(ifName, mtu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TUN interface '%s': %w", ifName, err)
+	}
+	dev, err := userspace.NewDevice(tun, userspace.Config{
+		PrivateKey: privKey,
+		ListenPort: cfg.listenPort,
+		FwMark:     cfg.fwMark,
+		Peer: userspace.PeerConfig{
+			PublicKey:           hostKey,
+			PresharedKey:        psk,
+			Endpoint:            cfg.endpoint,
+			PersistentKeepalive: cfg.keepalive,
+			AllowedIPs:          cfg.allowedIPs,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dev.Start(); err != nil {
+		dev.Close()
+		return nil, err
+	}
+	return dev, nil
+}
+
+// hostNetwork returns the network which contains only the given host
+func hostNetwork(ip net.IP) net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
@@ -0,0 +1,113 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/shell"
+)
+
+// startPresharedKeyRotation periodically negotiates new preshared key and applies it to the active interface
+// (see ConnectionParams.SetPresharedKeyRotation())
+// 'setPsk' - platform-specific function to update the preshared key of the peer (base64)
+// Returns the function which stops the rotation (and waits until the routine stopped).
+// If the new key can not be applied - the handshakes fail, the watchdog detects stalled tunnel
+// and the reconnection (with new negotiated key) is triggered.
+func (wg *WireGuard) startPresharedKeyRotation(setPsk func(psk string) error) (stop func()) {
+	interval := wg.connectParams.pskRotationInterval
+	negotiate := wg.connectParams.pskNegotiate
+	if interval <= 0 || negotiate == nil {
+		return func() {}
+	}
+
+	stopChan := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		log.Info(fmt.Sprintf("Preshared key rotation started (interval: %v)", interval))
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C:
+			}
+
+			psk, err := negotiate()
+			if err != nil {
+				log.Warning(fmt.Sprintf("Unable to negotiate new preshared key: %s", err))
+				continue
+			}
+			// the server already uses the new key: it has to be in use after pause/resume or interface re-configuration
+			wg.setPresharedKeyValue(psk)
+			if err := setPsk(psk); err != nil {
+				log.Error(fmt.Sprintf("Unable to apply new preshared key: %s", err))
+				continue
+			}
+			log.Info("Preshared key rotated")
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() { close(stopChan) })
+		<-stopped
+	}
+}
+
+// getPresharedKey returns the actual preshared key of the peer (base64; empty - not in use)
+func (wg *WireGuard) getPresharedKey() string {
+	wg.pskMutex.Lock()
+	defer wg.pskMutex.Unlock()
+	return wg.connectParams.presharedKey
+}
+
+// setPresharedKeyValue saves new preshared key of the peer (the interface configuration is not changed)
+func (wg *WireGuard) setPresharedKeyValue(psk string) {
+	wg.pskMutex.Lock()
+	defer wg.pskMutex.Unlock()
+	wg.connectParams.presharedKey = psk
+}
+
+// setPresharedKeyByTool updates the preshared key of the peer using WireGuard tool
+// (command: 'wg set <interface> peer <public-key> preshared-key <file>')
+func (wg *WireGuard) setPresharedKeyByTool(interfaceName string, psk string) error {
+	pskFile := wg.configFilePath + ".psk"
+	if err := os.WriteFile(pskFile, []byte(psk), 0600); err != nil {
+		return fmt.Errorf("failed to save preshared key into a file: %w", err)
+	}
+	defer os.Remove(pskFile)
+
+	_, outErrText, _, err := shell.ExecAndGetOutput(nil, 1024, "", wg.toolBinaryPath, "set", interfaceName, "peer", wg.connectParams.hostPublicKey, "preshared-key", pskFile)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(outErrText))
+	}
+	return nil
+}
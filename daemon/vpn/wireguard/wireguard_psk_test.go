@@ -0,0 +1,75 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPresharedKeyRotation(t *testing.T) {
+	var negotiated int32
+	wg := &WireGuard{}
+	wg.connectParams.SetPresharedKey("initial")
+	wg.connectParams.SetPresharedKeyRotation(time.Millisecond, func() (string, error) {
+		return fmt.Sprintf("psk%d", atomic.AddInt32(&negotiated, 1)), nil
+	})
+
+	applied := make(chan string, 100)
+	stop := wg.startPresharedKeyRotation(func(psk string) error {
+		select {
+		case applied <- psk:
+		default:
+		}
+		return nil
+	})
+
+	// the key is read concurrently with the rotation (e.g. interface re-configuration after resume)
+	for i := 0; i < 100 && atomic.LoadInt32(&negotiated) < 3; i++ {
+		_ = wg.getPresharedKey()
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	stop() // can be called more than once
+
+	n := atomic.LoadInt32(&negotiated)
+	if n == 0 {
+		t.Fatal("the key was not rotated")
+	}
+	time.Sleep(time.Millisecond * 10)
+	if atomic.LoadInt32(&negotiated) != n {
+		t.Error("the rotation continues after stop")
+	}
+	if psk := wg.getPresharedKey(); psk != fmt.Sprintf("psk%d", n) {
+		t.Errorf("the last negotiated key expected to be in use: '%s'", psk)
+	}
+	if psk := <-applied; psk != "psk1" {
+		t.Errorf("unexpected applied key: '%s'", psk)
+	}
+
+	// rotation is not configured
+	wgNoRotation := &WireGuard{}
+	wgNoRotation.startPresharedKeyRotation(func(psk string) error { return nil })()
+}
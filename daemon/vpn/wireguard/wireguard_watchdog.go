@@ -0,0 +1,253 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/shell"
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+// The watchdog periodically checks the WireGuard peer statistics (latest handshake time and rx/tx counters).
+// When the tunnel is stalled - the DEGRADED state is reported (and RECOVERED state when it is working again).
+// If the tunnel stays stalled longer than the stall timeout - the reconnection is triggered
+// (the connection is stopped with TunnelStalledError; the service can decide to fail over to another server).
+const (
+	// DefaultStallTimeout - default time the tunnel may stay stalled (DEGRADED state) before reconnection
+	DefaultStallTimeout = time.Second * 60
+
+	watchdogInterval = time.Second * 5
+	// max time to wait for the first handshake after connection
+	watchdogFirstHandshakeTimeout = time.Second * 20
+	// the session keys are not valid after this time since latest handshake (REJECT-AFTER-TIME)
+	watchdogHandshakeMaxAge = time.Second * 180
+	// the tunnel is stalled when there is outgoing traffic (more than 'watchdogRxStallMinTxBytes')
+	// but nothing received during 'watchdogRxStallTimeout'
+	watchdogRxStallTimeout    = time.Second * 60
+	watchdogRxStallMinTxBytes = 4096
+)
+
+// peerStats - statistics of the WireGuard peer (VPN server)
+type peerStats struct {
+	lastHandshake time.Time
+	rxBytes       uint64
+	txBytes       uint64
+}
+
+// TunnelStalledError - the connection was stopped by the watchdog because the tunnel stayed stalled longer than the stall timeout
+type TunnelStalledError struct {
+	Reason error
+}
+
+func (e *TunnelStalledError) Error() string {
+	return fmt.Sprintf("WireGuard tunnel stalled: %s", e.Reason)
+}
+
+func (e *TunnelStalledError) Unwrap() error { return e.Reason }
+
+// newStalledReconnectionError returns the error which requests the service to reconnect because the tunnel stalled
+func newStalledReconnectionError(reason error) error {
+	return &vpn.ReconnectionRequiredError{Err: &TunnelStalledError{Reason: reason}}
+}
+
+// stallState keeps the reason why the connection was stopped by the watchdog
+// (it is set from the watchdog routine and read from the connection routine)
+type stallState struct {
+	mutex  sync.Mutex
+	reason error
+}
+
+func (s *stallState) set(reason error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reason = reason
+}
+
+// pop returns the reason (nil - the connection was not stopped by the watchdog) and resets it
+func (s *stallState) pop() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := s.reason
+	s.reason = nil
+	return ret
+}
+
+// watchdog - WireGuard tunnel health monitor
+type watchdog struct {
+	stopChan chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// Stop stops the watchdog (and waits until it stopped)
+func (w *watchdog) Stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() { close(w.stopChan) })
+	w.stopped.Wait()
+}
+
+// startWatchdog starts the WireGuard tunnel health monitor
+// 'getStats' - function to obtain current peer statistics (platform-specific)
+// 'onStall' - called (once) when the tunnel stays stalled longer than the stall timeout; the watchdog stops after that
+func (wg *WireGuard) startWatchdog(stateChan chan<- vpn.StateInfo, getStats func() (peerStats, error), onStall func(reason error)) *watchdog {
+	stallTimeout := wg.connectParams.stallTimeout
+	if stallTimeout <= 0 {
+		stallTimeout = DefaultStallTimeout
+	}
+
+	isKeepaliveOff := wg.connectParams.getPersistentKeepalive() == 0
+
+	wg.stats.mutex.Lock()
+	wg.stats.getPeerStats = getStats
+	if wg.stats.connectedSince.IsZero() {
+		wg.stats.connectedSince = time.Now()
+	}
+	wg.stats.mutex.Unlock()
+
+	w := &watchdog{stopChan: make(chan struct{})}
+	w.stopped.Add(1)
+	go func() {
+		defer w.stopped.Done()
+
+		started := time.Now()
+		var lastRx, txOnLastRx uint64
+		lastRxTime := started
+		var degradedSince time.Time
+
+		ticker := time.NewTicker(watchdogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case <-ticker.C:
+			}
+
+			stats, err := getStats()
+			if err != nil {
+				log.Debug(fmt.Sprintf("Watchdog: unable to get WireGuard statistics: %s", err))
+				continue
+			}
+
+			wg.stats.trafficCounter.Update(stats.rxBytes, stats.txBytes)
+
+			now := time.Now()
+			if stats.rxBytes != lastRx {
+				lastRx = stats.rxBytes
+				txOnLastRx = stats.txBytes
+				lastRxTime = now
+			}
+
+			stallReason := checkStall(stats, now, started, lastRxTime, txOnLastRx, isKeepaliveOff)
+			wg.stats.mutex.Lock()
+			wg.stats.stallReason = stallReason
+			wg.stats.mutex.Unlock()
+
+			if stallReason == nil {
+				if !degradedSince.IsZero() {
+					degradedSince = time.Time{}
+					log.Info("Watchdog: the tunnel is recovered")
+					// Note: it is not a new connection, so the CONNECTED state is not reported again
+					stateChan <- vpn.NewStateInfo(vpn.RECOVERED, "Tunnel recovered")
+				}
+				continue
+			}
+
+			if degradedSince.IsZero() {
+				degradedSince = now
+				log.Warning(fmt.Sprintf("Watchdog: the tunnel is stalled: %s (rx: %d; tx: %d)", stallReason, stats.rxBytes, stats.txBytes))
+				stateChan <- vpn.NewStateInfo(vpn.DEGRADED, stallReason.Error())
+				continue
+			}
+
+			if now.Sub(degradedSince) >= stallTimeout {
+				log.Warning(fmt.Sprintf("Watchdog: the tunnel is stalled longer than %v: %s. Reconnecting...", stallTimeout, stallReason))
+				onStall(stallReason)
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// checkStall returns error with the description of the problem when the tunnel is stalled; otherwise - nil
+// 'isKeepaliveOff' - the persistent keepalive is disabled: the handshakes happen only when there is outgoing traffic,
+// so the handshake age of idle tunnel is not a sign of stall (only the traffic counters are checked)
+func checkStall(stats peerStats, now, started, lastRxTime time.Time, txOnLastRx uint64, isKeepaliveOff bool) error {
+	if !isKeepaliveOff {
+		if stats.lastHandshake.IsZero() {
+			if now.Sub(started) > watchdogFirstHandshakeTimeout {
+				return fmt.Errorf("no handshake with the server")
+			}
+			return nil
+		}
+		if age := now.Sub(stats.lastHandshake); age > watchdogHandshakeMaxAge {
+			return fmt.Errorf("no handshake with the server for %v", age.Truncate(time.Second))
+		}
+	}
+	if now.Sub(lastRxTime) > watchdogRxStallTimeout && stats.txBytes > txOnLastRx+watchdogRxStallMinTxBytes {
+		return fmt.Errorf("no data received for %v", now.Sub(lastRxTime).Truncate(time.Second))
+	}
+	return nil
+}
+
+// getPeerStatsByTool returns peer statistics using WireGuard tool ('wg show <interface> dump')
+func (wg *WireGuard) getPeerStatsByTool(interfaceName string) (peerStats, error) {
+	outText, outErrText, _, err := shell.ExecAndGetOutput(nil, 1024*10, "", wg.toolBinaryPath, "show", interfaceName, "dump")
+	if err != nil {
+		return peerStats{}, fmt.Errorf("%w: %s", err, strings.TrimSpace(outErrText))
+	}
+	return parseWgDump(outText, wg.connectParams.hostPublicKey)
+}
+
+// parseWgDump parses the output of 'wg show <interface> dump' and returns statistics of the peer
+// Output format: the first line contains interface info; each next line describes a peer:
+// <public-key> <preshared-key> <endpoint> <allowed-ips> <latest-handshake> <transfer-rx> <transfer-tx> <persistent-keepalive>
+func parseWgDump(dump string, peerPublicKey string) (peerStats, error) {
+	lines := strings.Split(strings.TrimSpace(dump), "\n")
+	for _, line := range lines[1:] {
+		cols := strings.Split(strings.TrimSpace(line), "\t")
+		if len(cols) < 8 || cols[0] != peerPublicKey {
+			continue
+		}
+		handshake, err1 := strconv.ParseInt(cols[4], 10, 64)
+		rx, err2 := strconv.ParseUint(cols[5], 10, 64)
+		tx, err3 := strconv.ParseUint(cols[6], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return peerStats{}, fmt.Errorf("unexpected WireGuard tool output: %s", line)
+		}
+		stats := peerStats{rxBytes: rx, txBytes: tx}
+		if handshake > 0 {
+			stats.lastHandshake = time.Unix(handshake, 0)
+		}
+		return stats, nil
+	}
+	return peerStats{}, fmt.Errorf("peer not found")
+}
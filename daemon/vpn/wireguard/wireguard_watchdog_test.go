@@ -0,0 +1,122 @@
+//
+//  Daemon for IVPN Client Desktop
+//  https://github.com/ivpn/desktop-app
+//
+//  Created by Stelnykovych Alexandr.
+//  Copyright (c) 2022 Privatus Limited.
+//
+//  This file is part of the Daemon for IVPN Client Desktop.
+//
+//  The Daemon for IVPN Client Desktop is free software: you can redistribute it and/or
+//  modify it under the terms of the GNU General Public License as published by the Free
+//  Software Foundation, either version 3 of the License, or (at your option) any later version.
+//
+//  The Daemon for IVPN Client Desktop is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY
+//  or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more
+//  details.
+//
+//  You should have received a copy of the GNU General Public License
+//  along with the Daemon for IVPN Client Desktop. If not, see <https://www.gnu.org/licenses/>.
+//
+
+package wireguard
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivpn/desktop-app/daemon/vpn"
+)
+
+func TestCheckStall(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		stats          peerStats
+		started        time.Time
+		lastRxTime     time.Time
+		txOnLastRx     uint64
+		isKeepaliveOff bool
+		isStalled      bool
+	}{
+		{"no handshake yet (just connected)", peerStats{}, now.Add(-time.Second * 5), now, 0, false, false},
+		{"no handshake", peerStats{}, started, now, 0, false, true},
+		{"fresh handshake", peerStats{lastHandshake: now.Add(-time.Second * 30), rxBytes: 100, txBytes: 100}, started, now, 100, false, false},
+		{"old handshake", peerStats{lastHandshake: now.Add(-time.Minute * 4), rxBytes: 100, txBytes: 100}, started, now, 100, false, true},
+		{"idle tunnel (keepalives only)", peerStats{lastHandshake: now.Add(-time.Minute), rxBytes: 100, txBytes: 300}, started, now.Add(-time.Second * 90), 100, false, false},
+		{"sending but nothing received", peerStats{lastHandshake: now.Add(-time.Minute), rxBytes: 100, txBytes: 100000}, started, now.Add(-time.Second * 90), 100, false, true},
+		{"keepalive off: idle tunnel (no handshake)", peerStats{}, started, now, 0, true, false},
+		{"keepalive off: idle tunnel (old handshake)", peerStats{lastHandshake: now.Add(-time.Minute * 10), rxBytes: 100, txBytes: 100}, started, now.Add(-time.Minute * 10), 100, true, false},
+		{"keepalive off: sending but nothing received", peerStats{lastHandshake: now.Add(-time.Minute * 4), rxBytes: 100, txBytes: 100000}, started, now.Add(-time.Second * 90), 100, true, true},
+	}
+
+	for _, tc := range tests {
+		err := checkStall(tc.stats, now, tc.started, tc.lastRxTime, tc.txOnLastRx, tc.isKeepaliveOff)
+		if (err != nil) != tc.isStalled {
+			t.Errorf("%s: unexpected result: %v", tc.name, err)
+		}
+	}
+}
+
+func TestParseWgDump(t *testing.T) {
+	const peerKey = "mTrcCv2Tu8w4Mzmwy0KPBhAy9ysNNrSzdjYmBHMlsn4="
+	dump := "cIyZ2uUjnVRqH5bZ3VUyu0ELhbVApYuP3wTfBcE3+lY=\tw2eTBy8Jgs0xW3lZBY5bEKfQ8HREJOrZv+ixdKWBMQs=\t51820\t0xca6c\n" +
+		"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\t(none)\t1.2.3.4:2049\t0.0.0.0/0\t0\t0\t0\toff\n" +
+		peerKey + "\t(none)\t185.102.219.26:2049\t0.0.0.0/0,::/0\t1650000000\t12345\t67890\t25\n"
+
+	stats, err := parseWgDump(dump, peerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.lastHandshake.Equal(time.Unix(1650000000, 0)) || stats.rxBytes != 12345 || stats.txBytes != 67890 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	if _, err := parseWgDump(dump, "unknown"); err == nil {
+		t.Fatal("error expected for unknown peer")
+	}
+}
+
+func TestStalledReconnectionError(t *testing.T) {
+	reason := fmt.Errorf("no handshake with the server")
+	err := newStalledReconnectionError(reason)
+
+	// the service must be able to recognize both: the reconnection request and the stall (for failover)
+	var reconnectErr *vpn.ReconnectionRequiredError
+	if !errors.As(err, &reconnectErr) {
+		t.Fatal("ReconnectionRequiredError expected")
+	}
+	var stalledErr *TunnelStalledError
+	if !errors.As(err, &stalledErr) || !errors.Is(err, reason) {
+		t.Fatal("TunnelStalledError with the stall reason expected")
+	}
+}
+
+func TestStallState(t *testing.T) {
+	var s stallState
+	if s.pop() != nil {
+		t.Fatal("empty state expected")
+	}
+
+	// set from the watchdog routine; read from the connection routine
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.set(fmt.Errorf("stalled"))
+	}()
+	wg.Wait()
+
+	if s.pop() == nil {
+		t.Fatal("stall reason expected")
+	}
+	if s.pop() != nil {
+		t.Fatal("the reason must be reset after pop()")
+	}
+}
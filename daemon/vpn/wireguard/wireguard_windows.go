@@ -61,6 +61,7 @@ type internalVariables struct {
 	pauseRequireChan      chan operation  // control connection pause\resume or disconnect from paused state
 	isDisconnectRequested bool
 	isPaused              bool
+	stallState            stallState // the reason of the tunnel stop by watchdog (tunnel stalled)
 }
 
 const (
@@ -132,6 +133,20 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 
 	wg.internals.pauseRequireChan = make(chan operation, 1)
 
+	// tunnel health monitor
+	// (it must be restarted each time the WireGuard service re-installed: on resume or on restart)
+	startWatchdog := func() *watchdog {
+		return wg.startWatchdog(stateChan,
+			func() (peerStats, error) { return wg.getPeerStatsByTool(wg.getTunnelName()) },
+			func(reason error) { wg.internals.stallState.set(reason) })
+	}
+	wd := startWatchdog()
+	defer func() { wd.Stop() }()
+
+	// post-quantum preshared key rotation (if enabled)
+	stopPskRotation := wg.startPresharedKeyRotation(func(psk string) error { return wg.setPresharedKeyByTool(wg.getTunnelName(), psk) })
+	defer stopPskRotation()
+
 	// this method is synchronous. Waiting until service stop
 	// (periodically checking of service status)
 	// TODO: Probably we should avoid checking the service state in a loop (with constant delay). Think about it.
@@ -159,6 +174,7 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 
 				log.Info("Pausing...")
 
+				wd.Stop()
 				if err := wg.uninstallService(); err != nil {
 					log.Error("failed to pause connection (disconnection error):", err.Error())
 					return err
@@ -188,6 +204,7 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 
 					// reconnected successfully
 					wg.internals.isPaused = false
+					wd = startWatchdog()
 					log.Info("Resumed")
 					break
 				}
@@ -204,6 +221,7 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 			stateChan <- vpn.NewStateInfo(vpn.RECONNECTING, "Reconnecting with new connection parameters")
 
 			log.Info("Restarting...")
+			wd.Stop()
 			if err := wg.uninstallService(); err != nil {
 				log.Error("failed to restart connection (disconnection error):", err.Error())
 			} else {
@@ -214,6 +232,15 @@ func (wg *WireGuard) connect(stateChan chan<- vpn.StateInfo) error {
 					log.Info("Connection restarted")
 				}
 			}
+			wd = startWatchdog()
+		}
+
+		// the tunnel was stalled: request the service to reconnect
+		if stallReason := wg.internals.stallState.pop(); stallReason != nil {
+			if err := wg.uninstallService(); err != nil {
+				log.Error("failed to stop stalled connection:", err.Error())
+			}
+			return newStalledReconnectionError(stallReason)
 		}
 	}
 
@@ -335,7 +362,23 @@ func (wg *WireGuard) getOSSpecificConfigParams() (interfaceCfg []string, peerCfg
 	// We need to disable WireGuard-s firewall because we have our own implementation of firewall.
 	// For example, we have to control 'Allow LAN' functionality
 	//  For details, refer to WireGuard-windows sources: https://git.zx2c4.com/wireguard-windows/tree/tunnel/addressconfig.go (enableFirewall(...) method)
-	peerCfg = append(peerCfg, "AllowedIPs = 128.0.0.0/1, 0.0.0.0/1"+allowedIPsV6)
+	if wg.connectParams.isCustomConfig {
+		// custom configuration: the default routes are split in the same way (to disable WireGuard-s firewall)
+		var allowedIPs []string
+		for _, n := range wg.connectParams.getAllowedIPs() {
+			switch n.String() {
+			case "0.0.0.0/0":
+				allowedIPs = append(allowedIPs, "128.0.0.0/1", "0.0.0.0/1")
+			case "::/0":
+				allowedIPs = append(allowedIPs, "8000::/1", "::/1")
+			default:
+				allowedIPs = append(allowedIPs, n.String())
+			}
+		}
+		peerCfg = append(peerCfg, "AllowedIPs = "+strings.Join(allowedIPs, ", "))
+	} else {
+		peerCfg = append(peerCfg, "AllowedIPs = 128.0.0.0/1, 0.0.0.0/1"+allowedIPsV6)
+	}
 
 	return interfaceCfg, peerCfg
 }
@@ -575,3 +618,7 @@ func (wg *WireGuard) onRoutingChanged() error {
 	// do nothing for Windows
 	return nil
 }
+
+func isNativeConfigSupported() bool {
+	return false
+}
@@ -1399,7 +1399,7 @@ async function Disconnect() {
   // Here we just saving 'Resumed' state
   store.dispatch("vpnState/pauseState", PauseStateEnum.Resumed);
 
-  if (store.state.vpnState.connectionState === VpnStateEnum.CONNECTED)
+  if (store.getters["vpnState/isConnected"])
     store.commit("vpnState/connectionState", VpnStateEnum.DISCONNECTING);
   await sendRecv(
     {
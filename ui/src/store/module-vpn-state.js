@@ -275,7 +275,15 @@ export default {
       }
     },
     isConnected: (state) => {
-      return state.connectionState === VpnStateEnum.CONNECTED;
+      // the stalled (DEGRADED) or recovered WireGuard tunnel is still connected
+      switch (state.connectionState) {
+        case VpnStateEnum.CONNECTED:
+        case VpnStateEnum.DEGRADED:
+        case VpnStateEnum.RECOVERED:
+          return true;
+        default:
+          return false;
+      }
     },
     vpnStateText: (state) => {
       return enumValueName(VpnStateEnum, state.connectionState);
@@ -52,7 +52,9 @@ export const VpnStateEnum = Object.freeze({
   RECONNECTING: 8, // A restart has occurred.
   TCP_CONNECT: 9, // TCP_CONNECT
   EXITING: 10, // A graceful exit is in progress.
-  DISCONNECTING: 11,
+  DEGRADED: 11, // (WireGuard) Connected but the tunnel is stalled (no handshake or no incoming traffic)
+  RECOVERED: 12, // (WireGuard) The stalled tunnel is working again (connected)
+  DISCONNECTING: 13,
 });
 
 export const PingQuality = Object.freeze({ Good: 0, Moderate: 1, Bad: 2 });